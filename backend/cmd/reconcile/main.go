@@ -0,0 +1,63 @@
+// Command reconcile runs the payment reconciliation once and prints the
+// discrepancy report. The report is also stored, like scheduled runs.
+//
+//	go run ./cmd/reconcile [-fix]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"FreeConnect/internal/config"
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "fix safe discrepancies automatically")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	db, err := models.ConnectDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Database connection failed: %v", err)
+	}
+	if err := models.Migrate(db); err != nil {
+		log.Fatalf("AutoMigrate failed: %v", err)
+	}
+	gateway, err := payments.NewGateway(cfg.PaymentProvider)
+	if err != nil {
+		log.Fatalf("Payment gateway setup failed: %v", err)
+	}
+
+	transactions := services.NewTransactionService(repositories.NewTransactionRepository(db), gateway)
+	reconciliation := services.NewReconciliationService(repositories.NewReconciliationRepository(db), transactions, gateway)
+
+	run, err := reconciliation.Run("cli", *fix)
+	if run != nil {
+		fmt.Printf("Reconciliation run %d: %d discrepancies, %d fixed\n", run.ID, run.DiscrepancyCount, run.FixedCount)
+		for _, d := range run.Discrepancies {
+			status := ""
+			if d.FixedAt != nil {
+				status = " (fixed)"
+			} else if d.Fixable {
+				status = " (fixable)"
+			}
+			fmt.Printf("  %-28s %s #%d: expected %s, got %s%s\n", d.Kind, d.EntityType, d.EntityID, d.Expected, d.Actual, status)
+		}
+	}
+	if err != nil {
+		log.Printf("Reconciliation failed: %v", err)
+		os.Exit(1)
+	}
+	if run.DiscrepancyCount > run.FixedCount {
+		os.Exit(2)
+	}
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"FreeConnect/internal/config"
 	"FreeConnect/internal/controllers"
+	"FreeConnect/internal/documents"
+	"FreeConnect/internal/jobs"
+	"FreeConnect/internal/mail"
 	"FreeConnect/internal/middleware"
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
 	"FreeConnect/internal/repositories"
 	"FreeConnect/internal/services"
 
@@ -27,22 +33,55 @@ func main() {
 		log.Fatalf("Database connection failed: %v", err)
 	}
 
-	// 3) Auto-migrate models
-	if err := db.AutoMigrate(
-		&models.User{},
-		&models.Project{},
-		&models.Skill{},
-		&models.Proposal{},
-		&models.Review{},
-		&models.Transaction{},
-		&models.Task{},
-		&models.Notification{},
-		&models.Invoice{},
-	); err != nil {
+	// 3) Migrate data and auto-migrate models
+	if err := models.Migrate(db); err != nil {
 		log.Fatalf("AutoMigrate failed: %v", err)
 	}
 
-	// 4) Initialize repositories
+	// 4) Initialize the payment gateway, payout provider, mailer and invoice numbering and layout
+	paymentGateway, err := payments.NewGateway(cfg.PaymentProvider)
+	if err != nil {
+		log.Fatalf("Payment gateway setup failed: %v", err)
+	}
+	payoutProvider, err := payments.NewPayoutProvider(cfg.PayoutProvider)
+	if err != nil {
+		log.Fatalf("Payout provider setup failed: %v", err)
+	}
+	mailer, err := mail.NewMailer(cfg.MailProvider, cfg.MailFrom, mail.SMTPConfig{
+		Addr:     cfg.SMTPAddr,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+	})
+	if err != nil {
+		log.Fatalf("Mailer setup failed: %v", err)
+	}
+	payoutMinimum, err := money.Parse(cfg.PayoutMinimum, money.DefaultCurrency)
+	if err != nil {
+		log.Fatalf("Invalid PAYOUT_MINIMUM: %v", err)
+	}
+
+	invoiceNumbering := services.InvoiceNumbering{Format: cfg.InvoiceNumberFormat, CreditNoteFormat: cfg.CreditNoteNumberFormat}
+	if invoiceNumbering.Format == "" {
+		invoiceNumbering.Format = services.DefaultInvoiceNumberFormat
+	}
+	if invoiceNumbering.CreditNoteFormat == "" {
+		invoiceNumbering.CreditNoteFormat = services.DefaultCreditNoteNumberFormat
+	}
+	if err := invoiceNumbering.Validate(); err != nil {
+		log.Fatalf("Invalid INVOICE_NUMBER_FORMAT or CREDIT_NOTE_NUMBER_FORMAT: %v", err)
+	}
+
+	invoicePDF, err := documents.NewInvoiceTemplate(documents.Branding{
+		Name:         cfg.BrandName,
+		Color:        cfg.BrandColor,
+		Footer:       cfg.InvoiceFooter,
+		PaymentTerms: cfg.InvoicePaymentTerms,
+	})
+	if err != nil {
+		log.Fatalf("Invalid invoice branding: %v", err)
+	}
+
+	// 5) Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
 	projectRepo := repositories.NewProjectRepository(db)
 	skillRepo := repositories.NewSkillRepository(db)
@@ -52,19 +91,51 @@ func main() {
 	taskRepo := repositories.NewTaskRepository(db)
 	notificationRepo := repositories.NewNotificationRepository(db)
 	invoiceRepo := repositories.NewInvoiceRepository(db)
+	exchangeRateRepo := repositories.NewExchangeRateRepository(db)
+	webhookRepo := repositories.NewWebhookRepository(db)
+	idempotencyRepo := repositories.NewIdempotencyRepository(db)
+	feeRuleRepo := repositories.NewFeeRuleRepository(db)
+	payoutRepo := repositories.NewPayoutRepository(db)
+	reconciliationRepo := repositories.NewReconciliationRepository(db)
+	taxRepo := repositories.NewTaxRepository(db)
+	invoiceReminderRepo := repositories.NewInvoiceReminderRepository(db)
+	invoiceScheduleRepo := repositories.NewInvoiceScheduleRepository(db)
+	invoiceLinkRepo := repositories.NewInvoiceLinkRepository(db)
+	invoiceAllocationRepo := repositories.NewInvoiceAllocationRepository(db)
+	bidCreditRepo := repositories.NewBidCreditRepository(db)
+	projectInvitationRepo := repositories.NewProjectInvitationRepository(db)
+	proposalScoringRepo := repositories.NewProposalScoringRepository(db)
 
-	// 5) Initialize services
+	// 6) Initialize services
 	userService := services.NewUserService(userRepo)
 	projectService := services.NewProjectService(projectRepo)
 	skillService := services.NewSkillService(skillRepo)
 	proposalService := services.NewProposalService(proposalRepo)
 	reviewService := services.NewReviewService(reviewRepo)
-	transactionService := services.NewTransactionService(transactionRepo)
+	transactionService := services.NewTransactionService(transactionRepo, paymentGateway)
 	taskService := services.NewTaskService(taskRepo)
 	notificationService := services.NewNotificationService(notificationRepo)
-	invoiceService := services.NewInvoiceService(invoiceRepo)
+	invoiceService := services.NewInvoiceService(invoiceRepo, invoiceNumbering)
+	exchangeRateService := services.NewExchangeRateService(exchangeRateRepo)
+	feeService := services.NewFeeService(feeRuleRepo, transactionRepo, exchangeRateService)
+	payoutService := services.NewPayoutService(payoutRepo, payoutProvider, services.PayoutPolicy{
+		Minimum:        payoutMinimum,
+		NewAccountHold: cfg.PayoutHold,
+	})
+	reconciliationService := services.NewReconciliationService(reconciliationRepo, transactionService, paymentGateway)
+	reportService := services.NewReportService(transactionRepo, exchangeRateService)
+	webhookService := services.NewWebhookService(webhookRepo, transactionService, cfg.PaymentWebhookSecret)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo)
+	taxService := services.NewTaxService(taxRepo, invoiceRepo, exchangeRateService)
+	invoiceReminderService := services.NewInvoiceReminderService(invoiceReminderRepo, mailer)
+	invoiceScheduleService := services.NewInvoiceScheduleService(invoiceScheduleRepo, invoiceNumbering, mailer)
+	invoiceLinkService := services.NewInvoiceLinkService(invoiceLinkRepo, invoiceService, paymentGateway, cfg.InvoiceLinkSecret)
+	invoiceSettlementService := services.NewInvoiceSettlementService(invoiceAllocationRepo)
+	bidCreditService := services.NewBidCreditService(bidCreditRepo, paymentGateway)
+	projectInvitationService := services.NewProjectInvitationService(projectInvitationRepo)
+	proposalScoringService := services.NewProposalScoringService(proposalScoringRepo)
 
-	// 6) Initialize controllers
+	// 7) Initialize controllers
 	userController := controllers.NewUserController(userService)
 	projectController := controllers.NewProjectController(projectService)
 	skillController := controllers.NewSkillController(skillService)
@@ -73,7 +144,21 @@ func main() {
 	transactionController := controllers.NewTransactionController(transactionService)
 	taskController := controllers.NewTaskController(taskService)
 	notificationController := controllers.NewNotificationController(notificationService)
-	invoiceController := controllers.NewInvoiceController(invoiceService)
+	invoiceController := controllers.NewInvoiceController(invoiceService, invoicePDF)
+	exchangeRateController := controllers.NewExchangeRateController(exchangeRateService)
+	reportController := controllers.NewReportController(reportService)
+	webhookController := controllers.NewWebhookController(webhookService)
+	feeController := controllers.NewFeeController(feeService)
+	payoutController := controllers.NewPayoutController(payoutService)
+	reconciliationController := controllers.NewReconciliationController(reconciliationService)
+	taxController := controllers.NewTaxController(taxService)
+	reminderController := controllers.NewReminderController(invoiceReminderService, invoiceService)
+	invoiceScheduleController := controllers.NewInvoiceScheduleController(invoiceScheduleService, projectService)
+	invoiceLinkController := controllers.NewInvoiceLinkController(invoiceLinkService, invoiceService, invoicePDF)
+	invoiceSettlementController := controllers.NewInvoiceSettlementController(invoiceSettlementService, invoiceService)
+	bidCreditController := controllers.NewBidCreditController(bidCreditService)
+	projectInvitationController := controllers.NewProjectInvitationController(projectInvitationService, projectService)
+	proposalScoringController := controllers.NewProposalScoringController(proposalScoringService, projectService)
 
 	// Auth & Admin controllers
 	jwtService := services.NewJWTService()
@@ -83,12 +168,45 @@ func main() {
 	// Real-time SSE controller
 	rtc := controllers.NewRealTimeController()
 
-	// 7) Setup Gin + CORS
+	// 8) Start background jobs
+	scheduler := jobs.NewScheduler()
+	scheduler.Every("payout-batch", cfg.PayoutBatchInterval, func() error {
+		result, err := payoutService.RunBatch()
+		if err == nil && result.Paid+result.Failed > 0 {
+			log.Printf("payout batch: %d paid, %d failed", result.Paid, result.Failed)
+		}
+		return err
+	})
+	scheduler.Every("reconciliation", cfg.ReconciliationInterval, func() error {
+		run, err := reconciliationService.Run("schedule", cfg.ReconciliationAutoFix)
+		if err == nil && run.DiscrepancyCount > 0 {
+			log.Printf("reconciliation run %d: %d discrepancies, %d fixed", run.ID, run.DiscrepancyCount, run.FixedCount)
+		}
+		return err
+	})
+	scheduler.Every("invoice-reminders", cfg.ReminderInterval, func() error {
+		result, err := invoiceReminderService.Run(time.Now())
+		if err == nil && result.MarkedOverdue+result.LateFees+result.Sent > 0 {
+			log.Printf("invoice reminders: %d marked overdue, %d late fees, %d reminders sent", result.MarkedOverdue, result.LateFees, result.Sent)
+		}
+		return err
+	})
+	scheduler.Every("recurring-invoices", cfg.RecurringInvoiceInterval, func() error {
+		result, err := invoiceScheduleService.Run(time.Now())
+		if err == nil && result.Issued+result.Failed > 0 {
+			log.Printf("recurring invoices: %d issued, %d schedules failed", result.Issued, result.Failed)
+		}
+		return err
+	})
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	// 9) Setup Gin + CORS
 	router := gin.Default()
 	router.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", middleware.IdempotencyKeyHeader},
 		AllowCredentials: true,
 	}))
 
@@ -111,6 +229,14 @@ func main() {
 
 		// 4) Real-time SSE (optional if you want it public)
 		rtc.RegisterRoutes(public)
+
+		// 5) Payment provider callbacks (authenticated by HMAC signature, not JWT)
+		public.POST("/webhooks/payments/:provider", webhookController.ReceivePaymentWebhook)
+
+		// 6) Shared invoices (authenticated by the signed token in the URL)
+		public.GET("/public/invoices/:token", invoiceLinkController.ViewInvoice)
+		public.GET("/public/invoices/:token/pdf", invoiceLinkController.ViewInvoicePDF)
+		public.POST("/public/invoices/:token/pay", invoiceLinkController.PayInvoice)
 	}
 
 	//--------------------------------------------------------------------
@@ -120,6 +246,10 @@ func main() {
 	// so c.Get("userRole") and c.Get("userID") will be set if needed.
 	secure := router.Group("/api")
 	secure.Use(middleware.AuthMiddleware(jwtService))
+
+	// POSTs with payment side effects replay their first response when
+	// retried with the same Idempotency-Key
+	idempotent := middleware.IdempotencyMiddleware(idempotencyService)
 	{
 		// ---------------- ADMIN EXAMPLES ----------------
 		// (If you want a stricter admin-only approach, use RoleMiddleware("admin"))
@@ -129,11 +259,15 @@ func main() {
 		secure.GET("/users/:id", userController.GetUser)
 		secure.PUT("/users/:id", userController.UpdateUser)
 		secure.PUT("/users/:id/skills", userController.UpdateUserSkills)
+		secure.GET("/users/:id/tax-profile", taxController.GetTaxProfile)
+		secure.PUT("/users/:id/tax-profile", taxController.SaveTaxProfile)
+		secure.GET("/users/:id/credits", invoiceSettlementController.GetClientCredits)
 
 		// ---------------- PROJECTS ----------------
 		// NOTE: Now creation does NOT require a freelancer_id.
 		secure.POST("/projects", projectController.CreateProject)
 		secure.PUT("/projects/:projectId", projectController.UpdateProject)
+		secure.PUT("/projects/:projectId/skills", projectController.UpdateProjectSkills)
 		secure.DELETE("/projects/:id", projectController.DeleteProject)
 
 		// ADDITIONAL: route for setting the freelancer
@@ -146,7 +280,28 @@ func main() {
 		secure.GET("/projects/:id/proposals", proposalController.GetProposalsByProject)
 		secure.PUT("/proposals/:id", proposalController.UpdateProposal)
 		secure.DELETE("/proposals/:id", proposalController.DeleteProposal)
-		secure.POST("/proposals/:id/accept", proposalController.AcceptProposal)
+		secure.POST("/proposals/:id/accept", idempotent, proposalController.AcceptProposal)
+		secure.POST("/proposals/:id/counter-offers", proposalController.CounterOffer)
+		secure.GET("/proposals/:id/revisions", proposalController.GetRevisions)
+		secure.POST("/proposals/:id/shortlist", proposalController.ShortlistProposal)
+		secure.POST("/proposals/:id/reject", proposalController.RejectProposal)
+		secure.POST("/proposals/:id/withdraw", proposalController.WithdrawProposal)
+		secure.POST("/proposals/:id/submit", proposalController.SubmitProposal)
+		secure.GET("/projects/:id/proposals/scores", proposalScoringController.RankProposals)
+		secure.GET("/projects/:id/proposals/compare", proposalScoringController.CompareProposals)
+
+		// ---------------- INVITATIONS ----------------
+		secure.POST("/projects/:id/invitations", projectInvitationController.Invite)
+		secure.GET("/projects/:id/invitations", projectInvitationController.GetProjectInvitations)
+		secure.GET("/users/:id/invitations", projectInvitationController.GetFreelancerInvitations)
+		secure.POST("/invitations/:id/accept", projectInvitationController.AcceptInvitation)
+		secure.POST("/invitations/:id/decline", projectInvitationController.DeclineInvitation)
+
+		// ---------------- BID CREDITS ----------------
+		secure.GET("/users/:id/bid-credits", bidCreditController.GetBalance)
+		secure.GET("/users/:id/bid-credits/ledger", bidCreditController.GetLedger)
+		secure.POST("/bid-credits/top-ups", idempotent, bidCreditController.BuyCredits)
+		secure.GET("/projects/:id/bid-cost", bidCreditController.GetProposalCost)
 
 		// ---------------- REVIEWS ----------------
 		secure.POST("/reviews", reviewController.CreateReview)
@@ -156,10 +311,16 @@ func main() {
 		secure.DELETE("/reviews/:id", reviewController.DeleteReview)
 
 		// ---------------- TRANSACTIONS ----------------
-		secure.POST("/transactions", transactionController.CreateTransaction)
+		secure.POST("/transactions", idempotent, transactionController.CreateTransaction)
 		secure.GET("/transactions/:id", transactionController.GetTransaction)
 		secure.GET("/projects/:id/transactions", transactionController.GetTransactionsByProject)
 		secure.PUT("/transactions/:id", transactionController.UpdateTransaction)
+		secure.POST("/transactions/:id/capture", transactionController.CaptureTransaction)
+		secure.POST("/transactions/:id/sync", transactionController.SyncTransactionStatus)
+		secure.POST("/transactions/:id/retry", idempotent, transactionController.RetryTransaction)
+		secure.GET("/transactions/:id/history", transactionController.GetStatusHistory)
+		secure.POST("/transactions/:id/refunds", idempotent, transactionController.RefundTransaction)
+		secure.GET("/transactions/:id/refunds", transactionController.GetRefunds)
 		secure.DELETE("/transactions/:id", transactionController.DeleteTransaction)
 
 		// ---------------- TASKS ----------------
@@ -177,11 +338,92 @@ func main() {
 		secure.DELETE("/notifications/:id", notificationController.DeleteNotification)
 
 		// ---------------- INVOICES ----------------
-		secure.POST("/invoices", invoiceController.CreateInvoice)
+		secure.POST("/invoices", idempotent, invoiceController.CreateInvoice)
 		secure.GET("/invoices/:id", invoiceController.GetInvoice)
+		secure.GET("/invoices/:id/pdf", invoiceController.GetInvoicePDF)
+		secure.GET("/invoices/:id/ubl", invoiceController.GetInvoiceUBL)
 		secure.GET("/projects/:id/invoices", invoiceController.GetInvoicesByProject)
+		secure.POST("/projects/:id/invoices/generate", idempotent, invoiceController.GenerateInvoice)
 		secure.PUT("/invoices/:id", invoiceController.UpdateInvoice)
+		secure.POST("/invoices/:id/finalise", idempotent, invoiceController.FinaliseInvoice)
+		secure.POST("/invoices/:id/void", invoiceController.VoidInvoice)
+		secure.POST("/invoices/:id/credit-notes", idempotent, invoiceController.CreateCreditNote)
+		secure.GET("/invoices/:id/credit-notes", invoiceController.GetCreditNotes)
+		secure.GET("/invoices/:id/reminders", reminderController.GetInvoiceReminders)
+		secure.POST("/invoices/:id/links", idempotent, invoiceLinkController.CreateLink)
+		secure.GET("/invoices/:id/links", invoiceLinkController.GetLinks)
+		secure.DELETE("/invoices/:id/links/:linkId", invoiceLinkController.RevokeLink)
+		secure.GET("/invoices/:id/payments", invoiceLinkController.GetPayments)
+		secure.GET("/invoices/:id/balance", invoiceSettlementController.GetInvoiceBalance)
+		secure.POST("/invoices/:id/apply-credit", idempotent, invoiceSettlementController.ApplyCredit)
+		secure.POST("/projects/:id/invoice-schedules", idempotent, invoiceScheduleController.CreateSchedule)
+		secure.GET("/projects/:id/invoice-schedules", invoiceScheduleController.GetProjectSchedules)
+		secure.GET("/invoice-schedules/:id/invoices", invoiceScheduleController.GetScheduleInvoices)
+		secure.POST("/invoice-schedules/:id/pause", invoiceScheduleController.PauseSchedule)
+		secure.POST("/invoice-schedules/:id/resume", invoiceScheduleController.ResumeSchedule)
+		secure.POST("/invoice-schedules/:id/cancel", invoiceScheduleController.CancelSchedule)
 		secure.DELETE("/invoices/:id", invoiceController.DeleteInvoice)
+
+		// ---------------- PAYOUTS ----------------
+		secure.GET("/payout-methods", payoutController.ListPayoutMethods)
+		secure.POST("/payout-methods", payoutController.AddPayoutMethod)
+		secure.DELETE("/payout-methods/:id", payoutController.DeletePayoutMethod)
+		secure.GET("/payouts/balance", payoutController.GetPayoutBalance)
+		secure.GET("/withdrawals", payoutController.ListMyWithdrawals)
+		secure.POST("/withdrawals", idempotent, payoutController.RequestWithdrawal)
+	}
+
+	//--------------------------------------------------------------------
+	// ADMIN ROUTES (Require a valid JWT with the admin role)
+	//--------------------------------------------------------------------
+	admin := router.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(jwtService), middleware.RoleMiddleware("admin"))
+	{
+		// ---------------- EXCHANGE RATES ----------------
+		admin.GET("/exchange-rates", exchangeRateController.ListRates)
+		admin.POST("/exchange-rates/import", exchangeRateController.ImportRates)
+
+		// ---------------- REPORTS ----------------
+		admin.GET("/reports/transactions", reportController.GetTransactionVolume)
+		admin.GET("/reports/revenue", reportController.GetPlatformRevenue)
+		admin.GET("/reports/tax", taxController.GetTaxSummary)
+
+		// ---------------- TAXES ----------------
+		admin.GET("/tax-rules", taxController.ListTaxRules)
+		admin.PUT("/tax-rules/:country", taxController.SaveTaxRule)
+
+		// ---------------- INVOICE REMINDERS ----------------
+		admin.GET("/users/:id/billing-terms", reminderController.GetBillingTerms)
+		admin.PUT("/users/:id/billing-terms", reminderController.SaveBillingTerms)
+		admin.POST("/invoices/reminders/run", reminderController.RunReminders)
+		admin.GET("/invoices/export", invoiceController.ExportInvoicesUBL)
+		admin.POST("/invoice-schedules/run", invoiceScheduleController.RunSchedules)
+
+		// ---------------- FEES ----------------
+		admin.GET("/fee-rules", feeController.ListFeeRules)
+		admin.POST("/fee-rules", feeController.CreateFeeRule)
+		admin.PUT("/fee-rules/:id", feeController.UpdateFeeRule)
+		admin.DELETE("/fee-rules/:id", feeController.DeleteFeeRule)
+
+		// ---------------- CHARGEBACKS ----------------
+		admin.GET("/chargebacks", transactionController.ListChargebacks)
+		admin.POST("/transactions/:id/chargebacks", transactionController.RecordChargeback)
+
+		// ---------------- PAYOUTS ----------------
+		admin.GET("/withdrawals", payoutController.ListWithdrawals)
+		admin.PUT("/withdrawals/:id/approve", payoutController.ApproveWithdrawal)
+		admin.PUT("/withdrawals/:id/reject", payoutController.RejectWithdrawal)
+		admin.POST("/payouts/run", payoutController.RunPayoutBatch)
+
+		// ---------------- RECONCILIATION ----------------
+		admin.POST("/reconciliation/run", reconciliationController.RunReconciliation)
+		admin.GET("/reconciliation/runs", reconciliationController.ListRuns)
+		admin.GET("/reconciliation/runs/:id", reconciliationController.GetRun)
+		admin.POST("/reconciliation/discrepancies/:id/fix", reconciliationController.FixDiscrepancy)
+
+		// ---------------- WEBHOOKS ----------------
+		admin.GET("/webhooks/dead-letters", webhookController.ListDeadLetters)
+		admin.POST("/webhooks/events/:id/reprocess", webhookController.ReprocessEvent)
 	}
 
 	//--------------------------------------------------------------------
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +13,36 @@ import (
 type Config struct {
 	DB_DSN string // e.g. "host=localhost user=postgres password=root dbname=freeconnect sslmode=disable"
 	Port   string // e.g. "8080"
+
+	PaymentProvider      string // e.g. "fake" (default)
+	PaymentWebhookSecret string // HMAC secret shared with the payment provider
+
+	PayoutProvider      string        // e.g. "fake" (default)
+	PayoutMinimum       string        // minimum withdrawal in EUR, e.g. "50.00"
+	PayoutHold          time.Duration // how long new accounts wait before their first withdrawal
+	PayoutBatchInterval time.Duration // how often approved withdrawals are paid out; 0 disables the job
+
+	ReconciliationInterval time.Duration // how often the reconciliation job runs; 0 disables it
+	ReconciliationAutoFix  bool          // let scheduled runs fix safe discrepancies
+
+	InvoiceNumberFormat    string        // e.g. "FC-{YYYY}-{SEQ:6}"; see services.InvoiceNumbering
+	CreditNoteNumberFormat string        // e.g. "CN-{YYYY}-{SEQ:6}"; must differ from InvoiceNumberFormat
+	ReminderInterval       time.Duration // how often overdue invoices are flagged and reminders sent; 0 disables the job
+
+	RecurringInvoiceInterval time.Duration // how often recurring invoice schedules are run; 0 disables the job
+	InvoiceLinkSecret        string        // HMAC secret signing public invoice links
+
+	MailProvider string // e.g. "log" (default) or "smtp"
+	MailFrom     string // sender address of outgoing e-mail
+	SMTPAddr     string // host:port of the SMTP relay
+	SMTPUsername string
+	SMTPPassword string
+
+	// Branding of rendered documents; empty values use documents.DefaultBranding
+	BrandName           string
+	BrandColor          string // #RRGGBB
+	InvoiceFooter       string // text/template, see documents.TemplateData
+	InvoicePaymentTerms string // text/template, see documents.TemplateData
 }
 
 func LoadConfig() (*Config, error) {
@@ -31,13 +63,104 @@ func LoadConfig() (*Config, error) {
 	if port == "" {
 		port = "8080"
 	}
+	// Webhook signing secret; the fallback is only suitable for local dev
+	webhookSecret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if webhookSecret == "" {
+		webhookSecret = "ChangeThisWebhookSecretInProduction"
+	}
+	// Signs public invoice links; changing it invalidates every link issued so far
+	invoiceLinkSecret := os.Getenv("INVOICE_LINK_SECRET")
+	if invoiceLinkSecret == "" {
+		invoiceLinkSecret = "ChangeThisInvoiceLinkSecretInProduction"
+	}
+
+	// Payout limits and schedule
+	payoutMinimum := os.Getenv("PAYOUT_MINIMUM")
+	if payoutMinimum == "" {
+		payoutMinimum = "50.00"
+	}
+	holdDays := 14
+	if v := os.Getenv("PAYOUT_HOLD_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("invalid PAYOUT_HOLD_DAYS %q", v)
+		}
+		holdDays = days
+	}
+	batchInterval, err := durationEnv("PAYOUT_BATCH_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliationInterval, err := durationEnv("RECONCILIATION_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	autoFix := false
+	if v := os.Getenv("RECONCILIATION_AUTOFIX"); v != "" {
+		if autoFix, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid RECONCILIATION_AUTOFIX %q", v)
+		}
+	}
+
+	reminderInterval, err := durationEnv("INVOICE_REMINDER_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	recurringInvoiceInterval, err := durationEnv("RECURRING_INVOICE_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		DB_DSN: dsn,
-		Port:   port,
+		DB_DSN:               dsn,
+		Port:                 port,
+		PaymentProvider:      os.Getenv("PAYMENT_PROVIDER"),
+		PaymentWebhookSecret: webhookSecret,
+		PayoutProvider:       os.Getenv("PAYOUT_PROVIDER"),
+		PayoutMinimum:        payoutMinimum,
+		PayoutHold:           time.Duration(holdDays) * 24 * time.Hour,
+		PayoutBatchInterval:  batchInterval,
+
+		ReconciliationInterval: reconciliationInterval,
+		ReconciliationAutoFix:  autoFix,
+
+		InvoiceNumberFormat:    os.Getenv("INVOICE_NUMBER_FORMAT"),
+		CreditNoteNumberFormat: os.Getenv("CREDIT_NOTE_NUMBER_FORMAT"),
+		ReminderInterval:       reminderInterval,
+
+		RecurringInvoiceInterval: recurringInvoiceInterval,
+		InvoiceLinkSecret:        invoiceLinkSecret,
+
+		MailProvider: os.Getenv("MAIL_PROVIDER"),
+		MailFrom:     os.Getenv("MAIL_FROM"),
+		SMTPAddr:     os.Getenv("SMTP_ADDR"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+
+		BrandName:           os.Getenv("BRAND_NAME"),
+		BrandColor:          os.Getenv("BRAND_COLOR"),
+		InvoiceFooter:       os.Getenv("INVOICE_FOOTER"),
+		InvoicePaymentTerms: os.Getenv("INVOICE_PAYMENT_TERMS"),
 	}
 	return cfg, nil
 }
 
+// durationEnv reads a Go duration (e.g. "1h30m") from the environment,
+// falling back to def when unset.
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", key, v, err)
+	}
+	return d, nil
+}
+
 // LoadTestConfig is used only in tests to load TEST_DB_DSN from .env or environment
 func LoadTestConfig() (*Config, error) {
 	// Make sure this actually loads .env:
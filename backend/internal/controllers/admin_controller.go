@@ -4,6 +4,7 @@ import (
 	"net/http" // For HTTP status codes and response writing.
 	"strconv"  // For converting string parameters to integers.
 
+	"FreeConnect/internal/money"        // Exact money amounts.
 	"FreeConnect/internal/repositories" // Importing the repository layer to access the database.
 	"github.com/gin-gonic/gin"          // Gin framework for routing and HTTP handling.
 )
@@ -30,17 +31,18 @@ func (ac *AdminController) ListAllUsers(c *gin.Context) {
 
 	// Define an anonymous struct to hold the selected user fields.
 	var users []struct {
-		UserID   uint    `json:"user_id"`  // Unique user identifier.
-		Email    string  `json:"email"`    // User email address.
-		Name     string  `json:"name"`     // Full name.
-		Role     string  `json:"role"`     // Role: admin, client, or freelancer.
-		Bio      string  `json:"bio"`      // Short biography.
-		Earnings float64 `json:"earnings"` // Total earnings (if applicable).
+		UserID   uint        `json:"user_id"`                                           // Unique user identifier.
+		Email    string      `json:"email"`                                             // User email address.
+		Name     string      `json:"name"`                                              // Full name.
+		Role     string      `json:"role"`                                              // Role: admin, client, or freelancer.
+		Bio      string      `json:"bio"`                                               // Short biography.
+		Earnings money.Money `gorm:"embedded;embeddedPrefix:earnings_" json:"earnings"` // Total earnings (if applicable).
+		Flagged  bool        `json:"flagged"`                                           // Flagged for review (e.g. after a chargeback).
 	}
 
 	// Execute a raw SQL query to fetch the desired fields from the 'users' table.
 	// Note: You may choose to use GORM methods instead of raw SQL if you prefer.
-	if err := db.Raw(`SELECT user_id, email, name, role, bio, earnings FROM users`).Scan(&users).Error; err != nil {
+	if err := db.Raw(`SELECT user_id, email, name, role, bio, earnings_amount, earnings_currency, flagged FROM users`).Scan(&users).Error; err != nil {
 		// If an error occurs, respond with a 500 Internal Server Error and the error message.
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
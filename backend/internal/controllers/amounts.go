@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"net/http" // Provides HTTP status codes.
+
+	"FreeConnect/internal/money" // Exact money amounts.
+	"github.com/gin-gonic/gin"   // Gin framework for HTTP routing.
+)
+
+// positiveAmount writes a 400 response unless the amount is above zero and
+// reports whether it was. Money amounts are structs, so binding:"required"
+// can't reject a missing one, and a negative amount would pass it anyway.
+func positiveAmount(c *gin.Context, field string, m money.Money) bool {
+	if m.IsPositive() {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": field + " must be a positive amount"})
+	return false
+}
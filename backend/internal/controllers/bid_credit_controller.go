@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+
+	"FreeConnect/internal/services" // Contains the bid credit service.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// BidCreditController handles the bid credits freelancers spend on proposals:
+// their balance, the ledger of credit movements and buying top-ups.
+type BidCreditController struct {
+	creditService services.BidCreditService // Service layer for bid credits.
+}
+
+// NewBidCreditController creates a new BidCreditController with the given BidCreditService.
+func NewBidCreditController(cs services.BidCreditService) *BidCreditController {
+	return &BidCreditController{creditService: cs}
+}
+
+// GetBalance handles GET /api/users/:id/bid-credits.
+// A freelancer (or an admin) sees the credit balance, the monthly allowance
+// and the packs on sale.
+func (cc *BidCreditController) GetBalance(c *gin.Context) {
+	userID, ok := ownUserID(c)
+	if !ok {
+		return
+	}
+
+	balance, err := cc.creditService.GetBalance(userID)
+	if err != nil {
+		respondBidCreditError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bid_credits": balance})
+}
+
+// GetLedger handles GET /api/users/:id/bid-credits/ledger.
+// It lists every credit movement on the account, newest first.
+func (cc *BidCreditController) GetLedger(c *gin.Context) {
+	userID, ok := ownUserID(c)
+	if !ok {
+		return
+	}
+
+	entries, err := cc.creditService.GetLedger(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// BuyCredits handles POST /api/bid-credits/top-ups.
+// A freelancer buys one of the credit packs through the payment gateway.
+func (cc *BidCreditController) BuyCredits(c *gin.Context) {
+	if c.GetString("userRole") != "freelancer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only freelancers can buy bid credits"})
+		return
+	}
+
+	var payload struct {
+		Pack          string `json:"pack" binding:"required"`                                                  // Name of the pack, see the balance.
+		PaymentMethod string `json:"payment_method" binding:"required,oneof=credit_card paypal bank_transfer"` // How the pack is paid.
+		PaymentToken  string `json:"payment_token"`                                                            // Token from the provider's checkout.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	purchase, err := cc.creditService.BuyCredits(c.GetUint("userID"), payload.Pack, payload.PaymentMethod, payload.PaymentToken)
+	if err != nil {
+		respondBidCreditError(c, err)
+		return
+	}
+	switch purchase.Status {
+	case "failed":
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "Payment was declined", "purchase": purchase})
+	case "processing":
+		c.JSON(http.StatusAccepted, gin.H{"purchase": purchase})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"purchase": purchase})
+	}
+}
+
+// GetProposalCost handles GET /api/projects/:id/bid-cost.
+// It tells a freelancer how many credits a proposal on the project costs.
+func (cc *BidCreditController) GetProposalCost(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	cost, err := cc.creditService.GetProposalCost(uint(projectID))
+	if err != nil {
+		respondBidCreditError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"project_id": projectID, "credits": cost})
+}
+
+// ownUserID parses the user ID from the URL and checks that it is the caller
+// or that the caller is an admin. It writes the error response when it fails.
+func ownUserID(c *gin.Context) (uint, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	if uint(userID) != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own bid credits"})
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// respondBidCreditError maps bid credit service errors to HTTP responses.
+func respondBidCreditError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, services.ErrUnknownCreditPack):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoExchangeRate):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
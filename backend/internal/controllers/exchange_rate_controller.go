@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"io"       // For reading the raw CSV body.
+	"net/http" // Provides HTTP status codes.
+
+	"FreeConnect/internal/services" // Business logic layer for exchange rates.
+	"github.com/gin-gonic/gin"      // Gin framework for routing.
+)
+
+// ExchangeRateController handles the admin endpoints for currency exchange rates.
+type ExchangeRateController struct {
+	rateService services.ExchangeRateService // Service to import and look up rates.
+}
+
+// NewExchangeRateController constructs a new ExchangeRateController by injecting the ExchangeRateService.
+func NewExchangeRateController(rs services.ExchangeRateService) *ExchangeRateController {
+	return &ExchangeRateController{rateService: rs}
+}
+
+// ImportRates handles POST /api/admin/exchange-rates/import.
+// It accepts either a multipart upload in the "file" field or a raw text/csv body
+// with the header base_currency,quote_currency,rate,effective_date.
+func (ec *ExchangeRateController) ImportRates(c *gin.Context) {
+	// Prefer an uploaded file; fall back to the request body.
+	var reader io.Reader = c.Request.Body
+	source := "csv-import"
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		reader = file
+		source = fileHeader.Filename
+	}
+
+	// Validate and store all rows in one go.
+	imported, err := ec.rateService.ImportCSV(reader, source)
+	if err != nil {
+		// Return HTTP 400: import errors are almost always bad input.
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Report how many rates were stored.
+	c.JSON(http.StatusOK, gin.H{"imported": imported})
+}
+
+// ListRates handles GET /api/admin/exchange-rates.
+// It returns all stored rates, newest first per currency pair.
+func (ec *ExchangeRateController) ListRates(c *gin.Context) {
+	rates, err := ec.rateService.GetAllRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exchange_rates": rates})
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+
+	"FreeConnect/internal/models"   // Contains the FeeRule model.
+	"FreeConnect/internal/money"    // Exact money amounts.
+	"FreeConnect/internal/services" // Contains the FeeService.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+)
+
+// FeeController handles the admin endpoints for platform fee rules.
+type FeeController struct {
+	feeService services.FeeService // Service layer for fee rules.
+}
+
+// NewFeeController creates a new FeeController with the given FeeService.
+func NewFeeController(fs services.FeeService) *FeeController {
+	return &FeeController{feeService: fs}
+}
+
+// feeRulePayload is the JSON body accepted when creating or updating a fee rule.
+type feeRulePayload struct {
+	Name       string           `json:"name" binding:"required"` // Display name, shown on the fee ledger line.
+	Type       string           `json:"type" binding:"required"` // percentage, flat or tiered.
+	Category   string           `json:"category"`                // Project category; empty applies to all.
+	Percent    int64            `json:"percent"`                 // Basis points for percentage rules (1000 = 10%).
+	FlatAmount money.Money      `json:"flat_amount"`             // Amount for flat rules.
+	Tiers      []models.FeeTier `json:"tiers"`                   // Thresholds for tiered rules.
+	Active     *bool            `json:"active"`                  // Defaults to true.
+}
+
+// apply copies the payload onto a fee rule.
+func (p feeRulePayload) apply(rule *models.FeeRule) {
+	rule.Name = p.Name
+	rule.Type = p.Type
+	rule.Category = p.Category
+	rule.Percent = p.Percent
+	rule.FlatAmount = p.FlatAmount
+	rule.Tiers = p.Tiers
+	for i := range rule.Tiers {
+		rule.Tiers[i].ID = 0 // tiers are always replaced as a whole
+	}
+	rule.Active = p.Active == nil || *p.Active
+}
+
+// ListFeeRules handles GET /api/admin/fee-rules.
+// It lists all fee rules with their tiers.
+func (fc *FeeController) ListFeeRules(c *gin.Context) {
+	rules, err := fc.feeService.GetRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"fee_rules": rules})
+}
+
+// CreateFeeRule handles POST /api/admin/fee-rules.
+// It creates a new fee rule from the JSON payload.
+func (fc *FeeController) CreateFeeRule(c *gin.Context) {
+	// Bind the JSON payload.
+	var payload feeRulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rule models.FeeRule
+	payload.apply(&rule)
+
+	// Validate and store through the FeeService.
+	if err := fc.feeService.CreateRule(&rule); err != nil {
+		respondFeeRuleError(c, err)
+		return
+	}
+
+	// Respond with 201 Created and the new rule.
+	c.JSON(http.StatusCreated, gin.H{"fee_rule": rule})
+}
+
+// UpdateFeeRule handles PUT /api/admin/fee-rules/:id.
+// It replaces the rule's settings, including its tiers. Fees already charged are not affected.
+func (fc *FeeController) UpdateFeeRule(c *gin.Context) {
+	// Extract the rule ID from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fee rule ID"})
+		return
+	}
+
+	// Retrieve the current rule.
+	rule, err := fc.feeService.GetRuleByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fee rule not found"})
+		return
+	}
+
+	// Bind the JSON payload.
+	var payload feeRulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	payload.apply(rule)
+
+	// Validate and store through the FeeService.
+	if err := fc.feeService.UpdateRule(rule); err != nil {
+		respondFeeRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"fee_rule": rule})
+}
+
+// DeleteFeeRule handles DELETE /api/admin/fee-rules/:id.
+// Fee lines already charged keep their amounts; only the link to the rule is cleared.
+func (fc *FeeController) DeleteFeeRule(c *gin.Context) {
+	// Extract the rule ID from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fee rule ID"})
+		return
+	}
+
+	if err := fc.feeService.DeleteRule(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Fee rule deleted successfully"})
+}
+
+// respondFeeRuleError maps fee rule validation errors to 400 and anything else to 500.
+func respondFeeRuleError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidFeeRule) || errors.Is(err, services.ErrUnsupportedCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
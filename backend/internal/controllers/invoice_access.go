@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+
+	"FreeConnect/internal/models"   // Contains the Invoice model.
+	"FreeConnect/internal/services" // Contains the invoice service.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+)
+
+// The access rules for invoices. Every invoice endpoint checks them through
+// issuerInvoice, partyInvoice or isInvoiceParty so they can't drift apart.
+
+// isInvoiceIssuer reports whether the caller issued the invoice or is an admin.
+func isInvoiceIssuer(c *gin.Context, invoice *models.Invoice) bool {
+	isIssuer := invoice.IssuerID != nil && *invoice.IssuerID == c.GetUint("userID")
+	return isIssuer || c.GetString("userRole") == "admin"
+}
+
+// isInvoiceParty reports whether the caller is the invoice's issuer, its
+// client or an admin.
+func isInvoiceParty(c *gin.Context, invoice *models.Invoice) bool {
+	return isInvoiceIssuer(c, invoice) || invoice.ClientID == c.GetUint("userID")
+}
+
+// issuerInvoice loads the invoice from the URL and checks that the caller is
+// its issuer or an admin. It writes the error response when it fails.
+func issuerInvoice(c *gin.Context, is services.InvoiceService) (*models.Invoice, bool) {
+	invoice, ok := urlInvoice(c, is)
+	if ok && !isInvoiceIssuer(c, invoice) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the issuer can do this"})
+		return nil, false
+	}
+	return invoice, ok
+}
+
+// partyInvoice loads the invoice from the URL and checks that the caller is
+// its issuer, its client or an admin. It writes the error response when it fails.
+func partyInvoice(c *gin.Context, is services.InvoiceService) (*models.Invoice, bool) {
+	invoice, ok := urlInvoice(c, is)
+	if ok && !isInvoiceParty(c, invoice) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't view this invoice"})
+		return nil, false
+	}
+	return invoice, ok
+}
+
+// urlInvoice loads the invoice named by the :id URL parameter. It writes the
+// error response when it fails.
+func urlInvoice(c *gin.Context, is services.InvoiceService) (*models.Invoice, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return nil, false
+	}
+	invoice, err := is.GetInvoiceByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return nil, false
+	}
+	return invoice, true
+}
@@ -1,37 +1,69 @@
 package controllers
 
 import (
+	"bytes"    // Buffers the export archive.
+	"errors"   // For matching service errors.
+	"fmt"      // For building download file names.
 	"net/http" // Provides HTTP status codes.
 	"strconv"  // Used for string-to-int conversion.
 	"time"     // Used for parsing date/time strings.
 
-	"FreeConnect/internal/models"   // Database models.
-	"FreeConnect/internal/services" // Business logic layer for invoices.
-	"github.com/gin-gonic/gin"      // Gin framework for routing.
+	"FreeConnect/internal/documents" // Invoice PDF and UBL rendering.
+	"FreeConnect/internal/models"    // Database models.
+	"FreeConnect/internal/money"     // Exact money amounts.
+	"FreeConnect/internal/services"  // Business logic layer for invoices.
+	"github.com/gin-gonic/gin"       // Gin framework for routing.
+	"gorm.io/gorm"                   // For detecting missing records.
 )
 
 // InvoiceController handles endpoints related to invoices.
 type InvoiceController struct {
-	invoiceService services.InvoiceService // Service to manage invoices.
+	invoiceService services.InvoiceService    // Service to manage invoices.
+	pdfTemplate    *documents.InvoiceTemplate // Branded layout for invoice PDFs.
 }
 
-// NewInvoiceController constructs a new InvoiceController by injecting the InvoiceService.
-func NewInvoiceController(is services.InvoiceService) *InvoiceController {
-	return &InvoiceController{invoiceService: is}
+// NewInvoiceController constructs a new InvoiceController by injecting the InvoiceService
+// and the template used to render invoice PDFs.
+func NewInvoiceController(is services.InvoiceService, tmpl *documents.InvoiceTemplate) *InvoiceController {
+	return &InvoiceController{invoiceService: is, pdfTemplate: tmpl}
+}
+
+// invoiceLinePayload is one line item in an invoice create or update request.
+type invoiceLinePayload struct {
+	Description string      `json:"description" binding:"required"` // What is billed.
+	Quantity    int64       `json:"quantity"`                       // Defaults to 1.
+	UnitPrice   money.Money `json:"unit_price"`                     // Net price per unit.
+	TaskID      *uint       `json:"task_id"`                        // Task the line bills; only kept on drafts generated from tasks.
+}
+
+// toInvoiceLines converts line payloads into invoice lines.
+func toInvoiceLines(payload []invoiceLinePayload) []models.InvoiceLine {
+	lines := make([]models.InvoiceLine, 0, len(payload))
+	for _, p := range payload {
+		if p.Quantity == 0 {
+			p.Quantity = 1
+		}
+		lines = append(lines, models.InvoiceLine{Description: p.Description, Quantity: p.Quantity, UnitPrice: p.UnitPrice, TaskID: p.TaskID})
+	}
+	return lines
 }
 
 // CreateInvoice handles POST /api/invoices.
-// It expects a JSON payload with the invoice number, amount due, payment status, due date,
-// project ID, and client ID, then creates a new invoice in the database.
+// It expects a JSON payload with the net amount, due date and project ID, then
+// creates a new draft invoice in the database. The caller must be the project's
+// hired freelancer (an admin bills on their behalf); the project's client is
+// charged. The invoice number
+// is allocated when the draft is finalised. Instead of a
+// net amount, line items may be given. Tax and the gross amount due are calculated
+// from the issuer's and the client's tax profiles.
 func (ic *InvoiceController) CreateInvoice(c *gin.Context) {
 	// Define a struct to bind the incoming JSON payload.
 	var payload struct {
-		InvoiceNumber string  `json:"invoice_number" binding:"required"` // Unique invoice number.
-		AmountDue     float64 `json:"amount_due" binding:"required"`     // Amount that is due.
-		PaymentStatus string  `json:"payment_status"`                    // Optional; defaults to "pending".
-		DueDate       string  `json:"due_date" binding:"required"`       // Due date in RFC3339 format.
-		ProjectID     uint    `json:"project_id" binding:"required"`     // Associated project.
-		ClientID      uint    `json:"client_id" binding:"required"`      // ID of the client to be charged.
+		NetAmount money.Money          `json:"net_amount"`                    // Amount before tax.
+		AmountDue money.Money          `json:"amount_due"`                    // Deprecated: read as the net amount.
+		DueDate   string               `json:"due_date" binding:"required"`   // Due date in RFC3339 format.
+		ProjectID uint                 `json:"project_id" binding:"required"` // Associated project; its client is charged.
+		Lines     []invoiceLinePayload `json:"lines" binding:"dive"`          // Optional line items; replace net_amount.
 	}
 
 	// Bind the JSON payload to our struct.
@@ -41,6 +73,20 @@ func (ic *InvoiceController) CreateInvoice(c *gin.Context) {
 		return
 	}
 
+	if payload.NetAmount.IsZero() {
+		payload.NetAmount = payload.AmountDue
+	}
+	if len(payload.Lines) == 0 && !positiveAmount(c, "net_amount", payload.NetAmount) {
+		return
+	}
+	// Tasks are only billed through POST /api/projects/:id/invoices/generate
+	for _, line := range payload.Lines {
+		if line.TaskID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "task_id can only be kept on lines of invoices generated from tasks"})
+			return
+		}
+	}
+
 	// Parse the due date string into a time.Time object.
 	dueDate, err := time.Parse(time.RFC3339, payload.DueDate)
 	if err != nil {
@@ -49,25 +95,22 @@ func (ic *InvoiceController) CreateInvoice(c *gin.Context) {
 		return
 	}
 
-	// Create a new Invoice model instance.
+	// Create a new Invoice model instance. The caller issues it; for an admin
+	// the issuer defaults to the project's freelancer.
 	invoice := models.Invoice{
-		InvoiceNumber: payload.InvoiceNumber,
-		AmountDue:     payload.AmountDue,
-		PaymentStatus: payload.PaymentStatus,
-		DueDate:       dueDate,
-		ProjectID:     payload.ProjectID,
-		ClientID:      payload.ClientID,
+		NetAmount: payload.NetAmount,
+		DueDate:   dueDate,
+		ProjectID: payload.ProjectID,
+		Lines:     toInvoiceLines(payload.Lines),
 	}
-
-	// If PaymentStatus is empty, default it to "pending".
-	if invoice.PaymentStatus == "" {
-		invoice.PaymentStatus = "pending"
+	if c.GetString("userRole") != "admin" {
+		issuerID := c.GetUint("userID")
+		invoice.IssuerID = &issuerID
 	}
 
 	// Call the service layer to save the invoice in the database.
 	if err := ic.invoiceService.CreateInvoice(&invoice); err != nil {
-		// Return HTTP 500 if saving fails.
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondInvoiceError(c, err)
 		return
 	}
 
@@ -76,22 +119,11 @@ func (ic *InvoiceController) CreateInvoice(c *gin.Context) {
 }
 
 // GetInvoice handles GET /api/invoices/:id.
-// It retrieves an invoice by its ID.
+// The issuer, the client or an admin retrieves an invoice by its ID.
 func (ic *InvoiceController) GetInvoice(c *gin.Context) {
-	// Extract the invoice ID from the URL.
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		// Return HTTP 400 if the invoice ID is invalid.
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
-		return
-	}
-
-	// Retrieve the invoice using the service.
-	invoice, err := ic.invoiceService.GetInvoiceByID(uint(id))
-	if err != nil {
-		// Return HTTP 404 if the invoice is not found.
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+	// Retrieve the invoice; only its parties may see it.
+	invoice, ok := partyInvoice(c, ic.invoiceService)
+	if !ok {
 		return
 	}
 
@@ -100,7 +132,8 @@ func (ic *InvoiceController) GetInvoice(c *gin.Context) {
 }
 
 // GetInvoicesByProject handles GET /api/projects/:id/invoices.
-// It returns all invoices related to a specific project.
+// It returns the invoices of a project the caller issued or is billed for;
+// admins see all of them.
 func (ic *InvoiceController) GetInvoicesByProject(c *gin.Context) {
 	// Extract the project ID from the URL.
 	projectIDStr := c.Param("id")
@@ -119,36 +152,35 @@ func (ic *InvoiceController) GetInvoicesByProject(c *gin.Context) {
 		return
 	}
 
+	// Keep the invoices the caller is a party to.
+	visible := make([]models.Invoice, 0, len(invoices))
+	for i := range invoices {
+		if isInvoiceParty(c, &invoices[i]) {
+			visible = append(visible, invoices[i])
+		}
+	}
+
 	// Return the list of invoices.
-	c.JSON(http.StatusOK, gin.H{"invoices": invoices})
+	c.JSON(http.StatusOK, gin.H{"invoices": visible})
 }
 
 // UpdateInvoice handles PUT /api/invoices/:id.
-// It updates an existing invoice with new data.
+// The issuer (or an admin) updates a draft invoice. Its project and client stay
+// as created, and the payment status can't be set: it follows the payments
+// settled on the invoice.
 func (ic *InvoiceController) UpdateInvoice(c *gin.Context) {
-	// Extract the invoice ID from the URL.
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
-		return
-	}
-
-	// Retrieve the current invoice from the database.
-	invoice, err := ic.invoiceService.GetInvoiceByID(uint(id))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+	// Retrieve the current invoice; only the issuer edits their invoices.
+	invoice, ok := issuerInvoice(c, ic.invoiceService)
+	if !ok {
 		return
 	}
 
 	// Define a payload for fields that can be updated.
 	var payload struct {
-		InvoiceNumber string  `json:"invoice_number"`
-		AmountDue     float64 `json:"amount_due"`
-		PaymentStatus string  `json:"payment_status"`
-		DueDate       string  `json:"due_date"` // Expected in RFC3339 format.
-		ProjectID     uint    `json:"project_id"`
-		ClientID      uint    `json:"client_id"`
+		NetAmount money.Money           `json:"net_amount"`
+		AmountDue money.Money           `json:"amount_due"`                     // Deprecated: read as the net amount.
+		Lines     *[]invoiceLinePayload `json:"lines" binding:"omitempty,dive"` // Replaces all lines when present.
+		DueDate   string                `json:"due_date"`                       // Expected in RFC3339 format.
 	}
 
 	// Bind the JSON payload.
@@ -158,14 +190,17 @@ func (ic *InvoiceController) UpdateInvoice(c *gin.Context) {
 	}
 
 	// Update invoice fields if new values are provided.
-	if payload.InvoiceNumber != "" {
-		invoice.InvoiceNumber = payload.InvoiceNumber
+	if payload.NetAmount.IsZero() {
+		payload.NetAmount = payload.AmountDue
 	}
-	if payload.AmountDue != 0 {
-		invoice.AmountDue = payload.AmountDue
+	if !payload.NetAmount.IsZero() {
+		if !positiveAmount(c, "net_amount", payload.NetAmount) {
+			return
+		}
+		invoice.NetAmount = payload.NetAmount
 	}
-	if payload.PaymentStatus != "" {
-		invoice.PaymentStatus = payload.PaymentStatus
+	if payload.Lines != nil {
+		invoice.Lines = toInvoiceLines(*payload.Lines)
 	}
 	if payload.DueDate != "" {
 		dueDate, err := time.Parse(time.RFC3339, payload.DueDate)
@@ -175,16 +210,10 @@ func (ic *InvoiceController) UpdateInvoice(c *gin.Context) {
 		}
 		invoice.DueDate = dueDate
 	}
-	if payload.ProjectID != 0 {
-		invoice.ProjectID = payload.ProjectID
-	}
-	if payload.ClientID != 0 {
-		invoice.ClientID = payload.ClientID
-	}
 
 	// Update the invoice using the service.
 	if err := ic.invoiceService.UpdateInvoice(invoice); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondInvoiceError(c, err)
 		return
 	}
 
@@ -193,22 +222,257 @@ func (ic *InvoiceController) UpdateInvoice(c *gin.Context) {
 }
 
 // DeleteInvoice handles DELETE /api/invoices/:id.
-// It deletes the specified invoice from the database.
+// The issuer (or an admin) deletes a draft invoice.
 func (ic *InvoiceController) DeleteInvoice(c *gin.Context) {
-	// Extract the invoice ID from the URL.
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+	// Only the issuer deletes their drafts.
+	invoice, ok := issuerInvoice(c, ic.invoiceService)
+	if !ok {
 		return
 	}
 
-	// Call the service to delete the invoice.
-	if err := ic.invoiceService.DeleteInvoice(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Call the service to delete the invoice; only drafts can be deleted.
+	if err := ic.invoiceService.DeleteInvoice(invoice.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+			return
+		}
+		respondInvoiceError(c, err)
 		return
 	}
 
 	// Respond with a success message.
 	c.JSON(http.StatusOK, gin.H{"message": "Invoice deleted successfully"})
 }
+
+// GenerateInvoice handles POST /api/projects/:id/invoices/generate.
+// The project's freelancer bills its completed tasks that haven't been invoiced
+// yet in a draft invoice. Optional task_ids restricts the invoice to those tasks.
+func (ic *InvoiceController) GenerateInvoice(c *gin.Context) {
+	// Extract the project ID from the URL.
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var payload struct {
+		DueDate string `json:"due_date" binding:"required"` // Due date in RFC3339 format.
+		TaskIDs []uint `json:"task_ids"`                    // Optional subset of tasks to bill.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dueDate, err := time.Parse(time.RFC3339, payload.DueDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid due_date format. Use RFC3339"})
+		return
+	}
+
+	invoice, err := ic.invoiceService.GenerateFromTasks(uint(projectID), c.GetUint("userID"), payload.TaskIDs, dueDate)
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invoice": invoice})
+}
+
+// FinaliseInvoice handles POST /api/invoices/:id/finalise.
+// The issuer (or an admin) finalises a draft, which allocates its invoice number.
+func (ic *InvoiceController) FinaliseInvoice(c *gin.Context) {
+	// Only the issuer numbers their own invoices.
+	invoice, ok := issuerInvoice(c, ic.invoiceService)
+	if !ok {
+		return
+	}
+
+	invoice, err := ic.invoiceService.FinaliseInvoice(invoice.ID)
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invoice": invoice})
+}
+
+// VoidInvoice handles POST /api/invoices/:id/void.
+// The issuer (or an admin) cancels a finalised, unpaid invoice; a reason is required.
+func (ic *InvoiceController) VoidInvoice(c *gin.Context) {
+	invoice, ok := issuerInvoice(c, ic.invoiceService)
+	if !ok {
+		return
+	}
+
+	// Bind the JSON payload.
+	var payload struct {
+		Reason string `json:"reason" binding:"required"` // Why the invoice is cancelled.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invoice, err := ic.invoiceService.VoidInvoice(invoice.ID, payload.Reason)
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invoice": invoice})
+}
+
+// CreateCreditNote handles POST /api/invoices/:id/credit-notes.
+// The issuer (or an admin) offsets a finalised invoice. Without net_amount the
+// whole amount not yet credited is credited.
+func (ic *InvoiceController) CreateCreditNote(c *gin.Context) {
+	invoice, ok := issuerInvoice(c, ic.invoiceService)
+	if !ok {
+		return
+	}
+
+	// Bind the JSON payload.
+	var payload struct {
+		NetAmount *money.Money `json:"net_amount"`                // Optional partial amount, before tax.
+		Reason    string       `json:"reason" binding:"required"` // Printed on the credit note.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	note, err := ic.invoiceService.CreateCreditNote(invoice.ID, payload.NetAmount, payload.Reason)
+	if err != nil {
+		respondInvoiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"credit_note": note})
+}
+
+// GetCreditNotes handles GET /api/invoices/:id/credit-notes.
+// The issuer, the client or an admin can list the credit notes of an invoice.
+func (ic *InvoiceController) GetCreditNotes(c *gin.Context) {
+	invoice, ok := partyInvoice(c, ic.invoiceService)
+	if !ok {
+		return
+	}
+
+	notes, err := ic.invoiceService.GetCreditNotes(invoice.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"credit_notes": notes})
+}
+
+// GetInvoicePDF handles GET /api/invoices/:id/pdf.
+// The issuer, the client or an admin can download the invoice as a PDF.
+func (ic *InvoiceController) GetInvoicePDF(c *gin.Context) {
+	data, ok := ic.viewableDocument(c)
+	if !ok {
+		return
+	}
+	inv := data.Invoice
+
+	// Render and send it inline, so browsers display it.
+	body, err := ic.pdfTemplate.Render(*data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	filename := fmt.Sprintf("invoice-draft-%d.pdf", inv.ID)
+	if inv.InvoiceNumber != "" {
+		filename = "invoice-" + inv.InvoiceNumber + ".pdf"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", body)
+}
+
+// GetInvoiceUBL handles GET /api/invoices/:id/ubl.
+// The issuer, the client or an admin can download a finalised invoice or credit
+// note as UBL 2.1 XML (Peppol BIS Billing 3.0) for their accounting software.
+func (ic *InvoiceController) GetInvoiceUBL(c *gin.Context) {
+	data, ok := ic.viewableDocument(c)
+	if !ok {
+		return
+	}
+
+	body, err := documents.RenderUBL(*data)
+	if err != nil {
+		if errors.Is(err, documents.ErrNotIssued) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", documents.UBLFileName(data.Invoice)))
+	c.Data(http.StatusOK, "application/xml", body)
+}
+
+// ExportInvoicesUBL handles GET /api/admin/invoices/export.
+// It downloads a ZIP with the UBL XML of every invoice and credit note issued in
+// the period. Optional query parameters: from and to (YYYY-MM-DD, to is exclusive;
+// defaults to the current month).
+func (ic *InvoiceController) ExportInvoicesUBL(c *gin.Context) {
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	docs, err := ic.invoiceService.GetIssuedDocuments(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Build the archive in memory so a failure can still be reported as JSON.
+	var buf bytes.Buffer
+	if err := documents.WriteUBLArchive(&buf, docs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	filename := fmt.Sprintf("invoices-%s-%s.zip", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// viewableDocument loads the invoice from the URL with its parties, project and
+// lines, and checks that the caller is its issuer, its client or an admin. It
+// writes the error response when it fails.
+func (ic *InvoiceController) viewableDocument(c *gin.Context) (*documents.InvoiceData, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return nil, false
+	}
+	data, err := ic.invoiceService.GetInvoiceDocument(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return nil, false
+	}
+	if !isInvoiceParty(c, &data.Invoice) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't view this invoice"})
+		return nil, false
+	}
+	return data, true
+}
+
+// respondInvoiceError maps invoice service errors to HTTP responses.
+func respondInvoiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotProjectFreelancer):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, services.ErrInvalidInvoiceLine), errors.Is(err, services.ErrUnsupportedCurrency),
+		errors.Is(err, money.ErrCurrencyMismatch), errors.Is(err, services.ErrInvalidCreditNote),
+		errors.Is(err, services.ErrReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNothingToInvoice), errors.Is(err, services.ErrTaskNotBillable),
+		errors.Is(err, services.ErrInvoiceNotDraft), errors.Is(err, services.ErrNoIssuer),
+		errors.Is(err, services.ErrInvoiceImmutable), errors.Is(err, services.ErrInvoiceNotVoidable),
+		errors.Is(err, services.ErrInvoiceNotCreditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
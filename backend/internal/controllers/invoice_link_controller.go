@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"fmt"      // For building download file names.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+	"time"     // Used for link lifetimes.
+
+	"FreeConnect/internal/documents" // Invoice PDF rendering.
+	"FreeConnect/internal/services"  // Contains the invoice and link services.
+	"github.com/gin-gonic/gin"       // Gin framework for HTTP routing.
+	"gorm.io/gorm"                   // For detecting missing records.
+)
+
+// InvoiceLinkController handles public links to invoices: issuers create and
+// revoke them, and anyone holding one can view and pay the invoice without an
+// account.
+type InvoiceLinkController struct {
+	linkService    services.InvoiceLinkService // Signs, checks and tracks links and their payments.
+	invoiceService services.InvoiceService     // Used to check access to an invoice.
+	pdfTemplate    *documents.InvoiceTemplate  // Branded layout for invoice PDFs.
+}
+
+// NewInvoiceLinkController creates a new InvoiceLinkController with the given
+// services and the template used to render invoice PDFs.
+func NewInvoiceLinkController(ls services.InvoiceLinkService, is services.InvoiceService, tmpl *documents.InvoiceTemplate) *InvoiceLinkController {
+	return &InvoiceLinkController{linkService: ls, invoiceService: is, pdfTemplate: tmpl}
+}
+
+// CreateLink handles POST /api/invoices/:id/links.
+// The issuer shares a finalised invoice. Optional payload: expires_in_days
+// (default 30, at most 180). The response holds the token for the public URL;
+// it is not shown again.
+func (lc *InvoiceLinkController) CreateLink(c *gin.Context) {
+	invoice, ok := issuerInvoice(c, lc.invoiceService)
+	if !ok {
+		return
+	}
+
+	var payload struct {
+		ExpiresInDays int `json:"expires_in_days"` // Link lifetime; 0 uses the default.
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	link, token, err := lc.linkService.CreateLink(invoice.ID, c.GetUint("userID"), time.Duration(payload.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		respondInvoiceLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"link": link, "token": token})
+}
+
+// GetLinks handles GET /api/invoices/:id/links.
+// The issuer sees every link of the invoice with how often it was opened.
+func (lc *InvoiceLinkController) GetLinks(c *gin.Context) {
+	invoice, ok := issuerInvoice(c, lc.invoiceService)
+	if !ok {
+		return
+	}
+
+	links, err := lc.linkService.GetLinks(invoice.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links, "open_count": invoice.OpenCount, "last_opened_at": invoice.LastOpenedAt})
+}
+
+// RevokeLink handles DELETE /api/invoices/:id/links/:linkId.
+// A revoked link stops working immediately.
+func (lc *InvoiceLinkController) RevokeLink(c *gin.Context) {
+	invoice, ok := issuerInvoice(c, lc.invoiceService)
+	if !ok {
+		return
+	}
+	linkID, err := strconv.Atoi(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	link, err := lc.linkService.RevokeLink(invoice.ID, uint(linkID))
+	if err != nil {
+		respondInvoiceLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"link": link})
+}
+
+// GetPayments handles GET /api/invoices/:id/payments.
+// The issuer sees the payments attempted through the invoice's links.
+func (lc *InvoiceLinkController) GetPayments(c *gin.Context) {
+	invoice, ok := issuerInvoice(c, lc.invoiceService)
+	if !ok {
+		return
+	}
+
+	invoicePayments, err := lc.linkService.GetPayments(invoice.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"payments": invoicePayments})
+}
+
+// ViewInvoice handles GET /api/public/invoices/:token.
+// It shows the invoice read-only to anyone holding a valid link.
+func (lc *InvoiceLinkController) ViewInvoice(c *gin.Context) {
+	invoice, _, err := lc.linkService.OpenInvoice(c.Param("token"))
+	if err != nil {
+		respondInvoiceLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invoice": invoice})
+}
+
+// ViewInvoicePDF handles GET /api/public/invoices/:token/pdf.
+// It renders the linked invoice as a PDF.
+func (lc *InvoiceLinkController) ViewInvoicePDF(c *gin.Context) {
+	_, data, err := lc.linkService.OpenInvoice(c.Param("token"))
+	if err != nil {
+		respondInvoiceLinkError(c, err)
+		return
+	}
+
+	body, err := lc.pdfTemplate.Render(*data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", "invoice-"+data.Invoice.InvoiceNumber+".pdf"))
+	c.Data(http.StatusOK, "application/pdf", body)
+}
+
+// PayInvoice handles POST /api/public/invoices/:token/pay.
+// It pays what is left on the linked invoice through the payment gateway.
+// A declined payment is answered with 402 and can be retried.
+func (lc *InvoiceLinkController) PayInvoice(c *gin.Context) {
+	var payload struct {
+		PaymentMethod string `json:"payment_method" binding:"required,oneof=credit_card paypal bank_transfer"` // How the invoice is paid.
+		PaymentToken  string `json:"payment_token"`                                                            // Token from the provider's checkout.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment, err := lc.linkService.PayInvoice(c.Param("token"), payload.PaymentMethod, payload.PaymentToken)
+	if err != nil {
+		respondInvoiceLinkError(c, err)
+		return
+	}
+	switch payment.Status {
+	case "failed":
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "Payment was declined", "payment": payment})
+	case "processing":
+		c.JSON(http.StatusAccepted, gin.H{"payment": payment})
+	default:
+		c.JSON(http.StatusOK, gin.H{"payment": payment})
+	}
+}
+
+// respondInvoiceLinkError maps invoice link service errors to HTTP responses.
+func respondInvoiceLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInvoiceLink):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, services.ErrInvalidLinkExpiry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvoiceNotShared), errors.Is(err, services.ErrInvoiceNotPayable),
+		errors.Is(err, services.ErrPaymentInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+	"time"     // Used for parsing schedule dates.
+
+	"FreeConnect/internal/models"   // Contains the InvoiceSchedule model.
+	"FreeConnect/internal/money"    // Exact money amounts.
+	"FreeConnect/internal/services" // Contains the schedule and project services.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// InvoiceScheduleController handles recurring invoice schedules, e.g. for
+// monthly retainers.
+type InvoiceScheduleController struct {
+	scheduleService services.InvoiceScheduleService // Manages schedules and issues their invoices.
+	projectService  services.ProjectService         // Used to check access to a project.
+}
+
+// NewInvoiceScheduleController creates a new InvoiceScheduleController with the given services.
+func NewInvoiceScheduleController(ss services.InvoiceScheduleService, ps services.ProjectService) *InvoiceScheduleController {
+	return &InvoiceScheduleController{scheduleService: ss, projectService: ps}
+}
+
+// CreateSchedule handles POST /api/projects/:id/invoice-schedules.
+// The project's freelancer sets up an invoice for the same net amount every
+// interval, starting on start_date and, optionally, ending on end_date.
+func (sc *InvoiceScheduleController) CreateSchedule(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	// Bind the JSON payload.
+	var payload struct {
+		Interval    string      `json:"interval" binding:"required"`    // weekly, monthly, quarterly or yearly.
+		Amount      money.Money `json:"amount"`                         // Net amount per period.
+		Description string      `json:"description" binding:"required"` // Printed on every invoice with its period.
+		DueDays     *int        `json:"due_days"`                       // Payment term in days; defaults to 14.
+		StartDate   string      `json:"start_date" binding:"required"`  // First period, YYYY-MM-DD.
+		EndDate     string      `json:"end_date"`                       // Optional; no period starts after it.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule := models.InvoiceSchedule{
+		Interval:    payload.Interval,
+		Amount:      payload.Amount,
+		Description: payload.Description,
+		DueDays:     services.DefaultScheduleDueDays,
+		ProjectID:   uint(projectID),
+		IssuerID:    c.GetUint("userID"),
+	}
+	if payload.DueDays != nil {
+		schedule.DueDays = *payload.DueDays
+	}
+	if schedule.StartDate, err = time.Parse("2006-01-02", payload.StartDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date. Use YYYY-MM-DD"})
+		return
+	}
+	if payload.EndDate != "" {
+		end, err := time.Parse("2006-01-02", payload.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date. Use YYYY-MM-DD"})
+			return
+		}
+		schedule.EndDate = &end
+	}
+
+	if err := sc.scheduleService.CreateSchedule(&schedule); err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"schedule": schedule})
+}
+
+// GetProjectSchedules handles GET /api/projects/:id/invoice-schedules.
+// The project's client and freelancer and admins can list its schedules.
+func (sc *InvoiceScheduleController) GetProjectSchedules(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	if !sc.canView(c, uint(projectID)) {
+		return
+	}
+
+	schedules, err := sc.scheduleService.GetSchedulesByProject(uint(projectID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// GetScheduleInvoices handles GET /api/invoice-schedules/:id/invoices.
+// It lists the invoices issued by a schedule, one per period.
+func (sc *InvoiceScheduleController) GetScheduleInvoices(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+	schedule, err := sc.scheduleService.GetSchedule(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+	if !sc.canView(c, schedule.ProjectID) {
+		return
+	}
+
+	invoices, err := sc.scheduleService.GetScheduleInvoices(schedule.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invoices": invoices})
+}
+
+// PauseSchedule handles POST /api/invoice-schedules/:id/pause.
+// Periods starting while the schedule is paused are not billed.
+func (sc *InvoiceScheduleController) PauseSchedule(c *gin.Context) {
+	sc.changeState(c, func(id uint) (*models.InvoiceSchedule, error) {
+		return sc.scheduleService.PauseSchedule(id)
+	})
+}
+
+// ResumeSchedule handles POST /api/invoice-schedules/:id/resume.
+// Billing continues with the next period that starts from now on.
+func (sc *InvoiceScheduleController) ResumeSchedule(c *gin.Context) {
+	sc.changeState(c, func(id uint) (*models.InvoiceSchedule, error) {
+		return sc.scheduleService.ResumeSchedule(id, time.Now())
+	})
+}
+
+// CancelSchedule handles POST /api/invoice-schedules/:id/cancel.
+// A cancelled schedule never issues invoices again; issued ones are kept.
+func (sc *InvoiceScheduleController) CancelSchedule(c *gin.Context) {
+	sc.changeState(c, func(id uint) (*models.InvoiceSchedule, error) {
+		return sc.scheduleService.CancelSchedule(id)
+	})
+}
+
+// RunSchedules handles POST /api/admin/invoice-schedules/run.
+// It issues the invoices that are due now instead of waiting for the scheduled job.
+func (sc *InvoiceScheduleController) RunSchedules(c *gin.Context) {
+	result, err := sc.scheduleService.Run(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// changeState checks that the caller issues the schedule's invoices or is an
+// admin, and applies the state change.
+func (sc *InvoiceScheduleController) changeState(c *gin.Context, change func(id uint) (*models.InvoiceSchedule, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+	schedule, err := sc.scheduleService.GetSchedule(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+	if schedule.IssuerID != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the issuer can change this schedule"})
+		return
+	}
+
+	schedule, err = change(schedule.ID)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+// canView checks that the caller is the project's client or freelancer or an
+// admin. It writes the error response when they aren't.
+func (sc *InvoiceScheduleController) canView(c *gin.Context, projectID uint) bool {
+	project, err := sc.projectService.GetProjectByID(projectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return false
+	}
+	userID := c.GetUint("userID")
+	isFreelancer := project.FreelancerID != nil && *project.FreelancerID == userID
+	if !isFreelancer && project.ClientID != userID && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't view this project's schedules"})
+		return false
+	}
+	return true
+}
+
+// respondScheduleError maps schedule service errors to HTTP responses.
+func respondScheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSchedule), errors.Is(err, services.ErrUnsupportedCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotProjectFreelancer):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, services.ErrScheduleStateChange):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
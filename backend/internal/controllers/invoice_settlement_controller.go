@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+
+	"FreeConnect/internal/services" // Contains the invoice and settlement services.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// InvoiceSettlementController handles how invoices are paid: their remaining
+// balance and the client credit left over from overpayments.
+type InvoiceSettlementController struct {
+	settlementService services.InvoiceSettlementService // Balances and client credit.
+	invoiceService    services.InvoiceService           // Used to check access to an invoice.
+}
+
+// NewInvoiceSettlementController creates a new InvoiceSettlementController with the given services.
+func NewInvoiceSettlementController(ss services.InvoiceSettlementService, is services.InvoiceService) *InvoiceSettlementController {
+	return &InvoiceSettlementController{settlementService: ss, invoiceService: is}
+}
+
+// GetInvoiceBalance handles GET /api/invoices/:id/balance.
+// The issuer, the client or an admin sees what has been paid on the invoice,
+// by which transactions and links, and what is left.
+func (sc *InvoiceSettlementController) GetInvoiceBalance(c *gin.Context) {
+	invoice, ok := partyInvoice(c, sc.invoiceService)
+	if !ok {
+		return
+	}
+
+	balance, err := sc.settlementService.GetInvoiceBalance(invoice.ID)
+	if err != nil {
+		respondSettlementError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"balance": balance})
+}
+
+// ApplyCredit handles POST /api/invoices/:id/apply-credit.
+// The client's credit with the issuer pays as much of the invoice as it covers.
+func (sc *InvoiceSettlementController) ApplyCredit(c *gin.Context) {
+	invoice, ok := partyInvoice(c, sc.invoiceService)
+	if !ok {
+		return
+	}
+
+	balance, err := sc.settlementService.ApplyCredit(invoice.ID)
+	if err != nil {
+		respondSettlementError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"balance": balance})
+}
+
+// GetClientCredits handles GET /api/users/:id/credits.
+// A client (or an admin) sees their credit balance with each freelancer.
+func (sc *InvoiceSettlementController) GetClientCredits(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if uint(userID) != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own credit"})
+		return
+	}
+
+	balances, credits, err := sc.settlementService.GetCredits(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"balances": balances, "credits": credits})
+}
+
+// respondSettlementError maps settlement service errors to HTTP responses.
+func respondSettlementError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+	case errors.Is(err, services.ErrInvoiceNotPayable), errors.Is(err, services.ErrNoClientCredit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+
+	"FreeConnect/internal/models"   // Contains the payout models.
+	"FreeConnect/internal/money"    // Exact money amounts.
+	"FreeConnect/internal/services" // Contains the PayoutService.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// PayoutController handles payout methods, withdrawal requests and the admin payout endpoints.
+type PayoutController struct {
+	payoutService services.PayoutService // Service layer for payouts.
+}
+
+// NewPayoutController creates a new PayoutController with the given PayoutService.
+func NewPayoutController(ps services.PayoutService) *PayoutController {
+	return &PayoutController{payoutService: ps}
+}
+
+// ListPayoutMethods handles GET /api/payout-methods.
+// It lists the payout methods of the logged-in freelancer.
+func (pc *PayoutController) ListPayoutMethods(c *gin.Context) {
+	methods, err := pc.payoutService.GetMethods(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"payout_methods": methods})
+}
+
+// AddPayoutMethod handles POST /api/payout-methods.
+// It adds a payout method to the logged-in freelancer's profile.
+func (pc *PayoutController) AddPayoutMethod(c *gin.Context) {
+	// Define a payload for the new method.
+	var payload struct {
+		Type        string `json:"type" binding:"required"`        // bank_transfer or paypal.
+		Label       string `json:"label"`                          // Optional display name.
+		Destination string `json:"destination" binding:"required"` // IBAN or PayPal e-mail.
+		IsDefault   bool   `json:"is_default"`                     // Make this the default method.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	method := models.PayoutMethod{
+		UserID:      c.GetUint("userID"),
+		Type:        payload.Type,
+		Label:       payload.Label,
+		Destination: payload.Destination,
+		IsDefault:   payload.IsDefault,
+	}
+	if err := pc.payoutService.AddMethod(&method); err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"payout_method": method})
+}
+
+// DeletePayoutMethod handles DELETE /api/payout-methods/:id.
+func (pc *PayoutController) DeletePayoutMethod(c *gin.Context) {
+	// Extract the method ID from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payout method ID"})
+		return
+	}
+
+	if err := pc.payoutService.DeleteMethod(c.GetUint("userID"), uint(id)); err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Payout method deleted successfully"})
+}
+
+// GetPayoutBalance handles GET /api/payouts/balance.
+// It returns the freelancer's earnings, the amount reserved by open withdrawals and what is available.
+func (pc *PayoutController) GetPayoutBalance(c *gin.Context) {
+	balance, err := pc.payoutService.GetBalance(c.GetUint("userID"))
+	if err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"balance": balance})
+}
+
+// RequestWithdrawal handles POST /api/withdrawals.
+// It reserves part of the available balance for payout; an admin has to approve it.
+func (pc *PayoutController) RequestWithdrawal(c *gin.Context) {
+	// Define a payload for the request.
+	var payload struct {
+		Amount         money.Money `json:"amount"`                              // Amount to withdraw, in the freelancer's currency.
+		PayoutMethodID uint        `json:"payout_method_id" binding:"required"` // Where to send the money.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !positiveAmount(c, "amount", payload.Amount) {
+		return
+	}
+
+	withdrawal, err := pc.payoutService.RequestWithdrawal(c.GetUint("userID"), payload.PayoutMethodID, payload.Amount)
+	if err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"withdrawal": withdrawal})
+}
+
+// ListMyWithdrawals handles GET /api/withdrawals.
+// It lists the logged-in freelancer's withdrawal requests, newest first.
+func (pc *PayoutController) ListMyWithdrawals(c *gin.Context) {
+	withdrawals, err := pc.payoutService.GetWithdrawals(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals})
+}
+
+// ListWithdrawals handles GET /api/admin/withdrawals.
+// An optional status query parameter filters the list (e.g. ?status=pending).
+func (pc *PayoutController) ListWithdrawals(c *gin.Context) {
+	withdrawals, err := pc.payoutService.ListWithdrawals(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals})
+}
+
+// ApproveWithdrawal handles PUT /api/admin/withdrawals/:id/approve.
+// Approved withdrawals are paid out by the next payout batch.
+func (pc *PayoutController) ApproveWithdrawal(c *gin.Context) {
+	// Extract the withdrawal ID from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid withdrawal ID"})
+		return
+	}
+
+	withdrawal, err := pc.payoutService.ApproveWithdrawal(uint(id), c.GetUint("userID"))
+	if err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawal": withdrawal})
+}
+
+// RejectWithdrawal handles PUT /api/admin/withdrawals/:id/reject.
+// The reserved amount becomes available again.
+func (pc *PayoutController) RejectWithdrawal(c *gin.Context) {
+	// Extract the withdrawal ID from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid withdrawal ID"})
+		return
+	}
+
+	var payload struct {
+		Reason string `json:"reason" binding:"required"` // Shown to the freelancer.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	withdrawal, err := pc.payoutService.RejectWithdrawal(uint(id), c.GetUint("userID"), payload.Reason)
+	if err != nil {
+		respondPayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawal": withdrawal})
+}
+
+// RunPayoutBatch handles POST /api/admin/payouts/run.
+// It pays out all approved withdrawals now instead of waiting for the scheduled job.
+func (pc *PayoutController) RunPayoutBatch(c *gin.Context) {
+	result, err := pc.payoutService.RunBatch()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
+
+// respondPayoutError maps payout errors to HTTP statuses.
+func respondPayoutError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, services.ErrNotFreelancer), errors.Is(err, services.ErrAccountOnHold):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWithdrawalNotReviewable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPayoutMethod), errors.Is(err, services.ErrBelowMinimumPayout),
+		errors.Is(err, services.ErrInsufficientBalance), errors.Is(err, services.ErrReasonRequired),
+		errors.Is(err, money.ErrInvalidAmount), errors.Is(err, money.ErrCurrencyMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
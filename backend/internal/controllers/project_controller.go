@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 	"FreeConnect/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -29,12 +30,14 @@ func (pc *ProjectController) CreateProject(c *gin.Context) {
 	// Define a payload structure to bind the JSON input.
 	// Notice that FreelancerID is removed from the payload.
 	var payload struct {
-		Title       string  `json:"title" binding:"required"`       // Project title is mandatory.
-		Description string  `json:"description" binding:"required"` // Project description is mandatory.
-		Budget      float64 `json:"budget" binding:"required"`      // Project budget is mandatory.
-		Duration    int     `json:"duration" binding:"required"`    // Duration (in days) is mandatory.
-		Status      string  `json:"status"`                         // Optional; defaults to "open" if not provided.
-		ClientID    uint    `json:"client_id" binding:"required"`   // The client ID that creates the project.
+		Title       string      `json:"title" binding:"required"`       // Project title is mandatory.
+		Description string      `json:"description" binding:"required"` // Project description is mandatory.
+		Budget      money.Money `json:"budget"`                         // Project budget is mandatory.
+		Category    string      `json:"category"`                       // Optional category, e.g. "design".
+		Duration    int         `json:"duration" binding:"required"`    // Duration (in days) is mandatory.
+		Status      string      `json:"status"`                         // Optional; defaults to "open" if not provided.
+		InviteOnly  bool        `json:"invite_only"`                    // Optional; only invited freelancers can bid.
+		ClientID    uint        `json:"client_id" binding:"required"`   // The client ID that creates the project.
 	}
 
 	// Bind the JSON from the request into the payload.
@@ -44,13 +47,19 @@ func (pc *ProjectController) CreateProject(c *gin.Context) {
 		return
 	}
 
+	if !positiveAmount(c, "budget", payload.Budget) {
+		return
+	}
+
 	// Create a new Project model instance using the input data.
 	project := models.Project{
 		Title:        payload.Title,
 		Description:  payload.Description,
 		Budget:       payload.Budget,
+		Category:     payload.Category,
 		Duration:     payload.Duration,
 		Status:       payload.Status,
+		InviteOnly:   payload.InviteOnly,
 		ClientID:     payload.ClientID,
 		CreationDate: time.Now(), // Set the current time as the creation date.
 	}
@@ -136,13 +145,15 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 
 	// Define a payload structure to receive updated values.
 	var payload struct {
-		Title        string  `json:"title"`
-		Description  string  `json:"description"`
-		Budget       float64 `json:"budget"`
-		Duration     int     `json:"duration"`
-		Status       string  `json:"status"`
-		ClientID     uint    `json:"client_id"`
-		FreelancerID *uint   `json:"freelancer_id"`
+		Title        string      `json:"title"`
+		Description  string      `json:"description"`
+		Budget       money.Money `json:"budget"`
+		Category     string      `json:"category"`
+		Duration     int         `json:"duration"`
+		Status       string      `json:"status"`
+		InviteOnly   *bool       `json:"invite_only"`
+		ClientID     uint        `json:"client_id"`
+		FreelancerID *uint       `json:"freelancer_id"`
 	}
 
 	// Bind the incoming JSON to the payload.
@@ -152,8 +163,8 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 	}
 
 	// Retrieve user details from context (set by JWT middleware)
-	userID := c.GetUint("id")
-	userRole := c.GetString("role")
+	userID := c.GetUint("userID")
+	userRole := c.GetString("userRole")
 	// Only allow the client who created the project or an admin to update.
 	if project.ClientID != userID && userRole != "admin" {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the owner of this project"})
@@ -167,15 +178,24 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 	if payload.Description != "" {
 		project.Description = payload.Description
 	}
-	if payload.Budget != 0 {
+	if !payload.Budget.IsZero() {
+		if !positiveAmount(c, "budget", payload.Budget) {
+			return
+		}
 		project.Budget = payload.Budget
 	}
+	if payload.Category != "" {
+		project.Category = payload.Category
+	}
 	if payload.Duration != 0 {
 		project.Duration = payload.Duration
 	}
 	if payload.Status != "" {
 		project.Status = payload.Status
 	}
+	if payload.InviteOnly != nil {
+		project.InviteOnly = *payload.InviteOnly
+	}
 	if payload.ClientID != 0 {
 		project.ClientID = payload.ClientID
 	}
@@ -213,8 +233,8 @@ func (pc *ProjectController) SetProjectFreelancer(c *gin.Context) {
 	}
 
 	// Ensure that only the project owner (or an admin) can assign a freelancer.
-	userID := c.GetUint("id")
-	userRole := c.GetString("role")
+	userID := c.GetUint("userID")
+	userRole := c.GetString("userRole")
 	if project.ClientID != userID && userRole != "admin" {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the owner of this project"})
 		return
@@ -245,6 +265,50 @@ func (pc *ProjectController) SetProjectFreelancer(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"project": project})
 }
 
+// UpdateProjectSkills handles PUT /api/projects/:projectId/skills.
+// The project owner (or an admin) sets the skills the project requires; they
+// are matched against freelancers' skills when proposals are ranked.
+func (pc *ProjectController) UpdateProjectSkills(c *gin.Context) {
+	// Parse the project ID from the URL; the PUT routes name it projectId.
+	id, err := strconv.Atoi(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	project, err := pc.projectService.GetProjectByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if project.ClientID != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the owner of this project"})
+		return
+	}
+
+	// Define a payload struct to capture the list of skill IDs.
+	var payload struct {
+		SkillIDs []uint `json:"skill_ids" binding:"required"` // An array of skill IDs is required.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := pc.projectService.UpdateProjectSkills(project.ID, payload.SkillIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the project with its new skills.
+	project, err = pc.projectService.GetProjectByID(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"project": project})
+}
+
 // DeleteProject handles DELETE /api/projects/:id.
 // It removes the project from the database.
 func (pc *ProjectController) DeleteProject(c *gin.Context) {
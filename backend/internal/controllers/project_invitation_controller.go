@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+
+	"FreeConnect/internal/services" // Contains the invitation and project services.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// ProjectInvitationController handles invitations from a client to specific
+// freelancers to bid on a project.
+type ProjectInvitationController struct {
+	invitationService services.ProjectInvitationService // Sends and answers invitations.
+	projectService    services.ProjectService           // Used to check access to a project.
+}
+
+// NewProjectInvitationController creates a new ProjectInvitationController with the given services.
+func NewProjectInvitationController(is services.ProjectInvitationService, ps services.ProjectService) *ProjectInvitationController {
+	return &ProjectInvitationController{invitationService: is, projectService: ps}
+}
+
+// Invite handles POST /api/projects/:id/invitations.
+// The project's client invites a freelancer to bid, with an optional message.
+func (ic *ProjectInvitationController) Invite(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	var payload struct {
+		FreelancerID uint   `json:"freelancer_id" binding:"required"` // The freelancer to invite.
+		Message      string `json:"message"`                          // Optional note to the freelancer.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := ic.invitationService.Invite(uint(projectID), c.GetUint("userID"), payload.FreelancerID, payload.Message)
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invitation": invitation})
+}
+
+// GetProjectInvitations handles GET /api/projects/:id/invitations.
+// The project's client (or an admin) tracks who was invited and who answered.
+func (ic *ProjectInvitationController) GetProjectInvitations(c *gin.Context) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	project, err := ic.projectService.GetProjectByID(uint(projectID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if project.ClientID != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the owner of this project"})
+		return
+	}
+
+	invitations, err := ic.invitationService.GetProjectInvitations(project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// GetFreelancerInvitations handles GET /api/users/:id/invitations.
+// A freelancer (or an admin) sees the invitations they received.
+func (ic *ProjectInvitationController) GetFreelancerInvitations(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if uint(userID) != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own invitations"})
+		return
+	}
+
+	invitations, err := ic.invitationService.GetFreelancerInvitations(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// AcceptInvitation handles POST /api/invitations/:id/accept.
+// The invited freelancer accepts and gets a draft proposal to fill in and submit.
+func (ic *ProjectInvitationController) AcceptInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	invitation, draft, err := ic.invitationService.AcceptInvitation(uint(id), c.GetUint("userID"))
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invitation": invitation, "proposal": draft})
+}
+
+// DeclineInvitation handles POST /api/invitations/:id/decline.
+// The invited freelancer declines, optionally saying why.
+func (ic *ProjectInvitationController) DeclineInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	var payload struct {
+		Message string `json:"message" binding:"max=500"` // Optional reason, shown to the client.
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	invitation, err := ic.invitationService.DeclineInvitation(uint(id), c.GetUint("userID"), payload.Message)
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitation": invitation})
+}
+
+// respondInvitationError maps invitation service errors to HTTP responses.
+func respondInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, services.ErrNotProjectOwner), errors.Is(err, services.ErrNotInvitee):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidInvitee):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyInvited), errors.Is(err, services.ErrInvitationClosed),
+		errors.Is(err, services.ErrProjectNotOpen), errors.Is(err, services.ErrDuplicateProposal):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"errors"   // For matching service errors.
 	"net/http" // For HTTP status codes.
 	"strconv"  // For converting URL parameters to integers.
 
 	"FreeConnect/internal/models"   // For the Proposal model.
+	"FreeConnect/internal/money"    // For exact bid amounts.
 	"FreeConnect/internal/services" // For the ProposalService.
 	"github.com/gin-gonic/gin"      // Gin framework for routing and HTTP responses.
+	"gorm.io/gorm"                  // For detecting missing records.
 )
 
 // ProposalController handles endpoints for proposals.
@@ -20,17 +23,17 @@ func NewProposalController(ps services.ProposalService) *ProposalController {
 }
 
 // CreateProposal handles POST /api/proposals.
-// It allows freelancers to submit a proposal for a project.
-// The expected JSON payload must include proposal text, estimated duration, bid amount,
-// project ID, and freelancer ID.
+// It allows freelancers to submit a proposal for an open project, paying for
+// it with bid credits. The expected JSON payload must include proposal text,
+// estimated duration, bid amount and project ID; the freelancer is the caller.
 func (pc *ProposalController) CreateProposal(c *gin.Context) {
 	// Define a payload structure for the incoming JSON.
 	var payload struct {
-		ProposalText      string  `json:"proposal_text" binding:"required"`      // Proposal description.
-		EstimatedDuration int     `json:"estimated_duration" binding:"required"` // Estimated duration (days).
-		BidAmount         float64 `json:"bid_amount" binding:"required"`         // Proposed bid amount.
-		ProjectID         uint    `json:"project_id" binding:"required"`         // ID of the project.
-		FreelancerID      uint    `json:"freelancer_id" binding:"required"`      // ID of the freelancer submitting the proposal.
+		ProposalText      string      `json:"proposal_text" binding:"required"`      // Proposal description.
+		EstimatedDuration int         `json:"estimated_duration" binding:"required"` // Estimated duration (days).
+		BidAmount         money.Money `json:"bid_amount"`                            // Proposed bid amount.
+		ProjectID         uint        `json:"project_id" binding:"required"`         // ID of the project.
+		FreelancerID      uint        `json:"freelancer_id"`                         // Optional; must be the caller.
 	}
 
 	// Bind the JSON payload to the struct.
@@ -40,27 +43,42 @@ func (pc *ProposalController) CreateProposal(c *gin.Context) {
 		return
 	}
 
-	// Create a new Proposal model with the provided values.
-	proposal := models.Proposal{
-		ProposalText:      payload.ProposalText,
-		EstimatedDuration: payload.EstimatedDuration,
-		BidAmount:         payload.BidAmount,
-		Status:            "pending", // Default status for a new proposal.
-		ProjectID:         payload.ProjectID,
-		FreelancerID:      payload.FreelancerID,
+	if !positiveAmount(c, "bid_amount", payload.BidAmount) {
+		return
 	}
 
 	// Check if the user role from context is "freelancer".
-	userRole := c.GetString("role")
+	userRole := c.GetString("userRole")
 	if userRole != "freelancer" {
 		// Only freelancers can create proposals.
 		c.JSON(http.StatusForbidden, gin.H{"error": "Only freelancers can create proposals"})
 		return
 	}
 
+	// Proposals are paid with the caller's credits, so they bid as themselves.
+	userID := c.GetUint("userID")
+	if payload.FreelancerID != 0 && payload.FreelancerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only submit proposals as yourself"})
+		return
+	}
+
+	// Create a new Proposal model with the provided values.
+	proposal := models.Proposal{
+		ProposalText:      payload.ProposalText,
+		EstimatedDuration: payload.EstimatedDuration,
+		BidAmount:         payload.BidAmount,
+		Status:            "pending", // Default status for a new proposal.
+		ProjectID:         payload.ProjectID,
+		FreelancerID:      userID,
+	}
+
 	// Use the proposal service to create the proposal in the database.
 	if err := pc.proposalService.CreateProposal(&proposal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		respondProposalError(c, err)
 		return
 	}
 
@@ -80,9 +98,10 @@ func (pc *ProposalController) GetProposal(c *gin.Context) {
 		return
 	}
 
-	// Retrieve the proposal using the service.
+	// Retrieve the proposal using the service. Someone else's draft doesn't
+	// exist as far as the caller is concerned.
 	proposal, err := pc.proposalService.GetProposalByID(uint(id))
-	if err != nil {
+	if err != nil || !isProposalVisible(c, proposal) {
 		// Respond with HTTP 404 if the proposal is not found.
 		c.JSON(http.StatusNotFound, gin.H{"error": "Proposal not found"})
 		return
@@ -93,7 +112,8 @@ func (pc *ProposalController) GetProposal(c *gin.Context) {
 }
 
 // GetProposalsByProject handles GET /api/projects/:id/proposals.
-// It retrieves all proposals associated with a specific project.
+// It retrieves the proposals associated with a specific project, leaving out
+// drafts the caller isn't writing.
 func (pc *ProposalController) GetProposalsByProject(c *gin.Context) {
 	// Extract the project ID from the URL.
 	projectIDStr := c.Param("id")
@@ -110,12 +130,21 @@ func (pc *ProposalController) GetProposalsByProject(c *gin.Context) {
 		return
 	}
 
+	visible := make([]models.Proposal, 0, len(proposals))
+	for i := range proposals {
+		if isProposalVisible(c, &proposals[i]) {
+			visible = append(visible, proposals[i])
+		}
+	}
+
 	// Return the list of proposals.
-	c.JSON(http.StatusOK, gin.H{"proposals": proposals})
+	c.JSON(http.StatusOK, gin.H{"proposals": visible})
 }
 
 // UpdateProposal handles PUT /api/proposals/:id.
-// It updates fields of an existing proposal.
+// The freelancer can edit the proposal text, and the terms of a draft. Once
+// submitted, bid amount and duration change through counter-offers, the
+// status through its own endpoints.
 func (pc *ProposalController) UpdateProposal(c *gin.Context) {
 	// Extract the proposal ID from the URL.
 	idStr := c.Param("id")
@@ -132,12 +161,17 @@ func (pc *ProposalController) UpdateProposal(c *gin.Context) {
 		return
 	}
 
+	if proposal.FreelancerID != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the freelancer can edit the proposal"})
+		return
+	}
+
 	// Define a payload struct for fields that can be updated.
 	var payload struct {
-		ProposalText      string  `json:"proposal_text"`      // New proposal text.
-		EstimatedDuration int     `json:"estimated_duration"` // New estimated duration.
-		BidAmount         float64 `json:"bid_amount"`         // New bid amount.
-		Status            string  `json:"status"`             // New status.
+		ProposalText      string      `json:"proposal_text"`      // New proposal text.
+		EstimatedDuration int         `json:"estimated_duration"` // New estimated duration.
+		BidAmount         money.Money `json:"bid_amount"`         // New bid amount.
+		Status            string      `json:"status"`             // New status.
 	}
 
 	// Bind the incoming JSON.
@@ -153,7 +187,10 @@ func (pc *ProposalController) UpdateProposal(c *gin.Context) {
 	if payload.EstimatedDuration != 0 {
 		proposal.EstimatedDuration = payload.EstimatedDuration
 	}
-	if payload.BidAmount != 0 {
+	if !payload.BidAmount.IsZero() {
+		if !positiveAmount(c, "bid_amount", payload.BidAmount) {
+			return
+		}
 		proposal.BidAmount = payload.BidAmount
 	}
 	if payload.Status != "" {
@@ -162,7 +199,7 @@ func (pc *ProposalController) UpdateProposal(c *gin.Context) {
 
 	// Use the service to update the proposal.
 	if err := pc.proposalService.UpdateProposal(proposal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProposalError(c, err)
 		return
 	}
 
@@ -171,7 +208,8 @@ func (pc *ProposalController) UpdateProposal(c *gin.Context) {
 }
 
 // DeleteProposal handles DELETE /api/proposals/:id.
-// It deletes a proposal from the database.
+// When the freelancer deletes their proposal it is withdrawn, keeping its
+// history; only admins remove a proposal from the database.
 func (pc *ProposalController) DeleteProposal(c *gin.Context) {
 	// Extract the proposal ID from the URL.
 	idStr := c.Param("id")
@@ -181,6 +219,16 @@ func (pc *ProposalController) DeleteProposal(c *gin.Context) {
 		return
 	}
 
+	if c.GetString("userRole") != "admin" {
+		proposal, err := pc.proposalService.WithdrawProposal(uint(id), c.GetUint("userID"), c.Query("reason"))
+		if err != nil {
+			respondProposalError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Proposal withdrawn successfully", "proposal": proposal})
+		return
+	}
+
 	// Call the service layer to delete the proposal.
 	if err := pc.proposalService.DeleteProposal(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -192,7 +240,8 @@ func (pc *ProposalController) DeleteProposal(c *gin.Context) {
 }
 
 // AcceptProposal handles POST /api/proposals/:id/accept.
-// It marks the proposal as accepted and triggers related business logic (e.g., assigning a freelancer to the project).
+// It accepts the terms on the table and triggers related business logic (e.g., assigning a freelancer to the project).
+// The client accepts the freelancer's offer; the freelancer accepts the client's counter-offer.
 func (pc *ProposalController) AcceptProposal(c *gin.Context) {
 	// Extract the proposal ID from the URL.
 	idStr := c.Param("id")
@@ -209,13 +258,169 @@ func (pc *ProposalController) AcceptProposal(c *gin.Context) {
 		return
 	}
 
-	// Call the service layer to accept the proposal.
+	// Call the service layer to accept the proposal. Only the party who didn't
+	// make the offer can accept it; the service checks on the locked proposal.
 	// This should update the proposal's status and also update the project by assigning the freelancer.
-	if err := pc.proposalService.AcceptProposal(proposal); err != nil {
+	admin := c.GetString("userRole") == "admin"
+	if err := pc.proposalService.AcceptProposal(proposal, c.GetUint("userID"), admin); err != nil {
+		respondProposalError(c, err)
+		return
+	}
+
+	// Return a success message with the agreed terms.
+	c.JSON(http.StatusOK, gin.H{"message": "Proposal accepted successfully", "proposal": proposal})
+}
+
+// CounterOffer handles POST /api/proposals/:id/counter-offers.
+// The project's client or the proposal's freelancer puts new terms on the
+// table; the other party can accept them or counter again.
+func (pc *ProposalController) CounterOffer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+
+	var payload struct {
+		BidAmount         money.Money `json:"bid_amount"`                            // Offered amount, in the bid's currency.
+		EstimatedDuration int         `json:"estimated_duration" binding:"required"` // Offered duration (days).
+		Message           string      `json:"message"`                               // Optional note to the other party.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	proposal, err := pc.proposalService.CounterOffer(uint(id), c.GetUint("userID"), payload.BidAmount, payload.EstimatedDuration, payload.Message)
+	if err != nil {
+		respondProposalError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"proposal": proposal})
+}
+
+// SubmitProposal handles POST /api/proposals/:id/submit.
+// The freelancer submits the draft opened by accepting an invitation, after
+// filling in its text and terms through PUT /api/proposals/:id.
+func (pc *ProposalController) SubmitProposal(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+
+	proposal, err := pc.proposalService.SubmitProposal(uint(id), c.GetUint("userID"))
+	if err != nil {
+		respondProposalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal})
+}
+
+// ShortlistProposal handles POST /api/proposals/:id/shortlist.
+// The project's client shortlists an open proposal, optionally with a message
+// to the freelancer.
+func (pc *ProposalController) ShortlistProposal(c *gin.Context) {
+	pc.changeStatus(c, pc.proposalService.ShortlistProposal)
+}
+
+// RejectProposal handles POST /api/proposals/:id/reject.
+// The project's client rejects an open proposal, optionally with a message
+// to the freelancer.
+func (pc *ProposalController) RejectProposal(c *gin.Context) {
+	pc.changeStatus(c, pc.proposalService.RejectProposal)
+}
+
+// WithdrawProposal handles POST /api/proposals/:id/withdraw.
+// The freelancer withdraws their open proposal, optionally saying why.
+func (pc *ProposalController) WithdrawProposal(c *gin.Context) {
+	pc.changeStatus(c, pc.proposalService.WithdrawProposal)
+}
+
+// changeStatus parses the proposal ID and the optional message and applies
+// one of the status changes of the proposal service for the caller.
+func (pc *ProposalController) changeStatus(c *gin.Context, change func(proposalID, userID uint, message string) (*models.Proposal, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+
+	var payload struct {
+		Message string `json:"message" binding:"max=500"` // Optional reason, shown to the other party.
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	proposal, err := change(uint(id), c.GetUint("userID"), payload.Message)
+	if err != nil {
+		respondProposalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"proposal": proposal})
+}
+
+// GetRevisions handles GET /api/proposals/:id/revisions.
+// The negotiating parties (and admins) see every offer made on the proposal.
+func (pc *ProposalController) GetRevisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+	proposal, err := pc.proposalService.GetProposalByID(uint(id))
+	if err != nil || !isProposalVisible(c, proposal) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Proposal not found"})
+		return
+	}
+	if c.GetString("userRole") != "admin" {
+		if _, err := pc.proposalService.ProposalParty(proposal, c.GetUint("userID")); err != nil {
+			respondProposalError(c, err)
+			return
+		}
+	}
+
+	revisions, err := pc.proposalService.GetRevisions(proposal.ID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
 
-	// Return a success message.
-	c.JSON(http.StatusOK, gin.H{"message": "Proposal accepted successfully"})
+// isProposalVisible reports whether the caller may see the proposal. Drafts
+// are private to their freelancer (and admins) until submitted.
+func isProposalVisible(c *gin.Context, proposal *models.Proposal) bool {
+	if proposal.Status != "draft" || c.GetString("userRole") == "admin" {
+		return true
+	}
+	return proposal.FreelancerID == c.GetUint("userID")
+}
+
+// respondProposalError maps proposal service errors to HTTP responses.
+func respondProposalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Proposal not found"})
+	case errors.Is(err, services.ErrNotProposalParty), errors.Is(err, services.ErrClientOnly),
+		errors.Is(err, services.ErrFreelancerOnly), errors.Is(err, services.ErrNotInvited):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidOffer), errors.Is(err, services.ErrUnsupportedCurrency),
+		errors.Is(err, services.ErrIncompleteDraft):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInsufficientBidCredits):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProposalClosed), errors.Is(err, services.ErrTermsNegotiated),
+		errors.Is(err, services.ErrOwnOffer), errors.Is(err, services.ErrProposalStatusOwned),
+		errors.Is(err, services.ErrInvalidProposalTransition), errors.Is(err, services.ErrProjectNotOpen),
+		errors.Is(err, services.ErrDuplicateProposal), errors.Is(err, services.ErrNoExchangeRate),
+		errors.Is(err, services.ErrNotDraft):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+	"strings"  // For splitting the list of compared proposal IDs.
+
+	"FreeConnect/internal/services" // Contains the scoring and project services.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// ProposalScoringController ranks the proposals on a project for its client,
+// explaining each score, and compares selected proposals side by side.
+type ProposalScoringController struct {
+	scoringService services.ProposalScoringService // Scores proposals.
+	projectService services.ProjectService         // Used to check access to a project.
+}
+
+// NewProposalScoringController creates a new ProposalScoringController with the given services.
+func NewProposalScoringController(ss services.ProposalScoringService, ps services.ProjectService) *ProposalScoringController {
+	return &ProposalScoringController{scoringService: ss, projectService: ps}
+}
+
+// RankProposals handles GET /api/projects/:id/proposals/scores?sort=score.
+// The project's client (or an admin) gets the proposals best first, by total
+// score or by one factor: price, duration, rating, skills or completion.
+func (sc *ProposalScoringController) RankProposals(c *gin.Context) {
+	projectID, ok := sc.ownProjectID(c)
+	if !ok {
+		return
+	}
+
+	scores, err := sc.scoringService.RankProposals(projectID, c.Query("sort"))
+	if err != nil {
+		respondScoringError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"proposals": scores})
+}
+
+// CompareProposals handles GET /api/projects/:id/proposals/compare?ids=1,2,3.
+// The project's client (or an admin) compares selected proposals factor by factor.
+func (sc *ProposalScoringController) CompareProposals(c *gin.Context) {
+	projectID, ok := sc.ownProjectID(c)
+	if !ok {
+		return
+	}
+
+	var ids []uint
+	for _, part := range strings.Split(c.Query("ids"), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ids must be a comma-separated list of proposal IDs"})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+
+	comparison, err := sc.scoringService.CompareProposals(projectID, ids)
+	if err != nil {
+		respondScoringError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"comparison": comparison})
+}
+
+// ownProjectID parses the project ID from the URL and checks that the caller
+// owns the project or is an admin. It writes the error response when it fails.
+func (sc *ProposalScoringController) ownProjectID(c *gin.Context) (uint, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return 0, false
+	}
+	project, err := sc.projectService.GetProjectByID(uint(projectID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return 0, false
+	}
+	if project.ClientID != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not the owner of this project"})
+		return 0, false
+	}
+	return project.ID, true
+}
+
+// respondScoringError maps scoring service errors to HTTP responses.
+func respondScoringError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, services.ErrInvalidSortKey), errors.Is(err, services.ErrInvalidComparison):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
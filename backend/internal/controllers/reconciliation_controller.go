@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+
+	"FreeConnect/internal/services" // Contains the ReconciliationService.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// ReconciliationController exposes the reconciliation job and its reports to admins.
+type ReconciliationController struct {
+	reconciliationService services.ReconciliationService // Runs and stores reconciliations.
+}
+
+// NewReconciliationController creates a new ReconciliationController with the given service.
+func NewReconciliationController(rs services.ReconciliationService) *ReconciliationController {
+	return &ReconciliationController{reconciliationService: rs}
+}
+
+// RunReconciliation handles POST /api/admin/reconciliation/run.
+// It runs a reconciliation now. With {"auto_fix": true}, safe discrepancies are fixed right away.
+func (rc *ReconciliationController) RunReconciliation(c *gin.Context) {
+	// The body is optional.
+	var payload struct {
+		AutoFix bool `json:"auto_fix"` // Fix the safe discrepancies automatically.
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	run, err := rc.reconciliationService.Run("admin", payload.AutoFix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "run": run})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"run": run})
+}
+
+// ListRuns handles GET /api/admin/reconciliation/runs.
+// It lists the 50 most recent runs, newest first.
+func (rc *ReconciliationController) ListRuns(c *gin.Context) {
+	runs, err := rc.reconciliationService.GetRuns(50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// GetRun handles GET /api/admin/reconciliation/runs/:id.
+// It returns one run with its discrepancy report.
+func (rc *ReconciliationController) GetRun(c *gin.Context) {
+	// Extract the run ID from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+		return
+	}
+
+	run, err := rc.reconciliationService.GetRun(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reconciliation run not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"run": run})
+}
+
+// FixDiscrepancy handles POST /api/admin/reconciliation/discrepancies/:id/fix.
+// Only discrepancies marked fixable can be fixed this way.
+func (rc *ReconciliationController) FixDiscrepancy(c *gin.Context) {
+	// Extract the discrepancy ID from the URL.
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discrepancy ID"})
+		return
+	}
+
+	discrepancy, err := rc.reconciliationService.FixDiscrepancy(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Discrepancy not found"})
+		case errors.Is(err, services.ErrNotFixable), errors.Is(err, services.ErrInvalidTransition):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"discrepancy": discrepancy})
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+	"time"     // The reminder run uses the current time.
+
+	"FreeConnect/internal/models"   // Contains the BillingTerms model.
+	"FreeConnect/internal/services" // Contains the reminder and invoice services.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// ReminderController handles payment reminders and the billing terms that
+// decide late fees.
+type ReminderController struct {
+	reminderService services.InvoiceReminderService // Sends reminders and applies late fees.
+	invoiceService  services.InvoiceService         // Used to check access to an invoice.
+}
+
+// NewReminderController creates a new ReminderController with the given services.
+func NewReminderController(rs services.InvoiceReminderService, is services.InvoiceService) *ReminderController {
+	return &ReminderController{reminderService: rs, invoiceService: is}
+}
+
+// GetInvoiceReminders handles GET /api/invoices/:id/reminders.
+// The issuer, the client or an admin can see which reminders were sent.
+func (rc *ReminderController) GetInvoiceReminders(c *gin.Context) {
+	invoice, ok := partyInvoice(c, rc.invoiceService)
+	if !ok {
+		return
+	}
+
+	reminders, err := rc.reminderService.GetReminders(invoice.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reminders": reminders})
+}
+
+// GetBillingTerms handles GET /api/admin/users/:id/billing-terms.
+func (rc *ReminderController) GetBillingTerms(c *gin.Context) {
+	clientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	terms, err := rc.reminderService.GetTerms(uint(clientID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No billing terms for this client"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"billing_terms": terms})
+}
+
+// SaveBillingTerms handles PUT /api/admin/users/:id/billing-terms.
+// It creates or replaces the late fee terms of a client.
+func (rc *ReminderController) SaveBillingTerms(c *gin.Context) {
+	clientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Bind the JSON payload.
+	var payload struct {
+		LateFeeRate int64 `json:"late_fee_rate"` // Basis points of the amount due (150 = 1.5%); 0 disables late fees.
+		GraceDays   int   `json:"grace_days"`    // Days after the due date before the fee is charged.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	terms := models.BillingTerms{
+		ClientID:    uint(clientID),
+		LateFeeRate: payload.LateFeeRate,
+		GraceDays:   payload.GraceDays,
+	}
+	if err := rc.reminderService.SaveTerms(&terms); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidBillingTerms):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"billing_terms": terms})
+}
+
+// RunReminders handles POST /api/admin/invoices/reminders/run.
+// It flags overdue invoices and sends due reminders now instead of waiting for the scheduled job.
+func (rc *ReminderController) RunReminders(c *gin.Context) {
+	result, err := rc.reminderService.Run(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
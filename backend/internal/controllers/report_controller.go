@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"   // For building period validation errors and matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"time"     // Used for parsing report periods.
+
+	"FreeConnect/internal/services" // Business logic layer for reports.
+	"github.com/gin-gonic/gin"      // Gin framework for routing.
+)
+
+// ReportController handles the admin reporting endpoints.
+type ReportController struct {
+	reportService services.ReportService // Service that aggregates report data.
+}
+
+// NewReportController constructs a new ReportController by injecting the ReportService.
+func NewReportController(rs services.ReportService) *ReportController {
+	return &ReportController{reportService: rs}
+}
+
+// GetTransactionVolume handles GET /api/admin/reports/transactions.
+// Optional query parameters: from and to (YYYY-MM-DD, to is exclusive; defaults to
+// the current month) and currency (the report currency; defaults to EUR).
+func (rc *ReportController) GetTransactionVolume(c *gin.Context) {
+	// Work out the reporting period.
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Build the report, normalised into the requested currency.
+	report, err := rc.reportService.TransactionVolume(from, to, c.Query("currency"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// GetPlatformRevenue handles GET /api/admin/reports/revenue.
+// Optional query parameters: from and to (as for GetTransactionVolume), currency,
+// and interval (day, week or month; defaults to month).
+func (rc *ReportController) GetPlatformRevenue(c *gin.Context) {
+	// Work out the reporting period.
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Build the report, grouped by the requested interval.
+	report, err := rc.reportService.PlatformRevenue(from, to, c.Query("currency"), c.Query("interval"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInterval) || errors.Is(err, services.ErrUnsupportedCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// parsePeriod reads the from/to query parameters (YYYY-MM-DD). Missing values
+// default to the current calendar month; to is exclusive.
+func parsePeriod(c *gin.Context) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("Invalid from date. Use YYYY-MM-DD")
+		}
+		from = parsed
+	}
+	if v := c.Query("to"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("Invalid to date. Use YYYY-MM-DD")
+		}
+		to = parsed
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, errors.New("to must be after from")
+	}
+	return from, to, nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 	"FreeConnect/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -26,12 +27,12 @@ func NewTaskController(ts services.TaskService) *TaskController {
 func (tc *TaskController) CreateTask(c *gin.Context) {
 	// Define the JSON payload structure for creating a task.
 	var payload struct {
-		Title       string  `json:"title" binding:"required"`       // Task title is mandatory.
-		Description string  `json:"description" binding:"required"` // Task description is mandatory.
-		Deadline    string  `json:"deadline" binding:"required"`    // Deadline in RFC3339 format.
-		Budget      float64 `json:"budget"`                         // Optional task budget.
-		Status      string  `json:"status"`                         // Optional; defaults to "open".
-		ProjectID   uint    `json:"project_id" binding:"required"`  // ID of the project this task belongs to.
+		Title       string      `json:"title" binding:"required"`       // Task title is mandatory.
+		Description string      `json:"description" binding:"required"` // Task description is mandatory.
+		Deadline    string      `json:"deadline" binding:"required"`    // Deadline in RFC3339 format.
+		Budget      money.Money `json:"budget"`                         // Optional task budget.
+		Status      string      `json:"status"`                         // Optional; defaults to "open".
+		ProjectID   uint        `json:"project_id" binding:"required"`  // ID of the project this task belongs to.
 	}
 
 	// Bind the JSON payload.
@@ -136,11 +137,11 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 
 	// Define a payload struct for the update.
 	var payload struct {
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		Deadline    string  `json:"deadline"` // expects RFC3339 format
-		Budget      float64 `json:"budget"`
-		Status      string  `json:"status"`
+		Title       string      `json:"title"`
+		Description string      `json:"description"`
+		Deadline    string      `json:"deadline"` // expects RFC3339 format
+		Budget      money.Money `json:"budget"`
+		Status      string      `json:"status"`
 	}
 
 	// Bind the JSON payload.
@@ -164,7 +165,10 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 		}
 		task.Deadline = parsedDeadline
 	}
-	if payload.Budget != 0 {
+	if !payload.Budget.IsZero() {
+		if !positiveAmount(c, "budget", payload.Budget) {
+			return
+		}
 		task.Budget = payload.Budget
 	}
 	if payload.Status != "" {
@@ -240,11 +244,11 @@ func (tc *TaskController) EditTask(c *gin.Context) {
 
 	// 6) Define a payload for fields that can be updated.
 	var payload struct {
-		Title       string     `json:"title"`       // New title (if provided)
-		Description string     `json:"description"` // New description (if provided)
-		Deadline    *time.Time `json:"deadline"`    // New deadline (optional, pointer to detect absence)
-		Budget      *float64   `json:"budget"`      // New budget (optional)
-		Status      string     `json:"status"`      // New status (if provided)
+		Title       string       `json:"title"`       // New title (if provided)
+		Description string       `json:"description"` // New description (if provided)
+		Deadline    *time.Time   `json:"deadline"`    // New deadline (optional, pointer to detect absence)
+		Budget      *money.Money `json:"budget"`      // New budget (optional)
+		Status      string       `json:"status"`      // New status (if provided)
 	}
 
 	// Bind the JSON payload to the structure.
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters from strings to integers.
+
+	"FreeConnect/internal/models"   // Contains the tax models.
+	"FreeConnect/internal/services" // Contains the TaxService.
+	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
+)
+
+// TaxController handles tax profiles, tax rules and the tax summary report.
+type TaxController struct {
+	taxService services.TaxService // Service layer for tax handling.
+}
+
+// NewTaxController creates a new TaxController with the given TaxService.
+func NewTaxController(ts services.TaxService) *TaxController {
+	return &TaxController{taxService: ts}
+}
+
+// GetTaxProfile handles GET /api/users/:id/tax-profile.
+// Users can read their own profile; admins can read any.
+func (tc *TaxController) GetTaxProfile(c *gin.Context) {
+	userID, ok := taxProfileOwner(c)
+	if !ok {
+		return
+	}
+
+	profile, err := tc.taxService.GetProfile(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tax profile not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tax_profile": profile})
+}
+
+// SaveTaxProfile handles PUT /api/users/:id/tax-profile.
+// It creates or replaces the user's tax profile.
+func (tc *TaxController) SaveTaxProfile(c *gin.Context) {
+	userID, ok := taxProfileOwner(c)
+	if !ok {
+		return
+	}
+
+	// Bind the JSON payload.
+	var payload struct {
+		Country         string `json:"country" binding:"required"` // ISO country code, e.g. "DE".
+		VATID           string `json:"vat_id"`                     // Optional VAT ID, prefixed with the country.
+		IsBusiness      bool   `json:"is_business"`                // Whether the user invoices as a business.
+		TaxExempt       bool   `json:"tax_exempt"`                 // Whether the user charges no tax.
+		ExemptionReason string `json:"exemption_reason"`           // Printed on exempt invoices.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile := models.TaxProfile{
+		UserID:          userID,
+		Country:         payload.Country,
+		VATID:           payload.VATID,
+		IsBusiness:      payload.IsBusiness,
+		TaxExempt:       payload.TaxExempt,
+		ExemptionReason: payload.ExemptionReason,
+	}
+	if err := tc.taxService.SaveProfile(&profile); err != nil {
+		if errors.Is(err, services.ErrInvalidTaxProfile) || errors.Is(err, services.ErrInvalidVATID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tax_profile": profile})
+}
+
+// ListTaxRules handles GET /api/admin/tax-rules.
+// It lists the tax rate of every configured country.
+func (tc *TaxController) ListTaxRules(c *gin.Context) {
+	rules, err := tc.taxService.GetRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tax_rules": rules})
+}
+
+// SaveTaxRule handles PUT /api/admin/tax-rules/:country.
+// It creates or replaces the tax rule of a country.
+func (tc *TaxController) SaveTaxRule(c *gin.Context) {
+	// Bind the JSON payload.
+	var payload struct {
+		Name     string `json:"name"`      // Tax name; defaults to "VAT".
+		Rate     int64  `json:"rate"`      // Basis points (2000 = 20%).
+		EUMember bool   `json:"eu_member"` // Whether intra-EU reverse charge applies.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.TaxRule{
+		Country:  c.Param("country"),
+		Name:     payload.Name,
+		Rate:     payload.Rate,
+		EUMember: payload.EUMember,
+	}
+	if err := tc.taxService.SaveRule(&rule); err != nil {
+		if errors.Is(err, services.ErrInvalidTaxRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tax_rule": rule})
+}
+
+// GetTaxSummary handles GET /api/admin/reports/tax.
+// Optional query parameters: from and to (YYYY-MM-DD, to is exclusive; defaults to
+// the current month) and currency (the report currency; defaults to EUR).
+func (tc *TaxController) GetTaxSummary(c *gin.Context) {
+	// Work out the reporting period.
+	from, to, err := parsePeriod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Total the invoices by tax treatment, country and rate.
+	report, err := tc.taxService.Summary(from, to, c.Query("currency"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// taxProfileOwner parses the user ID from the URL and checks that the caller
+// is that user or an admin. It writes the error response when it fails.
+func taxProfileOwner(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	if uint(id) != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage your own tax profile"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"   // For matching service errors.
 	"net/http" // Provides HTTP status codes.
 	"strconv"  // For converting URL parameters from strings to integers.
 
 	"FreeConnect/internal/models"   // Contains the Transaction model.
+	"FreeConnect/internal/money"    // Exact money amounts.
+	"FreeConnect/internal/payments" // Payment gateway errors.
 	"FreeConnect/internal/services" // Contains the TransactionService.
 	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
+	"gorm.io/gorm"                  // For detecting missing records.
 )
 
 // TransactionController handles endpoints related to payment transactions.
@@ -20,15 +24,16 @@ func NewTransactionController(ts services.TransactionService) *TransactionContro
 }
 
 // CreateTransaction handles POST /api/transactions.
-// It creates a new transaction using the provided JSON payload.
+// It creates a new transaction paid by the caller, using the provided JSON payload.
 func (tc *TransactionController) CreateTransaction(c *gin.Context) {
 	// Define a payload struct for binding the JSON request.
 	var payload struct {
-		Amount        float64 `json:"amount" binding:"required"`         // Amount to be transacted.
-		PaymentMethod string  `json:"payment_method" binding:"required"` // Payment method (e.g., credit_card, paypal).
-		ClientID      uint    `json:"client_id" binding:"required"`      // ID of the client making the payment.
-		FreelancerID  uint    `json:"freelancer_id" binding:"required"`  // ID of the freelancer receiving the payment.
-		ProjectID     uint    `json:"project_id" binding:"required"`     // ID of the project associated with the transaction.
+		Amount        money.Money `json:"amount"`                            // Amount to be transacted.
+		PaymentMethod string      `json:"payment_method" binding:"required"` // Payment method (e.g., credit_card, paypal).
+		FreelancerID  uint        `json:"freelancer_id" binding:"required"`  // ID of the freelancer receiving the payment.
+		ProjectID     uint        `json:"project_id" binding:"required"`     // ID of the project associated with the transaction.
+		PaymentToken  string      `json:"payment_token"`                     // Provider token for the payer's instrument (optional).
+		InvoiceIDs    []uint      `json:"invoice_ids"`                       // Invoices the payment settles (optional).
 	}
 
 	// Bind the JSON payload to the struct.
@@ -38,19 +43,33 @@ func (tc *TransactionController) CreateTransaction(c *gin.Context) {
 		return
 	}
 
+	if !positiveAmount(c, "amount", payload.Amount) {
+		return
+	}
+
 	// Create a new Transaction model instance.
 	transaction := models.Transaction{
 		Amount:        payload.Amount,
 		PaymentMethod: payload.PaymentMethod,
-		Status:        "pending", // Default status is pending.
-		ClientID:      payload.ClientID,
+		Status:        "pending",           // Default status is pending.
+		ClientID:      c.GetUint("userID"), // The caller pays.
 		FreelancerID:  payload.FreelancerID,
 		ProjectID:     payload.ProjectID,
 	}
+	// The amounts are allocated to the invoices once the payment completes;
+	// anything paid beyond them becomes client credit.
+	for _, invoiceID := range payload.InvoiceIDs {
+		transaction.Allocations = append(transaction.Allocations, models.InvoiceAllocation{InvoiceID: invoiceID})
+	}
 
-	// Call the TransactionService to create the transaction.
-	if err := tc.transactionService.CreateTransaction(&transaction); err != nil {
-		// Respond with 500 Internal Server Error if creation fails.
+	// Call the TransactionService to create the transaction and authorise it with the gateway.
+	// A declined payment is still created, with status "failed".
+	if err := tc.transactionService.CreateTransaction(&transaction, payload.PaymentToken); err != nil {
+		// Respond with 400 for invoices the payment can't settle, 500 otherwise.
+		if errors.Is(err, services.ErrInvalidAllocation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -124,10 +143,11 @@ func (tc *TransactionController) UpdateTransaction(c *gin.Context) {
 	}
 
 	// Define a payload for the fields that can be updated.
+	// Status is not accepted here; it only changes through the payment gateway.
 	var payload struct {
-		Amount        float64 `json:"amount"`         // Updated amount.
-		PaymentMethod string  `json:"payment_method"` // Updated payment method.
-		Status        string  `json:"status"`         // Updated status.
+		Amount        money.Money `json:"amount"`         // Updated amount.
+		PaymentMethod string      `json:"payment_method"` // Updated payment method.
+		Status        string      `json:"status"`         // Rejected unless unchanged.
 	}
 
 	// Bind the JSON payload.
@@ -137,7 +157,10 @@ func (tc *TransactionController) UpdateTransaction(c *gin.Context) {
 	}
 
 	// Update the transaction fields if new values are provided.
-	if payload.Amount != 0 {
+	if !payload.Amount.IsZero() {
+		if !positiveAmount(c, "amount", payload.Amount) {
+			return
+		}
 		transaction.Amount = payload.Amount
 	}
 	if payload.PaymentMethod != "" {
@@ -149,6 +172,11 @@ func (tc *TransactionController) UpdateTransaction(c *gin.Context) {
 
 	// Persist the updated transaction using the TransactionService.
 	if err := tc.transactionService.UpdateTransaction(transaction); err != nil {
+		if errors.Is(err, services.ErrStatusManagedByGateway) || errors.Is(err, services.ErrPaymentTermsLocked) {
+			// Respond with 409 Conflict: the change contradicts the payment state.
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -158,7 +186,7 @@ func (tc *TransactionController) UpdateTransaction(c *gin.Context) {
 }
 
 // DeleteTransaction handles DELETE /api/transactions/:id.
-// It deletes the specified transaction from the database.
+// It deletes a transaction that was never sent to the payment gateway.
 func (tc *TransactionController) DeleteTransaction(c *gin.Context) {
 	// Extract the transaction ID from the URL.
 	idStr := c.Param("id")
@@ -170,10 +198,263 @@ func (tc *TransactionController) DeleteTransaction(c *gin.Context) {
 
 	// Call the TransactionService to delete the transaction.
 	if err := tc.transactionService.DeleteTransaction(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		case errors.Is(err, services.ErrTransactionInLedger):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	// Respond with a success message.
 	c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted successfully"})
 }
+
+// CaptureTransaction handles POST /api/transactions/:id/capture.
+// It asks the payment gateway to collect the authorised payment and returns the
+// transaction with the status reported by the gateway.
+func (tc *TransactionController) CaptureTransaction(c *gin.Context) {
+	// Only the paying client (or an admin) may act on the payment.
+	current, ok := tc.partyTransaction(c, "client")
+	if !ok {
+		return
+	}
+
+	// Capture through the TransactionService.
+	transaction, err := tc.transactionService.CaptureTransaction(current.ID)
+	if err != nil {
+		respondTransitionError(c, err)
+		return
+	}
+
+	// Return the updated transaction.
+	c.JSON(http.StatusOK, gin.H{"transaction": transaction})
+}
+
+// SyncTransactionStatus handles POST /api/transactions/:id/sync.
+// It queries the payment gateway for the current state of the payment and applies it,
+// e.g. to pick up a delayed capture that has settled.
+func (tc *TransactionController) SyncTransactionStatus(c *gin.Context) {
+	// Only the paying client (or an admin) may act on the payment.
+	current, ok := tc.partyTransaction(c, "client")
+	if !ok {
+		return
+	}
+
+	// Sync through the TransactionService.
+	transaction, err := tc.transactionService.SyncTransactionStatus(current.ID)
+	if err != nil {
+		respondTransitionError(c, err)
+		return
+	}
+
+	// Return the updated transaction.
+	c.JSON(http.StatusOK, gin.H{"transaction": transaction})
+}
+
+// RefundTransaction handles POST /api/transactions/:id/refunds.
+// It refunds all or part of a completed transaction through the payment gateway.
+// Leaving out the amount refunds whatever has not been refunded yet.
+// The refund comes out of the freelancer's earnings, so only the freelancer
+// who was paid (or an admin) can make it.
+func (tc *TransactionController) RefundTransaction(c *gin.Context) {
+	current, ok := tc.partyTransaction(c, "freelancer")
+	if !ok {
+		return
+	}
+
+	// Define a payload for the refund.
+	var payload struct {
+		Amount money.Money `json:"amount"`                    // Amount to refund (optional, defaults to the rest).
+		Reason string      `json:"reason" binding:"required"` // Why the money is returned.
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Refund through the TransactionService.
+	refund, err := tc.transactionService.RefundTransaction(current.ID, payload.Amount, payload.Reason)
+	if err != nil {
+		respondReversalError(c, err)
+		return
+	}
+
+	// Respond with 201 Created and the refund record.
+	c.JSON(http.StatusCreated, gin.H{"refund": refund})
+}
+
+// GetRefunds handles GET /api/transactions/:id/refunds.
+// It lists the refunds made against a transaction.
+func (tc *TransactionController) GetRefunds(c *gin.Context) {
+	// Extract the transaction ID from the URL.
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	// Retrieve the refunds using the TransactionService.
+	refunds, err := tc.transactionService.GetRefunds(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the list of refunds.
+	c.JSON(http.StatusOK, gin.H{"refunds": refunds})
+}
+
+// RecordChargeback handles POST /api/admin/transactions/:id/chargebacks.
+// It records a chargeback reported by the payer's bank, claws back the
+// freelancer's share and flags both accounts.
+func (tc *TransactionController) RecordChargeback(c *gin.Context) {
+	// Extract the transaction ID from the URL.
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	// Define a payload for the chargeback.
+	var payload struct {
+		Amount            money.Money `json:"amount"`                    // Amount charged back (optional, defaults to the rest).
+		Reason            string      `json:"reason" binding:"required"` // Reason code or description from the bank.
+		ProviderReference string      `json:"provider_reference"`        // The provider's dispute ID (optional).
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Record through the TransactionService.
+	chargeback, err := tc.transactionService.RecordChargeback(uint(id), payload.Amount, payload.Reason, payload.ProviderReference)
+	if err != nil {
+		respondReversalError(c, err)
+		return
+	}
+
+	// Respond with 201 Created and the chargeback record.
+	c.JSON(http.StatusCreated, gin.H{"chargeback": chargeback})
+}
+
+// ListChargebacks handles GET /api/admin/chargebacks.
+// It lists all recorded chargebacks, newest first.
+func (tc *TransactionController) ListChargebacks(c *gin.Context) {
+	chargebacks, err := tc.transactionService.GetChargebacks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"chargebacks": chargebacks})
+}
+
+// respondReversalError maps refund and chargeback errors to HTTP statuses.
+func respondReversalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+	case errors.Is(err, services.ErrNotReversible), errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, payments.ErrInvalidState):
+		// 409 Conflict: the transaction is not in a state that can be reversed.
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReversalTooLarge), errors.Is(err, services.ErrReasonRequired),
+		errors.Is(err, money.ErrInvalidAmount), errors.Is(err, money.ErrCurrencyMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// RetryTransaction handles POST /api/transactions/:id/retry.
+// It authorises a failed payment again, optionally with a new payment token.
+func (tc *TransactionController) RetryTransaction(c *gin.Context) {
+	// Only the paying client (or an admin) may act on the payment.
+	current, ok := tc.partyTransaction(c, "client")
+	if !ok {
+		return
+	}
+
+	// The body is optional; without a token the gateway's default applies.
+	var payload struct {
+		PaymentToken string `json:"payment_token"` // Provider token for the payer's instrument.
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Retry through the TransactionService.
+	transaction, err := tc.transactionService.RetryTransaction(current.ID, payload.PaymentToken)
+	if err != nil {
+		respondTransitionError(c, err)
+		return
+	}
+
+	// Return the transaction, now pending again (or failed if declined again).
+	c.JSON(http.StatusOK, gin.H{"transaction": transaction})
+}
+
+// GetStatusHistory handles GET /api/transactions/:id/history.
+// It lists every status the transaction went through, with reasons and timestamps.
+func (tc *TransactionController) GetStatusHistory(c *gin.Context) {
+	// Extract the transaction ID from the URL.
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	history, err := tc.transactionService.GetStatusHistory(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+// partyTransaction parses the transaction ID from the URL and loads the
+// transaction, checking that the caller is its client (or its freelancer, for
+// party "freelancer") or an admin. It writes the error response when it fails.
+func (tc *TransactionController) partyTransaction(c *gin.Context, party string) (*models.Transaction, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return nil, false
+	}
+	transaction, err := tc.transactionService.GetTransactionByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return nil, false
+	}
+
+	partyID := transaction.ClientID
+	if party == "freelancer" {
+		partyID = transaction.FreelancerID
+	}
+	if partyID != c.GetUint("userID") && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the transaction's " + party + " or an admin can do this"})
+		return nil, false
+	}
+	return transaction, true
+}
+
+// respondTransitionError maps state machine errors to 409 and missing transactions to 404.
+func respondTransitionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+	case errors.Is(err, services.ErrInvalidTransition), errors.Is(err, payments.ErrInvalidState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
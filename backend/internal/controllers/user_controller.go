@@ -3,8 +3,10 @@ package controllers
 import (
 	"net/http" // For HTTP status codes.
 	"strconv"  // For converting string parameters to integers.
+	"strings"  // For case-insensitive currency comparison.
 
 	"FreeConnect/internal/models"   // Contains the User model.
+	"FreeConnect/internal/money"    // Exact money amounts.
 	"FreeConnect/internal/services" // Provides the UserService for user operations.
 	"github.com/gin-gonic/gin"      // Gin framework for HTTP routing.
 )
@@ -31,6 +33,7 @@ func (uc *UserController) RegisterUser(c *gin.Context) {
 		Password        string `json:"password" binding:"required,min=6"`        // Password (minimum 6 characters).
 		ConfirmPassword string `json:"confirmPassword" binding:"required,min=6"` // Confirmation of the password.
 		Role            string `json:"role" binding:"required"`                  // Role: admin, client, or freelancer.
+		Currency        string `json:"currency"`                                 // Optional; balance/payout currency, defaults to EUR.
 	}
 
 	// Bind the JSON payload.
@@ -47,9 +50,10 @@ func (uc *UserController) RegisterUser(c *gin.Context) {
 
 	// Create a new User model instance.
 	user := models.User{
-		Name:  payload.Name,
-		Email: payload.Email,
-		Role:  payload.Role,
+		Name:     payload.Name,
+		Email:    payload.Email,
+		Role:     payload.Role,
+		Currency: payload.Currency,
 	}
 
 	// Call the UserService to register the user.
@@ -106,12 +110,13 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 
 	// Define a payload structure for updating user fields.
 	var payload struct {
-		Name         string  `json:"name"`         // Updated name.
-		Bio          string  `json:"bio"`          // Updated biography.
-		CompanyName  string  `json:"company_name"` // Updated company name.
-		Rating       float64 `json:"rating"`       // Updated rating.
-		HourlyRate   float64 `json:"hourly_rate"`  // Updated hourly rate.
-		Availability *bool   `json:"availability"` // Updated availability; pointer to detect absence.
+		Name         string      `json:"name"`         // Updated name.
+		Bio          string      `json:"bio"`          // Updated biography.
+		CompanyName  string      `json:"company_name"` // Updated company name.
+		Rating       float64     `json:"rating"`       // Updated rating.
+		HourlyRate   money.Money `json:"hourly_rate"`  // Updated hourly rate.
+		Availability *bool       `json:"availability"` // Updated availability; pointer to detect absence.
+		Currency     string      `json:"currency"`     // Updated balance/payout currency.
 	}
 
 	// Bind the JSON payload.
@@ -133,12 +138,30 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 	if payload.Rating != 0 {
 		user.Rating = payload.Rating
 	}
-	if payload.HourlyRate != 0 {
+	if !payload.HourlyRate.IsZero() {
+		if !positiveAmount(c, "hourly_rate", payload.HourlyRate) {
+			return
+		}
 		user.HourlyRate = payload.HourlyRate
 	}
 	if payload.Availability != nil {
 		user.Availability = *payload.Availability
 	}
+	if payload.Currency != "" && !strings.EqualFold(payload.Currency, user.Currency) {
+		// Balances are stored in the user's currency, so switching is only
+		// safe while they are empty.
+		if !money.IsSupported(payload.Currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+			return
+		}
+		if !user.Earnings.IsZero() || !user.TotalSpent.IsZero() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Currency cannot be changed while balances are non-zero"})
+			return
+		}
+		user.Currency = money.Zero(payload.Currency).Currency
+		user.Earnings = money.Zero(user.Currency)
+		user.TotalSpent = money.Zero(user.Currency)
+	}
 
 	// Use the UserService to update the user in the database.
 	if err := uc.userService.UpdateUser(user); err != nil {
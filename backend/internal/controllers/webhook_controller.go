@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"errors"   // For matching service errors.
+	"io"       // For reading the raw request body.
+	"net/http" // Provides HTTP status codes.
+	"strconv"  // For converting URL parameters to integers.
+
+	"FreeConnect/internal/payments" // Signature header and webhook errors.
+	"FreeConnect/internal/services" // Business logic layer for webhooks.
+	"github.com/gin-gonic/gin"      // Gin framework for routing.
+	"gorm.io/gorm"                  // For detecting unknown records.
+)
+
+// maxWebhookBody caps the size of a provider callback.
+const maxWebhookBody = 1 << 20
+
+// WebhookController receives payment provider callbacks and exposes the
+// admin tooling around failed events.
+type WebhookController struct {
+	webhookService services.WebhookService // Service that stores and applies events.
+}
+
+// NewWebhookController constructs a new WebhookController by injecting the WebhookService.
+func NewWebhookController(ws services.WebhookService) *WebhookController {
+	return &WebhookController{webhookService: ws}
+}
+
+// ReceivePaymentWebhook handles POST /api/webhooks/payments/:provider.
+// The body must be signed (see payments.SignatureHeader). Duplicates are acknowledged
+// without being applied again; processing failures return 500 so the provider retries.
+func (wc *WebhookController) ReceivePaymentWebhook(c *gin.Context) {
+	// Read the raw body: the signature is computed over the exact bytes.
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Verify, store and process the event.
+	event, duplicate, err := wc.webhookService.Receive(c.Param("provider"), c.GetHeader(payments.SignatureHeader), body)
+	switch {
+	case errors.Is(err, payments.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, payments.ErrInvalidEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if duplicate {
+		c.JSON(http.StatusOK, gin.H{"status": "duplicate", "event_id": event.EventID})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": event.Status, "event_id": event.EventID})
+}
+
+// ListDeadLetters handles GET /api/admin/webhooks/dead-letters.
+// It returns all webhook events that failed and have not been reprocessed yet.
+func (wc *WebhookController) ListDeadLetters(c *gin.Context) {
+	letters, err := wc.webhookService.GetOpenDeadLetters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters})
+}
+
+// ReprocessEvent handles POST /api/admin/webhooks/events/:id/reprocess.
+// It replays the stored raw event; on success its dead letter is resolved.
+func (wc *WebhookController) ReprocessEvent(c *gin.Context) {
+	// Extract the webhook event ID from the URL.
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook event ID"})
+		return
+	}
+
+	// Replay the event.
+	event, err := wc.webhookService.Reprocess(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook event not found"})
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "webhook_event": event})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhook_event": event})
+}
@@ -0,0 +1,413 @@
+// Package documents renders customer-facing documents such as invoice PDFs.
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/pdf"
+)
+
+// Branding is the look of rendered documents. Footer and PaymentTerms are
+// text/template strings executed with a TemplateData.
+type Branding struct {
+	Name         string // shown in the header band
+	Color        string // accent colour as #RRGGBB
+	Footer       string
+	PaymentTerms string
+}
+
+// DefaultBranding is used for settings left empty
+var DefaultBranding = Branding{
+	Name:         "FreeConnect",
+	Color:        "#1F6FEB",
+	Footer:       "{{.Brand.Name}} · {{.Title}}",
+	PaymentTerms: "Please pay {{.Total}} by {{.DueDate}}, quoting {{.Reference}}.",
+}
+
+// InvoiceData is everything shown on an invoice. The invoice should have
+// its project, client, issuer and lines loaded; tax profiles may be nil.
+type InvoiceData struct {
+	Invoice   models.Invoice
+	IssuerTax *models.TaxProfile
+	ClientTax *models.TaxProfile
+}
+
+// TemplateData is available to the Footer and PaymentTerms templates
+type TemplateData struct {
+	Brand     Branding
+	Invoice   models.Invoice
+	Title     string // e.g. "Invoice FC-2026-000123"
+	Reference string // the invoice number, or "draft <id>"
+	Total     string // formatted amount due
+	DueDate   string
+	Page      int
+	Pages     int
+}
+
+// InvoiceTemplate renders invoices with a fixed branding
+type InvoiceTemplate struct {
+	brand  Branding
+	accent pdf.Color
+	footer *template.Template
+	terms  *template.Template
+}
+
+// NewInvoiceTemplate parses the branding; empty fields fall back to
+// DefaultBranding.
+func NewInvoiceTemplate(brand Branding) (*InvoiceTemplate, error) {
+	if brand.Name == "" {
+		brand.Name = DefaultBranding.Name
+	}
+	if brand.Color == "" {
+		brand.Color = DefaultBranding.Color
+	}
+	if brand.Footer == "" {
+		brand.Footer = DefaultBranding.Footer
+	}
+	if brand.PaymentTerms == "" {
+		brand.PaymentTerms = DefaultBranding.PaymentTerms
+	}
+
+	accent, err := pdf.ParseHexColor(brand.Color)
+	if err != nil {
+		return nil, err
+	}
+	footer, err := template.New("footer").Option("missingkey=error").Parse(brand.Footer)
+	if err != nil {
+		return nil, fmt.Errorf("parsing footer template: %w", err)
+	}
+	terms, err := template.New("terms").Option("missingkey=error").Parse(brand.PaymentTerms)
+	if err != nil {
+		return nil, fmt.Errorf("parsing payment terms template: %w", err)
+	}
+	return &InvoiceTemplate{brand: brand, accent: accent, footer: footer, terms: terms}, nil
+}
+
+// Layout in points on an A4 page
+const (
+	marginX    = 50.0
+	rightX     = pdf.PageWidth - marginX
+	headerH    = 90.0
+	rowHeight  = 18.0
+	tableTop   = 535.0
+	pageBottom = 90.0
+	bodySize   = 9.5
+	smallSize  = 8.0
+)
+
+var (
+	grey  = pdf.Color{R: 0.4, G: 0.4, B: 0.4}
+	light = pdf.Color{R: 0.94, G: 0.94, B: 0.94}
+)
+
+// Right edges of the numeric table columns; the description fills the rest
+var (
+	colQty   = 300.0
+	colUnit  = 380.0
+	colNet   = 450.0
+	colTax   = 490.0
+	colGross = rightX
+)
+
+// tableRow is one printed line item
+type tableRow struct {
+	description string
+	quantity    int64
+	unit, net   money.Money
+	rate        int64
+	gross       money.Money
+}
+
+// Render produces the invoice PDF. The output only depends on the data and
+// the branding, so the same invoice always renders to the same bytes.
+func (t *InvoiceTemplate) Render(data InvoiceData) ([]byte, error) {
+	inv := data.Invoice
+	td := TemplateData{
+		Brand:     t.brand,
+		Invoice:   inv,
+		Reference: inv.InvoiceNumber,
+		Total:     formatMoney(inv.AmountDue),
+		DueDate:   inv.DueDate.Format("2006-01-02"),
+	}
+	td.Title = "Invoice " + inv.InvoiceNumber
+	switch {
+	case inv.Status == "draft":
+		td.Reference = fmt.Sprintf("draft %d", inv.ID)
+		td.Title = "Draft invoice " + strconv.FormatUint(uint64(inv.ID), 10)
+	case inv.Kind == "credit_note":
+		td.Title = "Credit note " + inv.InvoiceNumber
+	}
+
+	doc := pdf.New()
+	doc.Title = td.Title
+	doc.Author = t.brand.Name
+
+	page := doc.AddPage()
+	t.header(page, inv)
+	t.parties(page, data)
+	t.details(page, inv)
+
+	y := t.tableHeader(page, tableTop)
+	for _, row := range rows(inv) {
+		if y < pageBottom+rowHeight {
+			page = doc.AddPage()
+			t.header(page, inv)
+			y = t.tableHeader(page, pdf.PageHeight-headerH-40)
+		}
+		y -= rowHeight
+		page.Text(marginX+4, y, pdf.Helvetica, bodySize, pdf.Black,
+			pdf.Truncate(pdf.Helvetica, bodySize, colQty-marginX-40, row.description))
+		page.TextRight(colQty, y, pdf.Helvetica, bodySize, pdf.Black, strconv.FormatInt(row.quantity, 10))
+		page.TextRight(colUnit, y, pdf.Helvetica, bodySize, pdf.Black, formatAmount(row.unit))
+		page.TextRight(colNet, y, pdf.Helvetica, bodySize, pdf.Black, formatAmount(row.net))
+		page.TextRight(colTax, y, pdf.Helvetica, bodySize, pdf.Black, formatRate(row.rate))
+		page.TextRight(colGross, y, pdf.Helvetica, bodySize, pdf.Black, formatAmount(row.gross))
+		page.Line(marginX, y-6, rightX, y-6, 0.3, light)
+	}
+
+	// Totals, notes and payment terms need about 150pt
+	if y < pageBottom+150 {
+		page = doc.AddPage()
+		t.header(page, inv)
+		y = pdf.PageHeight - headerH - 40
+	}
+	y = t.totals(page, y-10, inv)
+
+	var notes []string
+	if inv.Status == "void" && inv.VoidedAt != nil {
+		notes = append(notes, fmt.Sprintf("This invoice was voided on %s: %s", inv.VoidedAt.Format("2006-01-02"), inv.VoidReason))
+	}
+	if inv.CreditReason != "" {
+		notes = append(notes, "Reason for credit: "+inv.CreditReason)
+	}
+	if inv.TaxNote != "" {
+		notes = append(notes, inv.TaxNote)
+	}
+	// Payment terms only apply to invoices that are still to be paid
+	if inv.Kind != "credit_note" && inv.Status != "void" {
+		terms, err := execute(t.terms, td)
+		if err != nil {
+			return nil, err
+		}
+		if terms != "" {
+			notes = append(notes, terms)
+		}
+	}
+	y -= 24
+	for _, note := range notes {
+		for _, line := range wrap(pdf.Helvetica, bodySize, rightX-marginX, note) {
+			page.Text(marginX, y, pdf.Helvetica, bodySize, grey, line)
+			y -= 13
+		}
+		y -= 6
+	}
+
+	// Footers go on last, once the page count is known
+	pages := doc.Pages()
+	for i, p := range pages {
+		td.Page, td.Pages = i+1, len(pages)
+		footer, err := execute(t.footer, td)
+		if err != nil {
+			return nil, err
+		}
+		p.Line(marginX, 48, rightX, 48, 0.5, light)
+		p.Text(marginX, 34, pdf.Helvetica, smallSize, grey, footer)
+		p.TextRight(rightX, 34, pdf.Helvetica, smallSize, grey, fmt.Sprintf("Page %d of %d", td.Page, td.Pages))
+	}
+	return doc.Bytes(), nil
+}
+
+// header draws the branded band with the document title
+func (t *InvoiceTemplate) header(page *pdf.Page, inv models.Invoice) {
+	page.Rect(0, pdf.PageHeight-headerH, pdf.PageWidth, headerH, t.accent)
+	page.Text(marginX, pdf.PageHeight-55, pdf.HelveticaBold, 22, pdf.White, t.brand.Name)
+	title := "INVOICE"
+	switch {
+	case inv.Kind == "credit_note":
+		title = "CREDIT NOTE"
+	case inv.Status == "draft":
+		title = "DRAFT INVOICE"
+	case inv.Status == "void":
+		title = "VOID INVOICE"
+	}
+	page.TextRight(rightX, pdf.PageHeight-55, pdf.HelveticaBold, 16, pdf.White, title)
+}
+
+// parties draws the issuer and the client side by side
+func (t *InvoiceTemplate) parties(page *pdf.Page, data InvoiceData) {
+	top := pdf.PageHeight - headerH - 40
+	var issuer models.User
+	if data.Invoice.Issuer != nil {
+		issuer = *data.Invoice.Issuer
+	}
+	party(page, marginX, top, "FROM", issuer, data.IssuerTax)
+	party(page, 310, top, "BILL TO", data.Invoice.Client, data.ClientTax)
+}
+
+func party(page *pdf.Page, x, y float64, label string, user models.User, tax *models.TaxProfile) {
+	page.Text(x, y, pdf.HelveticaBold, smallSize, grey, label)
+	lines := []string{user.CompanyName, user.Email}
+	if tax != nil {
+		lines = append(lines, "Country: "+tax.Country)
+		if tax.VATID != "" {
+			lines = append(lines, "VAT ID: "+tax.VATID)
+		}
+	}
+	y -= 16
+	page.Text(x, y, pdf.HelveticaBold, 11, pdf.Black, pdf.Truncate(pdf.HelveticaBold, 11, 230, user.Name))
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		y -= 13
+		page.Text(x, y, pdf.Helvetica, bodySize, pdf.Black, pdf.Truncate(pdf.Helvetica, bodySize, 230, line))
+	}
+}
+
+// details draws the invoice number, dates and project
+func (t *InvoiceTemplate) details(page *pdf.Page, inv models.Invoice) {
+	number := inv.InvoiceNumber
+	if inv.Status == "draft" {
+		number = "Not yet issued"
+	}
+	rows := [][2]string{
+		{"Invoice number", number},
+		{"Issue date", inv.Date.Format("2006-01-02")},
+		{"Due date", inv.DueDate.Format("2006-01-02")},
+		{"Project", inv.Project.Title},
+	}
+	if inv.Kind == "credit_note" {
+		credited := ""
+		if inv.CreditedInvoice != nil {
+			credited = inv.CreditedInvoice.InvoiceNumber
+		}
+		rows = [][2]string{
+			{"Credit note number", number},
+			{"Issue date", inv.Date.Format("2006-01-02")},
+			{"Credits invoice", credited},
+			{"Project", inv.Project.Title},
+		}
+	}
+	y := 610.0
+	for _, r := range rows {
+		page.Text(marginX, y, pdf.Helvetica, bodySize, grey, r[0])
+		page.Text(marginX+100, y, pdf.Helvetica, bodySize, pdf.Black, pdf.Truncate(pdf.Helvetica, bodySize, 340, r[1]))
+		y -= 14
+	}
+}
+
+// tableHeader draws the column titles with their top at y and returns the
+// baseline of the header row
+func (t *InvoiceTemplate) tableHeader(page *pdf.Page, y float64) float64 {
+	page.Rect(marginX, y-20, rightX-marginX, 20, light)
+	y -= 14
+	page.Text(marginX+4, y, pdf.HelveticaBold, smallSize, pdf.Black, "Description")
+	page.TextRight(colQty, y, pdf.HelveticaBold, smallSize, pdf.Black, "Qty")
+	page.TextRight(colUnit, y, pdf.HelveticaBold, smallSize, pdf.Black, "Unit price")
+	page.TextRight(colNet, y, pdf.HelveticaBold, smallSize, pdf.Black, "Net")
+	page.TextRight(colTax, y, pdf.HelveticaBold, smallSize, pdf.Black, "Tax")
+	page.TextRight(colGross, y, pdf.HelveticaBold, smallSize, pdf.Black, "Gross")
+	return y - 6
+}
+
+// totals draws net, tax and the amount due below the table
+func (t *InvoiceTemplate) totals(page *pdf.Page, y float64, inv models.Invoice) float64 {
+	rows := [][2]string{
+		{"Net", formatMoney(inv.NetAmount)},
+		{"Tax " + formatRate(inv.TaxRate), formatMoney(inv.TaxAmount)},
+	}
+	for _, r := range rows {
+		y -= 16
+		page.Text(colNet-60, y, pdf.Helvetica, bodySize, grey, r[0])
+		page.TextRight(rightX, y, pdf.Helvetica, bodySize, pdf.Black, r[1])
+	}
+	y -= 8
+	page.Line(colNet-60, y, rightX, y, 0.8, t.accent)
+	y -= 18
+	label := "Total due"
+	if inv.Kind == "credit_note" {
+		label = "Total credited"
+	}
+	page.Text(colNet-60, y, pdf.HelveticaBold, 11, pdf.Black, label)
+	page.TextRight(rightX, y, pdf.HelveticaBold, 11, pdf.Black, formatMoney(inv.AmountDue))
+	return y
+}
+
+// rows lists the line items, or a single row for invoices without lines
+func rows(inv models.Invoice) []tableRow {
+	if len(inv.Lines) == 0 {
+		description := inv.Project.Title
+		if description == "" {
+			description = "Services"
+		}
+		return []tableRow{{
+			description: description, quantity: 1,
+			unit: inv.NetAmount, net: inv.NetAmount, rate: inv.TaxRate, gross: inv.AmountDue,
+		}}
+	}
+	out := make([]tableRow, len(inv.Lines))
+	for i, l := range inv.Lines {
+		out[i] = tableRow{l.Description, l.Quantity, l.UnitPrice, l.NetAmount, l.TaxRate, l.GrossAmount}
+	}
+	return out
+}
+
+func execute(tmpl *template.Template, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering %s template: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// wrap breaks text into lines no wider than width
+func wrap(font pdf.Font, size, width float64, text string) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && pdf.TextWidth(font, size, candidate) > width {
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatAmount renders an amount with thousands separators, e.g. "1,234.50"
+func formatAmount(m money.Money) string {
+	s := m.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	return sign + whole + "." + frac
+}
+
+func formatMoney(m money.Money) string {
+	return formatAmount(m) + " " + m.CurrencyOrDefault()
+}
+
+// formatRate renders basis points as a percentage, e.g. 2550 as "25.5%"
+func formatRate(bps int64) string {
+	s := fmt.Sprintf("%d.%02d", bps/100, bps%100)
+	s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	return s + "%"
+}
@@ -0,0 +1,345 @@
+package documents
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+)
+
+var ErrNotIssued = errors.New("only finalised invoices and credit notes can be exported")
+
+// Identifiers of the Peppol BIS Billing 3.0 specification
+const (
+	peppolCustomizationID = "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0"
+	peppolProfileID       = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+)
+
+// UBL 2.1 namespaces
+const (
+	nsInvoice    = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	nsCreditNote = "urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+	nsCAC        = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	nsCBC        = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+)
+
+// The element order of the types below follows the UBL 2.1 schema sequences.
+
+type ublDocument struct {
+	XMLName xml.Name
+	Xmlns   string `xml:"xmlns,attr"`
+	XmlnsAC string `xml:"xmlns:cac,attr"`
+	XmlnsBC string `xml:"xmlns:cbc,attr"`
+
+	CustomizationID      string           `xml:"cbc:CustomizationID"`
+	ProfileID            string           `xml:"cbc:ProfileID"`
+	ID                   string           `xml:"cbc:ID"`
+	IssueDate            string           `xml:"cbc:IssueDate"`
+	DueDate              string           `xml:"cbc:DueDate,omitempty"`
+	InvoiceTypeCode      string           `xml:"cbc:InvoiceTypeCode,omitempty"`
+	CreditNoteTypeCode   string           `xml:"cbc:CreditNoteTypeCode,omitempty"`
+	Notes                []string         `xml:"cbc:Note"`
+	DocumentCurrencyCode string           `xml:"cbc:DocumentCurrencyCode"`
+	BuyerReference       string           `xml:"cbc:BuyerReference"`
+	BillingReference     *ublReference    `xml:"cac:BillingReference>cac:InvoiceDocumentReference"`
+	Supplier             ublParty         `xml:"cac:AccountingSupplierParty>cac:Party"`
+	Customer             ublParty         `xml:"cac:AccountingCustomerParty>cac:Party"`
+	TaxTotal             ublTaxTotal      `xml:"cac:TaxTotal"`
+	Totals               ublMonetaryTotal `xml:"cac:LegalMonetaryTotal"`
+	InvoiceLines         []ublLine        `xml:"cac:InvoiceLine"`
+	CreditNoteLines      []ublLine        `xml:"cac:CreditNoteLine"`
+}
+
+type ublAmount struct {
+	Currency string `xml:"currencyID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublParty struct {
+	EndpointID ublIdentifier      `xml:"cbc:EndpointID"`
+	Name       string             `xml:"cac:PartyName>cbc:Name"`
+	Address    *ublAddress        `xml:"cac:PostalAddress"`
+	TaxScheme  *ublPartyTaxScheme `xml:"cac:PartyTaxScheme"`
+	LegalName  string             `xml:"cac:PartyLegalEntity>cbc:RegistrationName"`
+	Email      string             `xml:"cac:Contact>cbc:ElectronicMail,omitempty"`
+}
+
+type ublReference struct {
+	ID string `xml:"cbc:ID"`
+}
+
+type ublAddress struct {
+	Country string `xml:"cac:Country>cbc:IdentificationCode"`
+}
+
+type ublIdentifier struct {
+	SchemeID string `xml:"schemeID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublPartyTaxScheme struct {
+	CompanyID   string `xml:"cbc:CompanyID"`
+	TaxSchemeID string `xml:"cac:TaxScheme>cbc:ID"`
+}
+
+type ublTaxTotal struct {
+	TaxAmount ublAmount        `xml:"cbc:TaxAmount"`
+	Subtotals []ublTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type ublTaxSubtotal struct {
+	TaxableAmount ublAmount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     ublAmount      `xml:"cbc:TaxAmount"`
+	Category      ublTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type ublTaxCategory struct {
+	ID                  string `xml:"cbc:ID"`
+	Percent             string `xml:"cbc:Percent,omitempty"`
+	ExemptionReasonCode string `xml:"cbc:TaxExemptionReasonCode,omitempty"`
+	ExemptionReason     string `xml:"cbc:TaxExemptionReason,omitempty"`
+	TaxSchemeID         string `xml:"cac:TaxScheme>cbc:ID"`
+}
+
+type ublMonetaryTotal struct {
+	LineExtensionAmount ublAmount `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount  ublAmount `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount  ublAmount `xml:"cbc:TaxInclusiveAmount"`
+	PayableAmount       ublAmount `xml:"cbc:PayableAmount"`
+}
+
+type ublLine struct {
+	ID                  string       `xml:"cbc:ID"`
+	InvoicedQuantity    *ublQuantity `xml:"cbc:InvoicedQuantity"`
+	CreditedQuantity    *ublQuantity `xml:"cbc:CreditedQuantity"`
+	LineExtensionAmount ublAmount    `xml:"cbc:LineExtensionAmount"`
+	Item                ublItem      `xml:"cac:Item"`
+	PriceAmount         ublAmount    `xml:"cac:Price>cbc:PriceAmount"`
+}
+
+type ublItem struct {
+	Name     string                   `xml:"cbc:Name"`
+	Category ublClassifiedTaxCategory `xml:"cac:ClassifiedTaxCategory"`
+}
+
+type ublClassifiedTaxCategory struct {
+	ID          string `xml:"cbc:ID"`
+	Percent     string `xml:"cbc:Percent,omitempty"`
+	TaxSchemeID string `xml:"cac:TaxScheme>cbc:ID"`
+}
+
+type ublQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    int64  `xml:",chardata"`
+}
+
+// RenderUBL produces a UBL 2.1 Invoice, or a CreditNote for credit notes,
+// following Peppol BIS Billing 3.0. Drafts and voided invoices are refused.
+func RenderUBL(data InvoiceData) ([]byte, error) {
+	inv := data.Invoice
+	if inv.Status != "finalised" {
+		return nil, ErrNotIssued
+	}
+	currency := inv.AmountDue.CurrencyOrDefault()
+
+	doc := ublDocument{
+		Xmlns:                nsInvoice,
+		XmlnsAC:              nsCAC,
+		XmlnsBC:              nsCBC,
+		CustomizationID:      peppolCustomizationID,
+		ProfileID:            peppolProfileID,
+		ID:                   inv.InvoiceNumber,
+		IssueDate:            inv.Date.UTC().Format("2006-01-02"),
+		DocumentCurrencyCode: currency,
+		BuyerReference:       buyerReference(inv),
+		Customer:             ublPartyOf(inv.Client, data.ClientTax),
+	}
+	if inv.Issuer != nil {
+		doc.Supplier = ublPartyOf(*inv.Issuer, data.IssuerTax)
+	}
+	if inv.TaxNote != "" {
+		doc.Notes = append(doc.Notes, inv.TaxNote)
+	}
+
+	credit := inv.Kind == "credit_note"
+	if credit {
+		doc.XMLName = xml.Name{Local: "CreditNote"}
+		doc.Xmlns = nsCreditNote
+		doc.CreditNoteTypeCode = "381"
+		if inv.CreditReason != "" {
+			doc.Notes = append(doc.Notes, inv.CreditReason)
+		}
+		if inv.CreditedInvoice != nil {
+			doc.BillingReference = &ublReference{ID: inv.CreditedInvoice.InvoiceNumber}
+		}
+	} else {
+		doc.XMLName = xml.Name{Local: "Invoice"}
+		doc.DueDate = inv.DueDate.UTC().Format("2006-01-02")
+		doc.InvoiceTypeCode = "380"
+	}
+
+	// Lines, and the tax breakdown per rate
+	category := ublCategory(inv.TaxTreatment, inv.TaxNote)
+	type group struct{ net, tax int64 }
+	groups := map[int64]*group{}
+	for i, row := range rows(inv) {
+		cat := category
+		if cat.Percent != "" {
+			cat.Percent = ublPercent(row.rate)
+		}
+		line := ublLine{
+			ID:                  strconv.Itoa(i + 1),
+			LineExtensionAmount: ublAmountOf(row.net, currency),
+			Item: ublItem{
+				Name:     row.description,
+				Category: ublClassifiedTaxCategory{ID: cat.ID, Percent: cat.Percent, TaxSchemeID: "VAT"},
+			},
+			PriceAmount: ublAmountOf(row.unit, currency),
+		}
+		quantity := &ublQuantity{UnitCode: "C62", Value: row.quantity}
+		if credit {
+			line.CreditedQuantity = quantity
+			doc.CreditNoteLines = append(doc.CreditNoteLines, line)
+		} else {
+			line.InvoicedQuantity = quantity
+			doc.InvoiceLines = append(doc.InvoiceLines, line)
+		}
+
+		g, ok := groups[row.rate]
+		if !ok {
+			g = &group{}
+			groups[row.rate] = g
+		}
+		g.net += row.net.Amount
+		g.tax += row.gross.Amount - row.net.Amount
+	}
+	rates := make([]int64, 0, len(groups))
+	for rate := range groups {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i] < rates[j] })
+	for _, rate := range rates {
+		cat := category
+		if cat.Percent != "" {
+			cat.Percent = ublPercent(rate)
+		}
+		doc.TaxTotal.Subtotals = append(doc.TaxTotal.Subtotals, ublTaxSubtotal{
+			TaxableAmount: ublAmountOf(money.New(groups[rate].net, currency), currency),
+			TaxAmount:     ublAmountOf(money.New(groups[rate].tax, currency), currency),
+			Category:      cat,
+		})
+	}
+
+	doc.TaxTotal.TaxAmount = ublAmountOf(inv.TaxAmount, currency)
+	doc.Totals = ublMonetaryTotal{
+		LineExtensionAmount: ublAmountOf(inv.NetAmount, currency),
+		TaxExclusiveAmount:  ublAmountOf(inv.NetAmount, currency),
+		TaxInclusiveAmount:  ublAmountOf(inv.AmountDue, currency),
+		PayableAmount:       ublAmountOf(inv.AmountDue, currency),
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// WriteUBLArchive writes a ZIP with the UBL document of every invoice. Entry
+// times are the issue dates, so the archive only depends on the invoices.
+func WriteUBLArchive(w io.Writer, docs []InvoiceData) error {
+	zw := zip.NewWriter(w)
+	for _, data := range docs {
+		body, err := RenderUBL(data)
+		if err != nil {
+			return err
+		}
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     UBLFileName(data.Invoice),
+			Method:   zip.Deflate,
+			Modified: data.Invoice.Date.UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// UBLFileName names the XML file of an invoice after its number
+func UBLFileName(inv models.Invoice) string {
+	name := strings.NewReplacer("/", "-", `\`, "-").Replace(inv.InvoiceNumber)
+	if inv.Kind == "credit_note" {
+		return "credit-note-" + name + ".xml"
+	}
+	return "invoice-" + name + ".xml"
+}
+
+// ublPartyOf describes a user; the e-mail address doubles as the Peppol
+// endpoint since users have no Peppol participant ID
+func ublPartyOf(user models.User, tax *models.TaxProfile) ublParty {
+	name := user.CompanyName
+	if name == "" {
+		name = user.Name
+	}
+	p := ublParty{
+		EndpointID: ublIdentifier{SchemeID: "EM", Value: user.Email},
+		Name:       name,
+		LegalName:  name,
+		Email:      user.Email,
+	}
+	if tax != nil {
+		if tax.Country != "" {
+			p.Address = &ublAddress{Country: tax.Country}
+		}
+		if tax.VATID != "" {
+			p.TaxScheme = &ublPartyTaxScheme{CompanyID: tax.VATID, TaxSchemeID: "VAT"}
+		}
+	}
+	return p
+}
+
+// ublCategory maps a tax treatment to its UNCL5305 tax category. Percent is
+// only set (to a placeholder) for categories that carry one.
+func ublCategory(treatment, note string) ublTaxCategory {
+	switch treatment {
+	case "standard":
+		return ublTaxCategory{ID: "S", Percent: "0", TaxSchemeID: "VAT"}
+	case "reverse_charge":
+		return ublTaxCategory{ID: "AE", Percent: "0", ExemptionReasonCode: "VATEX-EU-AE", ExemptionReason: "Reverse charge", TaxSchemeID: "VAT"}
+	case "exempt":
+		reason := note
+		if reason == "" {
+			reason = "Exempt from VAT"
+		}
+		return ublTaxCategory{ID: "E", Percent: "0", ExemptionReason: reason, TaxSchemeID: "VAT"}
+	default:
+		return ublTaxCategory{ID: "O", ExemptionReasonCode: "VATEX-EU-O", ExemptionReason: "Not subject to VAT", TaxSchemeID: "VAT"}
+	}
+}
+
+// buyerReference is required by Peppol; the project identifies the order
+func buyerReference(inv models.Invoice) string {
+	if inv.Project.Title != "" {
+		return inv.Project.Title
+	}
+	return "Project " + strconv.FormatUint(uint64(inv.ProjectID), 10)
+}
+
+func ublAmountOf(m money.Money, currency string) ublAmount {
+	return ublAmount{Currency: currency, Value: m.String()}
+}
+
+// ublPercent renders basis points as a plain percentage, e.g. 2550 as "25.5"
+func ublPercent(bps int64) string {
+	return strings.TrimSuffix(formatRate(bps), "%")
+}
@@ -0,0 +1,75 @@
+// Package jobs runs periodic background work such as payout batches.
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of periodic work. Returning an error only logs it; the job
+// runs again at the next tick.
+type Job func() error
+
+type entry struct {
+	name     string
+	interval time.Duration
+	run      Job
+}
+
+// Scheduler runs registered jobs on fixed intervals, each in its own
+// goroutine. A job never overlaps with itself: a slow run delays the next one.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries []entry
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{stop: make(chan struct{})}
+}
+
+// Every registers a job. It must be called before Start.
+func (s *Scheduler) Every(name string, interval time.Duration, run Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry{name: name, interval: interval, run: run})
+}
+
+// Start launches all registered jobs. Jobs with a non-positive interval are
+// disabled.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if e.interval <= 0 {
+			log.Printf("job %s disabled", e.name)
+			continue
+		}
+		s.wg.Add(1)
+		go s.loop(e)
+	}
+}
+
+// Stop signals all jobs to finish and waits for running ones.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(e entry) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := e.run(); err != nil {
+				log.Printf("job %s failed: %v", e.name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package mail sends e-mail to users. Services only talk to the Mailer
+// interface; the transport is picked at startup (see cmd/server).
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+var ErrUnknownProvider = errors.New("unknown mail provider")
+
+// Message is a plain-text e-mail.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is implemented by every mail transport.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTPConfig is the relay used by the smtp provider.
+type SMTPConfig struct {
+	Addr     string // host:port
+	Username string // optional; enables PLAIN auth
+	Password string
+}
+
+// NewMailer returns the transport with the given name. An empty name selects
+// the log mailer, which only writes messages to the server log.
+func NewMailer(name, from string, cfg SMTPConfig) (Mailer, error) {
+	switch name {
+	case "", "log":
+		return LogMailer{}, nil
+	case "smtp":
+		if cfg.Addr == "" || from == "" {
+			return nil, errors.New("the smtp mail provider needs SMTP_ADDR and MAIL_FROM")
+		}
+		return &SMTPMailer{from: from, cfg: cfg}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+}
+
+// LogMailer logs messages instead of sending them; useful for local dev.
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// SMTPMailer delivers messages through an SMTP relay.
+type SMTPMailer struct {
+	from string
+	cfg  SMTPConfig
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		host, _, err := net.SplitHostPort(m.cfg.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, host)
+	}
+	return smtp.SendMail(m.cfg.Addr, auth, m.from, []string{msg.To}, m.format(msg))
+}
+
+func (m *SMTPMailer) format(msg Message) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", headerValue(m.from))
+	fmt.Fprintf(&sb, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&sb, "Subject: %s\r\n", headerValue(msg.Subject))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	sb.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(sb.String())
+}
+
+// headerValue drops line breaks so values can't inject extra headers
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(s)
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"FreeConnect/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header clients set to make a POST safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyMiddleware stores the first response for each (user, Idempotency-Key)
+// and replays it on repeated requests. Requests without the header pass through.
+// It must run after AuthMiddleware, since keys are scoped per user.
+func IdempotencyMiddleware(idem services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		// Read the body for fingerprinting and put it back for the handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetUint("userID")
+		record, replay, err := idem.Begin(userID, key, c.Request.Method, c.Request.URL.Path, body)
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyMismatch), errors.Is(err, services.ErrIdempotencyKeyInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// Release the key unless a response gets stored for it, so a server
+		// error or a panic in the handler doesn't block retries until it expires
+		completed := false
+		defer func() {
+			if !completed {
+				_ = idem.Release(record)
+			}
+		}()
+
+		// Run the handler while capturing what it writes
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			// Don't pin a server error to the key; let the client retry.
+			return
+		}
+		if err := idem.Complete(record, status, recorder.body.Bytes()); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		completed = true
+	}
+}
+
+// responseRecorder tees the response body so it can be stored.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// BidCreditAccount holds the bid credits a freelancer spends on proposals.
+// Every month the account is granted an allowance; more credits can be
+// bought as top-ups. Balance is kept in step with the BidCreditEntries.
+type BidCreditAccount struct {
+	ID      uint `gorm:"column:bid_credit_account_id;primaryKey" json:"bid_credit_account_id"`
+	Balance int  `gorm:"not null;default:0;check:balance >= 0" json:"balance"`
+	// First day of the last month the allowance was granted for
+	AllowanceMonth *time.Time `json:"allowance_month,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	UserID uint `gorm:"not null;uniqueIndex" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// BidCreditEntry is one movement on a bid credit account: the monthly
+// allowance, a top-up, the cost of a proposal or its refund. Credits is
+// negative for spending.
+type BidCreditEntry struct {
+	ID           uint      `gorm:"column:bid_credit_entry_id;primaryKey" json:"bid_credit_entry_id"`
+	Kind         string    `gorm:"type:varchar(20);not null;check:kind IN ('allowance','top_up','proposal','refund')" json:"kind"`
+	Credits      int       `gorm:"not null" json:"credits"`
+	BalanceAfter int       `gorm:"not null" json:"balance_after"`
+	Description  string    `gorm:"type:varchar(255)" json:"description,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	// The proposal paid for or refunded; a proposal is refunded at most once
+	ProposalID *uint     `gorm:"index;uniqueIndex:idx_bid_credit_entries_refund,where:kind = 'refund'" json:"proposal_id,omitempty"`
+	Proposal   *Proposal `gorm:"foreignKey:ProposalID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	ProjectID  *uint     `gorm:"index" json:"project_id,omitempty"`
+	Project    *Project  `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+
+	PurchaseID *uint              `gorm:"index" json:"purchase_id,omitempty"`
+	Purchase   *BidCreditPurchase `gorm:"foreignKey:PurchaseID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
+
+// BidCreditPurchase is a top-up of bid credits paid through the payment
+// gateway. The credits are added once the payment has succeeded.
+type BidCreditPurchase struct {
+	ID            uint        `gorm:"column:bid_credit_purchase_id;primaryKey" json:"bid_credit_purchase_id"`
+	Pack          string      `gorm:"type:varchar(50);not null" json:"pack"`
+	Credits       int         `gorm:"not null;check:credits > 0" json:"credits"`
+	Price         money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	PaymentMethod string      `gorm:"type:varchar(50);check:payment_method IN ('credit_card','paypal','bank_transfer')" json:"payment_method"`
+	Status        string      `gorm:"type:varchar(20);not null;default:'pending';check:status IN ('pending','processing','succeeded','failed')" json:"status"`
+
+	Gateway          string `gorm:"type:varchar(50)" json:"gateway,omitempty"`
+	GatewayReference string `gorm:"type:varchar(100);index" json:"gateway_reference,omitempty"`
+	FailureReason    string `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
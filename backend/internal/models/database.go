@@ -15,3 +15,78 @@ func ConnectDatabase(cfg *config.Config) (*gorm.DB, error) {
 	}
 	return db, nil
 }
+
+// AllModels lists every model managed by AutoMigrate, in dependency order.
+func AllModels() []interface{} {
+	return []interface{}{
+		&User{},
+		&ExchangeRate{},
+		&Project{},
+		&Skill{},
+		&Proposal{},
+		&ProposalRevision{},
+		&ProjectInvitation{},
+		&BidCreditAccount{},
+		&BidCreditPurchase{},
+		&BidCreditEntry{},
+		&Review{},
+		&FeeRule{},
+		&FeeTier{},
+		&Transaction{},
+		&TransactionFee{},
+		&TransactionStatusChange{},
+		&Notification{},
+		&TaxProfile{},
+		&TaxRule{},
+		&InvoiceSchedule{},
+		&Invoice{},
+		&Task{},
+		&InvoiceLine{},
+		&InvoiceSequence{},
+		&BillingTerms{},
+		&InvoiceReminder{},
+		&InvoiceLink{},
+		&InvoicePayment{},
+		&InvoiceAllocation{},
+		&ClientCredit{},
+		&Refund{},
+		&Chargeback{},
+		&WebhookEvent{},
+		&WebhookDeadLetter{},
+		&IdempotencyKey{},
+		&PayoutMethod{},
+		&WithdrawalRequest{},
+		&ReconciliationRun{},
+		&Discrepancy{},
+	}
+}
+
+// Migrate runs the data migrations that AutoMigrate can't express on its own
+// and then auto-migrates all models. Both the server and the test setup use it.
+func Migrate(db *gorm.DB) error {
+	if err := migrateMoneyColumns(db); err != nil {
+		return err
+	}
+	if err := refreshCheckConstraints(db); err != nil {
+		return err
+	}
+	if err := migrateInvoiceNumbering(db); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(AllModels()...); err != nil {
+		return err
+	}
+	if err := backfillBalancesCredited(db); err != nil {
+		return err
+	}
+	if err := backfillSettlementAmounts(db); err != nil {
+		return err
+	}
+	if err := backfillInvoiceNetAmounts(db); err != nil {
+		return err
+	}
+	if err := backfillProposalRevisions(db); err != nil {
+		return err
+	}
+	return seedTaxRules(db)
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// ExchangeRate is the rate for a currency pair from its effective date until
+// the next rate for the same pair: 1 BaseCurrency = Rate QuoteCurrency.
+type ExchangeRate struct {
+	ID            uint       `gorm:"column:exchange_rate_id;primaryKey" json:"exchange_rate_id"`
+	BaseCurrency  string     `gorm:"type:char(3);not null;uniqueIndex:idx_exchange_rate_pair_date" json:"base_currency"`
+	QuoteCurrency string     `gorm:"type:char(3);not null;uniqueIndex:idx_exchange_rate_pair_date" json:"quote_currency"`
+	Rate          money.Rate `gorm:"type:bigint;not null;check:rate > 0" json:"rate"`
+	EffectiveDate time.Time  `gorm:"type:date;not null;uniqueIndex:idx_exchange_rate_pair_date" json:"effective_date"`
+	Source        string     `gorm:"type:varchar(100)" json:"source,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
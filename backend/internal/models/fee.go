@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// FeeRule is a platform commission rule. Active rules for a project's
+// category replace the default (category-less) rules; all rules in effect
+// are added up.
+//
+//   - percentage: Percent of the transaction amount
+//   - flat:       FlatAmount per transaction, converted into the transaction currency
+//   - tiered:     Percent of the tier matching the lifetime billing between
+//     the client and the freelancer
+type FeeRule struct {
+	ID         uint        `gorm:"column:fee_rule_id;primaryKey" json:"fee_rule_id"`
+	Name       string      `gorm:"type:varchar(255);not null" json:"name"`
+	Type       string      `gorm:"type:varchar(20);not null;check:type IN ('percentage','flat','tiered')" json:"type"`
+	Category   string      `gorm:"type:varchar(100);index" json:"category,omitempty"`                // empty applies to all categories
+	Percent    int64       `gorm:"default:0;check:percent >= 0 AND percent <= 10000" json:"percent"` // basis points, 1000 = 10%
+	FlatAmount money.Money `gorm:"embedded;embeddedPrefix:flat_amount_" json:"flat_amount"`
+	Active     bool        `gorm:"default:true" json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+
+	Tiers []FeeTier `gorm:"foreignKey:FeeRuleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"tiers,omitempty"`
+}
+
+// FeeTier applies Percent once the lifetime billing between a client and a
+// freelancer has reached MinBilling.
+type FeeTier struct {
+	ID         uint        `gorm:"column:fee_tier_id;primaryKey" json:"fee_tier_id"`
+	FeeRuleID  uint        `gorm:"not null;index" json:"fee_rule_id"`
+	MinBilling money.Money `gorm:"embedded;embeddedPrefix:min_billing_" json:"min_billing"`
+	Percent    int64       `gorm:"not null;check:percent >= 0 AND percent <= 10000" json:"percent"`
+}
+
+// TransactionFee is one ledger line of the platform fee charged on a
+// transaction. The fee is kept out of the freelancer's earnings.
+type TransactionFee struct {
+	ID          uint        `gorm:"column:transaction_fee_id;primaryKey" json:"transaction_fee_id"`
+	Description string      `gorm:"type:varchar(255)" json:"description"`
+	Amount      money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	// The same fee in the freelancer's currency, at the transaction's applied rate
+	SettlementAmount money.Money `gorm:"embedded;embeddedPrefix:settlement_amount_" json:"settlement_amount"`
+	CreatedAt        time.Time   `json:"created_at"`
+
+	TransactionID uint     `gorm:"not null;index" json:"transaction_id"`
+	FeeRuleID     *uint    `json:"fee_rule_id,omitempty"`
+	FeeRule       *FeeRule `gorm:"foreignKey:FeeRuleID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the first response to a request made with an
+// Idempotency-Key header so that retries get the same answer instead of
+// repeating the side effect. Keys are scoped per user.
+type IdempotencyKey struct {
+	ID           uint       `gorm:"column:idempotency_key_id;primaryKey" json:"idempotency_key_id"`
+	Key          string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	UserID       uint       `gorm:"not null;uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	Method       string     `gorm:"type:varchar(10);not null" json:"method"`
+	Path         string     `gorm:"type:varchar(255);not null" json:"path"`
+	RequestHash  string     `gorm:"type:char(64);not null" json:"request_hash"`
+	StatusCode   int        `gorm:"default:0" json:"status_code"` // 0 while the first request is still running
+	ResponseBody []byte     `gorm:"type:bytea" json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
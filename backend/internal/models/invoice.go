@@ -2,19 +2,80 @@ package models
 
 import (
 	"time"
+
+	"FreeConnect/internal/money"
 )
 
+// Invoice is issued by the project's freelancer to the client. AmountDue is
+// the gross amount: NetAmount plus TaxAmount. When the invoice has lines, the
+// amounts are their totals.
+//
+// Invoices start out as drafts without a number. Finalising one allocates the
+// next number of the issuer's sequence for the year; from then on the invoice
+// can't be changed or deleted. It is corrected by voiding it or by issuing
+// credit notes against it.
+//
+// A credit note is stored as an Invoice of kind credit_note that references
+// the invoice it offsets. Its amounts are positive and are subtracted from the
+// original in reports.
 type Invoice struct {
-	ID            uint      `gorm:"column:invoice_id;primaryKey" json:"invoice_id"`
-	InvoiceNumber string    `gorm:"type:varchar(50);unique;not null" json:"invoice_number"`
-	Date          time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"date"`
-	AmountDue     float64   `gorm:"type:decimal(10,2);not null" json:"amount_due"`
-	PaymentStatus string    `gorm:"type:varchar(50);default:'pending';check:payment_status IN ('pending','paid','overdue')" json:"payment_status"`
-	DueDate       time.Time `gorm:"not null" json:"due_date"`
+	ID            uint        `gorm:"column:invoice_id;primaryKey" json:"invoice_id"`
+	InvoiceNumber string      `gorm:"type:varchar(50);not null;default:'';uniqueIndex:idx_invoices_issuer_number,priority:2,where:invoice_number <> ''" json:"invoice_number,omitempty"`
+	Kind          string      `gorm:"type:varchar(20);not null;default:'invoice';check:kind IN ('invoice','credit_note')" json:"kind"`
+	Status        string      `gorm:"type:varchar(20);not null;default:'draft';check:status IN ('draft','finalised','void')" json:"status"`
+	Date          time.Time   `gorm:"default:CURRENT_TIMESTAMP" json:"date"` // the issue date once finalised
+	FinalisedAt   *time.Time  `json:"finalised_at,omitempty"`
+	NetAmount     money.Money `gorm:"embedded;embeddedPrefix:net_amount_" json:"net_amount"`
+	TaxAmount     money.Money `gorm:"embedded;embeddedPrefix:tax_amount_" json:"tax_amount"`
+	AmountDue     money.Money `gorm:"embedded;embeddedPrefix:amount_due_" json:"amount_due"`
+	PaymentStatus string      `gorm:"type:varchar(50);default:'pending';check:payment_status IN ('pending','paid','overdue','credited')" json:"payment_status"`
+	DueDate       time.Time   `gorm:"not null" json:"due_date"`
+
+	// Charged once when the invoice is overdue, according to the client's
+	// BillingTerms. It is owed on top of AmountDue.
+	LateFee          money.Money `gorm:"embedded;embeddedPrefix:late_fee_" json:"late_fee"`
+	LateFeeAppliedAt *time.Time  `json:"late_fee_applied_at,omitempty"`
+
+	// Paid so far through transactions, public links and client credit. The
+	// invoice is paid once this covers AmountDue and LateFee less credit notes.
+	AmountPaid money.Money `gorm:"embedded;embeddedPrefix:amount_paid_" json:"amount_paid"`
+	PaidAt     *time.Time  `json:"paid_at,omitempty"`
+
+	// Set when a finalised invoice is cancelled; its number stays used
+	VoidedAt   *time.Time `json:"voided_at,omitempty"`
+	VoidReason string     `gorm:"type:varchar(255)" json:"void_reason,omitempty"`
+
+	// Only on credit notes: the invoice offset and why
+	CreditedInvoiceID *uint    `gorm:"index" json:"credited_invoice_id,omitempty"`
+	CreditedInvoice   *Invoice `gorm:"foreignKey:CreditedInvoiceID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" json:"credited_invoice,omitempty"`
+	CreditReason      string   `gorm:"type:varchar(255)" json:"credit_reason,omitempty"`
+
+	// Tracked through the invoice's public links
+	OpenCount     int        `gorm:"not null;default:0" json:"open_count"`
+	FirstOpenedAt *time.Time `json:"first_opened_at,omitempty"`
+	LastOpenedAt  *time.Time `json:"last_opened_at,omitempty"`
+
+	// Only on invoices issued by a recurring schedule: the schedule and the
+	// start of the period billed
+	ScheduleID  *uint            `gorm:"uniqueIndex:idx_invoices_schedule_period,priority:1" json:"schedule_id,omitempty"`
+	Schedule    *InvoiceSchedule `gorm:"foreignKey:ScheduleID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	PeriodStart *time.Time       `gorm:"uniqueIndex:idx_invoices_schedule_period,priority:2" json:"period_start,omitempty"`
+
+	// How tax was determined, fixed when the invoice is created
+	TaxTreatment string `gorm:"type:varchar(20);not null;default:'none';check:tax_treatment IN ('none','standard','reverse_charge','exempt','outside_scope')" json:"tax_treatment"`
+	TaxRate      int64  `gorm:"default:0" json:"tax_rate"` // basis points
+	TaxCountry   string `gorm:"type:char(2)" json:"tax_country,omitempty"`
+	TaxNote      string `gorm:"type:varchar(255)" json:"tax_note,omitempty"`
 
 	ProjectID uint    `json:"project_id"`
 	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"project,omitempty"`
 
 	ClientID uint `json:"client_id"`
 	Client   User `gorm:"foreignKey:ClientID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"client,omitempty"`
+
+	// The freelancer issuing the invoice; defaults to the project's freelancer
+	IssuerID *uint `gorm:"uniqueIndex:idx_invoices_issuer_number,priority:1" json:"issuer_id,omitempty"`
+	Issuer   *User `gorm:"foreignKey:IssuerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"issuer,omitempty"`
+
+	Lines []InvoiceLine `gorm:"foreignKey:InvoiceID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"lines,omitempty"`
 }
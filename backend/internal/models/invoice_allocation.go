@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// InvoiceAllocation links a transaction to an invoice it pays. The client
+// names the invoices when paying; the amounts are only allocated once the
+// transaction completes, to the invoice due first, and whatever is left over
+// becomes ClientCredit.
+type InvoiceAllocation struct {
+	ID          uint        `gorm:"column:invoice_allocation_id;primaryKey" json:"invoice_allocation_id"`
+	Amount      money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"` // zero until allocated
+	AllocatedAt *time.Time  `json:"allocated_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+
+	TransactionID uint        `gorm:"not null;uniqueIndex:idx_invoice_allocations_transaction_invoice,priority:1" json:"transaction_id"`
+	Transaction   Transaction `gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	InvoiceID uint    `gorm:"not null;index;uniqueIndex:idx_invoice_allocations_transaction_invoice,priority:2" json:"invoice_id"`
+	Invoice   Invoice `gorm:"foreignKey:InvoiceID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// ClientCredit is one entry in the credit a client holds with a freelancer.
+// An overpayment adds a positive entry, applying credit to an invoice a
+// negative one; the balance is the sum of the entries per currency.
+type ClientCredit struct {
+	ID        uint        `gorm:"column:client_credit_id;primaryKey" json:"client_credit_id"`
+	Amount    money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Reason    string      `gorm:"type:varchar(255);not null" json:"reason"`
+	CreatedAt time.Time   `json:"created_at"`
+
+	ClientID     uint `gorm:"not null;index:idx_client_credits_parties,priority:1" json:"client_id"`
+	Client       User `gorm:"foreignKey:ClientID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	FreelancerID uint `gorm:"not null;index:idx_client_credits_parties,priority:2" json:"freelancer_id"`
+	Freelancer   User `gorm:"foreignKey:FreelancerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	// The overpaid transaction, or the invoice the credit was applied to
+	TransactionID *uint        `gorm:"index" json:"transaction_id,omitempty"`
+	Transaction   *Transaction `gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	InvoiceID     *uint        `gorm:"index" json:"invoice_id,omitempty"`
+	Invoice       *Invoice     `gorm:"foreignKey:InvoiceID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
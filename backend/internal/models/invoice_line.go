@@ -0,0 +1,22 @@
+package models
+
+import "FreeConnect/internal/money"
+
+// InvoiceLine is one billed item of an invoice. NetAmount is UnitPrice times
+// Quantity; tax is calculated per line at the invoice's rate.
+type InvoiceLine struct {
+	ID          uint        `gorm:"column:invoice_line_id;primaryKey" json:"invoice_line_id"`
+	InvoiceID   uint        `gorm:"not null;index" json:"invoice_id"`
+	Position    int         `gorm:"not null;default:0" json:"position"`
+	Description string      `gorm:"type:varchar(255);not null" json:"description"`
+	Quantity    int64       `gorm:"not null;default:1;check:quantity > 0" json:"quantity"`
+	UnitPrice   money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	NetAmount   money.Money `gorm:"embedded;embeddedPrefix:net_amount_" json:"net_amount"`
+	TaxRate     int64       `gorm:"default:0" json:"tax_rate"` // basis points
+	TaxAmount   money.Money `gorm:"embedded;embeddedPrefix:tax_amount_" json:"tax_amount"`
+	GrossAmount money.Money `gorm:"embedded;embeddedPrefix:gross_amount_" json:"gross_amount"`
+
+	// The task billed by this line, if it was generated from one
+	TaskID *uint `gorm:"index" json:"task_id,omitempty"`
+	Task   *Task `gorm:"foreignKey:TaskID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
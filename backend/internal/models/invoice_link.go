@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// InvoiceLink is a public, read-only link to an invoice for people without an
+// account, e.g. the client's finance department. The URL carries a token
+// signed by the server that names the link and its expiry; the row lets the
+// issuer revoke the link and counts how often it was opened.
+type InvoiceLink struct {
+	ID           uint       `gorm:"column:invoice_link_id;primaryKey" json:"invoice_link_id"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	OpenCount    int        `gorm:"not null;default:0" json:"open_count"`
+	LastOpenedAt *time.Time `json:"last_opened_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	InvoiceID uint    `gorm:"not null;index" json:"invoice_id"`
+	Invoice   Invoice `gorm:"foreignKey:InvoiceID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	CreatedByID uint `gorm:"not null" json:"created_by_id"`
+	CreatedBy   User `gorm:"foreignKey:CreatedByID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// InvoicePayment is a payment of an invoice made through one of its public
+// links. Like transactions, its status is only changed from the payment
+// gateway's results. An invoice has at most one payment that hasn't settled
+// yet, so the same invoice can't be paid twice in parallel.
+type InvoicePayment struct {
+	ID            uint        `gorm:"column:invoice_payment_id;primaryKey" json:"invoice_payment_id"`
+	Amount        money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	PaymentMethod string      `gorm:"type:varchar(50);check:payment_method IN ('credit_card','paypal','bank_transfer')" json:"payment_method"`
+	Status        string      `gorm:"type:varchar(20);not null;default:'pending';check:status IN ('pending','processing','succeeded','failed')" json:"status"`
+
+	Gateway          string `gorm:"type:varchar(50)" json:"gateway,omitempty"`
+	GatewayReference string `gorm:"type:varchar(100);index" json:"gateway_reference,omitempty"`
+	FailureReason    string `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	InvoiceID uint    `gorm:"not null;index;uniqueIndex:idx_invoice_payments_unsettled,where:status IN ('pending','processing')" json:"invoice_id"`
+	Invoice   Invoice `gorm:"foreignKey:InvoiceID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	LinkID *uint        `gorm:"index" json:"link_id,omitempty"`
+	Link   *InvoiceLink `gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
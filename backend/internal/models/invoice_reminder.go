@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// BillingTerms are the payment terms agreed with a client. Once one of the
+// client's invoices is more than GraceDays overdue it is charged a late fee
+// of LateFeeRate on the amount due. Clients without terms pay no late fees.
+type BillingTerms struct {
+	ID          uint      `gorm:"column:billing_terms_id;primaryKey" json:"billing_terms_id"`
+	ClientID    uint      `gorm:"not null;uniqueIndex" json:"client_id"`
+	LateFeeRate int64     `gorm:"not null;default:0;check:late_fee_rate >= 0 AND late_fee_rate <= 10000" json:"late_fee_rate"` // basis points, 150 = 1.5%
+	GraceDays   int       `gorm:"not null;default:0;check:grace_days >= 0" json:"grace_days"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Client User `gorm:"foreignKey:ClientID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// InvoiceReminder records that the reminder of one stage was sent for an
+// invoice, so every stage goes out at most once.
+type InvoiceReminder struct {
+	ID        uint      `gorm:"column:invoice_reminder_id;primaryKey" json:"invoice_reminder_id"`
+	InvoiceID uint      `gorm:"not null;uniqueIndex:idx_invoice_reminders_stage,priority:1" json:"invoice_id"`
+	Stage     string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_invoice_reminders_stage,priority:2;check:stage IN ('before_due','due','overdue_7','overdue_30')" json:"stage"`
+	SentAt    time.Time `gorm:"not null" json:"sent_at"`
+	Emailed   bool      `gorm:"default:false" json:"emailed"`
+
+	Invoice Invoice `gorm:"foreignKey:InvoiceID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// InvoiceSchedule bills a project the same net amount at a fixed interval,
+// e.g. a monthly retainer. Run n bills the period starting n intervals after
+// StartDate; NextRunAt is the start of the next period to bill and RunCount
+// the number of periods billed or skipped so far. Every invoice records its schedule and
+// period, and a unique index on the pair stops a period being billed twice.
+//
+// Paused schedules skip the periods that start while they are paused.
+// Cancelled and completed schedules never run again.
+type InvoiceSchedule struct {
+	ID          uint        `gorm:"column:invoice_schedule_id;primaryKey" json:"invoice_schedule_id"`
+	Interval    string      `gorm:"column:billing_interval;type:varchar(20);not null;check:billing_interval IN ('weekly','monthly','quarterly','yearly')" json:"interval"`
+	Amount      money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"` // net, per period
+	Description string      `gorm:"type:varchar(255);not null" json:"description"`
+	DueDays     int         `gorm:"not null;check:due_days >= 0" json:"due_days"` // payment term of each invoice
+	StartDate   time.Time   `gorm:"not null" json:"start_date"`
+	EndDate     *time.Time  `json:"end_date,omitempty"` // no period starts after it
+	Status      string      `gorm:"type:varchar(20);not null;default:'active';index;check:status IN ('active','paused','cancelled','completed')" json:"status"`
+	NextRunAt   time.Time   `gorm:"not null;index" json:"next_run_at"`
+	RunCount    int         `gorm:"not null;default:0" json:"run_count"`
+	LastRunAt   *time.Time  `json:"last_run_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+
+	ProjectID uint    `gorm:"not null;index" json:"project_id"`
+	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	// The freelancer who set the schedule up and issues its invoices
+	IssuerID uint `gorm:"not null" json:"issuer_id"`
+	Issuer   User `gorm:"foreignKey:IssuerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// InvoiceSequence is the last number handed out in one numbering series of
+// an issuer for a year. Rows are incremented inside the transaction that
+// finalises the invoice, so a rollback gives the number back and the series
+// stays gap-free.
+type InvoiceSequence struct {
+	ID         uint      `gorm:"column:invoice_sequence_id;primaryKey" json:"invoice_sequence_id"`
+	IssuerID   uint      `gorm:"not null;uniqueIndex:idx_invoice_sequences_scope,priority:1" json:"issuer_id"`
+	Series     string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_invoice_sequences_scope,priority:2" json:"series"`
+	Year       int       `gorm:"not null;uniqueIndex:idx_invoice_sequences_scope,priority:3" json:"year"`
+	LastNumber int64     `gorm:"not null;default:0" json:"last_number"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"fmt"
+
+	"FreeConnect/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// legacyMoneyColumn is a decimal(10,2) column that has been replaced by an
+// embedded money.Money (<column>_amount bigint + <column>_currency char(3)).
+type legacyMoneyColumn struct {
+	table  string
+	column string
+}
+
+var legacyMoneyColumns = []legacyMoneyColumn{
+	{"users", "hourly_rate"},
+	{"users", "total_spent"},
+	{"users", "earnings"},
+	{"projects", "budget"},
+	{"proposals", "bid_amount"},
+	{"transactions", "amount"},
+	{"tasks", "budget"},
+	{"invoices", "amount_due"},
+}
+
+// migrateMoneyColumns converts the old decimal money columns into integer
+// minor units. Values are rounded half away from zero, matching money.Money,
+// and tagged with the default currency. The step is idempotent: columns that
+// were already converted (or never existed, on a fresh database) are skipped.
+func migrateMoneyColumns(db *gorm.DB) error {
+	m := db.Migrator()
+	for _, lc := range legacyMoneyColumns {
+		if !m.HasTable(lc.table) || !m.HasColumn(lc.table, lc.column) {
+			continue
+		}
+
+		amountCol := lc.column + "_amount"
+		currencyCol := lc.column + "_currency"
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			stmts := []string{
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s bigint NOT NULL DEFAULT 0`, lc.table, amountCol),
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s char(3) NOT NULL DEFAULT '%s'`, lc.table, currencyCol, money.DefaultCurrency),
+				fmt.Sprintf(`UPDATE %s SET %s = ROUND(COALESCE(%s, 0) * 100)::bigint`, lc.table, amountCol, lc.column),
+				fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s`, lc.table, lc.column),
+			}
+			for _, stmt := range stmts {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migrating %s.%s to minor units: %w", lc.table, lc.column, err)
+		}
+	}
+	return nil
+}
+
+// changedCheckConstraints lists check constraints whose definition changed.
+// AutoMigrate only creates missing constraints, so these are dropped first
+// and recreated from the current struct tags.
+var changedCheckConstraints = []struct {
+	model interface{}
+	name  string
+}{
+	{&Transaction{}, "chk_transactions_status"},
+	{&Notification{}, "chk_notifications_type"},
+	{&Invoice{}, "chk_invoices_status"},
+	{&Invoice{}, "chk_invoices_payment_status"},
+	{&Proposal{}, "chk_proposals_status"},
+}
+
+func refreshCheckConstraints(db *gorm.DB) error {
+	m := db.Migrator()
+	for _, c := range changedCheckConstraints {
+		if !m.HasTable(c.model) || !m.HasConstraint(c.model, c.name) {
+			continue
+		}
+		if err := m.DropConstraint(c.model, c.name); err != nil {
+			return fmt.Errorf("dropping constraint %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// backfillBalancesCredited marks transactions that reached "completed" before
+// balances_credited_at existed as already credited, so they are never
+// credited again.
+func backfillBalancesCredited(db *gorm.DB) error {
+	return db.Exec(`UPDATE transactions SET balances_credited_at = date
+		WHERE balances_credited_at IS NULL AND status IN ('completed','refunded','charged_back')`).Error
+}
+
+// backfillSettlementAmounts fills in the settlement of transactions made
+// before amounts were converted into the freelancer's currency. They were
+// credited as paid, so they settle in the transaction currency at their
+// amount and fee.
+func backfillSettlementAmounts(db *gorm.DB) error {
+	return db.Exec(`UPDATE transactions SET settlement_amount_amount = amount_amount, settlement_amount_currency = amount_currency,
+		fee_settlement_amount = fee_amount_amount, fee_settlement_currency = amount_currency
+		WHERE settlement_amount_amount = 0 AND amount_amount <> 0`).Error
+}
+
+// migrateInvoiceNumbering prepares invoices for draft support and numbering
+// per issuer. Invoices without an issuer were issued by their project's
+// freelancer, which is backfilled before AutoMigrate builds the per-issuer
+// index. Invoices that existed before were issued with a number, so they are
+// marked finalised. The global unique constraint on the number is replaced by
+// the per-issuer index from the struct tags.
+func migrateInvoiceNumbering(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable("invoices") {
+		return nil
+	}
+	for _, stmt := range []string{
+		`ALTER TABLE invoices ADD COLUMN IF NOT EXISTS issuer_id bigint`,
+		`UPDATE invoices SET issuer_id = projects.freelancer_id FROM projects
+			WHERE invoices.project_id = projects.project_id AND invoices.issuer_id IS NULL`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("backfilling invoice issuers: %w", err)
+		}
+	}
+	if m.HasColumn("invoices", "status") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		stmts := []string{
+			`ALTER TABLE invoices ADD COLUMN status varchar(20) NOT NULL DEFAULT 'finalised'`,
+			`ALTER TABLE invoices ALTER COLUMN status SET DEFAULT 'draft'`,
+			`ALTER TABLE invoices ADD COLUMN IF NOT EXISTS finalised_at timestamptz`,
+			`UPDATE invoices SET finalised_at = date`,
+			`ALTER TABLE invoices DROP CONSTRAINT IF EXISTS uni_invoices_invoice_number`,
+			`ALTER TABLE invoices DROP CONSTRAINT IF EXISTS invoices_invoice_number_key`,
+			`DROP INDEX IF EXISTS idx_invoices_invoice_number`,
+		}
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("migrating invoice numbering: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// backfillInvoiceNetAmounts copies the amount of invoices created before tax
+// was tracked into their net amount. They were untaxed, so net equals gross.
+func backfillInvoiceNetAmounts(db *gorm.DB) error {
+	return db.Exec(`UPDATE invoices SET net_amount_amount = amount_due_amount, net_amount_currency = amount_due_currency
+		WHERE net_amount_amount = 0 AND tax_amount_amount = 0 AND amount_due_amount <> 0`).Error
+}
+
+// backfillProposalRevisions records the bid of proposals submitted before
+// negotiations were tracked as their first revision.
+func backfillProposalRevisions(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO proposal_revisions (kind, party, bid_amount_amount, bid_amount_currency, estimated_duration, created_at, proposal_id)
+		SELECT 'bid', 'freelancer', p.bid_amount_amount, p.bid_amount_currency, COALESCE(p.estimated_duration, 0), p.submission_date, p.proposal_id
+		FROM proposals p WHERE NOT EXISTS (SELECT 1 FROM proposal_revisions r WHERE r.proposal_id = p.proposal_id)`).Error
+}
+
+// euStandardVATRates are the standard VAT rates (basis points) of the EU
+// member states, seeded as tax rules. Greece uses EL as its VAT prefix but
+// GR as its country code.
+var euStandardVATRates = map[string]int64{
+	"AT": 2000, "BE": 2100, "BG": 2000, "CY": 1900, "CZ": 2100, "DE": 1900,
+	"DK": 2500, "EE": 2400, "ES": 2100, "FI": 2550, "FR": 2000, "GR": 2400,
+	"HR": 2500, "HU": 2700, "IE": 2300, "IT": 2200, "LT": 2100, "LU": 1700,
+	"LV": 2100, "MT": 1800, "NL": 2100, "PL": 2300, "PT": 2300, "RO": 2100,
+	"SE": 2500, "SI": 2200, "SK": 2300,
+}
+
+// seedTaxRules inserts the EU standard rates. Existing rules are left alone,
+// so rates edited by an admin survive restarts.
+func seedTaxRules(db *gorm.DB) error {
+	for country, rate := range euStandardVATRates {
+		err := db.Exec(`INSERT INTO tax_rules (country, name, rate, eu_member, created_at, updated_at)
+			VALUES (?, 'VAT', ?, true, NOW(), NOW()) ON CONFLICT (country) DO NOTHING`, country, rate).Error
+		if err != nil {
+			return fmt.Errorf("seeding tax rule %s: %w", country, err)
+		}
+	}
+	return nil
+}
@@ -8,7 +8,7 @@ type Notification struct {
 	Date       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"date"`
 	ReadStatus bool      `gorm:"default:false" json:"read_status"`
 
-	Type   string `gorm:"type:varchar(50);check:type IN ('proposal_update','payment_received','project_status','admin_message')" json:"type"`
+	Type   string `gorm:"type:varchar(50);check:type IN ('proposal_update','payment_received','project_status','admin_message','invoice_reminder','invoice_issued','project_invitation','payment_reversed')" json:"type"`
 	UserID uint   `json:"user_id"`
 	User   User   `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user,omitempty"`
 }
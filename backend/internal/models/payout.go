@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// PayoutMethod is where a freelancer wants their earnings sent.
+type PayoutMethod struct {
+	ID          uint      `gorm:"column:payout_method_id;primaryKey" json:"payout_method_id"`
+	Type        string    `gorm:"type:varchar(50);not null;check:type IN ('bank_transfer','paypal')" json:"type"`
+	Label       string    `gorm:"type:varchar(100)" json:"label,omitempty"`
+	Destination string    `gorm:"type:varchar(255);not null" json:"destination"` // IBAN or PayPal e-mail
+	IsDefault   bool      `gorm:"default:false" json:"is_default"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// WithdrawalRequest asks for part of a freelancer's earnings to be paid out.
+// Pending and approved requests reserve their amount; the earnings are only
+// debited once the payout provider reports the payout as paid.
+type WithdrawalRequest struct {
+	ID     uint        `gorm:"column:withdrawal_id;primaryKey" json:"withdrawal_id"`
+	Amount money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Status string      `gorm:"type:varchar(50);default:'pending';index;check:status IN ('pending','approved','rejected','paid','failed')" json:"status"`
+
+	// Snapshot of the payout method when the request was made
+	Method      string `gorm:"type:varchar(50);not null" json:"method"`
+	Destination string `gorm:"type:varchar(255);not null" json:"destination"`
+
+	RejectionReason   string     `gorm:"type:varchar(255)" json:"rejection_reason,omitempty"`
+	Provider          string     `gorm:"type:varchar(50)" json:"provider,omitempty"`
+	ProviderReference string     `gorm:"type:varchar(100)" json:"provider_reference,omitempty"`
+	FailureReason     string     `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty"`
+	ReviewedBy        *uint      `json:"reviewed_by,omitempty"`
+	PaidAt            *time.Time `json:"paid_at,omitempty"`
+
+	UserID         uint          `gorm:"not null;index" json:"user_id"`
+	User           User          `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	PayoutMethodID *uint         `json:"payout_method_id,omitempty"`
+	PayoutMethod   *PayoutMethod `gorm:"foreignKey:PayoutMethodID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
@@ -2,17 +2,21 @@ package models
 
 import (
 	"time"
+
+	"FreeConnect/internal/money"
 )
 
 // Project references the client who creates it, and an optional freelancer if awarded
 type Project struct {
-	ID           uint      `gorm:"column:project_id;primaryKey" json:"project_id"`
-	Title        string    `gorm:"type:varchar(255);not null" json:"title"`
-	Description  string    `gorm:"type:text;not null" json:"description"`
-	Budget       float64   `gorm:"type:decimal(10,2);not null" json:"budget"`
-	Duration     int       `gorm:"check:duration > 0" json:"duration"` // in days
-	Status       string    `gorm:"type:varchar(50);default:'open';check:status IN ('open','in_progress','completed','cancelled')" json:"status"`
-	CreationDate time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"creation_date"`
+	ID           uint        `gorm:"column:project_id;primaryKey" json:"project_id"`
+	Title        string      `gorm:"type:varchar(255);not null" json:"title"`
+	Description  string      `gorm:"type:text;not null" json:"description"`
+	Budget       money.Money `gorm:"embedded;embeddedPrefix:budget_" json:"budget"`
+	Category     string      `gorm:"type:varchar(100);index" json:"category,omitempty"` // e.g. "design", used for per-category fee rules
+	Duration     int         `gorm:"check:duration > 0" json:"duration"`                // in days
+	Status       string      `gorm:"type:varchar(50);default:'open';check:status IN ('open','in_progress','completed','cancelled')" json:"status"`
+	InviteOnly   bool        `gorm:"not null;default:false" json:"invite_only"` // only invited freelancers can bid
+	CreationDate time.Time   `gorm:"default:CURRENT_TIMESTAMP" json:"creation_date"`
 
 	ClientID uint `json:"client_id"`
 	Client   User `gorm:"foreignKey:ClientID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"client,omitempty"`
@@ -22,4 +26,7 @@ type Project struct {
 	Freelancer   *User `gorm:"foreignKey:FreelancerID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"freelancer,omitempty"`
 
 	Tasks []Task `json:"tasks,omitempty" gorm:"foreignKey:ProjectID"`
+
+	// Skills the project requires, used to rank proposals
+	Skills []Skill `gorm:"many2many:project_skills;" json:"skills,omitempty"`
 }
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ProjectInvitation asks a freelancer to bid on a project. Accepting it opens
+// a draft proposal for the freelancer to fill in and submit; invite-only
+// projects only take proposals from freelancers who accepted an invitation.
+// Pending invitations expire when the project closes.
+type ProjectInvitation struct {
+	ID          uint       `gorm:"column:project_invitation_id;primaryKey" json:"project_invitation_id"`
+	Message     string     `gorm:"type:text" json:"message,omitempty"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'pending';index;check:status IN ('pending','accepted','declined','expired')" json:"status"`
+	Reason      string     `gorm:"type:varchar(500)" json:"reason,omitempty"` // why the freelancer declined
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	ProjectID    uint    `gorm:"not null;uniqueIndex:idx_project_invitations_project_freelancer,priority:1" json:"project_id"`
+	Project      Project `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	FreelancerID uint    `gorm:"not null;index;uniqueIndex:idx_project_invitations_project_freelancer,priority:2" json:"freelancer_id"`
+	Freelancer   User    `gorm:"foreignKey:FreelancerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+
+	// The draft proposal opened by accepting the invitation
+	ProposalID *uint     `gorm:"index" json:"proposal_id,omitempty"`
+	Proposal   *Proposal `gorm:"foreignKey:ProposalID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+}
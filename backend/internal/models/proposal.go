@@ -1,18 +1,54 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
 
 // Proposal references the project and the freelancer who submitted it.
+//
+// BidAmount and EstimatedDuration are the terms currently on the table, last
+// offered by OfferedBy. The freelancer's bid and every counter-offer are kept
+// as ProposalRevisions; accepting the proposal locks in the current terms.
+//
+// A proposal accepted from a ProjectInvitation starts as a draft that only
+// its freelancer sees and edits until they submit it. A proposal is open
+// while pending or shortlisted. The client accepts or rejects it, the
+// freelancer can withdraw it; once another proposal is accepted it is
+// declined, and when the project closes it expires. StatusReason explains
+// the last status change.
 type Proposal struct {
-	ID                uint      `gorm:"column:proposal_id;primaryKey" json:"proposal_id"`
-	ProposalText      string    `gorm:"type:text;not null" json:"proposal_text"`
-	EstimatedDuration int       `gorm:"check:estimated_duration > 0" json:"estimated_duration"`
-	BidAmount         float64   `gorm:"type:decimal(10,2);not null" json:"bid_amount"`
-	SubmissionDate    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"submission_date"`
-	Status            string    `gorm:"type:varchar(50);default:'pending';check:status IN ('pending','accepted','rejected')" json:"status"`
+	ID                uint        `gorm:"column:proposal_id;primaryKey" json:"proposal_id"`
+	ProposalText      string      `gorm:"type:text;not null" json:"proposal_text"`
+	EstimatedDuration int         `gorm:"check:estimated_duration > 0" json:"estimated_duration"`
+	BidAmount         money.Money `gorm:"embedded;embeddedPrefix:bid_amount_" json:"bid_amount"`
+	SubmissionDate    time.Time   `gorm:"default:CURRENT_TIMESTAMP" json:"submission_date"`
+	Status            string      `gorm:"type:varchar(50);default:'pending';check:status IN ('draft','pending','shortlisted','accepted','rejected','withdrawn','declined','expired')" json:"status"`
+	StatusReason      string      `gorm:"type:varchar(500)" json:"status_reason,omitempty"`
+	StatusChangedAt   *time.Time  `json:"status_changed_at,omitempty"`
+
+	OfferedBy  string     `gorm:"type:varchar(20);not null;default:'freelancer';check:offered_by IN ('freelancer','client')" json:"offered_by"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
 
 	ProjectID    uint    `json:"project_id"`
 	Project      Project `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"project,omitempty"`
 	FreelancerID uint    `json:"freelancer_id"`
 	Freelancer   User    `gorm:"foreignKey:FreelancerID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"freelancer,omitempty"`
 }
+
+// ProposalRevision is one step of the negotiation on a proposal: the
+// freelancer's bid, a counter-offer by either party, or the acceptance of the
+// terms on the table. Party is who took the step; revisions never change.
+type ProposalRevision struct {
+	ID                uint        `gorm:"column:proposal_revision_id;primaryKey" json:"proposal_revision_id"`
+	Kind              string      `gorm:"type:varchar(20);not null;check:kind IN ('bid','counter_offer','accepted')" json:"kind"`
+	Party             string      `gorm:"type:varchar(20);not null;check:party IN ('freelancer','client')" json:"party"`
+	BidAmount         money.Money `gorm:"embedded;embeddedPrefix:bid_amount_" json:"bid_amount"`
+	EstimatedDuration int         `gorm:"not null" json:"estimated_duration"`
+	Message           string      `gorm:"type:text" json:"message,omitempty"`
+	CreatedAt         time.Time   `json:"created_at"`
+
+	ProposalID uint     `gorm:"not null;index" json:"proposal_id"`
+	Proposal   Proposal `gorm:"foreignKey:ProposalID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
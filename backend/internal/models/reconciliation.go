@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ReconciliationRun is one execution of the reconciliation job.
+type ReconciliationRun struct {
+	ID               uint       `gorm:"column:reconciliation_run_id;primaryKey" json:"reconciliation_run_id"`
+	Trigger          string     `gorm:"type:varchar(20);not null;check:trigger IN ('schedule','cli','admin')" json:"trigger"`
+	AutoFix          bool       `gorm:"default:false" json:"auto_fix"`
+	StartedAt        time.Time  `gorm:"not null" json:"started_at"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+	Error            string     `gorm:"type:text" json:"error,omitempty"`
+	DiscrepancyCount int        `gorm:"default:0" json:"discrepancy_count"`
+	FixedCount       int        `gorm:"default:0" json:"fixed_count"`
+
+	Discrepancies []Discrepancy `gorm:"foreignKey:RunID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"discrepancies,omitempty"`
+}
+
+// Discrepancy is a mismatch found by a reconciliation run. Fixable ones can
+// be corrected automatically without moving money; the rest need a human.
+type Discrepancy struct {
+	ID         uint       `gorm:"column:discrepancy_id;primaryKey" json:"discrepancy_id"`
+	RunID      uint       `gorm:"not null;index" json:"reconciliation_run_id"`
+	Kind       string     `gorm:"type:varchar(50);not null;index" json:"kind"`
+	EntityType string     `gorm:"type:varchar(20);not null" json:"entity_type"` // transaction, user, project, invoice, refund
+	EntityID   uint       `gorm:"not null" json:"entity_id"`
+	Expected   string     `gorm:"type:varchar(255)" json:"expected"`
+	Actual     string     `gorm:"type:varchar(255)" json:"actual"`
+	Details    string     `gorm:"type:text" json:"details,omitempty"`
+	Fixable    bool       `gorm:"default:false" json:"fixable"`
+	FixedAt    *time.Time `json:"fixed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
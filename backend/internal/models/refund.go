@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// Refund returns all or part of a completed transaction to the client. The
+// earnings and spending it reverses are recorded so the ledger stays traceable.
+type Refund struct {
+	ID     uint        `gorm:"column:refund_id;primaryKey" json:"refund_id"`
+	Amount money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	// Earnings taken back from the freelancer, in their currency. This is
+	// net of the platform fee, whose share is refunded by the platform.
+	SettlementAmount money.Money `gorm:"embedded;embeddedPrefix:settlement_amount_" json:"settlement_amount"`
+	Reason           string      `gorm:"type:varchar(255);not null" json:"reason"`
+	GatewayReference string      `gorm:"type:varchar(100)" json:"gateway_reference,omitempty"`
+	// Status is "pending" from before the gateway is asked until the refund
+	// is booked, so money the gateway returned is never missing from the
+	// ledger. A refund the gateway rejected is kept as "failed".
+	Status        string    `gorm:"type:varchar(20);not null;default:'succeeded';check:status IN ('pending','succeeded','failed')" json:"status"`
+	FailureReason string    `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	TransactionID uint        `gorm:"not null;index" json:"transaction_id"`
+	Transaction   Transaction `gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// Chargeback is a payment reversed by the payer's bank. Unlike a refund it is
+// forced on us: the freelancer's share is clawed back and both accounts are
+// flagged for review.
+type Chargeback struct {
+	ID                uint        `gorm:"column:chargeback_id;primaryKey" json:"chargeback_id"`
+	Amount            money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	SettlementAmount  money.Money `gorm:"embedded;embeddedPrefix:settlement_amount_" json:"settlement_amount"`
+	Reason            string      `gorm:"type:varchar(255);not null" json:"reason"`
+	ProviderReference string      `gorm:"type:varchar(100)" json:"provider_reference,omitempty"`
+	CreatedAt         time.Time   `json:"created_at"`
+
+	TransactionID uint        `gorm:"not null;index" json:"transaction_id"`
+	Transaction   Transaction `gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"transaction,omitempty"`
+}
@@ -2,16 +2,22 @@ package models
 
 import (
 	"time"
+
+	"FreeConnect/internal/money"
 )
 
 type Task struct {
-	ID          uint      `gorm:"column:task_id;primaryKey" json:"task_id"`
-	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
-	Description string    `gorm:"type:text;not null" json:"description"`
-	Deadline    time.Time `gorm:"not null" json:"deadline"`
-	Budget      float64   `gorm:"type:decimal(10,2)" json:"budget"`
-	Status      string    `gorm:"type:varchar(50);default:'open';check:status IN ('open','in_progress','completed','cancelled')" json:"status"`
+	ID          uint        `gorm:"column:task_id;primaryKey" json:"task_id"`
+	Title       string      `gorm:"type:varchar(255);not null" json:"title"`
+	Description string      `gorm:"type:text;not null" json:"description"`
+	Deadline    time.Time   `gorm:"not null" json:"deadline"`
+	Budget      money.Money `gorm:"embedded;embeddedPrefix:budget_" json:"budget"`
+	Status      string      `gorm:"type:varchar(50);default:'open';check:status IN ('open','in_progress','completed','cancelled')" json:"status"`
 
 	ProjectID uint    `json:"project_id"`
 	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"project,omitempty"`
+
+	// Set once the task is billed, so it can't be invoiced twice
+	InvoiceID *uint    `gorm:"index" json:"invoice_id,omitempty"`
+	Invoice   *Invoice `gorm:"foreignKey:InvoiceID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
 }
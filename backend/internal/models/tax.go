@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// TaxProfile holds the tax details of a user, used to work out how their
+// invoices are taxed. Users without a profile are invoiced without tax.
+type TaxProfile struct {
+	ID              uint      `gorm:"column:tax_profile_id;primaryKey" json:"tax_profile_id"`
+	UserID          uint      `gorm:"not null;uniqueIndex" json:"user_id"`
+	Country         string    `gorm:"type:char(2);not null" json:"country"` // ISO 3166-1 alpha-2
+	VATID           string    `gorm:"column:vat_id;type:varchar(20)" json:"vat_id,omitempty"`
+	IsBusiness      bool      `gorm:"default:false" json:"is_business"`
+	TaxExempt       bool      `gorm:"default:false" json:"tax_exempt"`                     // e.g. small business scheme; no tax is charged
+	ExemptionReason string    `gorm:"type:varchar(255)" json:"exemption_reason,omitempty"` // printed on the invoice
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// TaxRule is the standard tax rate of a country. EUMember marks the
+// countries where intra-EU B2B supplies are reverse charged.
+type TaxRule struct {
+	ID        uint      `gorm:"column:tax_rule_id;primaryKey" json:"tax_rule_id"`
+	Country   string    `gorm:"type:char(2);not null;uniqueIndex" json:"country"`
+	Name      string    `gorm:"type:varchar(50);not null;default:'VAT'" json:"name"`
+	Rate      int64     `gorm:"not null;check:rate >= 0 AND rate <= 10000" json:"rate"` // basis points, 2000 = 20%
+	EUMember  bool      `gorm:"default:false" json:"eu_member"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -1,13 +1,46 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"FreeConnect/internal/money"
+)
 
 type Transaction struct {
-	ID            uint      `gorm:"column:transaction_id;primaryKey" json:"transaction_id"`
-	Amount        float64   `gorm:"type:decimal(10,2);not null" json:"amount"`
-	Date          time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"date"`
-	PaymentMethod string    `gorm:"type:varchar(50);check:payment_method IN ('credit_card','paypal','bank_transfer')" json:"payment_method"`
-	Status        string    `gorm:"type:varchar(50);default:'pending';check:status IN ('pending','completed','failed')" json:"status"`
+	ID            uint        `gorm:"column:transaction_id;primaryKey" json:"transaction_id"`
+	Amount        money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Date          time.Time   `gorm:"default:CURRENT_TIMESTAMP" json:"date"`
+	PaymentMethod string      `gorm:"type:varchar(50);check:payment_method IN ('credit_card','paypal','bank_transfer')" json:"payment_method"`
+	Status        string      `gorm:"type:varchar(50);default:'pending';check:status IN ('pending','processing','completed','failed','refunded','charged_back')" json:"status"`
+
+	// Payment provider that handles this transaction and its reference there.
+	// Status is only ever changed from the provider's results.
+	Gateway          string `gorm:"type:varchar(50)" json:"gateway,omitempty"`
+	GatewayReference string `gorm:"type:varchar(100);index" json:"gateway_reference,omitempty"`
+	FailureReason    string `gorm:"type:varchar(255)" json:"failure_reason,omitempty"`
+	RetryCount       int    `gorm:"default:0" json:"retry_count"`
+
+	// Set in the same DB transaction that credits the user balances, so
+	// they are credited exactly once however often "completed" is reported.
+	BalancesCreditedAt *time.Time `json:"balances_credited_at,omitempty"`
+
+	// Total refunded or charged back so far. Once it reaches Amount the
+	// status becomes "refunded" or "charged_back".
+	ReversedAmount money.Money `gorm:"embedded;embeddedPrefix:reversed_amount_" json:"reversed_amount"`
+
+	// Platform fee, in the transaction currency and in the freelancer's
+	// currency. The freelancer is credited SettlementAmount - FeeSettlement.
+	// Fees holds the individual ledger lines.
+	FeeAmount     money.Money      `gorm:"embedded;embeddedPrefix:fee_amount_" json:"fee_amount"`
+	FeeSettlement money.Money      `gorm:"embedded;embeddedPrefix:fee_settlement_" json:"fee_settlement"`
+	Fees          []TransactionFee `gorm:"foreignKey:TransactionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"fees,omitempty"`
+
+	// Amount converted into the freelancer's currency at transaction time,
+	// together with the rate that was applied.
+	SettlementAmount money.Money   `gorm:"embedded;embeddedPrefix:settlement_amount_" json:"settlement_amount"`
+	AppliedRate      money.Rate    `gorm:"type:bigint;not null;default:100000000" json:"applied_rate"`
+	ExchangeRateID   *uint         `json:"exchange_rate_id,omitempty"`
+	ExchangeRate     *ExchangeRate `gorm:"foreignKey:ExchangeRateID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"exchange_rate,omitempty"`
 
 	ClientID     uint `json:"client_id"`
 	Client       User `gorm:"foreignKey:ClientID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"client,omitempty"`
@@ -16,4 +49,7 @@ type Transaction struct {
 
 	ProjectID uint    `json:"project_id"`
 	Project   Project `gorm:"foreignKey:ProjectID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"project,omitempty"`
+
+	// Invoices the transaction pays, see InvoiceAllocation
+	Allocations []InvoiceAllocation `gorm:"foreignKey:TransactionID" json:"allocations,omitempty"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// TransactionStatusChange records one step of a transaction's state machine,
+// with the reason it happened. FromStatus is empty for the initial state.
+type TransactionStatusChange struct {
+	ID         uint      `gorm:"column:transaction_status_change_id;primaryKey" json:"transaction_status_change_id"`
+	FromStatus string    `gorm:"type:varchar(50)" json:"from_status"`
+	ToStatus   string    `gorm:"type:varchar(50);not null" json:"to_status"`
+	Reason     string    `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	TransactionID uint `gorm:"not null;index" json:"transaction_id"`
+}
@@ -2,24 +2,29 @@ package models
 
 import (
 	"time"
+
+	"FreeConnect/internal/money"
 )
 
 type User struct {
-	ID           uint      `gorm:"column:user_id;primaryKey" json:"user_id"`
-	Name         string    `gorm:"type:varchar(255);not null" json:"name"`
-	Email        string    `gorm:"type:varchar(255);unique;not null" json:"email"`
-	PasswordHash string    `gorm:"type:text;not null" json:"-"`
-	Role         string    `gorm:"type:varchar(50);not null;check:role IN ('admin','client','freelancer')" json:"role"`
-	Bio          string    `gorm:"type:text" json:"bio,omitempty"`
-	CompanyName  string    `gorm:"type:varchar(255)" json:"company_name,omitempty"`
-	Rating       float64   `gorm:"type:decimal(3,2);check:rating BETWEEN 0 AND 5" json:"rating,omitempty"`
-	HourlyRate   float64   `gorm:"type:decimal(10,2)" json:"hourly_rate,omitempty"`
-	Availability bool      `gorm:"default:true" json:"availability"`
-	TotalSpent   float64   `gorm:"type:decimal(10,2);default:0.0" json:"total_spent"`
-	Earnings     float64   `gorm:"type:decimal(10,2);default:0.0" json:"earnings"`
-	LastLogin    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"last_login"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uint        `gorm:"column:user_id;primaryKey" json:"user_id"`
+	Name         string      `gorm:"type:varchar(255);not null" json:"name"`
+	Email        string      `gorm:"type:varchar(255);unique;not null" json:"email"`
+	PasswordHash string      `gorm:"type:text;not null" json:"-"`
+	Role         string      `gorm:"type:varchar(50);not null;check:role IN ('admin','client','freelancer')" json:"role"`
+	Bio          string      `gorm:"type:text" json:"bio,omitempty"`
+	CompanyName  string      `gorm:"type:varchar(255)" json:"company_name,omitempty"`
+	Rating       float64     `gorm:"type:decimal(3,2);check:rating BETWEEN 0 AND 5" json:"rating,omitempty"`
+	HourlyRate   money.Money `gorm:"embedded;embeddedPrefix:hourly_rate_" json:"hourly_rate"`
+	Availability bool        `gorm:"default:true" json:"availability"`
+	TotalSpent   money.Money `gorm:"embedded;embeddedPrefix:total_spent_" json:"total_spent"`
+	Earnings     money.Money `gorm:"embedded;embeddedPrefix:earnings_" json:"earnings"`
+	Currency     string      `gorm:"type:char(3);not null;default:'EUR'" json:"currency"` // balances and payouts are kept in this currency
+	Flagged      bool        `gorm:"default:false" json:"flagged"`                        // set by chargebacks, pending admin review
+	FlagReason   string      `gorm:"type:varchar(255)" json:"flag_reason,omitempty"`
+	LastLogin    time.Time   `gorm:"default:CURRENT_TIMESTAMP" json:"last_login"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
 
 	// If you have a many-to-many with skills:
 	Skills []Skill `gorm:"many2many:freelancer_skills;"`
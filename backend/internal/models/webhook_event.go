@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// WebhookEvent is a raw payment provider callback, stored exactly as received
+// so it can be replayed. (Provider, EventID) is unique, which is what makes
+// deliveries idempotent.
+type WebhookEvent struct {
+	ID          uint       `gorm:"column:webhook_event_id;primaryKey" json:"webhook_event_id"`
+	Provider    string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_webhook_provider_event" json:"provider"`
+	EventID     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_webhook_provider_event" json:"event_id"`
+	EventType   string     `gorm:"type:varchar(100);not null" json:"event_type"`
+	Payload     string     `gorm:"type:text;not null" json:"payload"`
+	Signature   string     `gorm:"type:text" json:"-"`
+	Status      string     `gorm:"type:varchar(50);default:'received';check:status IN ('received','processed','failed')" json:"status"`
+	Attempts    int        `gorm:"default:0" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	ReceivedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"received_at"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+}
+
+// WebhookDeadLetter records a webhook event that could not be processed.
+// It stays open until an admin reprocesses the event successfully.
+type WebhookDeadLetter struct {
+	ID         uint       `gorm:"column:dead_letter_id;primaryKey" json:"dead_letter_id"`
+	Error      string     `gorm:"type:text;not null" json:"error"`
+	Attempts   int        `gorm:"default:1" json:"attempts"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+
+	WebhookEventID uint         `gorm:"uniqueIndex" json:"webhook_event_id"`
+	WebhookEvent   WebhookEvent `gorm:"foreignKey:WebhookEventID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"webhook_event,omitempty"`
+}
@@ -0,0 +1,305 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency assumed for amounts that don't carry one
+// (legacy rows and clients that still send bare numbers).
+const DefaultCurrency = "EUR"
+
+// minorDigits is the number of decimal places used by all supported currencies.
+const minorDigits = 2
+
+// minorFactor converts between major and minor units (10^minorDigits).
+const minorFactor = 100
+
+var (
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	ErrInvalidAmount    = errors.New("money: invalid amount")
+)
+
+// Money is an exact monetary value stored as integer minor units (cents)
+// together with an ISO 4217 currency code.
+//
+// In the database it is embedded into the owning model, e.g.
+//
+//	Budget money.Money `gorm:"embedded;embeddedPrefix:budget_"`
+//
+// which maps to the columns budget_amount (bigint) and budget_currency (char(3)).
+type Money struct {
+	Amount   int64  `gorm:"type:bigint;not null;default:0"`
+	Currency string `gorm:"type:char(3);not null;default:'EUR'"`
+}
+
+// New builds a Money value from minor units.
+func New(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: normalizeCurrency(currency)}
+}
+
+// Zero returns a zero amount in the given currency.
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// Parse converts a decimal string such as "1234.5" or "-0.05" into Money.
+// More than two decimal places is rejected rather than silently rounded.
+func Parse(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	// strconv would accept a second sign in either part, e.g. "--5" or "1.+5"
+	if s == "" || strings.ContainsAny(s, "+-") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > minorDigits {
+		return Money{}, fmt.Errorf("%w: more than %d decimal places in %q", ErrInvalidAmount, minorDigits, s)
+	}
+	frac += strings.Repeat("0", minorDigits-len(frac))
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	minor, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || minor < 0 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	total := major*minorFactor + minor
+	if negative {
+		total = -total
+	}
+	return New(total, currency), nil
+}
+
+// MustParse is like Parse but panics on error. Intended for constants and tests.
+func MustParse(s, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// String returns the amount as a plain decimal string, e.g. "1234.50".
+func (m Money) String() string {
+	sign := ""
+	v := m.Amount
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/minorFactor, v%minorFactor)
+}
+
+// Float64 returns an approximate float value. Only use it for display or
+// ratios (e.g. scoring); never feed the result back into an amount.
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / minorFactor
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+
+// CurrencyOrDefault returns the currency code, falling back to DefaultCurrency.
+func (m Money) CurrencyOrDefault() string {
+	return normalizeCurrency(m.Currency)
+}
+
+// Add returns m + o. Both values must share a currency; a zero value without
+// a currency adopts the other operand's currency.
+func (m Money) Add(o Money) (Money, error) {
+	cur, err := commonCurrency(m, o)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(m.Amount+o.Amount, cur), nil
+}
+
+// Sub returns m - o. See Add for currency rules.
+func (m Money) Sub(o Money) (Money, error) {
+	cur, err := commonCurrency(m, o)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(m.Amount-o.Amount, cur), nil
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return New(-m.Amount, m.Currency)
+}
+
+// Cmp compares m and o, returning -1, 0 or +1. Currencies must match.
+func (m Money) Cmp(o Money) (int, error) {
+	if _, err := commonCurrency(m, o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Mul multiplies the amount by an integer quantity.
+func (m Money) Mul(qty int64) Money {
+	return New(m.Amount*qty, m.Currency)
+}
+
+// Percent returns the given share of m expressed in basis points
+// (1/100 of a percent, so 1250 = 12.5%). The result is rounded half away
+// from zero to the nearest minor unit, which is the rule used for all
+// fees, taxes and commissions.
+func (m Money) Percent(basisPoints int64) Money {
+	return New(mulDivRound(m.Amount, basisPoints, 10000), m.Currency)
+}
+
+// MulRatio multiplies m by num/den, rounding half away from zero.
+// Used for prorating amounts, e.g. partial periods or quantities.
+func (m Money) MulRatio(num, den int64) Money {
+	return New(mulDivRound(m.Amount, num, den), m.Currency)
+}
+
+// Allocate splits m into parts proportional to the given weights. Every part
+// is rounded down and the leftover minor units are handed out one by one,
+// starting with the first part, so the parts always sum to exactly m.
+func (m Money) Allocate(weights ...int64) ([]Money, error) {
+	var total int64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, errors.New("money: negative allocation weight")
+		}
+		total += w
+	}
+	if total == 0 {
+		return nil, errors.New("money: allocation weights sum to zero")
+	}
+
+	parts := make([]Money, len(weights))
+	var allocated int64
+	for i, w := range weights {
+		share := m.Amount * w / total
+		parts[i] = New(share, m.Currency)
+		allocated += share
+	}
+
+	remainder := m.Amount - allocated
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(parts) {
+		if weights[i] == 0 {
+			continue
+		}
+		parts[i].Amount += step
+		remainder -= step
+	}
+	return parts, nil
+}
+
+// Split divides m into n equal parts using the Allocate remainder rule.
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, errors.New("money: split count must be positive")
+	}
+	weights := make([]int64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return m.Allocate(weights...)
+}
+
+// jsonMoney is the wire format: the amount is a decimal string so that
+// clients never have to go through binary floating point.
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes Money as {"amount":"1234.50","currency":"EUR"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.String(), Currency: m.CurrencyOrDefault()})
+}
+
+// UnmarshalJSON accepts the object form produced by MarshalJSON as well as a
+// bare JSON number or string (interpreted in DefaultCurrency) so older
+// clients keep working.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(trimmed, "{") {
+		var raw struct {
+			Amount   json.Number `json:"amount"`
+			Currency string      `json:"currency"`
+		}
+		dec := json.NewDecoder(strings.NewReader(trimmed))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		parsed, err := Parse(raw.Amount.String(), raw.Currency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+
+	parsed, err := Parse(strings.Trim(trimmed, `"`), "")
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func normalizeCurrency(c string) string {
+	c = strings.ToUpper(strings.TrimSpace(c))
+	if c == "" {
+		return DefaultCurrency
+	}
+	return c
+}
+
+func commonCurrency(a, b Money) (string, error) {
+	ca, cb := strings.TrimSpace(a.Currency), strings.TrimSpace(b.Currency)
+	switch {
+	case ca == "" && cb == "":
+		return DefaultCurrency, nil
+	case ca == "":
+		return normalizeCurrency(cb), nil
+	case cb == "":
+		return normalizeCurrency(ca), nil
+	case !strings.EqualFold(ca, cb):
+		return "", fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, ca, cb)
+	}
+	return normalizeCurrency(ca), nil
+}
@@ -0,0 +1,122 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RateScale is the fixed-point scale of Rate: a Rate of RateScale equals 1.
+const RateScale = 100_000_000
+
+// rateDigits is the number of decimal places a Rate can represent.
+const rateDigits = 8
+
+// Identity is the rate used when no conversion is needed.
+const Identity Rate = RateScale
+
+// supportedCurrencies lists the currencies the platform accepts.
+var supportedCurrencies = map[string]bool{
+	"EUR": true,
+	"USD": true,
+	"BGN": true,
+}
+
+// IsSupported reports whether the currency code is accepted by the platform.
+func IsSupported(currency string) bool {
+	return supportedCurrencies[normalizeCurrency(currency)]
+}
+
+// Rate is an exchange rate with eight fixed decimal places, stored as an
+// integer so conversions never go through floating point.
+// A rate R for the pair BASE/QUOTE means 1 BASE = R QUOTE.
+type Rate int64
+
+// ParseRate converts a decimal string such as "1.95583" into a Rate.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > rateDigits || strings.HasPrefix(whole, "-") || strings.HasPrefix(whole, "+") {
+		return 0, fmt.Errorf("money: invalid rate %q", s)
+	}
+	frac += strings.Repeat("0", rateDigits-len(frac))
+
+	major, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("money: invalid rate %q", s)
+	}
+	minor, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("money: invalid rate %q", s)
+	}
+
+	r := Rate(major*RateScale + minor)
+	if r <= 0 {
+		return 0, fmt.Errorf("money: rate must be positive, got %q", s)
+	}
+	return r, nil
+}
+
+// String formats the rate without trailing zeros, e.g. "1.95583".
+func (r Rate) String() string {
+	s := fmt.Sprintf("%d.%08d", int64(r)/RateScale, int64(r)%RateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Inverse returns 1/r, rounded half away from zero.
+func (r Rate) Inverse() Rate {
+	if r == 0 {
+		return 0
+	}
+	return Rate(mulDivRound(RateScale, RateScale, int64(r)))
+}
+
+// MarshalJSON encodes the rate as a decimal string.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON accepts a decimal string or a bare JSON number.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseRate(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Convert applies the rate to m and returns the result in the target
+// currency, rounded half away from zero to the nearest minor unit.
+func (m Money) Convert(r Rate, to string) Money {
+	return New(mulDivRound(m.Amount, int64(r), RateScale), to)
+}
+
+// mulDivRound computes a*b/c rounded half away from zero without
+// overflowing the intermediate product.
+func mulDivRound(a, b, c int64) int64 {
+	num := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	den := big.NewInt(c)
+	if den.Sign() < 0 {
+		num.Neg(num)
+		den.Neg(den)
+	}
+
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	twiceR := new(big.Int).Abs(r)
+	twiceR.Lsh(twiceR, 1)
+	if twiceR.Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
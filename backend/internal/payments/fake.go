@@ -0,0 +1,181 @@
+package payments
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+// Test tokens understood by FakeGateway. Any other token (or none) uses the
+// gateway's default outcome.
+const (
+	FakeTokenSuccess = "fake_success"
+	FakeTokenDecline = "fake_decline"
+	FakeTokenDelay   = "fake_delay"
+)
+
+// FakeOutcome selects what FakeGateway does with a payment.
+type FakeOutcome string
+
+const (
+	FakeSucceed FakeOutcome = "success"
+	FakeDecline FakeOutcome = "decline"
+	FakeDelay   FakeOutcome = "delay"
+)
+
+// FakeGateway is a fully local, in-memory PaymentGateway for development and
+// tests. Depending on the token (or the default outcome) a payment is either
+// captured immediately, declined at authorisation, or stays "processing" for
+// Delay after capture before it succeeds.
+type FakeGateway struct {
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+	seq     int
+
+	DefaultOutcome FakeOutcome
+	Delay          time.Duration
+	Now            func() time.Time // overridable clock for tests
+}
+
+type fakeIntent struct {
+	Intent
+	outcome    FakeOutcome
+	capturedAt time.Time
+}
+
+// NewFakeGateway returns a fake that succeeds unless told otherwise.
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		intents:        map[string]*fakeIntent{},
+		DefaultOutcome: FakeSucceed,
+		Delay:          30 * time.Second,
+		Now:            time.Now,
+	}
+}
+
+func (g *FakeGateway) Name() string { return "fake" }
+
+func (g *FakeGateway) CreateIntent(req IntentRequest) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("fake gateway: amount must be positive")
+	}
+
+	outcome := g.DefaultOutcome
+	switch req.PaymentToken {
+	case FakeTokenSuccess:
+		outcome = FakeSucceed
+	case FakeTokenDecline:
+		outcome = FakeDecline
+	case FakeTokenDelay:
+		outcome = FakeDelay
+	}
+
+	g.seq++
+	fi := &fakeIntent{
+		Intent: Intent{
+			ID:             fmt.Sprintf("fake_pi_%06d", g.seq),
+			Status:         IntentRequiresCapture,
+			Amount:         req.Amount,
+			RefundedAmount: money.Zero(req.Amount.Currency),
+			UpdatedAt:      g.Now(),
+		},
+		outcome: outcome,
+	}
+	if outcome == FakeDecline {
+		fi.Status = IntentDeclined
+		fi.FailureReason = "card_declined"
+	}
+	g.intents[fi.ID] = fi
+
+	out := fi.Intent
+	return &out, nil
+}
+
+func (g *FakeGateway) Capture(intentID string) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fi, ok := g.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	g.settle(fi)
+
+	switch fi.Status {
+	case IntentRequiresCapture:
+		fi.capturedAt = g.Now()
+		fi.UpdatedAt = fi.capturedAt
+		if fi.outcome == FakeDelay {
+			fi.Status = IntentProcessing
+		} else {
+			fi.Status = IntentSucceeded
+		}
+	case IntentProcessing, IntentSucceeded:
+		// Capturing twice is a no-op, like real providers.
+	default:
+		return nil, ErrInvalidState
+	}
+
+	out := fi.Intent
+	return &out, nil
+}
+
+func (g *FakeGateway) Refund(intentID string, amount money.Money) (*Refund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fi, ok := g.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	g.settle(fi)
+	if fi.Status != IntentSucceeded {
+		return nil, ErrInvalidState
+	}
+
+	refunded, err := fi.RefundedAmount.Add(amount)
+	if err != nil {
+		return nil, err
+	}
+	if cmp, err := refunded.Cmp(fi.Amount); err != nil {
+		return nil, err
+	} else if cmp > 0 {
+		return nil, ErrRefundTooLarge
+	}
+
+	fi.RefundedAmount = refunded
+	fi.UpdatedAt = g.Now()
+	if refunded.Amount == fi.Amount.Amount {
+		fi.Status = IntentRefunded
+	}
+
+	g.seq++
+	return &Refund{ID: fmt.Sprintf("fake_re_%06d", g.seq), IntentID: fi.ID, Amount: amount}, nil
+}
+
+func (g *FakeGateway) Status(intentID string) (*Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	fi, ok := g.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	g.settle(fi)
+
+	out := fi.Intent
+	return &out, nil
+}
+
+// settle completes a delayed capture once its delay has passed.
+func (g *FakeGateway) settle(fi *fakeIntent) {
+	if fi.Status == IntentProcessing && !g.Now().Before(fi.capturedAt.Add(g.Delay)) {
+		fi.Status = IntentSucceeded
+		fi.UpdatedAt = g.Now()
+	}
+}
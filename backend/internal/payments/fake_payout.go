@@ -0,0 +1,66 @@
+package payments
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"FreeConnect/internal/money"
+)
+
+// FakePayoutProvider is an in-memory PayoutProvider for development and tests.
+// Every payout succeeds unless its destination contains "fail".
+type FakePayoutProvider struct {
+	mu      sync.Mutex
+	payouts map[string]*Payout // by reference
+	seq     int
+}
+
+func NewFakePayoutProvider() *FakePayoutProvider {
+	return &FakePayoutProvider{payouts: map[string]*Payout{}}
+}
+
+func (p *FakePayoutProvider) Name() string { return "fake" }
+
+func (p *FakePayoutProvider) Send(req PayoutRequest) (*Payout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if existing, ok := p.payouts[req.Reference]; ok {
+		out := *existing
+		return &out, nil
+	}
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("fake payout provider: amount must be positive")
+	}
+
+	p.seq++
+	payout := &Payout{
+		ID:        fmt.Sprintf("fake_po_%06d", p.seq),
+		Reference: req.Reference,
+		Status:    PayoutPaid,
+		Amount:    req.Amount,
+	}
+	if strings.Contains(req.Destination, "fail") {
+		payout.Status = PayoutFailed
+		payout.FailureReason = "destination_rejected"
+	}
+	p.payouts[req.Reference] = payout
+
+	out := *payout
+	return &out, nil
+}
+
+// Sent returns the total paid out so far, for tests.
+func (p *FakePayoutProvider) Sent(currency string) money.Money {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := money.Zero(currency)
+	for _, po := range p.payouts {
+		if po.Status == PayoutPaid && po.Amount.Currency == total.Currency {
+			total.Amount += po.Amount.Amount
+		}
+	}
+	return total
+}
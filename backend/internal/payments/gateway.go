@@ -0,0 +1,87 @@
+// Package payments abstracts the payment provider that actually moves money.
+// Services only talk to the PaymentGateway interface; the provider is picked
+// at startup (see cmd/server).
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"FreeConnect/internal/money"
+)
+
+var (
+	ErrIntentNotFound  = errors.New("payment intent not found")
+	ErrInvalidState    = errors.New("payment intent is not in a valid state for this operation")
+	ErrRefundTooLarge  = errors.New("refund exceeds the captured amount")
+	ErrUnknownProvider = errors.New("unknown payment provider")
+)
+
+// IntentStatus is the provider-side state of a payment intent.
+type IntentStatus string
+
+const (
+	// IntentRequiresCapture means the payment was authorised and waits for capture.
+	IntentRequiresCapture IntentStatus = "requires_capture"
+	// IntentProcessing means capture was requested but the provider hasn't settled yet.
+	IntentProcessing IntentStatus = "processing"
+	// IntentSucceeded means the money has been captured.
+	IntentSucceeded IntentStatus = "succeeded"
+	// IntentDeclined means the provider refused the payment.
+	IntentDeclined IntentStatus = "declined"
+	// IntentRefunded means the captured amount has been fully refunded.
+	IntentRefunded IntentStatus = "refunded"
+)
+
+// IntentRequest describes a payment to authorise.
+type IntentRequest struct {
+	Amount        money.Money
+	PaymentMethod string // credit_card, paypal, bank_transfer
+	// PaymentToken is the provider's opaque reference to the payer's
+	// instrument (card token, PayPal order, ...). Providers may also accept
+	// test tokens, see FakeGateway.
+	PaymentToken string
+	Description  string
+}
+
+// Intent is the provider's view of a payment.
+type Intent struct {
+	ID             string
+	Status         IntentStatus
+	Amount         money.Money
+	RefundedAmount money.Money
+	FailureReason  string
+	UpdatedAt      time.Time
+}
+
+// Refund is the provider's record of a (partial) refund.
+type Refund struct {
+	ID       string
+	IntentID string
+	Amount   money.Money
+}
+
+// PaymentGateway is implemented by every payment provider.
+type PaymentGateway interface {
+	// Name identifies the provider, e.g. "fake".
+	Name() string
+	// CreateIntent authorises a payment without capturing it.
+	CreateIntent(req IntentRequest) (*Intent, error)
+	// Capture collects an authorised payment.
+	Capture(intentID string) (*Intent, error)
+	// Refund returns all or part of a captured payment.
+	Refund(intentID string, amount money.Money) (*Refund, error)
+	// Status queries the current state of an intent.
+	Status(intentID string) (*Intent, error)
+}
+
+// NewGateway returns the provider with the given name. An empty name selects
+// the local fake, which is the only provider wired up so far.
+func NewGateway(name string) (PaymentGateway, error) {
+	switch name {
+	case "", "fake":
+		return NewFakeGateway(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+}
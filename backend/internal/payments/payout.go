@@ -0,0 +1,53 @@
+package payments
+
+import (
+	"fmt"
+
+	"FreeConnect/internal/money"
+)
+
+// PayoutStatus is the provider-side state of a payout.
+type PayoutStatus string
+
+const (
+	PayoutPaid   PayoutStatus = "paid"
+	PayoutFailed PayoutStatus = "failed"
+)
+
+// PayoutRequest describes money to send to a freelancer.
+type PayoutRequest struct {
+	Amount      money.Money
+	Method      string // bank_transfer, paypal
+	Destination string // IBAN, PayPal e-mail, ...
+	// Reference identifies the payout on our side. Providers must treat a
+	// repeated reference as the same payout, so retries never pay twice.
+	Reference string
+}
+
+// Payout is the provider's record of a payout.
+type Payout struct {
+	ID            string
+	Reference     string
+	Status        PayoutStatus
+	Amount        money.Money
+	FailureReason string
+}
+
+// PayoutProvider is implemented by every payout provider.
+type PayoutProvider interface {
+	// Name identifies the provider, e.g. "fake".
+	Name() string
+	// Send pays out the request. A payout the provider refuses is returned
+	// with status failed rather than as an error.
+	Send(req PayoutRequest) (*Payout, error)
+}
+
+// NewPayoutProvider returns the payout provider with the given name. An empty
+// name selects the local fake.
+func NewPayoutProvider(name string) (PayoutProvider, error) {
+	switch name {
+	case "", "fake":
+		return NewFakePayoutProvider(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BidCreditRepository interface {
+	LockAccount(userID uint) (*models.BidCreditAccount, error)
+	SaveAccount(account *models.BidCreditAccount) error
+
+	CreateEntry(entry *models.BidCreditEntry) error
+	FindEntries(userID uint) ([]models.BidCreditEntry, error)
+	FindUnrefundedSpending(projectID uint) ([]models.BidCreditEntry, error)
+
+	CreatePurchase(purchase *models.BidCreditPurchase) error
+	SavePurchase(purchase *models.BidCreditPurchase) error
+	LockPurchase(id uint) (*models.BidCreditPurchase, error)
+	FindProcessingPurchases(userID uint) ([]models.BidCreditPurchase, error)
+
+	GetDB() *gorm.DB
+}
+
+type bidCreditRepository struct {
+	db *gorm.DB
+}
+
+func NewBidCreditRepository(db *gorm.DB) BidCreditRepository {
+	return &bidCreditRepository{db: db}
+}
+
+func (r *bidCreditRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+// LockAccount loads the user's account, opening it if needed, and locks its
+// row until the surrounding transaction ends
+func (r *bidCreditRepository) LockAccount(userID uint) (*models.BidCreditAccount, error) {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.BidCreditAccount{UserID: userID}).Error; err != nil {
+		return nil, err
+	}
+	var account models.BidCreditAccount
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *bidCreditRepository) SaveAccount(account *models.BidCreditAccount) error {
+	return r.db.Save(account).Error
+}
+
+func (r *bidCreditRepository) CreateEntry(entry *models.BidCreditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// FindEntries lists the movements on a user's account, newest first
+func (r *bidCreditRepository) FindEntries(userID uint) ([]models.BidCreditEntry, error) {
+	var entries []models.BidCreditEntry
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC, bid_credit_entry_id DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FindUnrefundedSpending lists the credits spent on proposals for a project
+// that haven't been refunded yet
+func (r *bidCreditRepository) FindUnrefundedSpending(projectID uint) ([]models.BidCreditEntry, error) {
+	var entries []models.BidCreditEntry
+	err := r.db.Where("project_id = ? AND kind = ? AND proposal_id IS NOT NULL", projectID, "proposal").
+		Where("NOT EXISTS (SELECT 1 FROM bid_credit_entries refunds WHERE refunds.kind = ? AND refunds.proposal_id = bid_credit_entries.proposal_id)", "refund").
+		Order("bid_credit_entry_id").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *bidCreditRepository) CreatePurchase(purchase *models.BidCreditPurchase) error {
+	return r.db.Create(purchase).Error
+}
+
+func (r *bidCreditRepository) SavePurchase(purchase *models.BidCreditPurchase) error {
+	return r.db.Save(purchase).Error
+}
+
+// LockPurchase reloads a purchase and locks its row until the surrounding
+// transaction ends
+func (r *bidCreditRepository) LockPurchase(id uint) (*models.BidCreditPurchase, error) {
+	var purchase models.BidCreditPurchase
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&purchase, id).Error; err != nil {
+		return nil, err
+	}
+	return &purchase, nil
+}
+
+// FindProcessingPurchases lists a user's top-ups the gateway hasn't settled yet
+func (r *bidCreditRepository) FindProcessingPurchases(userID uint) ([]models.BidCreditPurchase, error) {
+	var purchases []models.BidCreditPurchase
+	if err := r.db.Where("user_id = ? AND status = ?", userID, "processing").Order("bid_credit_purchase_id").Find(&purchases).Error; err != nil {
+		return nil, err
+	}
+	return purchases, nil
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ChargebackRepository interface {
+	Create(chargeback *models.Chargeback) error
+	FindAll() ([]models.Chargeback, error)
+}
+
+type chargebackRepository struct {
+	db *gorm.DB
+}
+
+func NewChargebackRepository(db *gorm.DB) ChargebackRepository {
+	return &chargebackRepository{db: db}
+}
+
+func (r *chargebackRepository) Create(chargeback *models.Chargeback) error {
+	return r.db.Create(chargeback).Error
+}
+
+func (r *chargebackRepository) FindAll() ([]models.Chargeback, error) {
+	var chargebacks []models.Chargeback
+	if err := r.db.Preload("Transaction").Order("created_at DESC").Find(&chargebacks).Error; err != nil {
+		return nil, err
+	}
+	return chargebacks, nil
+}
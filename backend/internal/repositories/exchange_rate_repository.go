@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"time"
+
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRateRepository interface {
+	Upsert(rate *models.ExchangeRate) error
+	FindEffective(baseCurrency, quoteCurrency string, at time.Time) (*models.ExchangeRate, error)
+	FindAll() ([]models.ExchangeRate, error)
+	GetDB() *gorm.DB
+}
+
+type exchangeRateRepository struct {
+	db *gorm.DB
+}
+
+func NewExchangeRateRepository(db *gorm.DB) ExchangeRateRepository {
+	return &exchangeRateRepository{db: db}
+}
+
+// Upsert inserts the rate or, if the pair already has a rate for that
+// effective date, overwrites it.
+func (r *exchangeRateRepository) Upsert(rate *models.ExchangeRate) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base_currency"}, {Name: "quote_currency"}, {Name: "effective_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source"}),
+	}).Create(rate).Error
+}
+
+// FindEffective returns the most recent rate for the pair that was already
+// effective at the given moment.
+func (r *exchangeRateRepository) FindEffective(baseCurrency, quoteCurrency string, at time.Time) (*models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+	err := r.db.
+		Where("base_currency = ? AND quote_currency = ? AND effective_date <= ?", baseCurrency, quoteCurrency, at).
+		Order("effective_date DESC").
+		First(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *exchangeRateRepository) FindAll() ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	if err := r.db.Order("base_currency, quote_currency, effective_date DESC").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// GetDB returns the underlying *gorm.DB instance
+func (r *exchangeRateRepository) GetDB() *gorm.DB {
+	return r.db
+}
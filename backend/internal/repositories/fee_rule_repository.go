@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type FeeRuleRepository interface {
+	Create(rule *models.FeeRule) error
+	FindByID(id uint) (*models.FeeRule, error)
+	FindAll() ([]models.FeeRule, error)
+	FindActiveByCategory(category string) ([]models.FeeRule, error)
+	Update(rule *models.FeeRule) error
+	Delete(id uint) error
+}
+
+type feeRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewFeeRuleRepository(db *gorm.DB) FeeRuleRepository {
+	return &feeRuleRepository{db: db}
+}
+
+func (r *feeRuleRepository) Create(rule *models.FeeRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *feeRuleRepository) FindByID(id uint) (*models.FeeRule, error) {
+	var rule models.FeeRule
+	if err := r.db.Preload("Tiers").First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *feeRuleRepository) FindAll() ([]models.FeeRule, error) {
+	var rules []models.FeeRule
+	if err := r.db.Preload("Tiers").Order("fee_rule_id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// FindActiveByCategory lists the active rules for exactly this category
+// ("" for the default rules).
+func (r *feeRuleRepository) FindActiveByCategory(category string) ([]models.FeeRule, error) {
+	var rules []models.FeeRule
+	if err := r.db.Preload("Tiers").
+		Where("active = ? AND COALESCE(category, '') = ?", true, category).
+		Order("fee_rule_id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Update saves the rule and replaces its tiers
+func (r *feeRuleRepository) Update(rule *models.FeeRule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("fee_rule_id = ?", rule.ID).Delete(&models.FeeTier{}).Error; err != nil {
+			return err
+		}
+		for i := range rule.Tiers {
+			rule.Tiers[i].ID = 0
+			rule.Tiers[i].FeeRuleID = rule.ID
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(rule).Error
+	})
+}
+
+func (r *feeRuleRepository) Delete(id uint) error {
+	return r.db.Delete(&models.FeeRule{}, id).Error
+}
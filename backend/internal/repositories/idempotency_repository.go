@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	CreateIfAbsent(record *models.IdempotencyKey) (bool, error)
+	FindByUserAndKey(userID uint, key string) (*models.IdempotencyKey, error)
+	Update(record *models.IdempotencyKey) error
+	Delete(id uint) error
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// CreateIfAbsent inserts the record unless the user already used the key.
+// It reports whether a new row was created.
+func (r *idempotencyRepository) CreateIfAbsent(record *models.IdempotencyKey) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *idempotencyRepository) FindByUserAndKey(userID uint, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.db.Where("user_id = ? AND key = ?", userID, key).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Update(record *models.IdempotencyKey) error {
+	return r.db.Save(record).Error
+}
+
+func (r *idempotencyRepository) Delete(id uint) error {
+	return r.db.Delete(&models.IdempotencyKey{}, id).Error
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+
+	"gorm.io/gorm"
+)
+
+type InvoiceAllocationRepository interface {
+	FindByTransaction(transactionID uint) ([]models.InvoiceAllocation, error)
+	FindByInvoice(invoiceID uint) ([]models.InvoiceAllocation, error)
+	SaveAllocated(allocation *models.InvoiceAllocation) error
+
+	CreateCredit(credit *models.ClientCredit) error
+	FindCredits(clientID uint) ([]models.ClientCredit, error)
+	CreditBalance(clientID, freelancerID uint, currency string) (money.Money, error)
+	TransactionCredit(transactionID uint, currency string) (money.Money, error)
+
+	GetDB() *gorm.DB
+}
+
+type invoiceAllocationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceAllocationRepository(db *gorm.DB) InvoiceAllocationRepository {
+	return &invoiceAllocationRepository{db: db}
+}
+
+func (r *invoiceAllocationRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+func (r *invoiceAllocationRepository) FindByTransaction(transactionID uint) ([]models.InvoiceAllocation, error) {
+	var allocations []models.InvoiceAllocation
+	if err := r.db.Where("transaction_id = ?", transactionID).Order("invoice_allocation_id").Find(&allocations).Error; err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+func (r *invoiceAllocationRepository) FindByInvoice(invoiceID uint) ([]models.InvoiceAllocation, error) {
+	var allocations []models.InvoiceAllocation
+	if err := r.db.Where("invoice_id = ?", invoiceID).Order("created_at, invoice_allocation_id").Find(&allocations).Error; err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+// SaveAllocated stores the amount allocated to the invoice
+func (r *invoiceAllocationRepository) SaveAllocated(allocation *models.InvoiceAllocation) error {
+	return r.db.Model(allocation).Select("amount_amount", "amount_currency", "allocated_at").Updates(allocation).Error
+}
+
+func (r *invoiceAllocationRepository) CreateCredit(credit *models.ClientCredit) error {
+	return r.db.Create(credit).Error
+}
+
+// FindCredits lists a client's credit entries with all freelancers, newest first
+func (r *invoiceAllocationRepository) FindCredits(clientID uint) ([]models.ClientCredit, error) {
+	var credits []models.ClientCredit
+	if err := r.db.Where("client_id = ?", clientID).Order("created_at DESC, client_credit_id DESC").Find(&credits).Error; err != nil {
+		return nil, err
+	}
+	return credits, nil
+}
+
+// CreditBalance sums the client's credit with a freelancer in one currency
+func (r *invoiceAllocationRepository) CreditBalance(clientID, freelancerID uint, currency string) (money.Money, error) {
+	var total int64
+	err := r.db.Model(&models.ClientCredit{}).Select("COALESCE(SUM(amount_amount), 0)").
+		Where("client_id = ? AND freelancer_id = ? AND amount_currency = ?", clientID, freelancerID, currency).
+		Scan(&total).Error
+	return money.New(total, currency), err
+}
+
+// TransactionCredit sums the credit entries of a transaction: its overpayment
+// less what was taken back when it was reversed
+func (r *invoiceAllocationRepository) TransactionCredit(transactionID uint, currency string) (money.Money, error) {
+	var total int64
+	err := r.db.Model(&models.ClientCredit{}).Select("COALESCE(SUM(amount_amount), 0)").
+		Where("transaction_id = ? AND amount_currency = ?", transactionID, currency).
+		Scan(&total).Error
+	return money.New(total, currency), err
+}
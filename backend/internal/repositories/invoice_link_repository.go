@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"time"
+
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type InvoiceLinkRepository interface {
+	Create(link *models.InvoiceLink) error
+	FindByID(id uint) (*models.InvoiceLink, error)
+	FindByInvoice(invoiceID uint) ([]models.InvoiceLink, error)
+	Revoke(id uint, at time.Time) error
+	RecordOpen(link *models.InvoiceLink, at time.Time) error
+
+	CreatePayment(payment *models.InvoicePayment) error
+	SavePayment(payment *models.InvoicePayment) error
+	FindPayments(invoiceID uint) ([]models.InvoicePayment, error)
+	FindProcessingPayments(invoiceID uint) ([]models.InvoicePayment, error)
+
+	GetDB() *gorm.DB
+}
+
+type invoiceLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceLinkRepository(db *gorm.DB) InvoiceLinkRepository {
+	return &invoiceLinkRepository{db: db}
+}
+
+func (r *invoiceLinkRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+func (r *invoiceLinkRepository) Create(link *models.InvoiceLink) error {
+	return r.db.Create(link).Error
+}
+
+func (r *invoiceLinkRepository) FindByID(id uint) (*models.InvoiceLink, error) {
+	var link models.InvoiceLink
+	if err := r.db.First(&link, id).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *invoiceLinkRepository) FindByInvoice(invoiceID uint) ([]models.InvoiceLink, error) {
+	var links []models.InvoiceLink
+	if err := r.db.Where("invoice_id = ?", invoiceID).Order("created_at, invoice_link_id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Revoke disables a link; revoking it again keeps the first revocation time
+func (r *invoiceLinkRepository) Revoke(id uint, at time.Time) error {
+	return r.db.Model(&models.InvoiceLink{}).Where("invoice_link_id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+// RecordOpen counts an open on the link and on its invoice
+func (r *invoiceLinkRepository) RecordOpen(link *models.InvoiceLink, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.InvoiceLink{}).Where("invoice_link_id = ?", link.ID).Updates(map[string]interface{}{
+			"open_count":     gorm.Expr("open_count + 1"),
+			"last_opened_at": at,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Invoice{}).Where("invoice_id = ?", link.InvoiceID).Updates(map[string]interface{}{
+			"open_count":      gorm.Expr("open_count + 1"),
+			"first_opened_at": gorm.Expr("COALESCE(first_opened_at, ?)", at),
+			"last_opened_at":  at,
+		}).Error
+	})
+}
+
+func (r *invoiceLinkRepository) CreatePayment(payment *models.InvoicePayment) error {
+	return r.db.Create(payment).Error
+}
+
+// SavePayment stores the gateway's view of a payment
+func (r *invoiceLinkRepository) SavePayment(payment *models.InvoicePayment) error {
+	return r.db.Model(payment).Select("status", "gateway", "gateway_reference", "failure_reason", "updated_at").
+		Updates(payment).Error
+}
+
+func (r *invoiceLinkRepository) FindPayments(invoiceID uint) ([]models.InvoicePayment, error) {
+	var payments []models.InvoicePayment
+	if err := r.db.Where("invoice_id = ?", invoiceID).Order("created_at, invoice_payment_id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// FindProcessingPayments lists the payments captured but not settled by the gateway yet
+func (r *invoiceLinkRepository) FindProcessingPayments(invoiceID uint) ([]models.InvoicePayment, error) {
+	var payments []models.InvoicePayment
+	if err := r.db.Where("invoice_id = ? AND status = ?", invoiceID, "processing").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
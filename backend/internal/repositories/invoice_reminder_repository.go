@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceReminderRepository interface {
+	FindTermsByClient(clientID uint) (*models.BillingTerms, error)
+	SaveTerms(terms *models.BillingTerms) error
+
+	MarkOverdue(before time.Time) (int64, error)
+	FindUnpaid(dueBefore time.Time) ([]models.Invoice, error)
+	FindSentStages(invoiceIDs []uint) (map[uint][]string, error)
+	ApplyLateFee(invoiceID uint, fee money.Money, at time.Time) (bool, error)
+	RecordReminder(reminder *models.InvoiceReminder) (bool, error)
+	MarkEmailed(reminderID uint) error
+	FindByInvoice(invoiceID uint) ([]models.InvoiceReminder, error)
+
+	GetDB() *gorm.DB
+}
+
+type invoiceReminderRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceReminderRepository(db *gorm.DB) InvoiceReminderRepository {
+	return &invoiceReminderRepository{db: db}
+}
+
+func (r *invoiceReminderRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+func (r *invoiceReminderRepository) FindTermsByClient(clientID uint) (*models.BillingTerms, error) {
+	var terms models.BillingTerms
+	if err := r.db.Where("client_id = ?", clientID).First(&terms).Error; err != nil {
+		return nil, err
+	}
+	return &terms, nil
+}
+
+// SaveTerms creates the client's terms or replaces the existing ones
+func (r *invoiceReminderRepository) SaveTerms(terms *models.BillingTerms) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"late_fee_rate", "grace_days", "updated_at"}),
+	}).Create(terms).Error
+}
+
+// MarkOverdue flips finalised, pending invoices due before the given time to
+// overdue and returns how many changed. Credit notes are never due.
+func (r *invoiceReminderRepository) MarkOverdue(before time.Time) (int64, error) {
+	res := r.db.Model(&models.Invoice{}).
+		Where("kind = ? AND status = ? AND payment_status = ? AND due_date < ?", "invoice", "finalised", "pending", before).
+		Update("payment_status", "overdue")
+	return res.RowsAffected, res.Error
+}
+
+// FindUnpaid lists the finalised, unpaid invoices due before the given time
+// together with their client
+func (r *invoiceReminderRepository) FindUnpaid(dueBefore time.Time) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	err := r.db.Preload("Client").
+		Where("kind = ? AND status = ? AND payment_status IN ? AND due_date < ?", "invoice", "finalised", []string{"pending", "overdue"}, dueBefore).
+		Order("due_date, invoice_id").Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// FindSentStages returns the reminder stages already sent per invoice
+func (r *invoiceReminderRepository) FindSentStages(invoiceIDs []uint) (map[uint][]string, error) {
+	stages := make(map[uint][]string)
+	if len(invoiceIDs) == 0 {
+		return stages, nil
+	}
+	var reminders []models.InvoiceReminder
+	if err := r.db.Select("invoice_id", "stage").Where("invoice_id IN ?", invoiceIDs).Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	for _, rem := range reminders {
+		stages[rem.InvoiceID] = append(stages[rem.InvoiceID], rem.Stage)
+	}
+	return stages, nil
+}
+
+// ApplyLateFee sets the late fee unless one was applied already. It reports
+// whether the invoice changed.
+func (r *invoiceReminderRepository) ApplyLateFee(invoiceID uint, fee money.Money, at time.Time) (bool, error) {
+	res := r.db.Model(&models.Invoice{}).
+		Where("invoice_id = ? AND late_fee_applied_at IS NULL", invoiceID).
+		Updates(map[string]interface{}{
+			"late_fee_amount":     fee.Amount,
+			"late_fee_currency":   fee.Currency,
+			"late_fee_applied_at": at,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// RecordReminder stores the reminder unless the stage was already sent for
+// the invoice. It reports whether it was stored.
+func (r *invoiceReminderRepository) RecordReminder(reminder *models.InvoiceReminder) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *invoiceReminderRepository) MarkEmailed(reminderID uint) error {
+	return r.db.Model(&models.InvoiceReminder{}).Where("invoice_reminder_id = ?", reminderID).Update("emailed", true).Error
+}
+
+func (r *invoiceReminderRepository) FindByInvoice(invoiceID uint) ([]models.InvoiceReminder, error) {
+	var reminders []models.InvoiceReminder
+	if err := r.db.Where("invoice_id = ?", invoiceID).Order("sent_at").Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
@@ -1,9 +1,13 @@
 package repositories
 
 import (
+	"time"
+
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InvoiceRepository interface {
@@ -12,6 +16,21 @@ type InvoiceRepository interface {
 	FindByProject(projectID uint) ([]models.Invoice, error)
 	Update(invoice *models.Invoice) error
 	Delete(id uint) error
+	FindIssuedBetween(from, to time.Time) ([]models.Invoice, error)
+	FindIssuedBetweenWithParties(from, to time.Time) ([]models.Invoice, error)
+	FindBillableTasks(projectID uint, taskIDs []uint) ([]models.Task, error)
+	MarkTasksInvoiced(invoiceID uint, taskIDs []uint) error
+	FindByIDWithParties(id uint) (*models.Invoice, error)
+	LockByID(id uint) (*models.Invoice, error)
+	NextNumber(issuerID uint, series string, year int) (int64, error)
+	SaveFinalised(invoice *models.Invoice) error
+	UpdatePaymentStatus(id uint, status string) error
+	SavePaid(invoice *models.Invoice) error
+	SaveVoided(invoice *models.Invoice) error
+	ReleaseTasks(invoiceID uint) error
+	SumCredited(invoiceID uint) (net, gross money.Money, err error)
+	FindCreditNotes(invoiceID uint) ([]models.Invoice, error)
+	GetDB() *gorm.DB
 }
 
 type invoiceRepository struct {
@@ -22,13 +41,17 @@ func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
 	return &invoiceRepository{db: db}
 }
 
+func (r *invoiceRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
 func (r *invoiceRepository) Create(invoice *models.Invoice) error {
 	return r.db.Create(invoice).Error
 }
 
 func (r *invoiceRepository) FindByID(id uint) (*models.Invoice, error) {
 	var invoice models.Invoice
-	if err := r.db.First(&invoice, id).Error; err != nil {
+	if err := r.db.Preload("Lines", orderLines).First(&invoice, id).Error; err != nil {
 		return nil, err
 	}
 	return &invoice, nil
@@ -36,16 +59,175 @@ func (r *invoiceRepository) FindByID(id uint) (*models.Invoice, error) {
 
 func (r *invoiceRepository) FindByProject(projectID uint) ([]models.Invoice, error) {
 	var invoices []models.Invoice
-	if err := r.db.Where("project_id = ?", projectID).Find(&invoices).Error; err != nil {
+	if err := r.db.Preload("Lines", orderLines).Where("project_id = ?", projectID).Find(&invoices).Error; err != nil {
 		return nil, err
 	}
 	return invoices, nil
 }
 
+// Update saves the invoice and replaces its lines. Tasks whose lines were
+// removed become billable again.
 func (r *invoiceRepository) Update(invoice *models.Invoice) error {
-	return r.db.Save(invoice).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceLine{}).Error; err != nil {
+			return err
+		}
+		kept := []uint{0}
+		for i := range invoice.Lines {
+			invoice.Lines[i].ID = 0
+			invoice.Lines[i].InvoiceID = invoice.ID
+			if invoice.Lines[i].TaskID != nil {
+				kept = append(kept, *invoice.Lines[i].TaskID)
+			}
+		}
+		if err := tx.Model(&models.Task{}).
+			Where("invoice_id = ? AND task_id NOT IN ?", invoice.ID, kept).
+			Update("invoice_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(invoice).Error; err != nil {
+			return err
+		}
+		if len(invoice.Lines) == 0 {
+			return nil
+		}
+		return tx.Create(&invoice.Lines).Error
+	})
+}
+
+// FindBillableTasks locks the completed, not yet invoiced tasks of a
+// project. If taskIDs is not empty only those tasks are considered.
+func (r *invoiceRepository) FindBillableTasks(projectID uint, taskIDs []uint) ([]models.Task, error) {
+	q := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ? AND status = ? AND invoice_id IS NULL", projectID, "completed")
+	if len(taskIDs) > 0 {
+		q = q.Where("task_id IN ?", taskIDs)
+	}
+	var tasks []models.Task
+	if err := q.Order("task_id").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// MarkTasksInvoiced records that the tasks are billed by the invoice
+func (r *invoiceRepository) MarkTasksInvoiced(invoiceID uint, taskIDs []uint) error {
+	return r.db.Model(&models.Task{}).Where("task_id IN ?", taskIDs).Update("invoice_id", invoiceID).Error
+}
+
+// FindByIDWithParties loads an invoice with its project, client, issuer and
+// lines, and for credit notes the credited invoice
+func (r *invoiceRepository) FindByIDWithParties(id uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.db.Preload("Project").Preload("Client").Preload("Issuer").Preload("Lines", orderLines).
+		Preload("CreditedInvoice").First(&invoice, id).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// LockByID loads an invoice with SELECT ... FOR UPDATE; use inside a transaction
+func (r *invoiceRepository) LockByID(id uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, id).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// NextNumber increments the issuer's sequence for the series and year and
+// returns the new value. The row stays locked until the surrounding
+// transaction ends, so concurrent finalisations are serialised and a rollback
+// releases the number again.
+func (r *invoiceRepository) NextNumber(issuerID uint, series string, year int) (int64, error) {
+	err := r.db.Exec(`INSERT INTO invoice_sequences (issuer_id, series, year, last_number, updated_at)
+		VALUES (?, ?, ?, 0, NOW()) ON CONFLICT (issuer_id, series, year) DO NOTHING`, issuerID, series, year).Error
+	if err != nil {
+		return 0, err
+	}
+	var next int64
+	err = r.db.Raw(`UPDATE invoice_sequences SET last_number = last_number + 1, updated_at = NOW()
+		WHERE issuer_id = ? AND series = ? AND year = ? RETURNING last_number`, issuerID, series, year).Scan(&next).Error
+	return next, err
+}
+
+// SaveFinalised stores the number, status and issue date of a finalised invoice
+func (r *invoiceRepository) SaveFinalised(invoice *models.Invoice) error {
+	return r.db.Model(invoice).Select("invoice_number", "status", "date", "finalised_at").Updates(invoice).Error
+}
+
+func (r *invoiceRepository) UpdatePaymentStatus(id uint, status string) error {
+	return r.db.Model(&models.Invoice{}).Where("invoice_id = ?", id).Update("payment_status", status).Error
+}
+
+// SavePaid stores what has been paid on an invoice and its payment status
+func (r *invoiceRepository) SavePaid(invoice *models.Invoice) error {
+	return r.db.Model(invoice).Select("amount_paid_amount", "amount_paid_currency", "payment_status", "paid_at").Updates(invoice).Error
+}
+
+// SaveVoided stores the status and void details of a voided invoice
+func (r *invoiceRepository) SaveVoided(invoice *models.Invoice) error {
+	return r.db.Model(invoice).Select("status", "voided_at", "void_reason").Updates(invoice).Error
+}
+
+// ReleaseTasks makes the tasks billed by the invoice billable again
+func (r *invoiceRepository) ReleaseTasks(invoiceID uint) error {
+	return r.db.Model(&models.Task{}).Where("invoice_id = ?", invoiceID).Update("invoice_id", nil).Error
+}
+
+// SumCredited totals the credit notes issued against an invoice, in the
+// invoice's currency
+func (r *invoiceRepository) SumCredited(invoiceID uint) (net, gross money.Money, err error) {
+	var invoice models.Invoice
+	if err = r.db.Select("invoice_id", "net_amount_currency").First(&invoice, invoiceID).Error; err != nil {
+		return
+	}
+	var sums struct{ Net, Gross int64 }
+	err = r.db.Model(&models.Invoice{}).
+		Select("COALESCE(SUM(net_amount_amount), 0) AS net, COALESCE(SUM(amount_due_amount), 0) AS gross").
+		Where("credited_invoice_id = ? AND kind = ? AND status = ?", invoiceID, "credit_note", "finalised").
+		Scan(&sums).Error
+	currency := invoice.NetAmount.CurrencyOrDefault()
+	return money.New(sums.Net, currency), money.New(sums.Gross, currency), err
+}
+
+// FindCreditNotes lists the credit notes issued against an invoice
+func (r *invoiceRepository) FindCreditNotes(invoiceID uint) ([]models.Invoice, error) {
+	var notes []models.Invoice
+	if err := r.db.Preload("Lines", orderLines).Where("credited_invoice_id = ? AND kind = ?", invoiceID, "credit_note").
+		Order("date, invoice_id").Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	return notes, nil
 }
 
 func (r *invoiceRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Invoice{}, id).Error
 }
+
+// FindIssuedBetween lists the invoices and credit notes issued in [from, to);
+// drafts and voided invoices are left out
+func (r *invoiceRepository) FindIssuedBetween(from, to time.Time) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := r.db.Where("status = ? AND date >= ? AND date < ?", "finalised", from, to).Order("date").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// FindIssuedBetweenWithParties is FindIssuedBetween with the associations
+// FindByIDWithParties loads, ordered by number within each issue date
+func (r *invoiceRepository) FindIssuedBetweenWithParties(from, to time.Time) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := r.db.Preload("Project").Preload("Client").Preload("Issuer").Preload("Lines", orderLines).
+		Preload("CreditedInvoice").Where("status = ? AND date >= ? AND date < ?", "finalised", from, to).
+		Order("date, invoice_number").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// orderLines preloads invoice lines in their printed order
+func orderLines(db *gorm.DB) *gorm.DB {
+	return db.Order("position, invoice_line_id")
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"time"
+
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceScheduleRepository interface {
+	Create(schedule *models.InvoiceSchedule) error
+	FindByID(id uint) (*models.InvoiceSchedule, error)
+	FindByProject(projectID uint) ([]models.InvoiceSchedule, error)
+	LockByID(id uint) (*models.InvoiceSchedule, error)
+	SaveProgress(schedule *models.InvoiceSchedule) error
+	FindDue(now time.Time) ([]uint, error)
+	FindInvoices(scheduleID uint) ([]models.Invoice, error)
+	GetDB() *gorm.DB
+}
+
+type invoiceScheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceScheduleRepository(db *gorm.DB) InvoiceScheduleRepository {
+	return &invoiceScheduleRepository{db: db}
+}
+
+func (r *invoiceScheduleRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+func (r *invoiceScheduleRepository) Create(schedule *models.InvoiceSchedule) error {
+	return r.db.Create(schedule).Error
+}
+
+func (r *invoiceScheduleRepository) FindByID(id uint) (*models.InvoiceSchedule, error) {
+	var schedule models.InvoiceSchedule
+	if err := r.db.First(&schedule, id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *invoiceScheduleRepository) FindByProject(projectID uint) ([]models.InvoiceSchedule, error) {
+	var schedules []models.InvoiceSchedule
+	if err := r.db.Where("project_id = ?", projectID).Order("invoice_schedule_id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// LockByID loads a schedule with SELECT ... FOR UPDATE; use inside a transaction
+func (r *invoiceScheduleRepository) LockByID(id uint) (*models.InvoiceSchedule, error) {
+	var schedule models.InvoiceSchedule
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// SaveProgress stores the status and run position of a schedule
+func (r *invoiceScheduleRepository) SaveProgress(schedule *models.InvoiceSchedule) error {
+	return r.db.Model(schedule).Select("status", "next_run_at", "run_count", "last_run_at", "updated_at").Updates(schedule).Error
+}
+
+// FindDue lists the active schedules with a period starting at or before now
+func (r *invoiceScheduleRepository) FindDue(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.InvoiceSchedule{}).Where("status = ? AND next_run_at <= ?", "active", now).
+		Order("next_run_at, invoice_schedule_id").Pluck("invoice_schedule_id", &ids).Error
+	return ids, err
+}
+
+// FindInvoices lists the invoices a schedule has issued, by period
+func (r *invoiceScheduleRepository) FindInvoices(scheduleID uint) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := r.db.Preload("Lines", orderLines).Where("schedule_id = ?", scheduleID).
+		Order("period_start").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PayoutRepository interface {
+	CreateMethod(method *models.PayoutMethod) error
+	FindMethodByID(id uint) (*models.PayoutMethod, error)
+	FindMethodsByUser(userID uint) ([]models.PayoutMethod, error)
+	ClearDefaultMethod(userID uint) error
+	DeleteMethod(id uint) error
+
+	CreateWithdrawal(w *models.WithdrawalRequest) error
+	FindWithdrawalByID(id uint) (*models.WithdrawalRequest, error)
+	LockWithdrawalByID(id uint) (*models.WithdrawalRequest, error)
+	FindWithdrawalsByUser(userID uint) ([]models.WithdrawalRequest, error)
+	FindWithdrawalsByStatus(status string) ([]models.WithdrawalRequest, error)
+	SumOpenWithdrawals(userID uint, currency string) (int64, error)
+	UpdateWithdrawal(w *models.WithdrawalRequest) error
+
+	GetDB() *gorm.DB
+}
+
+type payoutRepository struct {
+	db *gorm.DB
+}
+
+func NewPayoutRepository(db *gorm.DB) PayoutRepository {
+	return &payoutRepository{db: db}
+}
+
+func (r *payoutRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+func (r *payoutRepository) CreateMethod(method *models.PayoutMethod) error {
+	return r.db.Create(method).Error
+}
+
+func (r *payoutRepository) FindMethodByID(id uint) (*models.PayoutMethod, error) {
+	var m models.PayoutMethod
+	if err := r.db.First(&m, id).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *payoutRepository) FindMethodsByUser(userID uint) ([]models.PayoutMethod, error) {
+	var methods []models.PayoutMethod
+	if err := r.db.Where("user_id = ?", userID).Order("payout_method_id").Find(&methods).Error; err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+func (r *payoutRepository) ClearDefaultMethod(userID uint) error {
+	return r.db.Model(&models.PayoutMethod{}).Where("user_id = ?", userID).Update("is_default", false).Error
+}
+
+func (r *payoutRepository) DeleteMethod(id uint) error {
+	return r.db.Delete(&models.PayoutMethod{}, id).Error
+}
+
+func (r *payoutRepository) CreateWithdrawal(w *models.WithdrawalRequest) error {
+	return r.db.Create(w).Error
+}
+
+func (r *payoutRepository) FindWithdrawalByID(id uint) (*models.WithdrawalRequest, error) {
+	var w models.WithdrawalRequest
+	if err := r.db.First(&w, id).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// LockWithdrawalByID loads the request with SELECT ... FOR UPDATE
+func (r *payoutRepository) LockWithdrawalByID(id uint) (*models.WithdrawalRequest, error) {
+	var w models.WithdrawalRequest
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&w, id).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *payoutRepository) FindWithdrawalsByUser(userID uint) ([]models.WithdrawalRequest, error) {
+	var ws []models.WithdrawalRequest
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&ws).Error; err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// FindWithdrawalsByStatus lists requests in the given status, oldest first
+// ("" lists all of them)
+func (r *payoutRepository) FindWithdrawalsByStatus(status string) ([]models.WithdrawalRequest, error) {
+	var ws []models.WithdrawalRequest
+	q := r.db.Order("created_at")
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if err := q.Find(&ws).Error; err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// SumOpenWithdrawals returns the amount reserved by the user's pending and
+// approved requests, in minor units of currency
+func (r *payoutRepository) SumOpenWithdrawals(userID uint, currency string) (int64, error) {
+	var total int64
+	err := r.db.Model(&models.WithdrawalRequest{}).
+		Where("user_id = ? AND amount_currency = ? AND status IN ?", userID, currency, []string{"pending", "approved"}).
+		Select("COALESCE(SUM(amount_amount), 0)").Scan(&total).Error
+	return total, err
+}
+
+func (r *payoutRepository) UpdateWithdrawal(w *models.WithdrawalRequest) error {
+	return r.db.Save(w).Error
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectInvitationRepository interface {
+	Create(invitation *models.ProjectInvitation) error
+	FindByID(id uint) (*models.ProjectInvitation, error)
+	LockByID(id uint) (*models.ProjectInvitation, error)
+	FindByProject(projectID uint) ([]models.ProjectInvitation, error)
+	FindByFreelancer(freelancerID uint) ([]models.ProjectInvitation, error)
+	Update(invitation *models.ProjectInvitation) error
+	ExpirePending(projectID uint) error
+
+	GetDB() *gorm.DB
+}
+
+type projectInvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewProjectInvitationRepository(db *gorm.DB) ProjectInvitationRepository {
+	return &projectInvitationRepository{db: db}
+}
+
+func (r *projectInvitationRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+func (r *projectInvitationRepository) Create(invitation *models.ProjectInvitation) error {
+	return r.db.Create(invitation).Error
+}
+
+func (r *projectInvitationRepository) FindByID(id uint) (*models.ProjectInvitation, error) {
+	var invitation models.ProjectInvitation
+	if err := r.db.First(&invitation, id).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// LockByID loads an invitation and locks its row until the surrounding
+// transaction ends
+func (r *projectInvitationRepository) LockByID(id uint) (*models.ProjectInvitation, error) {
+	var invitation models.ProjectInvitation
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invitation, id).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *projectInvitationRepository) FindByProject(projectID uint) ([]models.ProjectInvitation, error) {
+	var invitations []models.ProjectInvitation
+	if err := r.db.Where("project_id = ?", projectID).Order("created_at, project_invitation_id").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// FindByFreelancer lists the invitations a freelancer received, newest first
+func (r *projectInvitationRepository) FindByFreelancer(freelancerID uint) ([]models.ProjectInvitation, error) {
+	var invitations []models.ProjectInvitation
+	if err := r.db.Where("freelancer_id = ?", freelancerID).Order("created_at DESC, project_invitation_id DESC").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *projectInvitationRepository) Update(invitation *models.ProjectInvitation) error {
+	return r.db.Save(invitation).Error
+}
+
+// ExpirePending expires the invitations to a project nobody answered
+func (r *projectInvitationRepository) ExpirePending(projectID uint) error {
+	return r.db.Model(&models.ProjectInvitation{}).
+		Where("project_id = ? AND status = ?", projectID, "pending").
+		Updates(map[string]interface{}{"status": "expired"}).Error
+}
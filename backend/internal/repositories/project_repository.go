@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProjectRepository interface {
@@ -12,7 +14,10 @@ type ProjectRepository interface {
 	FindAll() ([]models.Project, error)
 	Update(project *models.Project) error
 	Delete(id uint) error
+	LockByID(id uint) (*models.Project, error)
 	SearchProjects(search, minBudgetStr, maxBudgetStr, status string) ([]models.Project, error)
+
+	GetDB() *gorm.DB
 }
 
 type projectRepository struct {
@@ -28,13 +33,20 @@ func (r *projectRepository) SearchProjects(search, minBudgetStr, maxBudgetStr, s
 	}
 
 	if minBudgetStr != "" {
-		// Convert to float
-		db = db.Where("budget >= ?", minBudgetStr)
+		// Budgets are stored in minor units
+		minBudget, err := money.Parse(minBudgetStr, "")
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("budget_amount >= ?", minBudget.Amount)
 	}
 
 	if maxBudgetStr != "" {
-		// Convert to float
-		db = db.Where("budget <= ?", maxBudgetStr)
+		maxBudget, err := money.Parse(maxBudgetStr, "")
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("budget_amount <= ?", maxBudget.Amount)
 	}
 
 	if status != "" {
@@ -53,6 +65,10 @@ func NewProjectRepository(db *gorm.DB) ProjectRepository {
 	return &projectRepository{db: db}
 }
 
+func (r *projectRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
 func (r *projectRepository) Create(project *models.Project) error {
 	return r.db.Create(project).Error
 }
@@ -60,7 +76,7 @@ func (r *projectRepository) Create(project *models.Project) error {
 func (r *projectRepository) FindByID(id uint) (*models.Project, error) {
 	var project models.Project
 	// If you want to preload tasks
-	if err := r.db.Preload("Tasks").First(&project, id).Error; err != nil {
+	if err := r.db.Preload("Tasks").Preload("Skills").First(&project, id).Error; err != nil {
 		return nil, err
 	}
 	return &project, nil
@@ -81,3 +97,13 @@ func (r *projectRepository) Update(project *models.Project) error {
 func (r *projectRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Project{}, id).Error
 }
+
+// LockByID loads a project and locks its row until the surrounding
+// transaction ends
+func (r *projectRepository) LockByID(id uint) (*models.Project, error) {
+	var project models.Project
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
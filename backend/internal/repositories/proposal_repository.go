@@ -4,6 +4,7 @@ import (
 	"FreeConnect/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProposalRepository interface {
@@ -12,6 +13,11 @@ type ProposalRepository interface {
 	FindByProject(projectID uint) ([]models.Proposal, error)
 	Update(proposal *models.Proposal) error
 	Delete(id uint) error
+	LockByID(id uint) (*models.Proposal, error)
+	LockOpenByProject(projectID uint) ([]models.Proposal, error)
+
+	CreateRevision(revision *models.ProposalRevision) error
+	FindRevisions(proposalID uint) ([]models.ProposalRevision, error)
 
 	// NEW: Return the underlying *gorm.DB
 	GetDB() *gorm.DB
@@ -53,6 +59,42 @@ func (r *proposalRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Proposal{}, id).Error
 }
 
+// LockByID loads a proposal and locks its row until the surrounding
+// transaction ends, so negotiation steps on it are serialised
+func (r *proposalRepository) LockByID(id uint) (*models.Proposal, error) {
+	var proposal models.Proposal
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&proposal, id).Error; err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// LockOpenByProject loads the draft, pending and shortlisted proposals on a project
+// and locks their rows until the surrounding transaction ends
+func (r *proposalRepository) LockOpenByProject(projectID uint) ([]models.Proposal, error) {
+	var proposals []models.Proposal
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ? AND status IN ?", projectID, []string{"draft", "pending", "shortlisted"}).
+		Order("proposal_id").Find(&proposals).Error
+	if err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+func (r *proposalRepository) CreateRevision(revision *models.ProposalRevision) error {
+	return r.db.Create(revision).Error
+}
+
+// FindRevisions lists the negotiation of a proposal, oldest first
+func (r *proposalRepository) FindRevisions(proposalID uint) ([]models.ProposalRevision, error) {
+	var revisions []models.ProposalRevision
+	if err := r.db.Where("proposal_id = ?", proposalID).Order("created_at, proposal_revision_id").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
 // GetDB returns the underlying *gorm.DB instance
 func (r *proposalRepository) GetDB() *gorm.DB {
 	return r.db
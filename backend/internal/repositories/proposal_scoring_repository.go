@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// FreelancerRecord is a freelancer's track record: their reviews and how
+// the projects they were hired for ended.
+type FreelancerRecord struct {
+	FreelancerID uint
+	Reviews      int64
+	AvgRating    float64
+	Completed    int64
+	Cancelled    int64
+}
+
+type ProposalScoringRepository interface {
+	FindRankable(projectID uint) ([]models.Proposal, error)
+	FindFreelancers(ids []uint) ([]models.User, error)
+	FindRecords(ids []uint) (map[uint]FreelancerRecord, error)
+
+	GetDB() *gorm.DB
+}
+
+type proposalScoringRepository struct {
+	db *gorm.DB
+}
+
+func NewProposalScoringRepository(db *gorm.DB) ProposalScoringRepository {
+	return &proposalScoringRepository{db: db}
+}
+
+func (r *proposalScoringRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+// FindRankable lists the submitted proposals on a project that haven't been
+// withdrawn
+func (r *proposalScoringRepository) FindRankable(projectID uint) ([]models.Proposal, error) {
+	var proposals []models.Proposal
+	err := r.db.Where("project_id = ? AND status NOT IN ?", projectID, []string{"draft", "withdrawn"}).
+		Order("proposal_id").Find(&proposals).Error
+	if err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+// FindFreelancers loads the users with their skills
+func (r *proposalScoringRepository) FindFreelancers(ids []uint) ([]models.User, error) {
+	var users []models.User
+	if err := r.db.Preload("Skills").Where("user_id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindRecords sums up the reviews and finished projects of each freelancer
+func (r *proposalScoringRepository) FindRecords(ids []uint) (map[uint]FreelancerRecord, error) {
+	records := make(map[uint]FreelancerRecord, len(ids))
+	for _, id := range ids {
+		records[id] = FreelancerRecord{FreelancerID: id}
+	}
+
+	var reviews []struct {
+		ReviewedeeID uint
+		Reviews      int64
+		AvgRating    float64
+	}
+	if err := r.db.Model(&models.Review{}).
+		Select("reviewedee_id, COUNT(*) AS reviews, AVG(rating) AS avg_rating").
+		Where("reviewedee_id IN ?", ids).Group("reviewedee_id").
+		Scan(&reviews).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range reviews {
+		record := records[row.ReviewedeeID]
+		record.Reviews, record.AvgRating = row.Reviews, row.AvgRating
+		records[row.ReviewedeeID] = record
+	}
+
+	var projects []struct {
+		FreelancerID uint
+		Completed    int64
+		Cancelled    int64
+	}
+	if err := r.db.Model(&models.Project{}).
+		Select("freelancer_id, "+
+			"COUNT(*) FILTER (WHERE status = 'completed') AS completed, "+
+			"COUNT(*) FILTER (WHERE status = 'cancelled') AS cancelled").
+		Where("freelancer_id IN ?", ids).Group("freelancer_id").
+		Scan(&projects).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range projects {
+		record := records[row.FreelancerID]
+		record.Completed, record.Cancelled = row.Completed, row.Cancelled
+		records[row.FreelancerID] = record
+	}
+	return records, nil
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ReconciliationRepository interface {
+	CreateRun(run *models.ReconciliationRun) error
+	UpdateRun(run *models.ReconciliationRun) error
+	FindRuns(limit int) ([]models.ReconciliationRun, error)
+	FindRunByID(id uint) (*models.ReconciliationRun, error)
+	CreateDiscrepancy(d *models.Discrepancy) error
+	FindDiscrepancyByID(id uint) (*models.Discrepancy, error)
+	UpdateDiscrepancy(d *models.Discrepancy) error
+	GetDB() *gorm.DB
+}
+
+type reconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewReconciliationRepository(db *gorm.DB) ReconciliationRepository {
+	return &reconciliationRepository{db: db}
+}
+
+func (r *reconciliationRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+func (r *reconciliationRepository) CreateRun(run *models.ReconciliationRun) error {
+	return r.db.Create(run).Error
+}
+
+func (r *reconciliationRepository) UpdateRun(run *models.ReconciliationRun) error {
+	return r.db.Omit("Discrepancies").Save(run).Error
+}
+
+// FindRuns lists the most recent runs without their discrepancies
+func (r *reconciliationRepository) FindRuns(limit int) ([]models.ReconciliationRun, error) {
+	var runs []models.ReconciliationRun
+	if err := r.db.Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *reconciliationRepository) FindRunByID(id uint) (*models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+	if err := r.db.Preload("Discrepancies", func(db *gorm.DB) *gorm.DB {
+		return db.Order("discrepancy_id")
+	}).First(&run, id).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *reconciliationRepository) CreateDiscrepancy(d *models.Discrepancy) error {
+	return r.db.Create(d).Error
+}
+
+func (r *reconciliationRepository) FindDiscrepancyByID(id uint) (*models.Discrepancy, error) {
+	var d models.Discrepancy
+	if err := r.db.First(&d, id).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *reconciliationRepository) UpdateDiscrepancy(d *models.Discrepancy) error {
+	return r.db.Save(d).Error
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefundRepository interface {
+	Create(refund *models.Refund) error
+	Update(refund *models.Refund) error
+	FindByID(id uint) (*models.Refund, error)
+	LockByID(id uint) (*models.Refund, error)
+	FindByTransaction(transactionID uint) ([]models.Refund, error)
+	FindPendingByTransaction(transactionID uint) ([]models.Refund, error)
+}
+
+type refundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) RefundRepository {
+	return &refundRepository{db: db}
+}
+
+func (r *refundRepository) Create(refund *models.Refund) error {
+	return r.db.Create(refund).Error
+}
+
+func (r *refundRepository) Update(refund *models.Refund) error {
+	return r.db.Save(refund).Error
+}
+
+func (r *refundRepository) FindByID(id uint) (*models.Refund, error) {
+	var refund models.Refund
+	if err := r.db.First(&refund, id).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// LockByID loads the refund with SELECT ... FOR UPDATE
+func (r *refundRepository) LockByID(id uint) (*models.Refund, error) {
+	var refund models.Refund
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, id).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *refundRepository) FindByTransaction(transactionID uint) ([]models.Refund, error) {
+	var refunds []models.Refund
+	if err := r.db.Where("transaction_id = ?", transactionID).Order("created_at").Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// FindPendingByTransaction lists the refunds of a transaction that were not
+// booked yet, oldest first
+func (r *refundRepository) FindPendingByTransaction(transactionID uint) ([]models.Refund, error) {
+	var refunds []models.Refund
+	if err := r.db.Where("transaction_id = ? AND status = ?", transactionID, "pending").
+		Order("created_at").Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaxRepository interface {
+	FindProfileByUser(userID uint) (*models.TaxProfile, error)
+	SaveProfile(profile *models.TaxProfile) error
+
+	FindRules() ([]models.TaxRule, error)
+	FindRuleByCountry(country string) (*models.TaxRule, error)
+	SaveRule(rule *models.TaxRule) error
+
+	GetDB() *gorm.DB
+}
+
+type taxRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxRepository(db *gorm.DB) TaxRepository {
+	return &taxRepository{db: db}
+}
+
+func (r *taxRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+func (r *taxRepository) FindProfileByUser(userID uint) (*models.TaxProfile, error) {
+	var profile models.TaxProfile
+	if err := r.db.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SaveProfile creates the user's profile or replaces the existing one
+func (r *taxRepository) SaveProfile(profile *models.TaxProfile) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"country", "vat_id", "is_business", "tax_exempt", "exemption_reason", "updated_at"}),
+	}).Create(profile).Error
+}
+
+func (r *taxRepository) FindRules() ([]models.TaxRule, error) {
+	var rules []models.TaxRule
+	if err := r.db.Order("country").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *taxRepository) FindRuleByCountry(country string) (*models.TaxRule, error) {
+	var rule models.TaxRule
+	if err := r.db.Where("country = ?", country).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveRule creates the country's rule or replaces the existing one
+func (r *taxRepository) SaveRule(rule *models.TaxRule) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "country"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "rate", "eu_member", "updated_at"}),
+	}).Create(rule).Error
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"FreeConnect/internal/models"
 
 	"gorm.io/gorm"
@@ -10,6 +12,10 @@ type TransactionRepository interface {
 	Create(transaction *models.Transaction) error
 	FindByID(id uint) (*models.Transaction, error)
 	FindByProject(projectID uint) ([]models.Transaction, error)
+	FindByGatewayReference(gateway, reference string) (*models.Transaction, error)
+	FindByStatusBetween(status string, from, to time.Time) ([]models.Transaction, error)
+	FindByStatusesBetween(statuses []string, from, to time.Time) ([]models.Transaction, error)
+	SumSettledBetweenUsers(clientID, freelancerID uint, currency string) (int64, error)
 	Update(transaction *models.Transaction) error
 	Delete(id uint) error
 	GetDB() *gorm.DB
@@ -47,6 +53,51 @@ func (r *transactionRepository) FindByProject(projectID uint) ([]models.Transact
 	return transactions, nil
 }
 
+func (r *transactionRepository) FindByGatewayReference(gateway, reference string) (*models.Transaction, error) {
+	var t models.Transaction
+	if err := r.db.Where("gateway = ? AND gateway_reference = ?", gateway, reference).First(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// FindByStatusBetween lists transactions with the given status dated in [from, to)
+func (r *transactionRepository) FindByStatusBetween(status string, from, to time.Time) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	if err := r.db.Where("status = ? AND date >= ? AND date < ?", status, from, to).Order("date").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// FindByStatusesBetween lists transactions in any of the statuses dated in [from, to)
+func (r *transactionRepository) FindByStatusesBetween(statuses []string, from, to time.Time) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	if err := r.db.Where("status IN ? AND date >= ? AND date < ?", statuses, from, to).Order("date").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// SumSettledBetweenUsers returns the lifetime billing between a client and a
+// freelancer: the settled amounts (in minor units of currency) of their
+// completed transactions, net of refunds and chargebacks.
+func (r *transactionRepository) SumSettledBetweenUsers(clientID, freelancerID uint, currency string) (int64, error) {
+	var txs []models.Transaction
+	if err := r.db.Where("client_id = ? AND freelancer_id = ? AND settlement_amount_currency = ? AND status = ?",
+		clientID, freelancerID, currency, "completed").Find(&txs).Error; err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, tx := range txs {
+		if tx.Amount.IsZero() {
+			continue
+		}
+		total += tx.SettlementAmount.Amount - tx.SettlementAmount.MulRatio(tx.ReversedAmount.Amount, tx.Amount.Amount).Amount
+	}
+	return total, nil
+}
+
 func (r *transactionRepository) Update(transaction *models.Transaction) error {
 	return r.db.Save(transaction).Error
 }
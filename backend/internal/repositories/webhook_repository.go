@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository interface {
+	CreateIfAbsent(event *models.WebhookEvent) (bool, error)
+	FindByID(id uint) (*models.WebhookEvent, error)
+	FindByProviderEvent(provider, eventID string) (*models.WebhookEvent, error)
+	Update(event *models.WebhookEvent) error
+
+	RecordDeadLetter(eventID uint, reason string) error
+	ResolveDeadLetter(eventID uint) error
+	FindOpenDeadLetters() ([]models.WebhookDeadLetter, error)
+
+	GetDB() *gorm.DB
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// CreateIfAbsent inserts the event unless (provider, event_id) already exists.
+// It reports whether a new row was created.
+func (r *webhookRepository) CreateIfAbsent(event *models.WebhookEvent) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *webhookRepository) FindByID(id uint) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := r.db.First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *webhookRepository) FindByProviderEvent(provider, eventID string) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := r.db.Where("provider = ? AND event_id = ?", provider, eventID).First(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *webhookRepository) Update(event *models.WebhookEvent) error {
+	return r.db.Save(event).Error
+}
+
+// RecordDeadLetter opens (or re-opens) the dead letter for an event and
+// counts the failed attempt.
+func (r *webhookRepository) RecordDeadLetter(eventID uint, reason string) error {
+	var dl models.WebhookDeadLetter
+	err := r.db.Where("webhook_event_id = ?", eventID).First(&dl).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.db.Create(&models.WebhookDeadLetter{WebhookEventID: eventID, Error: reason, Attempts: 1}).Error
+	}
+	if err != nil {
+		return err
+	}
+	dl.Error = reason
+	dl.Attempts++
+	dl.ResolvedAt = nil
+	return r.db.Save(&dl).Error
+}
+
+// ResolveDeadLetter closes the event's dead letter, if it has one.
+func (r *webhookRepository) ResolveDeadLetter(eventID uint) error {
+	return r.db.Model(&models.WebhookDeadLetter{}).
+		Where("webhook_event_id = ? AND resolved_at IS NULL", eventID).
+		Update("resolved_at", time.Now()).Error
+}
+
+func (r *webhookRepository) FindOpenDeadLetters() ([]models.WebhookDeadLetter, error) {
+	var letters []models.WebhookDeadLetter
+	if err := r.db.Preload("WebhookEvent").Where("resolved_at IS NULL").Order("created_at").Find(&letters).Error; err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// GetDB returns the underlying *gorm.DB instance
+func (r *webhookRepository) GetDB() *gorm.DB {
+	return r.db
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInsufficientBidCredits = errors.New("not enough bid credits for this proposal")
+	ErrUnknownCreditPack      = errors.New("unknown bid credit pack")
+)
+
+// MonthlyBidCredits are granted to every account once per calendar month.
+// Unused credits carry over.
+const MonthlyBidCredits = 20
+
+// BidCreditPack is a top-up of bid credits that can be bought.
+type BidCreditPack struct {
+	Name    string      `json:"name"`
+	Credits int         `json:"credits"`
+	Price   money.Money `json:"price"`
+}
+
+// BidCreditPacks are the top-ups on sale.
+var BidCreditPacks = []BidCreditPack{
+	{Name: "small", Credits: 10, Price: money.New(200, "EUR")},
+	{Name: "medium", Credits: 40, Price: money.New(700, "EUR")},
+	{Name: "large", Credits: 100, Price: money.New(1500, "EUR")},
+}
+
+// bidCostTiers price a proposal by the project's budget in EUR: bidding on a
+// project costs the credits of the first tier whose ceiling its budget is below.
+var bidCostTiers = []struct {
+	below   int64
+	credits int
+}{
+	{50000, 2},   // under 500 EUR
+	{200000, 4},  // under 2,000 EUR
+	{1000000, 6}, // under 10,000 EUR
+	{math.MaxInt64, 8},
+}
+
+// BidCreditBalance is a freelancer's credit balance and what they can buy.
+type BidCreditBalance struct {
+	UserID           uint            `json:"user_id"`
+	Balance          int             `json:"balance"`
+	MonthlyAllowance int             `json:"monthly_allowance"`
+	NextAllowanceAt  time.Time       `json:"next_allowance_at"`
+	Packs            []BidCreditPack `json:"packs"`
+}
+
+type BidCreditService interface {
+	// GetBalance grants the monthly allowance if it is due and settles
+	// top-ups that were still processing.
+	GetBalance(userID uint) (*BidCreditBalance, error)
+	GetLedger(userID uint) ([]models.BidCreditEntry, error)
+	// GetProposalCost is what a proposal on the project costs.
+	GetProposalCost(projectID uint) (int, error)
+	// BuyCredits charges one of the BidCreditPacks through the payment gateway.
+	BuyCredits(userID uint, pack, paymentMethod, paymentToken string) (*models.BidCreditPurchase, error)
+}
+
+type bidCreditService struct {
+	repo    repositories.BidCreditRepository
+	gateway payments.PaymentGateway
+}
+
+func NewBidCreditService(repo repositories.BidCreditRepository, gateway payments.PaymentGateway) BidCreditService {
+	return &bidCreditService{repo: repo, gateway: gateway}
+}
+
+func (s *bidCreditService) GetBalance(userID uint) (*BidCreditBalance, error) {
+	if err := s.syncPurchases(userID); err != nil {
+		return nil, err
+	}
+
+	var account *models.BidCreditAccount
+	now := time.Now()
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		var err error
+		account, err = lockBidCredits(db, userID, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BidCreditBalance{
+		UserID:           userID,
+		Balance:          account.Balance,
+		MonthlyAllowance: MonthlyBidCredits,
+		NextAllowanceAt:  monthStart(now).AddDate(0, 1, 0),
+		Packs:            BidCreditPacks,
+	}, nil
+}
+
+func (s *bidCreditService) GetLedger(userID uint) ([]models.BidCreditEntry, error) {
+	return s.repo.FindEntries(userID)
+}
+
+func (s *bidCreditService) GetProposalCost(projectID uint) (int, error) {
+	project, err := repositories.NewProjectRepository(s.repo.GetDB()).FindByID(projectID)
+	if err != nil {
+		return 0, err
+	}
+	return proposalCost(s.repo.GetDB(), project)
+}
+
+func (s *bidCreditService) BuyCredits(userID uint, pack, paymentMethod, paymentToken string) (*models.BidCreditPurchase, error) {
+	var chosen *BidCreditPack
+	for i := range BidCreditPacks {
+		if BidCreditPacks[i].Name == pack {
+			chosen = &BidCreditPacks[i]
+		}
+	}
+	if chosen == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCreditPack, pack)
+	}
+
+	purchase := &models.BidCreditPurchase{
+		Pack:          chosen.Name,
+		Credits:       chosen.Credits,
+		Price:         chosen.Price,
+		PaymentMethod: paymentMethod,
+		Status:        "pending",
+		Gateway:       s.gateway.Name(),
+		UserID:        userID,
+	}
+	if err := s.repo.CreatePurchase(purchase); err != nil {
+		return nil, err
+	}
+
+	intent, err := s.gateway.CreateIntent(payments.IntentRequest{
+		Amount:        purchase.Price,
+		PaymentMethod: paymentMethod,
+		PaymentToken:  paymentToken,
+		Description:   fmt.Sprintf("%d bid credits", purchase.Credits),
+	})
+	if err == nil && intent.Status == payments.IntentRequiresCapture {
+		purchase.GatewayReference = intent.ID
+		intent, err = s.gateway.Capture(intent.ID)
+	}
+	if err != nil {
+		purchase.Status = "failed"
+		purchase.FailureReason = err.Error()
+		if saveErr := s.repo.SavePurchase(purchase); saveErr != nil {
+			return nil, saveErr
+		}
+		return nil, err
+	}
+	purchase.GatewayReference = intent.ID
+	if err := s.settle(purchase, intent); err != nil {
+		return nil, err
+	}
+	return purchase, nil
+}
+
+// syncPurchases asks the gateway about top-ups that were still processing
+func (s *bidCreditService) syncPurchases(userID uint) error {
+	processing, err := s.repo.FindProcessingPurchases(userID)
+	if err != nil {
+		return err
+	}
+	for i := range processing {
+		intent, err := s.gateway.Status(processing[i].GatewayReference)
+		if err != nil {
+			return err
+		}
+		if err := s.settle(&processing[i], intent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settle stores the gateway's result for a top-up and adds the credits once
+// the money is captured
+func (s *bidCreditService) settle(purchase *models.BidCreditPurchase, intent *payments.Intent) error {
+	switch intent.Status {
+	case payments.IntentSucceeded:
+		purchase.Status = "succeeded"
+	case payments.IntentProcessing:
+		purchase.Status = "processing"
+	case payments.IntentDeclined:
+		purchase.Status = "failed"
+		purchase.FailureReason = intent.FailureReason
+	default:
+		return fmt.Errorf("%w: unexpected intent status %q", payments.ErrInvalidState, intent.Status)
+	}
+
+	return s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		// Credit each purchase once, however often the gateway reports it
+		repo := repositories.NewBidCreditRepository(db)
+		current, err := repo.LockPurchase(purchase.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status == "succeeded" || current.Status == "failed" {
+			*purchase = *current
+			return nil
+		}
+		if err := repo.SavePurchase(purchase); err != nil {
+			return err
+		}
+		if purchase.Status != "succeeded" {
+			return nil
+		}
+
+		account, err := lockBidCredits(db, purchase.UserID, time.Now())
+		if err != nil {
+			return err
+		}
+		return moveBidCredits(db, account, &models.BidCreditEntry{
+			Kind:        "top_up",
+			Credits:     purchase.Credits,
+			Description: fmt.Sprintf("%s pack, %s", purchase.Pack, formatMoney(purchase.Price)),
+			PurchaseID:  &purchase.ID,
+		})
+	})
+}
+
+// monthStart is the first moment of the calendar month, in UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// lockBidCredits locks the user's account inside db and grants the monthly
+// allowance if it hasn't been granted this month
+func lockBidCredits(db *gorm.DB, userID uint, now time.Time) (*models.BidCreditAccount, error) {
+	account, err := repositories.NewBidCreditRepository(db).LockAccount(userID)
+	if err != nil {
+		return nil, err
+	}
+	month := monthStart(now)
+	if account.AllowanceMonth != nil && !account.AllowanceMonth.Before(month) {
+		return account, nil
+	}
+	account.AllowanceMonth = &month
+	err = moveBidCredits(db, account, &models.BidCreditEntry{
+		Kind:        "allowance",
+		Credits:     MonthlyBidCredits,
+		Description: "Monthly allowance for " + month.Format("January 2006"),
+	})
+	return account, err
+}
+
+// moveBidCredits books an entry on a locked account and updates its balance
+func moveBidCredits(db *gorm.DB, account *models.BidCreditAccount, entry *models.BidCreditEntry) error {
+	if account.Balance+entry.Credits < 0 {
+		return fmt.Errorf("%w: %d needed, %d left", ErrInsufficientBidCredits, -entry.Credits, account.Balance)
+	}
+	account.Balance += entry.Credits
+	entry.UserID = account.UserID
+	entry.BalanceAfter = account.Balance
+
+	repo := repositories.NewBidCreditRepository(db)
+	if err := repo.SaveAccount(account); err != nil {
+		return err
+	}
+	return repo.CreateEntry(entry)
+}
+
+// proposalCost works out the credits a proposal on the project costs from
+// its budget, converted into EUR
+func proposalCost(db *gorm.DB, project *models.Project) (int, error) {
+	budget := project.Budget
+	if budget.CurrencyOrDefault() != "EUR" {
+		conv, err := NewExchangeRateService(repositories.NewExchangeRateRepository(db)).Convert(budget, "EUR", time.Now())
+		if err != nil {
+			return 0, err
+		}
+		budget = conv.Amount
+	}
+	for _, tier := range bidCostTiers {
+		if budget.Amount < tier.below {
+			return tier.credits, nil
+		}
+	}
+	return bidCostTiers[len(bidCostTiers)-1].credits, nil
+}
+
+// chargeProposal takes the cost of a new proposal from its freelancer's credits
+func chargeProposal(db *gorm.DB, proposal *models.Proposal, project *models.Project) error {
+	cost, err := proposalCost(db, project)
+	if err != nil {
+		return err
+	}
+	account, err := lockBidCredits(db, proposal.FreelancerID, time.Now())
+	if err != nil {
+		return err
+	}
+	return moveBidCredits(db, account, &models.BidCreditEntry{
+		Kind:        "proposal",
+		Credits:     -cost,
+		Description: "Proposal on project " + project.Title,
+		ProposalID:  &proposal.ID,
+		ProjectID:   &project.ID,
+	})
+}
+
+// refundProjectBids gives back the credits spent on proposals for a project
+// that was cancelled without hiring anyone
+func refundProjectBids(db *gorm.DB, project *models.Project) error {
+	spent, err := repositories.NewBidCreditRepository(db).FindUnrefundedSpending(project.ID)
+	if err != nil {
+		return err
+	}
+	for _, entry := range spent {
+		account, err := lockBidCredits(db, entry.UserID, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := moveBidCredits(db, account, &models.BidCreditEntry{
+			Kind:        "refund",
+			Credits:     -entry.Credits,
+			Description: "Project " + project.Title + " was cancelled without a hire",
+			ProposalID:  entry.ProposalID,
+			ProjectID:   &project.ID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoExchangeRate      = errors.New("no exchange rate available for currency pair")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+)
+
+// checkCurrency rejects amounts in currencies the platform doesn't handle.
+func checkCurrency(amount money.Money) error {
+	if !money.IsSupported(amount.Currency) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedCurrency, amount.Currency)
+	}
+	return nil
+}
+
+// Conversion is the result of converting an amount, including the rate that
+// was used so callers can record it.
+type Conversion struct {
+	Amount         money.Money
+	Rate           money.Rate
+	ExchangeRateID *uint // nil when no conversion was needed
+}
+
+type ExchangeRateService interface {
+	ImportCSV(r io.Reader, source string) (int, error)
+	GetAllRates() ([]models.ExchangeRate, error)
+	Convert(amount money.Money, to string, at time.Time) (*Conversion, error)
+}
+
+type exchangeRateService struct {
+	repo repositories.ExchangeRateRepository
+}
+
+func NewExchangeRateService(repo repositories.ExchangeRateRepository) ExchangeRateService {
+	return &exchangeRateService{repo: repo}
+}
+
+// ImportCSV loads rates from CSV with the header
+//
+//	base_currency,quote_currency,rate,effective_date
+//
+// where effective_date is YYYY-MM-DD. The whole file is validated first and
+// then upserted in a single database transaction, so a bad row imports nothing.
+func (s *exchangeRateService) ImportCSV(r io.Reader, source string) (int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	if len(records) < 2 {
+		return 0, errors.New("CSV contains no rates")
+	}
+
+	header := records[0]
+	expected := []string{"base_currency", "quote_currency", "rate", "effective_date"}
+	if len(header) != len(expected) {
+		return 0, fmt.Errorf("CSV header must be %s", strings.Join(expected, ","))
+	}
+	for i, col := range expected {
+		if strings.TrimSpace(strings.ToLower(header[i])) != col {
+			return 0, fmt.Errorf("CSV header must be %s", strings.Join(expected, ","))
+		}
+	}
+
+	rates := make([]models.ExchangeRate, 0, len(records)-1)
+	for i, rec := range records[1:] {
+		line := i + 2
+		base := strings.ToUpper(strings.TrimSpace(rec[0]))
+		quote := strings.ToUpper(strings.TrimSpace(rec[1]))
+		if !money.IsSupported(base) || !money.IsSupported(quote) {
+			return 0, fmt.Errorf("line %d: unsupported currency pair %s/%s", line, base, quote)
+		}
+		if base == quote {
+			return 0, fmt.Errorf("line %d: base and quote currency are the same", line)
+		}
+		rate, err := money.ParseRate(rec[2])
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		effective, err := time.Parse("2006-01-02", strings.TrimSpace(rec[3]))
+		if err != nil {
+			return 0, fmt.Errorf("line %d: invalid effective_date, use YYYY-MM-DD", line)
+		}
+		rates = append(rates, models.ExchangeRate{
+			BaseCurrency:  base,
+			QuoteCurrency: quote,
+			Rate:          rate,
+			EffectiveDate: effective,
+			Source:        source,
+		})
+	}
+
+	err = s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		txRepo := repositories.NewExchangeRateRepository(tx)
+		for i := range rates {
+			if err := txRepo.Upsert(&rates[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+func (s *exchangeRateService) GetAllRates() ([]models.ExchangeRate, error) {
+	return s.repo.FindAll()
+}
+
+// Convert converts the amount into the target currency using the rate that
+// was effective at the given time. A stored rate for the opposite direction
+// is inverted when there is no direct one.
+func (s *exchangeRateService) Convert(amount money.Money, to string, at time.Time) (*Conversion, error) {
+	from := amount.CurrencyOrDefault()
+	to = money.Zero(to).Currency
+	if from == to {
+		return &Conversion{Amount: money.New(amount.Amount, to), Rate: money.Identity}, nil
+	}
+
+	if rate, err := s.repo.FindEffective(from, to, at); err == nil {
+		return &Conversion{Amount: amount.Convert(rate.Rate, to), Rate: rate.Rate, ExchangeRateID: &rate.ID}, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if rate, err := s.repo.FindEffective(to, from, at); err == nil {
+		inverse := rate.Rate.Inverse()
+		return &Conversion{Amount: amount.Convert(inverse, to), Rate: inverse, ExchangeRateID: &rate.ID}, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("%w: %s/%s on %s", ErrNoExchangeRate, from, to, at.Format("2006-01-02"))
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+)
+
+var ErrInvalidFeeRule = errors.New("invalid fee rule")
+
+type FeeService interface {
+	CreateRule(rule *models.FeeRule) error
+	GetRules() ([]models.FeeRule, error)
+	GetRuleByID(id uint) (*models.FeeRule, error)
+	UpdateRule(rule *models.FeeRule) error
+	DeleteRule(id uint) error
+
+	// Calculate works out the platform fee for a transaction whose amount
+	// and settlement have already been set. It returns the fee ledger lines
+	// and their total in the transaction currency.
+	Calculate(tx *models.Transaction) ([]models.TransactionFee, money.Money, error)
+}
+
+type feeService struct {
+	repo   repositories.FeeRuleRepository
+	txRepo repositories.TransactionRepository
+	rates  ExchangeRateService
+}
+
+func NewFeeService(repo repositories.FeeRuleRepository, txRepo repositories.TransactionRepository, rates ExchangeRateService) FeeService {
+	return &feeService{repo: repo, txRepo: txRepo, rates: rates}
+}
+
+func (s *feeService) CreateRule(rule *models.FeeRule) error {
+	if err := validateFeeRule(rule); err != nil {
+		return err
+	}
+	return s.repo.Create(rule)
+}
+
+func (s *feeService) GetRules() ([]models.FeeRule, error) {
+	return s.repo.FindAll()
+}
+
+func (s *feeService) GetRuleByID(id uint) (*models.FeeRule, error) {
+	return s.repo.FindByID(id)
+}
+
+func (s *feeService) UpdateRule(rule *models.FeeRule) error {
+	if err := validateFeeRule(rule); err != nil {
+		return err
+	}
+	return s.repo.Update(rule)
+}
+
+func (s *feeService) DeleteRule(id uint) error {
+	return s.repo.Delete(id)
+}
+
+// validateFeeRule checks that the rule carries the settings its type needs
+func validateFeeRule(rule *models.FeeRule) error {
+	if rule.Percent < 0 || rule.Percent > 10000 {
+		return fmt.Errorf("%w: percent must be between 0 and 10000 basis points", ErrInvalidFeeRule)
+	}
+	switch rule.Type {
+	case "percentage":
+	case "flat":
+		if !rule.FlatAmount.IsPositive() {
+			return fmt.Errorf("%w: flat rules need a positive flat_amount", ErrInvalidFeeRule)
+		}
+		if err := checkCurrency(rule.FlatAmount); err != nil {
+			return err
+		}
+	case "tiered":
+		if len(rule.Tiers) == 0 {
+			return fmt.Errorf("%w: tiered rules need at least one tier", ErrInvalidFeeRule)
+		}
+		for _, t := range rule.Tiers {
+			if t.Percent < 0 || t.Percent > 10000 {
+				return fmt.Errorf("%w: tier percent must be between 0 and 10000 basis points", ErrInvalidFeeRule)
+			}
+			if t.MinBilling.IsNegative() {
+				return fmt.Errorf("%w: tier min_billing can't be negative", ErrInvalidFeeRule)
+			}
+			if err := checkCurrency(t.MinBilling); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidFeeRule, rule.Type)
+	}
+	return nil
+}
+
+func (s *feeService) Calculate(tx *models.Transaction) ([]models.TransactionFee, money.Money, error) {
+	total := money.Zero(tx.Amount.Currency)
+
+	rules, err := s.rulesFor(tx.ProjectID)
+	if err != nil {
+		return nil, total, err
+	}
+
+	var lines []models.TransactionFee
+	for _, rule := range rules {
+		fee, desc, err := s.ruleFee(rule, tx)
+		if err != nil {
+			return nil, total, err
+		}
+		if fee.IsZero() {
+			continue
+		}
+		// The fee can never take more than the payment itself
+		if remaining, _ := tx.Amount.Sub(total); fee.Amount > remaining.Amount {
+			fee = remaining
+		}
+		if total, err = total.Add(fee); err != nil {
+			return nil, total, err
+		}
+
+		ruleID := rule.ID
+		lines = append(lines, models.TransactionFee{
+			FeeRuleID:        &ruleID,
+			Description:      desc,
+			Amount:           fee,
+			SettlementAmount: fee.Convert(tx.AppliedRate, tx.SettlementAmount.Currency),
+		})
+	}
+	return lines, total, nil
+}
+
+// rulesFor returns the rules for the project's category, falling back to the
+// default rules when the category has none of its own.
+func (s *feeService) rulesFor(projectID uint) ([]models.FeeRule, error) {
+	var project models.Project
+	if err := s.txRepo.GetDB().First(&project, projectID).Error; err != nil {
+		return nil, err
+	}
+	if project.Category != "" {
+		rules, err := s.repo.FindActiveByCategory(project.Category)
+		if err != nil || len(rules) > 0 {
+			return rules, err
+		}
+	}
+	return s.repo.FindActiveByCategory("")
+}
+
+// ruleFee is the fee a single rule charges on tx, in the transaction currency
+func (s *feeService) ruleFee(rule models.FeeRule, tx *models.Transaction) (money.Money, string, error) {
+	switch rule.Type {
+	case "percentage":
+		return tx.Amount.Percent(rule.Percent), fmt.Sprintf("%s (%s%%)", rule.Name, basisPoints(rule.Percent)), nil
+
+	case "flat":
+		conv, err := s.rates.Convert(rule.FlatAmount, tx.Amount.Currency, tx.Date)
+		if err != nil {
+			return money.Money{}, "", err
+		}
+		return conv.Amount, rule.Name, nil
+
+	case "tiered":
+		tier, err := s.tierFor(rule, tx)
+		if err != nil || tier == nil {
+			return money.Zero(tx.Amount.Currency), "", err
+		}
+		return tx.Amount.Percent(tier.Percent), fmt.Sprintf("%s (%s%%)", rule.Name, basisPoints(tier.Percent)), nil
+	}
+	return money.Money{}, "", fmt.Errorf("%w: unknown type %q", ErrInvalidFeeRule, rule.Type)
+}
+
+// tierFor picks the highest tier whose threshold the client-freelancer
+// lifetime billing has reached. Billing is counted in the freelancer's
+// currency, so thresholds are converted into it.
+func (s *feeService) tierFor(rule models.FeeRule, tx *models.Transaction) (*models.FeeTier, error) {
+	currency := tx.SettlementAmount.Currency
+	billed, err := s.txRepo.SumSettledBetweenUsers(tx.ClientID, tx.FreelancerID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *models.FeeTier
+	var bestMin int64
+	for i, t := range rule.Tiers {
+		conv, err := s.rates.Convert(t.MinBilling, currency, tx.Date)
+		if err != nil {
+			return nil, err
+		}
+		min := conv.Amount.Amount
+		if billed >= min && (best == nil || min > bestMin) {
+			best, bestMin = &rule.Tiers[i], min
+		}
+	}
+	return best, nil
+}
+
+// basisPoints formats 1250 as "12.5"
+func basisPoints(bp int64) string {
+	s := fmt.Sprintf("%d.%02d", bp/100, bp%100)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
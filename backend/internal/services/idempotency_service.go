@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/repositories"
+)
+
+// IdempotencyKeyTTL is how long a stored response is replayed. After that the
+// key may be reused for a new request.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+var (
+	ErrIdempotencyKeyMismatch   = errors.New("Idempotency-Key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this Idempotency-Key is still being processed")
+)
+
+type IdempotencyService interface {
+	// Begin claims the key for a request. It returns the stored record and
+	// replay=true when an earlier response should be returned instead of
+	// running the handler.
+	Begin(userID uint, key, method, path string, body []byte) (record *models.IdempotencyKey, replay bool, err error)
+	Complete(record *models.IdempotencyKey, status int, body []byte) error
+	// Release forgets a claimed key, e.g. after a server error, so the
+	// client can retry with the same key.
+	Release(record *models.IdempotencyKey) error
+}
+
+type idempotencyService struct {
+	repo repositories.IdempotencyRepository
+	now  func() time.Time
+}
+
+func NewIdempotencyService(repo repositories.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{repo: repo, now: time.Now}
+}
+
+func (s *idempotencyService) Begin(userID uint, key, method, path string, body []byte) (*models.IdempotencyKey, bool, error) {
+	hash := requestHash(method, path, body)
+	record := &models.IdempotencyKey{
+		Key:         key,
+		UserID:      userID,
+		Method:      method,
+		Path:        path,
+		RequestHash: hash,
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := s.repo.CreateIfAbsent(record)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			return record, false, nil
+		}
+
+		existing, err := s.repo.FindByUserAndKey(userID, key)
+		if err != nil {
+			return nil, false, err
+		}
+		if s.now().Sub(existing.CreatedAt) > IdempotencyKeyTTL {
+			// Expired: drop it and claim the key again.
+			if err := s.repo.Delete(existing.ID); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		if existing.RequestHash != hash {
+			return nil, false, ErrIdempotencyKeyMismatch
+		}
+		if existing.StatusCode == 0 {
+			return nil, false, ErrIdempotencyKeyInProgress
+		}
+		return existing, true, nil
+	}
+	return nil, false, ErrIdempotencyKeyInProgress
+}
+
+func (s *idempotencyService) Complete(record *models.IdempotencyKey, status int, body []byte) error {
+	now := s.now()
+	record.StatusCode = status
+	record.ResponseBody = body
+	record.CompletedAt = &now
+	return s.repo.Update(record)
+}
+
+func (s *idempotencyService) Release(record *models.IdempotencyKey) error {
+	return s.repo.Delete(record.ID)
+}
+
+// requestHash fingerprints a request so a reused key with a different
+// request can be detected.
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
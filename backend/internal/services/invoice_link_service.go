@@ -0,0 +1,427 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"FreeConnect/internal/documents"
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidInvoiceLink = errors.New("invoice link is invalid, expired or revoked")
+	ErrInvalidLinkExpiry  = errors.New("link lifetime must be positive and at most 180 days")
+	ErrInvoiceNotShared   = errors.New("only finalised invoices can be shared")
+	ErrInvoiceNotPayable  = errors.New("invoice is not open for payment")
+	ErrPaymentInProgress  = errors.New("a payment of this invoice is already in progress")
+)
+
+const (
+	DefaultInvoiceLinkTTL = 30 * 24 * time.Hour
+	MaxInvoiceLinkTTL     = 180 * 24 * time.Hour
+)
+
+// PublicInvoice is what a public link shows: the printed content of the
+// invoice, without internal IDs or contact details.
+type PublicInvoice struct {
+	InvoiceNumber string              `json:"invoice_number"`
+	Kind          string              `json:"kind"`
+	Status        string              `json:"status"`
+	PaymentStatus string              `json:"payment_status"`
+	Date          time.Time           `json:"date"`
+	DueDate       time.Time           `json:"due_date"`
+	Issuer        string              `json:"issuer"`
+	Client        string              `json:"client"`
+	Project       string              `json:"project"`
+	Lines         []PublicInvoiceLine `json:"lines"`
+	NetAmount     money.Money         `json:"net_amount"`
+	TaxAmount     money.Money         `json:"tax_amount"`
+	AmountDue     money.Money         `json:"amount_due"`
+	LateFee       money.Money         `json:"late_fee"`
+	TaxNote       string              `json:"tax_note,omitempty"`
+	PaidAt        *time.Time          `json:"paid_at,omitempty"`
+	// What a payment through the link collects: the amount due plus late
+	// fees, less credit notes and what was paid. Zero unless Payable.
+	Outstanding   money.Money `json:"outstanding"`
+	Payable       bool        `json:"payable"`
+	LinkExpiresAt time.Time   `json:"link_expires_at"`
+}
+
+type PublicInvoiceLine struct {
+	Description string      `json:"description"`
+	Quantity    int64       `json:"quantity"`
+	UnitPrice   money.Money `json:"unit_price"`
+	NetAmount   money.Money `json:"net_amount"`
+	TaxRate     int64       `json:"tax_rate"`
+	GrossAmount money.Money `json:"gross_amount"`
+}
+
+type InvoiceLinkService interface {
+	// CreateLink shares a finalised invoice for ttl (DefaultInvoiceLinkTTL
+	// when 0). The token is only returned here; it can't be recovered later.
+	CreateLink(invoiceID, createdByID uint, ttl time.Duration) (*models.InvoiceLink, string, error)
+	GetLinks(invoiceID uint) ([]models.InvoiceLink, error)
+	RevokeLink(invoiceID, linkID uint) (*models.InvoiceLink, error)
+	GetPayments(invoiceID uint) ([]models.InvoicePayment, error)
+
+	// OpenInvoice checks the token, counts the open and returns the invoice
+	// both as its public view and as the data to render it.
+	OpenInvoice(token string) (*PublicInvoice, *documents.InvoiceData, error)
+	// PayInvoice collects the outstanding amount through the payment gateway.
+	// A declined payment is returned with status failed rather than as an error.
+	PayInvoice(token, paymentMethod, paymentToken string) (*models.InvoicePayment, error)
+}
+
+type invoiceLinkService struct {
+	repo     repositories.InvoiceLinkRepository
+	invoices InvoiceService
+	gateway  payments.PaymentGateway
+	secret   []byte
+}
+
+func NewInvoiceLinkService(repo repositories.InvoiceLinkRepository, invoices InvoiceService, gateway payments.PaymentGateway, secret string) InvoiceLinkService {
+	return &invoiceLinkService{repo: repo, invoices: invoices, gateway: gateway, secret: []byte(secret)}
+}
+
+func (s *invoiceLinkService) CreateLink(invoiceID, createdByID uint, ttl time.Duration) (*models.InvoiceLink, string, error) {
+	if ttl == 0 {
+		ttl = DefaultInvoiceLinkTTL
+	}
+	if ttl < 0 || ttl > MaxInvoiceLinkTTL {
+		return nil, "", ErrInvalidLinkExpiry
+	}
+	invoice, err := s.invoices.GetInvoiceByID(invoiceID)
+	if err != nil {
+		return nil, "", err
+	}
+	if invoice.Status != "finalised" {
+		return nil, "", ErrInvoiceNotShared
+	}
+
+	link := &models.InvoiceLink{
+		InvoiceID:   invoiceID,
+		CreatedByID: createdByID,
+		// Tokens carry whole seconds
+		ExpiresAt: time.Now().Add(ttl).Truncate(time.Second),
+	}
+	if err := s.repo.Create(link); err != nil {
+		return nil, "", err
+	}
+	return link, s.sign(link), nil
+}
+
+func (s *invoiceLinkService) GetLinks(invoiceID uint) ([]models.InvoiceLink, error) {
+	return s.repo.FindByInvoice(invoiceID)
+}
+
+func (s *invoiceLinkService) RevokeLink(invoiceID, linkID uint) (*models.InvoiceLink, error) {
+	link, err := s.repo.FindByID(linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link.InvoiceID != invoiceID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := s.repo.Revoke(link.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(link.ID)
+}
+
+func (s *invoiceLinkService) GetPayments(invoiceID uint) ([]models.InvoicePayment, error) {
+	return s.repo.FindPayments(invoiceID)
+}
+
+func (s *invoiceLinkService) OpenInvoice(token string) (*PublicInvoice, *documents.InvoiceData, error) {
+	link, err := s.verify(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Settle captures the gateway has completed since, so the view is current
+	if err := s.syncPayments(link.InvoiceID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.repo.RecordOpen(link, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	data, err := s.invoices.GetInvoiceDocument(link.InvoiceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	view, err := s.publicView(data, link)
+	if err != nil {
+		return nil, nil, err
+	}
+	return view, data, nil
+}
+
+// PayInvoice records the payment before the gateway is called, so a crash in
+// between leaves a pending payment that blocks paying twice.
+func (s *invoiceLinkService) PayInvoice(token, paymentMethod, paymentToken string) (*models.InvoicePayment, error) {
+	link, err := s.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncPayments(link.InvoiceID); err != nil {
+		return nil, err
+	}
+
+	var payment *models.InvoicePayment
+	var invoiceNumber string
+	err = s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		invoice, err := repositories.NewInvoiceRepository(db).LockByID(link.InvoiceID)
+		if err != nil {
+			return err
+		}
+		amount, err := invoiceBalance(db, invoice)
+		if err != nil {
+			return err
+		}
+		if !payable(invoice) || !amount.IsPositive() {
+			return ErrInvoiceNotPayable
+		}
+
+		var unsettled int64
+		if err := db.Model(&models.InvoicePayment{}).
+			Where("invoice_id = ? AND status IN ?", invoice.ID, []string{"pending", "processing"}).
+			Count(&unsettled).Error; err != nil {
+			return err
+		}
+		if unsettled > 0 {
+			return ErrPaymentInProgress
+		}
+
+		invoiceNumber = invoice.InvoiceNumber
+		payment = &models.InvoicePayment{
+			InvoiceID:     invoice.ID,
+			LinkID:        &link.ID,
+			Amount:        amount,
+			PaymentMethod: paymentMethod,
+			Status:        "pending",
+			Gateway:       s.gateway.Name(),
+		}
+		return repositories.NewInvoiceLinkRepository(db).CreatePayment(payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := s.gateway.CreateIntent(payments.IntentRequest{
+		Amount:        payment.Amount,
+		PaymentMethod: paymentMethod,
+		PaymentToken:  paymentToken,
+		Description:   "Invoice " + invoiceNumber,
+	})
+	if err != nil {
+		// Nothing was collected; free the invoice for another attempt
+		payment.Status = "failed"
+		payment.FailureReason = err.Error()
+		if saveErr := s.repo.SavePayment(payment); saveErr != nil {
+			return nil, saveErr
+		}
+		return nil, err
+	}
+	payment.GatewayReference = intent.ID
+	if intent.Status == payments.IntentRequiresCapture {
+		captured, err := s.gateway.Capture(intent.ID)
+		if err != nil {
+			// The capture may still have gone through; keep the payment
+			// processing until syncPayments learns the outcome
+			payment.Status = "processing"
+			if saveErr := s.repo.SavePayment(payment); saveErr != nil {
+				return nil, saveErr
+			}
+			return nil, err
+		}
+		intent = captured
+	}
+	if err := s.settle(payment, intent); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// syncPayments asks the gateway about captures that were still processing.
+// A capture that never reached the gateway is made again.
+func (s *invoiceLinkService) syncPayments(invoiceID uint) error {
+	processing, err := s.repo.FindProcessingPayments(invoiceID)
+	if err != nil {
+		return err
+	}
+	for i := range processing {
+		intent, err := s.gateway.Status(processing[i].GatewayReference)
+		if err == nil && intent.Status == payments.IntentRequiresCapture {
+			intent, err = s.gateway.Capture(intent.ID)
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.settle(&processing[i], intent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settle stores the gateway's result for a payment. Once the money is
+// captured the invoice is marked paid and its issuer notified.
+func (s *invoiceLinkService) settle(payment *models.InvoicePayment, intent *payments.Intent) error {
+	switch intent.Status {
+	case payments.IntentSucceeded:
+		payment.Status = "succeeded"
+	case payments.IntentProcessing:
+		payment.Status = "processing"
+	case payments.IntentDeclined:
+		payment.Status = "failed"
+		payment.FailureReason = intent.FailureReason
+	default:
+		return fmt.Errorf("%w: unexpected intent status %q", payments.ErrInvalidState, intent.Status)
+	}
+
+	return s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		// Settle each payment once, however often the gateway reports it
+		var current models.InvoicePayment
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, payment.ID).Error; err != nil {
+			return err
+		}
+		if current.Status == "succeeded" || current.Status == "failed" {
+			*payment = current
+			return nil
+		}
+		if err := repositories.NewInvoiceLinkRepository(db).SavePayment(payment); err != nil {
+			return err
+		}
+		if payment.Status != "succeeded" {
+			return nil
+		}
+
+		invoice, err := repositories.NewInvoiceRepository(db).LockByID(payment.InvoiceID)
+		if err != nil {
+			return err
+		}
+		excess, err := payInvoice(db, invoice, payment.Amount, time.Now())
+		if err != nil || invoice.IssuerID == nil {
+			return err
+		}
+		// Paid in the meantime, e.g. by a transaction
+		return creditOverpayment(db, &models.ClientCredit{
+			Amount:       excess,
+			Reason:       "overpayment of invoice " + invoice.InvoiceNumber + " through its public link",
+			ClientID:     invoice.ClientID,
+			FreelancerID: *invoice.IssuerID,
+			InvoiceID:    &invoice.ID,
+		})
+	})
+}
+
+func (s *invoiceLinkService) publicView(data *documents.InvoiceData, link *models.InvoiceLink) (*PublicInvoice, error) {
+	inv := data.Invoice
+	view := &PublicInvoice{
+		InvoiceNumber: inv.InvoiceNumber,
+		Kind:          inv.Kind,
+		Status:        inv.Status,
+		PaymentStatus: inv.PaymentStatus,
+		Date:          inv.Date,
+		DueDate:       inv.DueDate,
+		Client:        displayName(inv.Client),
+		Project:       inv.Project.Title,
+		NetAmount:     inv.NetAmount,
+		TaxAmount:     inv.TaxAmount,
+		AmountDue:     inv.AmountDue,
+		LateFee:       inv.LateFee,
+		TaxNote:       inv.TaxNote,
+		PaidAt:        inv.PaidAt,
+		Outstanding:   money.Zero(inv.AmountDue.CurrencyOrDefault()),
+		Payable:       payable(&inv),
+		LinkExpiresAt: link.ExpiresAt,
+	}
+	if inv.Issuer != nil {
+		view.Issuer = displayName(*inv.Issuer)
+	}
+	for _, l := range inv.Lines {
+		view.Lines = append(view.Lines, PublicInvoiceLine{
+			Description: l.Description,
+			Quantity:    l.Quantity,
+			UnitPrice:   l.UnitPrice,
+			NetAmount:   l.NetAmount,
+			TaxRate:     l.TaxRate,
+			GrossAmount: l.GrossAmount,
+		})
+	}
+	if view.Payable {
+		amount, err := invoiceBalance(s.repo.GetDB(), &inv)
+		if err != nil {
+			return nil, err
+		}
+		view.Outstanding = amount
+		view.Payable = amount.IsPositive()
+	}
+	return view, nil
+}
+
+// sign builds the token of a link: "<link id>.<expiry>.<signature>", where
+// the signature is an HMAC-SHA256 of the first two parts
+func (s *invoiceLinkService) sign(link *models.InvoiceLink) string {
+	payload := fmt.Sprintf("%d.%d", link.ID, link.ExpiresAt.Unix())
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+// verify checks a token's signature and expiry and that its link hasn't been
+// revoked. Every failure is reported as ErrInvalidInvoiceLink so the response
+// doesn't tell which links exist.
+func (s *invoiceLinkService) verify(token string) (*models.InvoiceLink, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidInvoiceLink
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, s.mac(parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidInvoiceLink
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidInvoiceLink
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return nil, ErrInvalidInvoiceLink
+	}
+
+	link, err := s.repo.FindByID(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidInvoiceLink
+	}
+	if err != nil {
+		return nil, err
+	}
+	if link.RevokedAt != nil || link.ExpiresAt.Unix() != expires {
+		return nil, ErrInvalidInvoiceLink
+	}
+	return link, nil
+}
+
+func (s *invoiceLinkService) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func displayName(u models.User) string {
+	if u.CompanyName != "" {
+		return u.CompanyName
+	}
+	return u.Name
+}
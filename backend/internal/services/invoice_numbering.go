@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultInvoiceNumberFormat produces numbers like FC-2026-000123
+const DefaultInvoiceNumberFormat = "FC-{YYYY}-{SEQ:6}"
+
+// DefaultCreditNoteNumberFormat produces numbers like CN-2026-000042
+const DefaultCreditNoteNumberFormat = "CN-{YYYY}-{SEQ:6}"
+
+// Numbering series of regular invoices and of credit notes
+const (
+	InvoiceSeries    = "INV"
+	CreditNoteSeries = "CN"
+)
+
+var ErrInvalidNumberFormat = errors.New("invalid invoice number format")
+
+// InvoiceNumbering configures how finalised invoices and credit notes are
+// numbered. Formats support {YYYY}, {YY} and {SEQ} (or {SEQ:n} for a
+// zero-padded width). Sequences restart every year, so a format needs the
+// year to keep its numbers unique.
+type InvoiceNumbering struct {
+	Format           string
+	CreditNoteFormat string
+}
+
+// Validate checks both formats. They must not produce the same numbers,
+// since invoices and credit notes of an issuer share one set of unique
+// numbers. Formats that differ only in spelling, like {SEQ} and {SEQ:1},
+// are caught by comparing sample numbers rather than the formats.
+func (n InvoiceNumbering) Validate() error {
+	if err := ValidateInvoiceNumberFormat(n.Format); err != nil {
+		return err
+	}
+	if err := ValidateInvoiceNumberFormat(n.CreditNoteFormat); err != nil {
+		return err
+	}
+	invoices := map[string]bool{}
+	for _, sample := range numberSamples(n.Format) {
+		invoices[sample] = true
+	}
+	for _, sample := range numberSamples(n.CreditNoteFormat) {
+		if invoices[sample] {
+			return fmt.Errorf("%w: invoices and credit notes would both be numbered %s", ErrInvalidNumberFormat, sample)
+		}
+	}
+	return nil
+}
+
+// numberSamples renders a format for a few years and sequence numbers,
+// including one wider than any padding
+func numberSamples(format string) []string {
+	var samples []string
+	for _, year := range []int{2026, 2027} {
+		for _, seq := range []int64{1, 7, 42, 1000, 123456789012345} {
+			samples = append(samples, FormatInvoiceNumber(format, year, seq))
+		}
+	}
+	return samples
+}
+
+var numberTokenPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// ValidateInvoiceNumberFormat checks that a format only uses known tokens,
+// contains the sequence number exactly once and the year at least once.
+func ValidateInvoiceNumberFormat(format string) error {
+	seqs, years := 0, 0
+	for _, token := range numberTokenPattern.FindAllString(format, -1) {
+		switch {
+		case token == "{YYYY}", token == "{YY}":
+			years++
+		case token == "{SEQ}":
+			seqs++
+		case strings.HasPrefix(token, "{SEQ:"):
+			width, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(token, "{SEQ:"), "}"))
+			if err != nil || width < 1 || width > 12 {
+				return fmt.Errorf("%w: bad width in %s", ErrInvalidNumberFormat, token)
+			}
+			seqs++
+		default:
+			return fmt.Errorf("%w: unknown token %s", ErrInvalidNumberFormat, token)
+		}
+	}
+	if seqs != 1 {
+		return fmt.Errorf("%w: %q must contain {SEQ} exactly once", ErrInvalidNumberFormat, format)
+	}
+	if years == 0 {
+		return fmt.Errorf("%w: %q must contain {YYYY} or {YY}, since the sequence restarts every year", ErrInvalidNumberFormat, format)
+	}
+	if len(FormatInvoiceNumber(format, 9999, 1)) > 40 {
+		return fmt.Errorf("%w: %q is too long", ErrInvalidNumberFormat, format)
+	}
+	return nil
+}
+
+// FormatInvoiceNumber renders the number of the seq-th invoice of a year.
+// The format must have been validated.
+func FormatInvoiceNumber(format string, year int, seq int64) string {
+	return numberTokenPattern.ReplaceAllStringFunc(format, func(token string) string {
+		switch token {
+		case "{YYYY}":
+			return fmt.Sprintf("%04d", year)
+		case "{YY}":
+			return fmt.Sprintf("%02d", year%100)
+		case "{SEQ}":
+			return strconv.FormatInt(seq, 10)
+		}
+		width, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(token, "{SEQ:"), "}"))
+		return fmt.Sprintf("%0*d", width, seq)
+	})
+}
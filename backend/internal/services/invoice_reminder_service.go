@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"FreeConnect/internal/mail"
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidBillingTerms = errors.New("invalid billing terms")
+
+// Reminder stages, named after when they are sent relative to the due date
+const (
+	ReminderBeforeDue = "before_due" // ReminderLeadDays before
+	ReminderDue       = "due"        // on the due date
+	ReminderOverdue7  = "overdue_7"  // a week late
+	ReminderOverdue30 = "overdue_30" // a month late
+)
+
+// ReminderLeadDays is how many days before the due date the first reminder goes out
+const ReminderLeadDays = 3
+
+// reminderStages in the order they are sent, with the day relative to the
+// due date from which each applies
+var reminderStages = []struct {
+	stage string
+	day   int
+}{
+	{ReminderBeforeDue, -ReminderLeadDays},
+	{ReminderDue, 0},
+	{ReminderOverdue7, 7},
+	{ReminderOverdue30, 30},
+}
+
+// ReminderRunResult summarises one run of the reminder job.
+type ReminderRunResult struct {
+	MarkedOverdue int `json:"marked_overdue"`
+	LateFees      int `json:"late_fees"`
+	Sent          int `json:"sent"`
+	EmailFailures int `json:"email_failures"`
+}
+
+type InvoiceReminderService interface {
+	GetTerms(clientID uint) (*models.BillingTerms, error)
+	SaveTerms(terms *models.BillingTerms) error
+	GetReminders(invoiceID uint) ([]models.InvoiceReminder, error)
+
+	// Run marks finalised invoices past their due date as overdue, applies
+	// late fees and sends the reminders that are due as of now.
+	Run(now time.Time) (*ReminderRunResult, error)
+}
+
+type invoiceReminderService struct {
+	repo   repositories.InvoiceReminderRepository
+	mailer mail.Mailer
+}
+
+func NewInvoiceReminderService(repo repositories.InvoiceReminderRepository, mailer mail.Mailer) InvoiceReminderService {
+	return &invoiceReminderService{repo: repo, mailer: mailer}
+}
+
+func (s *invoiceReminderService) GetTerms(clientID uint) (*models.BillingTerms, error) {
+	return s.repo.FindTermsByClient(clientID)
+}
+
+func (s *invoiceReminderService) SaveTerms(terms *models.BillingTerms) error {
+	if terms.LateFeeRate < 0 || terms.LateFeeRate > 10000 {
+		return fmt.Errorf("%w: late_fee_rate must be between 0 and 10000 basis points", ErrInvalidBillingTerms)
+	}
+	if terms.GraceDays < 0 {
+		return fmt.Errorf("%w: grace_days can't be negative", ErrInvalidBillingTerms)
+	}
+	var client models.User
+	if err := s.repo.GetDB().Select("user_id", "role").First(&client, terms.ClientID).Error; err != nil {
+		return err
+	}
+	if client.Role != "client" {
+		return fmt.Errorf("%w: billing terms can only be set for clients", ErrInvalidBillingTerms)
+	}
+	return s.repo.SaveTerms(terms)
+}
+
+func (s *invoiceReminderService) GetReminders(invoiceID uint) ([]models.InvoiceReminder, error) {
+	return s.repo.FindByInvoice(invoiceID)
+}
+
+// Run is safe to repeat and to run concurrently: the overdue flag and the
+// late fee are only set once, and each reminder stage is recorded before it
+// is sent. When the job was down for a while only the latest stage reached
+// is sent, not every stage that was missed.
+func (s *invoiceReminderService) Run(now time.Time) (*ReminderRunResult, error) {
+	result := &ReminderRunResult{}
+	today := startOfDay(now)
+
+	marked, err := s.repo.MarkOverdue(today)
+	if err != nil {
+		return nil, err
+	}
+	result.MarkedOverdue = int(marked)
+
+	invoices, err := s.repo.FindUnpaid(today.AddDate(0, 0, ReminderLeadDays+1))
+	if err != nil {
+		return result, err
+	}
+	ids := make([]uint, len(invoices))
+	for i, inv := range invoices {
+		ids[i] = inv.ID
+	}
+	sent, err := s.repo.FindSentStages(ids)
+	if err != nil {
+		return result, err
+	}
+
+	terms := make(map[uint]*models.BillingTerms)
+	for i := range invoices {
+		inv := &invoices[i]
+
+		applied, err := s.applyLateFee(inv, terms, now)
+		if err != nil {
+			return result, err
+		}
+		if applied {
+			result.LateFees++
+		}
+
+		stage := ReminderStage(inv.DueDate, now)
+		if stage == "" || reachedStage(sent[inv.ID], stage) {
+			continue
+		}
+		reminder, err := s.notify(inv, stage, now)
+		if err != nil {
+			return result, err
+		}
+		if reminder == nil {
+			continue // another run sent it first
+		}
+		result.Sent++
+
+		// E-mail is best effort; the in-app notification is already stored
+		if err := s.mailer.Send(reminderEmail(inv, stage, now)); err != nil {
+			log.Printf("invoice %d: sending %s reminder to %s failed: %v", inv.ID, stage, inv.Client.Email, err)
+			result.EmailFailures++
+			continue
+		}
+		if err := s.repo.MarkEmailed(reminder.ID); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// applyLateFee charges the late fee of the client's terms once the invoice is
+// more than the grace period overdue. terms caches the lookups of one run.
+func (s *invoiceReminderService) applyLateFee(inv *models.Invoice, terms map[uint]*models.BillingTerms, now time.Time) (bool, error) {
+	if inv.LateFeeAppliedAt != nil || !IsOverdue(inv.DueDate, now) {
+		return false, nil
+	}
+	t, ok := terms[inv.ClientID]
+	if !ok {
+		var err error
+		t, err = s.repo.FindTermsByClient(inv.ClientID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			t, err = nil, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		terms[inv.ClientID] = t
+	}
+	if t == nil || t.LateFeeRate == 0 || daysPastDue(inv.DueDate, now) <= t.GraceDays {
+		return false, nil
+	}
+
+	fee := inv.AmountDue.Percent(t.LateFeeRate)
+	if !fee.IsPositive() {
+		return false, nil
+	}
+	applied, err := s.repo.ApplyLateFee(inv.ID, fee, now)
+	if err != nil || !applied {
+		return false, err
+	}
+	inv.LateFee = fee
+	inv.LateFeeAppliedAt = &now
+	return true, nil
+}
+
+// notify records the reminder and notifies the client in one transaction.
+// It returns nil if the stage was already recorded.
+func (s *invoiceReminderService) notify(inv *models.Invoice, stage string, now time.Time) (*models.InvoiceReminder, error) {
+	var reminder *models.InvoiceReminder
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		r := &models.InvoiceReminder{InvoiceID: inv.ID, Stage: stage, SentAt: now}
+		created, err := repositories.NewInvoiceReminderRepository(db).RecordReminder(r)
+		if err != nil || !created {
+			return err
+		}
+		reminder = r
+		notifications := NewNotificationService(repositories.NewNotificationRepository(db))
+		return notifications.CreateNotification(&models.Notification{
+			Message: reminderText(inv, stage, now),
+			Date:    now,
+			Type:    "invoice_reminder",
+			UserID:  inv.ClientID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
+
+// ReminderStage returns the latest reminder stage reached at now for an
+// invoice due on the given date, or "" if it is too early for a reminder.
+// Stages go by calendar day in UTC.
+func ReminderStage(due, now time.Time) string {
+	days := daysPastDue(due, now)
+	stage := ""
+	for _, st := range reminderStages {
+		if days >= st.day {
+			stage = st.stage
+		}
+	}
+	return stage
+}
+
+// IsOverdue reports whether an invoice due on the given date is overdue at
+// now, i.e. its due date has passed. An invoice is not overdue on its due date.
+func IsOverdue(due, now time.Time) bool {
+	return daysPastDue(due, now) > 0
+}
+
+// daysPastDue counts calendar days from the due date to now; negative before it
+func daysPastDue(due, now time.Time) int {
+	return int(startOfDay(now).Sub(startOfDay(due)).Hours() / 24)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// reachedStage reports whether one of the sent stages is the given stage or a
+// later one
+func reachedStage(sent []string, stage string) bool {
+	for _, s := range sent {
+		if stageIndex(s) >= stageIndex(stage) {
+			return true
+		}
+	}
+	return false
+}
+
+func stageIndex(stage string) int {
+	for i, st := range reminderStages {
+		if st.stage == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// reminderText is the notification shown to the client
+func reminderText(inv *models.Invoice, stage string, now time.Time) string {
+	due := inv.DueDate.UTC().Format("2006-01-02")
+	var text string
+	switch stage {
+	case ReminderBeforeDue:
+		text = fmt.Sprintf("Invoice %s for %s is due on %s.", inv.InvoiceNumber, formatMoney(inv.AmountDue), due)
+	case ReminderDue:
+		text = fmt.Sprintf("Invoice %s for %s is due today.", inv.InvoiceNumber, formatMoney(inv.AmountDue))
+	default:
+		text = fmt.Sprintf("Invoice %s for %s is %d days overdue (due %s).",
+			inv.InvoiceNumber, formatMoney(inv.AmountDue), daysPastDue(inv.DueDate, now), due)
+	}
+	if inv.LateFee.IsPositive() {
+		text += fmt.Sprintf(" A late fee of %s has been added.", formatMoney(inv.LateFee))
+	}
+	if inv.AmountPaid.IsPositive() {
+		text += fmt.Sprintf(" %s has been paid so far.", formatMoney(inv.AmountPaid))
+	}
+	return text
+}
+
+func reminderEmail(inv *models.Invoice, stage string, now time.Time) mail.Message {
+	subject := "Payment reminder: invoice " + inv.InvoiceNumber
+	if stage == ReminderOverdue7 || stage == ReminderOverdue30 {
+		subject = "Overdue: invoice " + inv.InvoiceNumber
+	}
+	return mail.Message{
+		To:      inv.Client.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("Hello %s,\n\n%s\n\nPlease ignore this reminder if you have already paid.\n", inv.Client.Name, reminderText(inv, stage, now)),
+	}
+}
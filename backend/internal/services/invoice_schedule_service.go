@@ -0,0 +1,341 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"FreeConnect/internal/mail"
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSchedule     = errors.New("invalid invoice schedule")
+	ErrScheduleStateChange = errors.New("invoice schedule can't change state")
+)
+
+// Schedule intervals
+const (
+	IntervalWeekly    = "weekly"
+	IntervalMonthly   = "monthly"
+	IntervalQuarterly = "quarterly"
+	IntervalYearly    = "yearly"
+)
+
+// DefaultScheduleDueDays is the payment term of scheduled invoices unless the
+// schedule sets one
+const DefaultScheduleDueDays = 14
+
+// ScheduleRunResult summarises one run of the recurring invoice job.
+type ScheduleRunResult struct {
+	Issued        int `json:"issued"`
+	Failed        int `json:"failed"` // schedules that hit an error; they are retried on the next run
+	EmailFailures int `json:"email_failures"`
+}
+
+type InvoiceScheduleService interface {
+	// CreateSchedule starts billing a project at a fixed interval. Only the
+	// project's freelancer can set one up.
+	CreateSchedule(schedule *models.InvoiceSchedule) error
+	GetSchedule(id uint) (*models.InvoiceSchedule, error)
+	GetSchedulesByProject(projectID uint) ([]models.InvoiceSchedule, error)
+	GetScheduleInvoices(id uint) ([]models.Invoice, error)
+
+	PauseSchedule(id uint) (*models.InvoiceSchedule, error)
+	// ResumeSchedule reactivates a paused schedule; periods that started
+	// while it was paused are skipped, not billed.
+	ResumeSchedule(id uint, now time.Time) (*models.InvoiceSchedule, error)
+	CancelSchedule(id uint) (*models.InvoiceSchedule, error)
+
+	// Run issues and sends an invoice for every period of an active schedule
+	// that has started by now, including periods missed while the job was down.
+	Run(now time.Time) (*ScheduleRunResult, error)
+}
+
+type invoiceScheduleService struct {
+	repo      repositories.InvoiceScheduleRepository
+	numbering InvoiceNumbering
+	mailer    mail.Mailer
+}
+
+func NewInvoiceScheduleService(repo repositories.InvoiceScheduleRepository, numbering InvoiceNumbering, mailer mail.Mailer) InvoiceScheduleService {
+	return &invoiceScheduleService{repo: repo, numbering: numbering, mailer: mailer}
+}
+
+func (s *invoiceScheduleService) CreateSchedule(schedule *models.InvoiceSchedule) error {
+	switch schedule.Interval {
+	case IntervalWeekly, IntervalMonthly, IntervalQuarterly, IntervalYearly:
+	default:
+		return fmt.Errorf("%w: interval must be weekly, monthly, quarterly or yearly", ErrInvalidSchedule)
+	}
+	if schedule.Description == "" {
+		return fmt.Errorf("%w: description is required", ErrInvalidSchedule)
+	}
+	if !schedule.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidSchedule)
+	}
+	if err := checkCurrency(schedule.Amount); err != nil {
+		return err
+	}
+	if schedule.DueDays < 0 {
+		return fmt.Errorf("%w: due_days can't be negative", ErrInvalidSchedule)
+	}
+	// Periods start at midnight UTC; back-dated schedules would bill the past
+	schedule.StartDate = startOfDay(schedule.StartDate)
+	if schedule.StartDate.Before(startOfDay(time.Now())) {
+		return fmt.Errorf("%w: start_date can't be in the past", ErrInvalidSchedule)
+	}
+	if schedule.EndDate != nil {
+		end := startOfDay(*schedule.EndDate)
+		if end.Before(schedule.StartDate) {
+			return fmt.Errorf("%w: end_date must not be before start_date", ErrInvalidSchedule)
+		}
+		schedule.EndDate = &end
+	}
+
+	var project models.Project
+	if err := s.repo.GetDB().First(&project, schedule.ProjectID).Error; err != nil {
+		return err
+	}
+	if project.FreelancerID == nil || *project.FreelancerID != schedule.IssuerID {
+		return ErrNotProjectFreelancer
+	}
+
+	schedule.Status = "active"
+	schedule.RunCount = 0
+	schedule.NextRunAt = schedule.StartDate
+	schedule.LastRunAt = nil
+	return s.repo.Create(schedule)
+}
+
+func (s *invoiceScheduleService) GetSchedule(id uint) (*models.InvoiceSchedule, error) {
+	return s.repo.FindByID(id)
+}
+
+func (s *invoiceScheduleService) GetSchedulesByProject(projectID uint) ([]models.InvoiceSchedule, error) {
+	return s.repo.FindByProject(projectID)
+}
+
+func (s *invoiceScheduleService) GetScheduleInvoices(id uint) ([]models.Invoice, error) {
+	return s.repo.FindInvoices(id)
+}
+
+func (s *invoiceScheduleService) PauseSchedule(id uint) (*models.InvoiceSchedule, error) {
+	return s.transition(id, "paused", func(schedule *models.InvoiceSchedule) bool {
+		return schedule.Status == "active"
+	})
+}
+
+func (s *invoiceScheduleService) ResumeSchedule(id uint, now time.Time) (*models.InvoiceSchedule, error) {
+	return s.transition(id, "active", func(schedule *models.InvoiceSchedule) bool {
+		if schedule.Status != "paused" {
+			return false
+		}
+		for !schedule.NextRunAt.After(now) {
+			advance(schedule)
+		}
+		return true
+	})
+}
+
+func (s *invoiceScheduleService) CancelSchedule(id uint) (*models.InvoiceSchedule, error) {
+	return s.transition(id, "cancelled", func(schedule *models.InvoiceSchedule) bool {
+		return schedule.Status == "active" || schedule.Status == "paused"
+	})
+}
+
+// transition locks the schedule and moves it to the given status if allowed
+// approves; allowed may also adjust the schedule
+func (s *invoiceScheduleService) transition(id uint, status string, allowed func(*models.InvoiceSchedule) bool) (*models.InvoiceSchedule, error) {
+	var schedule *models.InvoiceSchedule
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		repo := repositories.NewInvoiceScheduleRepository(db)
+		var err error
+		if schedule, err = repo.LockByID(id); err != nil {
+			return err
+		}
+		from := schedule.Status
+		if !allowed(schedule) {
+			return fmt.Errorf("%w: a %s schedule can't become %s", ErrScheduleStateChange, from, status)
+		}
+		schedule.Status = status
+		if status == "active" && ended(schedule) {
+			schedule.Status = "completed"
+		}
+		return repo.SaveProgress(schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Run handles every due schedule in its own transaction, so one failing
+// schedule doesn't hold up the others.
+func (s *invoiceScheduleService) Run(now time.Time) (*ScheduleRunResult, error) {
+	result := &ScheduleRunResult{}
+	ids, err := s.repo.FindDue(now)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		issued, err := s.runSchedule(id, now)
+		if err != nil {
+			log.Printf("invoice schedule %d: %v", id, err)
+			result.Failed++
+			continue
+		}
+		result.Issued += len(issued)
+
+		// E-mail is best effort; the client already has the in-app notification
+		for i := range issued {
+			if err := s.send(&issued[i]); err != nil {
+				log.Printf("invoice %d: sending to the client failed: %v", issued[i].ID, err)
+				result.EmailFailures++
+			}
+		}
+	}
+	return result, nil
+}
+
+// runSchedule issues the invoices of every period of the schedule that has
+// started by now. The schedule row stays locked until they are all stored, so
+// concurrent runs can't bill a period twice.
+func (s *invoiceScheduleService) runSchedule(id uint, now time.Time) ([]models.Invoice, error) {
+	var issued []models.Invoice
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		repo := repositories.NewInvoiceScheduleRepository(db)
+		schedule, err := repo.LockByID(id)
+		if err != nil {
+			return err
+		}
+		if schedule.Status != "active" {
+			return nil // paused or cancelled since it was found
+		}
+
+		// Stop billing when the project is cancelled or changes hands
+		var project models.Project
+		if err := db.First(&project, schedule.ProjectID).Error; err != nil {
+			return err
+		}
+		if project.Status == "cancelled" || project.FreelancerID == nil || *project.FreelancerID != schedule.IssuerID {
+			log.Printf("invoice schedule %d: cancelled, project %d is cancelled or has another freelancer", schedule.ID, project.ID)
+			schedule.Status = "cancelled"
+			return repo.SaveProgress(schedule)
+		}
+
+		invoices := NewInvoiceService(repositories.NewInvoiceRepository(db), s.numbering)
+		notifications := NewNotificationService(repositories.NewNotificationRepository(db))
+		for !ended(schedule) && !schedule.NextRunAt.After(now) {
+			invoice, err := s.issue(invoices, schedule, &project, now)
+			if err != nil {
+				return err
+			}
+			err = notifications.CreateNotification(&models.Notification{
+				Message: fmt.Sprintf("Invoice %s for %s (%s) is due on %s.", invoice.InvoiceNumber,
+					formatMoney(invoice.AmountDue), schedule.Description, invoice.DueDate.UTC().Format("2006-01-02")),
+				Date:   now,
+				Type:   "invoice_issued",
+				UserID: invoice.ClientID,
+			})
+			if err != nil {
+				return err
+			}
+			issued = append(issued, *invoice)
+			advance(schedule)
+			schedule.LastRunAt = &now
+		}
+		if ended(schedule) {
+			schedule.Status = "completed"
+		}
+		return repo.SaveProgress(schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// issue creates and finalises the invoice of the schedule's next period
+func (s *invoiceScheduleService) issue(invoices InvoiceService, schedule *models.InvoiceSchedule, project *models.Project, now time.Time) (*models.Invoice, error) {
+	periodStart := schedule.NextRunAt
+	periodEnd := SchedulePeriodStart(schedule.StartDate, schedule.Interval, schedule.RunCount+1).AddDate(0, 0, -1)
+	issuerID := schedule.IssuerID
+	scheduleID := schedule.ID
+	invoice := &models.Invoice{
+		PaymentStatus: "pending",
+		DueDate:       startOfDay(now).AddDate(0, 0, schedule.DueDays),
+		ProjectID:     project.ID,
+		ClientID:      project.ClientID,
+		IssuerID:      &issuerID,
+		ScheduleID:    &scheduleID,
+		PeriodStart:   &periodStart,
+		Lines: []models.InvoiceLine{{
+			Description: fmt.Sprintf("%s (%s to %s)", schedule.Description,
+				periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02")),
+			Quantity:  1,
+			UnitPrice: schedule.Amount,
+		}},
+	}
+	if err := invoices.CreateInvoice(invoice); err != nil {
+		return nil, err
+	}
+	return invoices.FinaliseInvoice(invoice.ID)
+}
+
+// send e-mails a freshly issued invoice to the client
+func (s *invoiceScheduleService) send(invoice *models.Invoice) error {
+	var client models.User
+	if err := s.repo.GetDB().Select("user_id", "name", "email").First(&client, invoice.ClientID).Error; err != nil {
+		return err
+	}
+	return s.mailer.Send(mail.Message{
+		To:      client.Email,
+		Subject: "New invoice " + invoice.InvoiceNumber,
+		Body: fmt.Sprintf("Hello %s,\n\nInvoice %s for %s has been issued and is due on %s.\n",
+			client.Name, invoice.InvoiceNumber, formatMoney(invoice.AmountDue), invoice.DueDate.UTC().Format("2006-01-02")),
+	})
+}
+
+// advance moves the schedule on to its next period
+func advance(schedule *models.InvoiceSchedule) {
+	schedule.RunCount++
+	schedule.NextRunAt = SchedulePeriodStart(schedule.StartDate, schedule.Interval, schedule.RunCount)
+}
+
+// ended reports whether the schedule's next period starts after its end date
+func ended(schedule *models.InvoiceSchedule) bool {
+	return schedule.EndDate != nil && schedule.NextRunAt.After(*schedule.EndDate)
+}
+
+// SchedulePeriodStart returns the start of period n (counting from 0) of a
+// schedule starting on start. Periods are counted from the start date rather
+// than from the previous period, so a schedule starting on the 31st bills on
+// the last day of shorter months and returns to the 31st afterwards.
+func SchedulePeriodStart(start time.Time, interval string, n int) time.Time {
+	switch interval {
+	case IntervalWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case IntervalQuarterly:
+		return addMonths(start, 3*n)
+	case IntervalYearly:
+		return addMonths(start, 12*n)
+	default:
+		return addMonths(start, n)
+	}
+}
+
+// addMonths adds n months, clamping the day to the end of the target month
+// where time.AddDate would overflow into the next one
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
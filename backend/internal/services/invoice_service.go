@@ -1,8 +1,29 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"FreeConnect/internal/documents"
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidInvoiceLine   = errors.New("invalid invoice line")
+	ErrNothingToInvoice     = errors.New("no completed, uninvoiced tasks to bill")
+	ErrTaskNotBillable      = errors.New("task is not completed, already invoiced or has no budget")
+	ErrNotProjectFreelancer = errors.New("only the project's freelancer can invoice it")
+	ErrInvoiceNotDraft      = errors.New("only draft invoices can be finalised")
+	ErrNoIssuer             = errors.New("invoice has no issuer to number it for")
+	ErrInvoiceImmutable     = errors.New("finalised invoices can't be changed or deleted; void it or issue a credit note")
+	ErrInvoiceNotVoidable   = errors.New("only unpaid, finalised invoices without credit notes can be voided")
+	ErrInvoiceNotCreditable = errors.New("only finalised invoices can be credited")
+	ErrInvalidCreditNote    = errors.New("invalid credit note")
 )
 
 type InvoiceService interface {
@@ -11,17 +32,66 @@ type InvoiceService interface {
 	GetInvoicesByProject(projectID uint) ([]models.Invoice, error)
 	UpdateInvoice(invoice *models.Invoice) error
 	DeleteInvoice(id uint) error
+
+	// GenerateFromTasks bills the completed tasks of a project that haven't
+	// been invoiced yet, one line per task. With taskIDs only those are billed.
+	GenerateFromTasks(projectID, freelancerID uint, taskIDs []uint, dueDate time.Time) (*models.Invoice, error)
+
+	// FinaliseInvoice gives a draft the next number of its issuer's series
+	// for the current year.
+	FinaliseInvoice(id uint) (*models.Invoice, error)
+
+	// GetInvoiceDocument loads everything printed on the invoice
+	GetInvoiceDocument(id uint) (*documents.InvoiceData, error)
+	// GetIssuedDocuments does the same for everything issued in [from, to)
+	GetIssuedDocuments(from, to time.Time) ([]documents.InvoiceData, error)
+
+	// VoidInvoice cancels a finalised invoice that hasn't been paid or
+	// credited. Its number stays used and its tasks become billable again.
+	VoidInvoice(id uint, reason string) (*models.Invoice, error)
+
+	// CreateCreditNote issues a credit note against a finalised invoice.
+	// amount is the net amount to credit; nil credits everything not credited
+	// yet. The credit note is numbered in its own series right away.
+	CreateCreditNote(invoiceID uint, amount *money.Money, reason string) (*models.Invoice, error)
+	GetCreditNotes(invoiceID uint) ([]models.Invoice, error)
 }
 
 type invoiceService struct {
-	repo repositories.InvoiceRepository
+	repo      repositories.InvoiceRepository
+	numbering InvoiceNumbering
 }
 
-func NewInvoiceService(repo repositories.InvoiceRepository) InvoiceService {
-	return &invoiceService{repo: repo}
+func NewInvoiceService(repo repositories.InvoiceRepository, numbering InvoiceNumbering) InvoiceService {
+	if numbering.Format == "" {
+		numbering.Format = DefaultInvoiceNumberFormat
+	}
+	if numbering.CreditNoteFormat == "" {
+		numbering.CreditNoteFormat = DefaultCreditNoteNumberFormat
+	}
+	return &invoiceService{repo: repo, numbering: numbering}
 }
 
+// CreateInvoice stores a draft. It works out the tax from the issuer's and
+// the client's tax profiles and sets the gross amount due.
 func (s *invoiceService) CreateInvoice(invoice *models.Invoice) error {
+	invoice.Status = "draft"
+	invoice.InvoiceNumber = ""
+	invoice.FinalisedAt = nil
+	// Nothing is paid on a new invoice
+	invoice.PaymentStatus = "pending"
+	invoice.AmountPaid = money.Money{}
+	invoice.PaidAt = nil
+	if err := s.prepare(invoice); err != nil {
+		return err
+	}
+	decision, err := s.taxes().Determine(invoice.IssuerID, invoice.ClientID)
+	if err != nil {
+		return err
+	}
+	if err := applyTax(invoice, decision); err != nil {
+		return err
+	}
 	return s.repo.Create(invoice)
 }
 
@@ -33,10 +103,471 @@ func (s *invoiceService) GetInvoicesByProject(projectID uint) ([]models.Invoice,
 	return s.repo.FindByProject(projectID)
 }
 
+// UpdateInvoice keeps the tax treatment fixed at creation unless the parties
+// change, and recalculates the amounts from the net amount. Finalised invoices
+// can't change. The payment status is never taken from the caller: it follows
+// the payments settled on the invoice (see payInvoice).
 func (s *invoiceService) UpdateInvoice(invoice *models.Invoice) error {
+	existing, err := s.repo.FindByID(invoice.ID)
+	if err != nil {
+		return err
+	}
+	invoice.PaymentStatus = existing.PaymentStatus
+	invoice.AmountPaid = existing.AmountPaid
+	invoice.PaidAt = existing.PaidAt
+	if existing.Status != "draft" {
+		if !sameContent(existing, invoice) {
+			return ErrInvoiceImmutable
+		}
+		return nil
+	}
+	if err := s.prepare(invoice); err != nil {
+		return err
+	}
+	// Numbering only changes through FinaliseInvoice
+	invoice.Status = existing.Status
+	invoice.InvoiceNumber = existing.InvoiceNumber
+	invoice.FinalisedAt = existing.FinalisedAt
+	invoice.Date = existing.Date
+	// and late fees only through the reminder job
+	invoice.LateFee = existing.LateFee
+	invoice.LateFeeAppliedAt = existing.LateFeeAppliedAt
+
+	decision := TaxDecision{
+		Treatment: existing.TaxTreatment,
+		Rate:      existing.TaxRate,
+		Country:   existing.TaxCountry,
+		Note:      existing.TaxNote,
+	}
+	if existing.ClientID != invoice.ClientID || !sameUserID(existing.IssuerID, invoice.IssuerID) {
+		if decision, err = s.taxes().Determine(invoice.IssuerID, invoice.ClientID); err != nil {
+			return err
+		}
+	}
+	if err := applyTax(invoice, decision); err != nil {
+		return err
+	}
+	if err := s.checkLineTasks(invoice); err != nil {
+		return err
+	}
 	return s.repo.Update(invoice)
 }
 
+// checkLineTasks makes sure the lines of a draft only bill tasks the draft
+// already bills, each once. Tasks whose lines are dropped become billable
+// again; new ones are added by generating an invoice from them.
+func (s *invoiceService) checkLineTasks(invoice *models.Invoice) error {
+	var billed []uint
+	if err := s.repo.GetDB().Model(&models.Task{}).Where("invoice_id = ?", invoice.ID).
+		Pluck("task_id", &billed).Error; err != nil {
+		return err
+	}
+	open := make(map[uint]bool, len(billed))
+	for _, id := range billed {
+		open[id] = true
+	}
+	for _, line := range invoice.Lines {
+		if line.TaskID == nil {
+			continue
+		}
+		if !open[*line.TaskID] {
+			return fmt.Errorf("%w: task %d is not billed on this invoice", ErrInvalidInvoiceLine, *line.TaskID)
+		}
+		delete(open, *line.TaskID) // a task is billed on one line only
+	}
+	return nil
+}
+
+// DeleteInvoice only deletes drafts; finalised invoices are kept for the records
 func (s *invoiceService) DeleteInvoice(id uint) error {
+	invoice, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if invoice.Status != "draft" {
+		return ErrInvoiceImmutable
+	}
 	return s.repo.Delete(id)
 }
+
+func (s *invoiceService) GenerateFromTasks(projectID, freelancerID uint, taskIDs []uint, dueDate time.Time) (*models.Invoice, error) {
+	var invoice *models.Invoice
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		txs := s.withDB(db)
+
+		var project models.Project
+		if err := db.First(&project, projectID).Error; err != nil {
+			return err
+		}
+		if project.FreelancerID == nil || *project.FreelancerID != freelancerID {
+			return ErrNotProjectFreelancer
+		}
+
+		tasks, err := txs.repo.FindBillableTasks(projectID, taskIDs)
+		if err != nil {
+			return err
+		}
+		invoice = &models.Invoice{
+			PaymentStatus: "pending",
+			DueDate:       dueDate,
+			ProjectID:     project.ID,
+			ClientID:      project.ClientID,
+			IssuerID:      project.FreelancerID,
+		}
+		var billed []uint
+		for _, task := range tasks {
+			if !task.Budget.IsPositive() {
+				continue
+			}
+			taskID := task.ID
+			invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+				Description: task.Title,
+				Quantity:    1,
+				UnitPrice:   task.Budget,
+				TaskID:      &taskID,
+			})
+			billed = append(billed, task.ID)
+		}
+		if len(taskIDs) > 0 && len(billed) != len(taskIDs) {
+			return ErrTaskNotBillable
+		}
+		if len(billed) == 0 {
+			return ErrNothingToInvoice
+		}
+
+		if err := txs.CreateInvoice(invoice); err != nil {
+			return err
+		}
+		return txs.repo.MarkTasksInvoiced(invoice.ID, billed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (s *invoiceService) FinaliseInvoice(id uint) (*models.Invoice, error) {
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		txs := s.withDB(db)
+		invoice, err := txs.repo.LockByID(id)
+		if err != nil {
+			return err
+		}
+		if invoice.Status != "draft" {
+			return ErrInvoiceNotDraft
+		}
+		if invoice.IssuerID == nil {
+			return ErrNoIssuer
+		}
+
+		now := time.Now().UTC()
+		seq, err := txs.repo.NextNumber(*invoice.IssuerID, InvoiceSeries, now.Year())
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = FormatInvoiceNumber(s.numbering.Format, now.Year(), seq)
+		invoice.Status = "finalised"
+		invoice.Date = now
+		invoice.FinalisedAt = &now
+		return txs.repo.SaveFinalised(invoice)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(id)
+}
+
+func (s *invoiceService) VoidInvoice(id uint, reason string) (*models.Invoice, error) {
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		txs := s.withDB(db)
+		invoice, err := txs.repo.LockByID(id)
+		if err != nil {
+			return err
+		}
+		// Whatever was paid must be credited instead, so it becomes client credit
+		if invoice.Kind != "invoice" || invoice.Status != "finalised" || invoice.PaymentStatus == "paid" ||
+			invoice.AmountPaid.IsPositive() {
+			return ErrInvoiceNotVoidable
+		}
+		credited, _, err := txs.repo.SumCredited(id)
+		if err != nil {
+			return err
+		}
+		if !credited.IsZero() {
+			return ErrInvoiceNotVoidable
+		}
+
+		now := time.Now().UTC()
+		invoice.Status = "void"
+		invoice.VoidedAt = &now
+		invoice.VoidReason = reason
+		if err := txs.repo.SaveVoided(invoice); err != nil {
+			return err
+		}
+		return txs.repo.ReleaseTasks(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(id)
+}
+
+func (s *invoiceService) CreateCreditNote(invoiceID uint, amount *money.Money, reason string) (*models.Invoice, error) {
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	var note *models.Invoice
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		txs := s.withDB(db)
+		// Locking the original serialises credit notes against it
+		if _, err := txs.repo.LockByID(invoiceID); err != nil {
+			return err
+		}
+		original, err := txs.repo.FindByID(invoiceID)
+		if err != nil {
+			return err
+		}
+		if original.Kind != "invoice" || original.Status != "finalised" {
+			return ErrInvoiceNotCreditable
+		}
+		if original.IssuerID == nil {
+			return ErrNoIssuer
+		}
+
+		credited, _, err := txs.repo.SumCredited(invoiceID)
+		if err != nil {
+			return err
+		}
+		remaining, err := original.NetAmount.Sub(credited)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		note = &models.Invoice{
+			Kind:              "credit_note",
+			Status:            "finalised",
+			Date:              now,
+			FinalisedAt:       &now,
+			DueDate:           now,
+			ProjectID:         original.ProjectID,
+			ClientID:          original.ClientID,
+			IssuerID:          original.IssuerID,
+			CreditedInvoiceID: &original.ID,
+			CreditReason:      reason,
+		}
+		switch {
+		case amount == nil && credited.IsZero() && len(original.Lines) > 0:
+			// A full credit mirrors the original line by line
+			for _, l := range original.Lines {
+				note.Lines = append(note.Lines, models.InvoiceLine{Description: l.Description, Quantity: l.Quantity, UnitPrice: l.UnitPrice})
+			}
+		default:
+			net := remaining
+			if amount != nil {
+				net = *amount
+				if cmp, err := net.Cmp(remaining); err != nil || !net.IsPositive() || cmp > 0 {
+					return fmt.Errorf("%w: the amount must be positive, in %s and at most the %s not yet credited",
+						ErrInvalidCreditNote, remaining.CurrencyOrDefault(), remaining)
+				}
+			}
+			if !net.IsPositive() {
+				return fmt.Errorf("%w: the invoice is already fully credited", ErrInvalidCreditNote)
+			}
+			note.Lines = []models.InvoiceLine{{
+				Description: "Credit for invoice " + original.InvoiceNumber,
+				Quantity:    1,
+				UnitPrice:   net,
+			}}
+		}
+
+		// The credit note is taxed exactly like the invoice it offsets
+		decision := TaxDecision{
+			Treatment: original.TaxTreatment,
+			Rate:      original.TaxRate,
+			Country:   original.TaxCountry,
+			Note:      original.TaxNote,
+		}
+		if err := applyTax(note, decision); err != nil {
+			return err
+		}
+		seq, err := txs.repo.NextNumber(*original.IssuerID, CreditNoteSeries, now.Year())
+		if err != nil {
+			return err
+		}
+		note.InvoiceNumber = FormatInvoiceNumber(s.numbering.CreditNoteFormat, now.Year(), seq)
+		overpaidBefore, err := overpaid(db, original)
+		if err != nil {
+			return err
+		}
+		if err := txs.repo.Create(note); err != nil {
+			return err
+		}
+		// Crediting more than is left unpaid gives the client the paid part back as credit
+		reason := fmt.Sprintf("credit note %s on invoice %s", note.InvoiceNumber, original.InvoiceNumber)
+		if err := creditOverpaidInvoice(db, original, overpaidBefore, reason); err != nil {
+			return err
+		}
+
+		// Once fully credited the work can be billed again and nothing is owed
+		left, err := remaining.Sub(note.NetAmount)
+		if err != nil || left.IsPositive() {
+			return err
+		}
+		if err := txs.repo.ReleaseTasks(original.ID); err != nil {
+			return err
+		}
+		if original.PaymentStatus != "paid" {
+			return txs.repo.UpdatePaymentStatus(original.ID, "credited")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+func (s *invoiceService) GetCreditNotes(invoiceID uint) ([]models.Invoice, error) {
+	return s.repo.FindCreditNotes(invoiceID)
+}
+
+func (s *invoiceService) GetInvoiceDocument(id uint) (*documents.InvoiceData, error) {
+	invoice, err := s.repo.FindByIDWithParties(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.document(*invoice, map[uint]*models.TaxProfile{})
+}
+
+func (s *invoiceService) GetIssuedDocuments(from, to time.Time) ([]documents.InvoiceData, error) {
+	invoices, err := s.repo.FindIssuedBetweenWithParties(from, to)
+	if err != nil {
+		return nil, err
+	}
+	profiles := map[uint]*models.TaxProfile{}
+	docs := make([]documents.InvoiceData, 0, len(invoices))
+	for _, invoice := range invoices {
+		data, err := s.document(invoice, profiles)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, *data)
+	}
+	return docs, nil
+}
+
+// document adds the parties' tax profiles to an invoice; profiles caches
+// them by user across calls
+func (s *invoiceService) document(invoice models.Invoice, profiles map[uint]*models.TaxProfile) (*documents.InvoiceData, error) {
+	taxes := s.taxes()
+	profile := func(userID uint) (*models.TaxProfile, error) {
+		if p, ok := profiles[userID]; ok {
+			return p, nil
+		}
+		p, err := optionalProfile(taxes.GetProfile(userID))
+		if err != nil {
+			return nil, err
+		}
+		profiles[userID] = p
+		return p, nil
+	}
+
+	data := &documents.InvoiceData{Invoice: invoice}
+	var err error
+	if invoice.IssuerID != nil {
+		if data.IssuerTax, err = profile(*invoice.IssuerID); err != nil {
+			return nil, err
+		}
+	}
+	if data.ClientTax, err = profile(invoice.ClientID); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// optionalProfile treats a missing tax profile as nil
+func optionalProfile(p *models.TaxProfile, err error) (*models.TaxProfile, error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// withDB returns a copy of the service working on the given connection,
+// e.g. inside a transaction
+func (s *invoiceService) withDB(db *gorm.DB) *invoiceService {
+	return &invoiceService{repo: repositories.NewInvoiceRepository(db), numbering: s.numbering}
+}
+
+// prepare validates the amounts and the parties: the project must exist, the
+// issuer (by default the project's freelancer) must be its hired freelancer
+// and the client is always the project's. Callers that only set AmountDue are treated as sending the net
+// amount, as before tax was calculated.
+func (s *invoiceService) prepare(invoice *models.Invoice) error {
+	if len(invoice.Lines) > 0 {
+		for i, line := range invoice.Lines {
+			if line.Description == "" || line.Quantity <= 0 || !line.UnitPrice.IsPositive() {
+				return fmt.Errorf("%w: line %d needs a description, a positive quantity and a unit price", ErrInvalidInvoiceLine, i+1)
+			}
+			if err := checkCurrency(line.UnitPrice); err != nil {
+				return err
+			}
+		}
+	} else {
+		if invoice.NetAmount.IsZero() {
+			invoice.NetAmount = invoice.AmountDue
+		}
+		if err := checkCurrency(invoice.NetAmount); err != nil {
+			return err
+		}
+	}
+	// The project decides who bills whom: its hired freelancer issues the
+	// invoice and its client pays it
+	var project models.Project
+	err := s.repo.GetDB().Select("project_id", "client_id", "freelancer_id").First(&project, invoice.ProjectID).Error
+	if err != nil {
+		return err
+	}
+	if invoice.IssuerID == nil {
+		invoice.IssuerID = project.FreelancerID
+	}
+	if invoice.IssuerID == nil || !sameUserID(invoice.IssuerID, project.FreelancerID) {
+		return ErrNotProjectFreelancer
+	}
+	invoice.ClientID = project.ClientID
+	return nil
+}
+
+// taxes returns a TaxService on the same connection as the repository
+func (s *invoiceService) taxes() TaxService {
+	db := s.repo.GetDB()
+	return NewTaxService(repositories.NewTaxRepository(db), s.repo, NewExchangeRateService(repositories.NewExchangeRateRepository(db)))
+}
+
+// sameContent reports whether an update leaves everything printed on a
+// finalised invoice as it is
+func sameContent(existing, updated *models.Invoice) bool {
+	if existing.NetAmount != updated.NetAmount || !existing.DueDate.Equal(updated.DueDate) ||
+		existing.ProjectID != updated.ProjectID || existing.ClientID != updated.ClientID ||
+		!sameUserID(existing.IssuerID, updated.IssuerID) || len(existing.Lines) != len(updated.Lines) {
+		return false
+	}
+	for i, l := range existing.Lines {
+		u := updated.Lines[i]
+		if l.Description != u.Description || l.Quantity != u.Quantity || l.UnitPrice != u.UnitPrice {
+			return false
+		}
+	}
+	return true
+}
+
+func sameUserID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
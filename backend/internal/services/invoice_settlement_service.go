@@ -0,0 +1,495 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAllocation = errors.New("a transaction can only pay finalised, unpaid invoices of its client, freelancer and project, in its currency")
+	ErrNoClientCredit    = errors.New("no client credit is available for this invoice")
+)
+
+// InvoiceBalance breaks down what is left to pay on an invoice
+type InvoiceBalance struct {
+	InvoiceID     uint        `json:"invoice_id"`
+	PaymentStatus string      `json:"payment_status"`
+	AmountDue     money.Money `json:"amount_due"`
+	LateFee       money.Money `json:"late_fee"`
+	Credited      money.Money `json:"credited"` // gross amount of the credit notes
+	AmountPaid    money.Money `json:"amount_paid"`
+	Balance       money.Money `json:"balance"`
+
+	Allocations []models.InvoiceAllocation `json:"allocations"`
+	Payments    []models.InvoicePayment    `json:"payments"` // through public links
+}
+
+// CreditBalance is the credit a client holds with one freelancer
+type CreditBalance struct {
+	FreelancerID uint        `json:"freelancer_id"`
+	Balance      money.Money `json:"balance"`
+}
+
+// InvoiceSettlementService tracks how invoices are paid. Transactions naming
+// invoices are allocated to them when they complete (see
+// transactionService.transition); this service reports the result and spends
+// client credit.
+type InvoiceSettlementService interface {
+	GetInvoiceBalance(invoiceID uint) (*InvoiceBalance, error)
+	// GetCredits returns the client's credit balances per freelancer and
+	// currency together with the entries they add up from.
+	GetCredits(clientID uint) ([]CreditBalance, []models.ClientCredit, error)
+	// ApplyCredit pays as much of the invoice as the client's credit with the
+	// issuer covers.
+	ApplyCredit(invoiceID uint) (*InvoiceBalance, error)
+}
+
+type invoiceSettlementService struct {
+	repo repositories.InvoiceAllocationRepository
+}
+
+func NewInvoiceSettlementService(repo repositories.InvoiceAllocationRepository) InvoiceSettlementService {
+	return &invoiceSettlementService{repo: repo}
+}
+
+func (s *invoiceSettlementService) GetInvoiceBalance(invoiceID uint) (*InvoiceBalance, error) {
+	db := s.repo.GetDB()
+	invoice, err := repositories.NewInvoiceRepository(db).FindByID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	currency := invoice.AmountDue.CurrencyOrDefault()
+	_, credited, err := repositories.NewInvoiceRepository(db).SumCredited(invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := invoiceBalance(db, invoice)
+	if err != nil {
+		return nil, err
+	}
+	allocations, err := s.repo.FindByInvoice(invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+	linkPayments, err := repositories.NewInvoiceLinkRepository(db).FindPayments(invoice.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !payable(invoice) || balance.IsNegative() {
+		balance = money.Zero(currency)
+	}
+	return &InvoiceBalance{
+		InvoiceID:     invoice.ID,
+		PaymentStatus: invoice.PaymentStatus,
+		AmountDue:     invoice.AmountDue,
+		LateFee:       balanceIn(invoice.LateFee, currency),
+		Credited:      credited,
+		AmountPaid:    balanceIn(invoice.AmountPaid, currency),
+		Balance:       balance,
+		Allocations:   allocations,
+		Payments:      linkPayments,
+	}, nil
+}
+
+func (s *invoiceSettlementService) GetCredits(clientID uint) ([]CreditBalance, []models.ClientCredit, error) {
+	credits, err := s.repo.FindCredits(clientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	type key struct {
+		freelancerID uint
+		currency     string
+	}
+	totals := map[key]int64{}
+	var keys []key
+	for _, c := range credits {
+		k := key{c.FreelancerID, c.Amount.CurrencyOrDefault()}
+		if _, ok := totals[k]; !ok {
+			keys = append(keys, k)
+		}
+		totals[k] += c.Amount.Amount
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].freelancerID != keys[j].freelancerID {
+			return keys[i].freelancerID < keys[j].freelancerID
+		}
+		return keys[i].currency < keys[j].currency
+	})
+	balances := make([]CreditBalance, 0, len(keys))
+	for _, k := range keys {
+		balances = append(balances, CreditBalance{FreelancerID: k.freelancerID, Balance: money.New(totals[k], k.currency)})
+	}
+	return balances, credits, nil
+}
+
+func (s *invoiceSettlementService) ApplyCredit(invoiceID uint) (*InvoiceBalance, error) {
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		invoice, err := repositories.NewInvoiceRepository(db).LockByID(invoiceID)
+		if err != nil {
+			return err
+		}
+		if !payable(invoice) || invoice.IssuerID == nil {
+			return ErrInvoiceNotPayable
+		}
+		balance, err := invoiceBalance(db, invoice)
+		if err != nil {
+			return err
+		}
+		if !balance.IsPositive() {
+			return ErrInvoiceNotPayable
+		}
+
+		repo := repositories.NewInvoiceAllocationRepository(db)
+		available, err := repo.CreditBalance(invoice.ClientID, *invoice.IssuerID, balance.Currency)
+		if err != nil {
+			return err
+		}
+		if !available.IsPositive() {
+			return ErrNoClientCredit
+		}
+		amount := available
+		if available.Amount > balance.Amount {
+			amount = balance
+		}
+
+		if _, err := payInvoice(db, invoice, amount, time.Now()); err != nil {
+			return err
+		}
+		return repo.CreateCredit(&models.ClientCredit{
+			Amount:       money.New(-amount.Amount, amount.Currency),
+			Reason:       "applied to invoice " + invoice.InvoiceNumber,
+			ClientID:     invoice.ClientID,
+			FreelancerID: *invoice.IssuerID,
+			InvoiceID:    &invoice.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetInvoiceBalance(invoiceID)
+}
+
+// payable reports whether the invoice can still be paid
+func payable(inv *models.Invoice) bool {
+	return inv.Kind == "invoice" && inv.Status == "finalised" &&
+		(inv.PaymentStatus == "pending" || inv.PaymentStatus == "overdue")
+}
+
+// invoiceBalance is what is left to pay on an invoice: the amount due plus
+// the late fee, less the credit notes issued against it and what was paid
+func invoiceBalance(db *gorm.DB, inv *models.Invoice) (money.Money, error) {
+	currency := inv.AmountDue.CurrencyOrDefault()
+	_, credited, err := repositories.NewInvoiceRepository(db).SumCredited(inv.ID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	total, err := inv.AmountDue.Add(balanceIn(inv.LateFee, currency))
+	if err != nil {
+		return money.Money{}, err
+	}
+	if total, err = total.Sub(credited); err != nil {
+		return money.Money{}, err
+	}
+	return total.Sub(balanceIn(inv.AmountPaid, currency))
+}
+
+// payInvoice records a payment on a locked invoice and returns the part of
+// amount that exceeded the balance. Once nothing is left the invoice is paid
+// and its issuer notified. Invoices that can't be paid take nothing.
+func payInvoice(db *gorm.DB, inv *models.Invoice, amount money.Money, at time.Time) (excess money.Money, err error) {
+	if !payable(inv) {
+		return amount, nil
+	}
+	balance, err := invoiceBalance(db, inv)
+	if err != nil {
+		return money.Money{}, err
+	}
+	applied := amount
+	if cmp, err := amount.Cmp(balance); err != nil {
+		return money.Money{}, err
+	} else if cmp > 0 {
+		applied = balance
+	}
+	if !applied.IsPositive() {
+		return amount, nil
+	}
+	if excess, err = amount.Sub(applied); err != nil {
+		return money.Money{}, err
+	}
+
+	if inv.AmountPaid, err = balanceIn(inv.AmountPaid, applied.Currency).Add(applied); err != nil {
+		return money.Money{}, err
+	}
+	paid := applied.Amount == balance.Amount
+	if paid {
+		inv.PaymentStatus = "paid"
+		inv.PaidAt = &at
+	}
+	if err := repositories.NewInvoiceRepository(db).SavePaid(inv); err != nil {
+		return money.Money{}, err
+	}
+
+	if paid && inv.IssuerID != nil {
+		notifications := NewNotificationService(repositories.NewNotificationRepository(db))
+		err = notifications.CreateNotification(&models.Notification{
+			Message: fmt.Sprintf("Invoice %s has been paid in full (%s).", inv.InvoiceNumber, formatMoney(inv.AmountPaid)),
+			Date:    at,
+			Type:    "payment_received",
+			UserID:  *inv.IssuerID,
+		})
+	}
+	return excess, err
+}
+
+// creditOverpayment books money paid beyond an invoice's balance as credit
+// of the client with the freelancer
+func creditOverpayment(db *gorm.DB, credit *models.ClientCredit) error {
+	if !credit.Amount.IsPositive() {
+		return nil
+	}
+	return repositories.NewInvoiceAllocationRepository(db).CreateCredit(credit)
+}
+
+// checkAllocations validates the invoices a new transaction is meant to pay
+func checkAllocations(db *gorm.DB, tx *models.Transaction) error {
+	seen := map[uint]bool{}
+	for _, a := range tx.Allocations {
+		if seen[a.InvoiceID] {
+			return fmt.Errorf("%w: invoice %d is listed twice", ErrInvalidAllocation, a.InvoiceID)
+		}
+		seen[a.InvoiceID] = true
+
+		var inv models.Invoice
+		err := db.First(&inv, a.InvoiceID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: invoice %d not found", ErrInvalidAllocation, a.InvoiceID)
+		}
+		if err != nil {
+			return err
+		}
+		sameParties := inv.ClientID == tx.ClientID && inv.IssuerID != nil && *inv.IssuerID == tx.FreelancerID &&
+			inv.ProjectID == tx.ProjectID
+		if !payable(&inv) || !sameParties || inv.AmountDue.CurrencyOrDefault() != tx.Amount.CurrencyOrDefault() {
+			return fmt.Errorf("%w: invoice %d", ErrInvalidAllocation, a.InvoiceID)
+		}
+	}
+	return nil
+}
+
+// allocateTransaction spreads a completed transaction over the invoices it
+// names, the invoice due first getting paid first. What no invoice needs any
+// more becomes client credit. It runs in the DB transaction that completes the
+// transaction, once.
+func allocateTransaction(db *gorm.DB, tx *models.Transaction, at time.Time) error {
+	repo := repositories.NewInvoiceAllocationRepository(db)
+	allocations, err := repo.FindByTransaction(tx.ID)
+	if err != nil || len(allocations) == 0 {
+		return err
+	}
+
+	invoiceRepo := repositories.NewInvoiceRepository(db)
+	invoices := make(map[uint]*models.Invoice, len(allocations))
+	for _, a := range allocations {
+		if invoices[a.InvoiceID], err = invoiceRepo.LockByID(a.InvoiceID); err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(allocations, func(i, j int) bool {
+		a, b := invoices[allocations[i].InvoiceID], invoices[allocations[j].InvoiceID]
+		if !a.DueDate.Equal(b.DueDate) {
+			return a.DueDate.Before(b.DueDate)
+		}
+		return a.ID < b.ID
+	})
+
+	remaining := tx.Amount
+	for i := range allocations {
+		excess, err := payInvoice(db, invoices[allocations[i].InvoiceID], remaining, at)
+		if err != nil {
+			return err
+		}
+		if allocations[i].Amount, err = remaining.Sub(excess); err != nil {
+			return err
+		}
+		allocations[i].AllocatedAt = &at
+		if err := repo.SaveAllocated(&allocations[i]); err != nil {
+			return err
+		}
+		remaining = excess
+	}
+
+	return creditOverpayment(db, &models.ClientCredit{
+		Amount:        remaining,
+		Reason:        fmt.Sprintf("overpayment of transaction %d", tx.ID),
+		ClientID:      tx.ClientID,
+		FreelancerID:  tx.FreelancerID,
+		TransactionID: &tx.ID,
+	})
+}
+
+// unallocateTransaction takes amount of a refunded or charged back
+// transaction back out of what it paid: first from the credit its overpayment
+// left, then from the invoices it paid, the one due last first. Credit the
+// client already spent leaves their balance negative. It runs in the DB
+// transaction that books the reversal, on the locked transaction row.
+func unallocateTransaction(db *gorm.DB, tx *models.Transaction, amount money.Money, at time.Time) error {
+	repo := repositories.NewInvoiceAllocationRepository(db)
+	allocations, err := repo.FindByTransaction(tx.ID)
+	if err != nil || len(allocations) == 0 {
+		return err
+	}
+
+	remaining := amount
+	credit, err := repo.TransactionCredit(tx.ID, amount.CurrencyOrDefault())
+	if err != nil {
+		return err
+	}
+	if credit.IsPositive() {
+		taken := credit
+		if credit.Amount > remaining.Amount {
+			taken = remaining
+		}
+		err := repo.CreateCredit(&models.ClientCredit{
+			Amount:        taken.Neg(),
+			Reason:        fmt.Sprintf("reversal of transaction %d", tx.ID),
+			ClientID:      tx.ClientID,
+			FreelancerID:  tx.FreelancerID,
+			TransactionID: &tx.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if remaining, err = remaining.Sub(taken); err != nil {
+			return err
+		}
+	}
+
+	invoiceRepo := repositories.NewInvoiceRepository(db)
+	invoices := make(map[uint]*models.Invoice, len(allocations))
+	for _, a := range allocations {
+		if invoices[a.InvoiceID], err = invoiceRepo.LockByID(a.InvoiceID); err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(allocations, func(i, j int) bool {
+		a, b := invoices[allocations[i].InvoiceID], invoices[allocations[j].InvoiceID]
+		if !a.DueDate.Equal(b.DueDate) {
+			return a.DueDate.After(b.DueDate)
+		}
+		return a.ID > b.ID
+	})
+
+	for i := range allocations {
+		if !remaining.IsPositive() {
+			break
+		}
+		if !allocations[i].Amount.IsPositive() {
+			continue
+		}
+		taken := allocations[i].Amount
+		if taken.Amount > remaining.Amount {
+			taken = remaining
+		}
+		if err := unpayInvoice(db, invoices[allocations[i].InvoiceID], taken, at); err != nil {
+			return err
+		}
+		if allocations[i].Amount, err = allocations[i].Amount.Sub(taken); err != nil {
+			return err
+		}
+		if err := repo.SaveAllocated(&allocations[i]); err != nil {
+			return err
+		}
+		if remaining, err = remaining.Sub(taken); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpayInvoice takes a reversed payment back off a locked invoice. An invoice
+// that was paid in full is open again, and its issuer notified, unless its
+// credit notes cover what is left. Credit its credit notes gave back for the
+// payment is taken back too.
+func unpayInvoice(db *gorm.DB, inv *models.Invoice, amount money.Money, at time.Time) error {
+	before, err := overpaid(db, inv)
+	if err != nil {
+		return err
+	}
+	paid, err := balanceIn(inv.AmountPaid, amount.Currency).Sub(amount)
+	if err != nil {
+		return err
+	}
+	inv.AmountPaid = paid
+	if err := creditOverpaidInvoice(db, inv, before, "reversed payment on credited invoice "+inv.InvoiceNumber); err != nil {
+		return err
+	}
+	balance, err := invoiceBalance(db, inv)
+	if err != nil {
+		return err
+	}
+	reopened := inv.PaymentStatus == "paid" && balance.IsPositive()
+	switch {
+	case reopened:
+		inv.PaymentStatus, inv.PaidAt = "pending", nil
+		if inv.DueDate.Before(at) {
+			inv.PaymentStatus = "overdue"
+		}
+	case inv.PaymentStatus == "paid" && !paid.IsPositive():
+		// Nothing is paid any more; the credit notes settle it
+		inv.PaymentStatus, inv.PaidAt = "credited", nil
+	}
+	if err := repositories.NewInvoiceRepository(db).SavePaid(inv); err != nil {
+		return err
+	}
+
+	if reopened && inv.IssuerID != nil {
+		notifications := NewNotificationService(repositories.NewNotificationRepository(db))
+		return notifications.CreateNotification(&models.Notification{
+			Message: fmt.Sprintf("A payment on invoice %s was reversed; %s is open again.", inv.InvoiceNumber, formatMoney(balance)),
+			Date:    at,
+			Type:    "payment_reversed",
+			UserID:  *inv.IssuerID,
+		})
+	}
+	return nil
+}
+
+// overpaid is how much more was paid on an invoice than its credit notes left
+// to pay
+func overpaid(db *gorm.DB, inv *models.Invoice) (money.Money, error) {
+	balance, err := invoiceBalance(db, inv)
+	if err != nil || !balance.IsNegative() {
+		return money.Zero(inv.AmountDue.CurrencyOrDefault()), err
+	}
+	return balance.Neg(), nil
+}
+
+// creditOverpaidInvoice books how much the overpayment of a locked invoice
+// changed since before as client credit: a credit note on a paid invoice
+// gives the client credit, reversing the payment takes it back
+func creditOverpaidInvoice(db *gorm.DB, inv *models.Invoice, before money.Money, reason string) error {
+	after, err := overpaid(db, inv)
+	if err != nil {
+		return err
+	}
+	change, err := after.Sub(before)
+	if err != nil || change.IsZero() || inv.IssuerID == nil {
+		return err
+	}
+	return repositories.NewInvoiceAllocationRepository(db).CreateCredit(&models.ClientCredit{
+		Amount:       change,
+		Reason:       reason,
+		ClientID:     inv.ClientID,
+		FreelancerID: *inv.IssuerID,
+		InvoiceID:    &inv.ID,
+	})
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotFreelancer           = errors.New("only freelancers can receive payouts")
+	ErrInvalidPayoutMethod     = errors.New("invalid payout method")
+	ErrBelowMinimumPayout      = errors.New("amount is below the minimum withdrawal")
+	ErrInsufficientBalance     = errors.New("amount exceeds the available balance")
+	ErrAccountOnHold           = errors.New("new accounts can't withdraw yet")
+	ErrWithdrawalNotReviewable = errors.New("only pending withdrawals can be approved or rejected")
+)
+
+// PayoutPolicy holds the platform-wide withdrawal limits.
+type PayoutPolicy struct {
+	// Minimum withdrawal, converted into the freelancer's currency
+	Minimum money.Money
+	// Accounts younger than this can't request withdrawals
+	NewAccountHold time.Duration
+}
+
+// PayoutBalance is a freelancer's earnings split into what is reserved by
+// open withdrawal requests and what can still be withdrawn.
+type PayoutBalance struct {
+	Earnings  money.Money `json:"earnings"`
+	Reserved  money.Money `json:"reserved"`
+	Available money.Money `json:"available"`
+}
+
+// PayoutBatchResult summarises one run of the payout job.
+type PayoutBatchResult struct {
+	Paid   int `json:"paid"`
+	Failed int `json:"failed"`
+}
+
+type PayoutService interface {
+	AddMethod(method *models.PayoutMethod) error
+	GetMethods(userID uint) ([]models.PayoutMethod, error)
+	DeleteMethod(userID, methodID uint) error
+
+	GetBalance(userID uint) (*PayoutBalance, error)
+	RequestWithdrawal(userID, methodID uint, amount money.Money) (*models.WithdrawalRequest, error)
+	GetWithdrawals(userID uint) ([]models.WithdrawalRequest, error)
+	ListWithdrawals(status string) ([]models.WithdrawalRequest, error)
+	ApproveWithdrawal(id, adminID uint) (*models.WithdrawalRequest, error)
+	RejectWithdrawal(id, adminID uint, reason string) (*models.WithdrawalRequest, error)
+
+	// RunBatch pays out all approved withdrawals through the payout provider.
+	RunBatch() (*PayoutBatchResult, error)
+}
+
+type payoutService struct {
+	repo     repositories.PayoutRepository
+	provider payments.PayoutProvider
+	policy   PayoutPolicy
+	now      func() time.Time
+}
+
+func NewPayoutService(repo repositories.PayoutRepository, provider payments.PayoutProvider, policy PayoutPolicy) PayoutService {
+	return &payoutService{repo: repo, provider: provider, policy: policy, now: time.Now}
+}
+
+func (s *payoutService) AddMethod(method *models.PayoutMethod) error {
+	user, err := s.freelancer(s.repo.GetDB(), method.UserID)
+	if err != nil {
+		return err
+	}
+	if method.Type != "bank_transfer" && method.Type != "paypal" {
+		return fmt.Errorf("%w: type must be bank_transfer or paypal", ErrInvalidPayoutMethod)
+	}
+	if method.Destination == "" {
+		return fmt.Errorf("%w: destination is required", ErrInvalidPayoutMethod)
+	}
+
+	// The first method becomes the default
+	existing, err := s.repo.FindMethodsByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		method.IsDefault = true
+	} else if method.IsDefault {
+		if err := s.repo.ClearDefaultMethod(user.ID); err != nil {
+			return err
+		}
+	}
+	return s.repo.CreateMethod(method)
+}
+
+func (s *payoutService) GetMethods(userID uint) ([]models.PayoutMethod, error) {
+	return s.repo.FindMethodsByUser(userID)
+}
+
+// DeleteMethod removes one of the user's payout methods. Open requests keep
+// their own copy of the destination.
+func (s *payoutService) DeleteMethod(userID, methodID uint) error {
+	method, err := s.repo.FindMethodByID(methodID)
+	if err != nil {
+		return err
+	}
+	if method.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	return s.repo.DeleteMethod(methodID)
+}
+
+func (s *payoutService) GetBalance(userID uint) (*PayoutBalance, error) {
+	user, err := s.freelancer(s.repo.GetDB(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.balance(s.repo, user)
+}
+
+func (s *payoutService) balance(repo repositories.PayoutRepository, user *models.User) (*PayoutBalance, error) {
+	earnings := balanceIn(user.Earnings, user.Currency)
+	reserved, err := repo.SumOpenWithdrawals(user.ID, earnings.Currency)
+	if err != nil {
+		return nil, err
+	}
+	b := &PayoutBalance{Earnings: earnings, Reserved: money.New(reserved, earnings.Currency)}
+	if b.Available, err = earnings.Sub(b.Reserved); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// RequestWithdrawal reserves amount of the freelancer's available earnings
+// for payout to one of their methods. The user row is locked so concurrent
+// requests can't reserve the same money twice.
+func (s *payoutService) RequestWithdrawal(userID, methodID uint, amount money.Money) (*models.WithdrawalRequest, error) {
+	var request *models.WithdrawalRequest
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		repo := repositories.NewPayoutRepository(db)
+		user, err := s.freelancer(db.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+		if s.now().Sub(user.CreatedAt) < s.policy.NewAccountHold {
+			return ErrAccountOnHold
+		}
+
+		method, err := repo.FindMethodByID(methodID)
+		if err != nil || method.UserID != userID {
+			return fmt.Errorf("%w: unknown payout method", ErrInvalidPayoutMethod)
+		}
+
+		if amount.Currency == "" {
+			amount.Currency = user.Currency
+		}
+		if !amount.IsPositive() {
+			return money.ErrInvalidAmount
+		}
+		if amount.Currency != user.Currency {
+			return money.ErrCurrencyMismatch
+		}
+
+		if !s.policy.Minimum.IsZero() {
+			min, err := NewExchangeRateService(repositories.NewExchangeRateRepository(db)).
+				Convert(s.policy.Minimum, user.Currency, s.now())
+			if err != nil {
+				return err
+			}
+			if amount.Amount < min.Amount.Amount {
+				return fmt.Errorf("%w of %s %s", ErrBelowMinimumPayout, min.Amount, min.Amount.Currency)
+			}
+		}
+
+		bal, err := s.balance(repo, user)
+		if err != nil {
+			return err
+		}
+		if amount.Amount > bal.Available.Amount {
+			return ErrInsufficientBalance
+		}
+
+		request = &models.WithdrawalRequest{
+			UserID:         userID,
+			PayoutMethodID: &method.ID,
+			Method:         method.Type,
+			Destination:    method.Destination,
+			Amount:         amount,
+			Status:         "pending",
+		}
+		return repo.CreateWithdrawal(request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *payoutService) GetWithdrawals(userID uint) ([]models.WithdrawalRequest, error) {
+	return s.repo.FindWithdrawalsByUser(userID)
+}
+
+func (s *payoutService) ListWithdrawals(status string) ([]models.WithdrawalRequest, error) {
+	return s.repo.FindWithdrawalsByStatus(status)
+}
+
+func (s *payoutService) ApproveWithdrawal(id, adminID uint) (*models.WithdrawalRequest, error) {
+	return s.review(id, adminID, "approved", "")
+}
+
+// RejectWithdrawal releases the reserved amount back to the available balance
+func (s *payoutService) RejectWithdrawal(id, adminID uint, reason string) (*models.WithdrawalRequest, error) {
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	return s.review(id, adminID, "rejected", reason)
+}
+
+// review locks the request, so two admins can't both decide it, and the
+// freelancer, whose earnings must still cover everything reserved when the
+// request is approved (a chargeback may have lowered them since)
+func (s *payoutService) review(id, adminID uint, status, reason string) (*models.WithdrawalRequest, error) {
+	var w *models.WithdrawalRequest
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		repo := repositories.NewPayoutRepository(db)
+		var err error
+		if w, err = repo.LockWithdrawalByID(id); err != nil {
+			return err
+		}
+		if w.Status != "pending" {
+			return ErrWithdrawalNotReviewable
+		}
+		if status == "approved" {
+			user, err := s.freelancer(db.Clauses(clause.Locking{Strength: "UPDATE"}), w.UserID)
+			if err != nil {
+				return err
+			}
+			bal, err := s.balance(repo, user)
+			if err != nil {
+				return err
+			}
+			if bal.Available.IsNegative() {
+				return ErrInsufficientBalance
+			}
+		}
+
+		now := s.now()
+		w.Status = status
+		w.RejectionReason = reason
+		w.ReviewedAt = &now
+		w.ReviewedBy = &adminID
+		return repo.UpdateWithdrawal(w)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// RunBatch sends every approved withdrawal to the payout provider. The
+// withdrawal ID is the provider reference, so a batch interrupted between the
+// provider call and the database update is safe to run again. Earnings are
+// only debited for payouts the provider reports as paid.
+func (s *payoutService) RunBatch() (*PayoutBatchResult, error) {
+	approved, err := s.repo.FindWithdrawalsByStatus("approved")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PayoutBatchResult{}
+	for _, w := range approved {
+		covered, err := s.claim(w.ID)
+		if err != nil {
+			return result, err
+		}
+		if !covered {
+			result.Failed++
+			continue
+		}
+
+		payout, err := s.provider.Send(payments.PayoutRequest{
+			Amount:      w.Amount,
+			Method:      w.Method,
+			Destination: w.Destination,
+			Reference:   fmt.Sprintf("withdrawal-%d", w.ID),
+		})
+		if err != nil {
+			return result, err
+		}
+
+		paid, err := s.settle(w.ID, payout)
+		if err != nil {
+			return result, err
+		}
+		if paid {
+			result.Paid++
+		} else {
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// claim re-checks an approved withdrawal on the locked rows right before it
+// is sent: the freelancer's earnings must still cover it. One that isn't
+// covered any more fails instead of being paid. It reports whether the
+// withdrawal can be sent.
+func (s *payoutService) claim(id uint) (bool, error) {
+	covered := false
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		repo := repositories.NewPayoutRepository(db)
+		w, err := repo.LockWithdrawalByID(id)
+		if err != nil {
+			return err
+		}
+		if w.Status != "approved" {
+			return nil // settled by a concurrent run
+		}
+		var user models.User
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, w.UserID).Error; err != nil {
+			return err
+		}
+		if balanceIn(user.Earnings, user.Currency).Amount >= w.Amount.Amount {
+			covered = true
+			return nil
+		}
+		w.Status = "failed"
+		w.FailureReason = ErrInsufficientBalance.Error()
+		return repo.UpdateWithdrawal(w)
+	})
+	return covered, err
+}
+
+// settle records the provider's answer for one withdrawal and debits the
+// earnings if it was paid. It reports whether the payout was paid. Money the
+// provider paid is always debited; if the earnings no longer covered it the
+// freelancer is flagged for review.
+func (s *payoutService) settle(id uint, payout *payments.Payout) (bool, error) {
+	paid := payout.Status == payments.PayoutPaid
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		locked := db.Clauses(clause.Locking{Strength: "UPDATE"})
+
+		var w models.WithdrawalRequest
+		if err := locked.First(&w, id).Error; err != nil {
+			return err
+		}
+		if w.Status != "approved" {
+			return nil // settled by a concurrent run
+		}
+
+		w.Provider = s.provider.Name()
+		w.ProviderReference = payout.ID
+		if !paid {
+			w.Status = "failed"
+			w.FailureReason = payout.FailureReason
+			return db.Save(&w).Error
+		}
+
+		var user models.User
+		if err := locked.First(&user, w.UserID).Error; err != nil {
+			return err
+		}
+		earnings, err := balanceIn(user.Earnings, user.Currency).Sub(w.Amount)
+		if err != nil {
+			return err
+		}
+		user.Earnings = earnings
+		if earnings.IsNegative() {
+			user.Flagged = true
+			user.FlagReason = fmt.Sprintf("withdrawal %d paid out more than the earnings", w.ID)
+		}
+		if err := db.Save(&user).Error; err != nil {
+			return err
+		}
+
+		now := s.now()
+		w.Status = "paid"
+		w.PaidAt = &now
+		return db.Save(&w).Error
+	})
+	return paid, err
+}
+
+// freelancer loads the user and checks they can receive payouts
+func (s *payoutService) freelancer(db *gorm.DB, userID uint) (*models.User, error) {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.Role != "freelancer" {
+		return nil, ErrNotFreelancer
+	}
+	return &user, nil
+}
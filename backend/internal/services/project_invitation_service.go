@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotProjectOwner  = errors.New("only the project's client can invite freelancers")
+	ErrInvalidInvitee   = errors.New("only freelancers can be invited")
+	ErrAlreadyInvited   = errors.New("the freelancer was already invited to this project")
+	ErrNotInvitee       = errors.New("the invitation is addressed to another freelancer")
+	ErrInvitationClosed = errors.New("the invitation was already answered")
+)
+
+type ProjectInvitationService interface {
+	Invite(projectID, clientID, freelancerID uint, message string) (*models.ProjectInvitation, error)
+	GetProjectInvitations(projectID uint) ([]models.ProjectInvitation, error)
+	GetFreelancerInvitations(freelancerID uint) ([]models.ProjectInvitation, error)
+
+	// AcceptInvitation opens a draft proposal with the project's budget and
+	// duration for the freelancer to fill in and submit.
+	AcceptInvitation(id, userID uint) (*models.ProjectInvitation, *models.Proposal, error)
+	DeclineInvitation(id, userID uint, reason string) (*models.ProjectInvitation, error)
+}
+
+type projectInvitationService struct {
+	repo repositories.ProjectInvitationRepository
+}
+
+func NewProjectInvitationService(repo repositories.ProjectInvitationRepository) ProjectInvitationService {
+	return &projectInvitationService{repo: repo}
+}
+
+// Invite asks a freelancer to bid on an open project of the client
+func (s *projectInvitationService) Invite(projectID, clientID, freelancerID uint, message string) (*models.ProjectInvitation, error) {
+	var invitation *models.ProjectInvitation
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		project, err := repositories.NewProjectRepository(db).LockByID(projectID)
+		if err != nil {
+			return err
+		}
+		if project.ClientID != clientID {
+			return ErrNotProjectOwner
+		}
+		if project.Status != "open" {
+			return ErrProjectNotOpen
+		}
+		freelancer, err := repositories.NewUserRepository(db).FindByID(freelancerID)
+		if err != nil {
+			return err
+		}
+		if freelancer.Role != "freelancer" {
+			return ErrInvalidInvitee
+		}
+		var invited int64
+		if err := db.Model(&models.ProjectInvitation{}).
+			Where("project_id = ? AND freelancer_id = ?", projectID, freelancerID).
+			Count(&invited).Error; err != nil {
+			return err
+		}
+		if invited > 0 {
+			return ErrAlreadyInvited
+		}
+
+		invitation = &models.ProjectInvitation{
+			Message:      message,
+			Status:       "pending",
+			ProjectID:    projectID,
+			FreelancerID: freelancerID,
+		}
+		if err := repositories.NewProjectInvitationRepository(db).Create(invitation); err != nil {
+			return err
+		}
+		return notifyInvitation(db, freelancerID, fmt.Sprintf("You were invited to bid on project %s.", project.Title))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// GetProjectInvitations lists the invitations to a project, oldest first
+func (s *projectInvitationService) GetProjectInvitations(projectID uint) ([]models.ProjectInvitation, error) {
+	return s.repo.FindByProject(projectID)
+}
+
+func (s *projectInvitationService) GetFreelancerInvitations(freelancerID uint) ([]models.ProjectInvitation, error) {
+	return s.repo.FindByFreelancer(freelancerID)
+}
+
+func (s *projectInvitationService) AcceptInvitation(id, userID uint) (*models.ProjectInvitation, *models.Proposal, error) {
+	pending, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var invitation *models.ProjectInvitation
+	var draft *models.Proposal
+	err = s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		// Lock the project first, like bidding on it does
+		project, err := repositories.NewProjectRepository(db).LockByID(pending.ProjectID)
+		if err != nil {
+			return err
+		}
+		repo := repositories.NewProjectInvitationRepository(db)
+		if invitation, err = lockPendingInvitation(repo, id, userID); err != nil {
+			return err
+		}
+		if project.Status != "open" {
+			return ErrProjectNotOpen
+		}
+		bid, err := hasBid(db, project.ID, userID)
+		if err != nil {
+			return err
+		}
+		if bid {
+			return ErrDuplicateProposal
+		}
+
+		draft = &models.Proposal{
+			EstimatedDuration: project.Duration,
+			BidAmount:         money.New(project.Budget.Amount, project.Budget.CurrencyOrDefault()),
+			Status:            "draft",
+			OfferedBy:         PartyFreelancer,
+			ProjectID:         project.ID,
+			FreelancerID:      userID,
+		}
+		if err := repositories.NewProposalRepository(db).Create(draft); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		invitation.Status = "accepted"
+		invitation.RespondedAt = &now
+		invitation.ProposalID = &draft.ID
+		if err := repo.Update(invitation); err != nil {
+			return err
+		}
+		return notifyInvitation(db, project.ClientID, fmt.Sprintf("Your invitation to bid on %s was accepted.", project.Title))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return invitation, draft, nil
+}
+
+func (s *projectInvitationService) DeclineInvitation(id, userID uint, reason string) (*models.ProjectInvitation, error) {
+	var invitation *models.ProjectInvitation
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		repo := repositories.NewProjectInvitationRepository(db)
+		var err error
+		if invitation, err = lockPendingInvitation(repo, id, userID); err != nil {
+			return err
+		}
+		now := time.Now()
+		invitation.Status = "declined"
+		invitation.Reason = reason
+		invitation.RespondedAt = &now
+		if err := repo.Update(invitation); err != nil {
+			return err
+		}
+
+		project, err := repositories.NewProjectRepository(db).FindByID(invitation.ProjectID)
+		if err != nil {
+			return err
+		}
+		text := fmt.Sprintf("Your invitation to bid on %s was declined.", project.Title)
+		if reason != "" {
+			text += " " + reason
+		}
+		return notifyInvitation(db, project.ClientID, text)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// lockPendingInvitation locks an invitation the user may still answer
+func lockPendingInvitation(repo repositories.ProjectInvitationRepository, id, userID uint) (*models.ProjectInvitation, error) {
+	invitation, err := repo.LockByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.FreelancerID != userID {
+		return nil, ErrNotInvitee
+	}
+	if invitation.Status != "pending" {
+		return nil, ErrInvitationClosed
+	}
+	return invitation, nil
+}
+
+// notifyInvitation sends a project_invitation notification
+func notifyInvitation(db *gorm.DB, userID uint, text string) error {
+	notifications := NewNotificationService(repositories.NewNotificationRepository(db))
+	return notifications.CreateNotification(&models.Notification{
+		Message: text,
+		Date:    time.Now(),
+		Type:    "project_invitation",
+		UserID:  userID,
+	})
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"FreeConnect/internal/models"
 	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
 )
 
 type ProjectService interface {
@@ -11,6 +13,7 @@ type ProjectService interface {
 	GetAllProjects() ([]models.Project, error)
 	UpdateProject(project *models.Project) error
 	DeleteProject(id uint) error
+	UpdateProjectSkills(projectID uint, skillIDs []uint) error
 	SearchProjects(search, minBudgetStr, maxBudgetStr, status string) ([]models.Project, error)
 }
 
@@ -30,6 +33,9 @@ func (s *projectService) SearchProjects(search, minBudgetStr, maxBudgetStr, stat
 
 func (s *projectService) CreateProject(project *models.Project) error {
 	// Optionally validate project fields (e.g., budget > 0, duration > 0).
+	if err := checkCurrency(project.Budget); err != nil {
+		return err
+	}
 	return s.repo.Create(project)
 }
 
@@ -41,8 +47,46 @@ func (s *projectService) GetAllProjects() ([]models.Project, error) {
 	return s.repo.FindAll()
 }
 
+// UpdateProject saves the project. Closing it (completed or cancelled)
+// expires the proposals and invitations that are still open on it;
+// cancelling it without a hire also refunds the bid credits spent on it.
 func (s *projectService) UpdateProject(project *models.Project) error {
-	return s.repo.Update(project)
+	if err := checkCurrency(project.Budget); err != nil {
+		return err
+	}
+	return s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		if err := repositories.NewProjectRepository(db).Update(project); err != nil {
+			return err
+		}
+		if project.Status != "completed" && project.Status != "cancelled" {
+			return nil
+		}
+		if err := closeOpenProposals(db, project.ID, "expired", "the project was "+project.Status, 0); err != nil {
+			return err
+		}
+		if err := repositories.NewProjectInvitationRepository(db).ExpirePending(project.ID); err != nil {
+			return err
+		}
+		if project.Status == "cancelled" && project.FreelancerID == nil {
+			return refundProjectBids(db, project)
+		}
+		return nil
+	})
+}
+
+// UpdateProjectSkills replaces the skills the project requires
+func (s *projectService) UpdateProjectSkills(projectID uint, skillIDs []uint) error {
+	project, err := s.repo.FindByID(projectID)
+	if err != nil {
+		return err
+	}
+	db := s.repo.GetDB()
+
+	var skills []models.Skill
+	if err := db.Where("skill_id IN ?", skillIDs).Find(&skills).Error; err != nil {
+		return err
+	}
+	return db.Model(project).Association("Skills").Replace(&skills)
 }
 
 func (s *projectService) DeleteProject(id uint) error {
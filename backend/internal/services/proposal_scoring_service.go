@@ -0,0 +1,303 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/repositories"
+)
+
+var (
+	ErrInvalidSortKey    = errors.New("proposals can be sorted by score, price, duration, rating, skills or completion")
+	ErrInvalidComparison = errors.New("compare between 2 and 10 submitted proposals of the project")
+)
+
+// MaxComparedProposals is how many proposals can be compared side by side.
+const MaxComparedProposals = 10
+
+// scoreWeights are the factors of a proposal's score and their share of it,
+// in percent
+var scoreWeights = []struct {
+	name   string
+	weight int
+}{
+	{"price", 30},      // bid against the project budget
+	{"duration", 15},   // estimated duration against the project duration
+	{"rating", 25},     // the freelancer's reviews
+	{"skills", 20},     // the project's required skills the freelancer has
+	{"completion", 10}, // the freelancer's finished projects that were completed
+}
+
+// neutralScore is given to a factor there is nothing to judge by, e.g. the
+// rating of a freelancer without reviews
+const neutralScore = 50
+
+// ScoreFactor is one part of a proposal's score, from 0 to 100.
+type ScoreFactor struct {
+	Name        string  `json:"name"`
+	Weight      int     `json:"weight"` // percent of the total score
+	Score       float64 `json:"score"`
+	Explanation string  `json:"explanation"`
+}
+
+// ProposalScore ranks a proposal: Score is the weighted sum of its factors.
+type ProposalScore struct {
+	Proposal models.Proposal `json:"proposal"`
+	Score    float64         `json:"score"`
+	Factors  []ScoreFactor   `json:"factors"`
+}
+
+// ProposalComparison lists proposals side by side, in the order asked for.
+// Best names the proposal that leads each factor and the total score.
+type ProposalComparison struct {
+	Proposals []ProposalScore `json:"proposals"`
+	Best      map[string]uint `json:"best"`
+}
+
+type ProposalScoringService interface {
+	// RankProposals scores the submitted proposals on a project, best first
+	// by sortBy: "score" (the default) or one of the factors.
+	RankProposals(projectID uint, sortBy string) ([]ProposalScore, error)
+	CompareProposals(projectID uint, proposalIDs []uint) (*ProposalComparison, error)
+}
+
+type proposalScoringService struct {
+	repo repositories.ProposalScoringRepository
+}
+
+func NewProposalScoringService(repo repositories.ProposalScoringRepository) ProposalScoringService {
+	return &proposalScoringService{repo: repo}
+}
+
+func (s *proposalScoringService) RankProposals(projectID uint, sortBy string) ([]ProposalScore, error) {
+	if sortBy == "" {
+		sortBy = "score"
+	}
+	if sortBy != "score" && factorWeight(sortBy) == 0 {
+		return nil, ErrInvalidSortKey
+	}
+
+	scores, err := s.scoreProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scoreBy(scores[i], sortBy) > scoreBy(scores[j], sortBy)
+	})
+	return scores, nil
+}
+
+func (s *proposalScoringService) CompareProposals(projectID uint, proposalIDs []uint) (*ProposalComparison, error) {
+	if len(proposalIDs) < 2 || len(proposalIDs) > MaxComparedProposals {
+		return nil, ErrInvalidComparison
+	}
+	scores, err := s.scoreProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]ProposalScore, len(scores))
+	for _, score := range scores {
+		byID[score.Proposal.ID] = score
+	}
+
+	comparison := &ProposalComparison{Best: map[string]uint{}}
+	for _, id := range proposalIDs {
+		score, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: proposal %d", ErrInvalidComparison, id)
+		}
+		comparison.Proposals = append(comparison.Proposals, score)
+	}
+	keys := []string{"score"}
+	for _, w := range scoreWeights {
+		keys = append(keys, w.name)
+	}
+	for _, key := range keys {
+		best := comparison.Proposals[0]
+		for _, score := range comparison.Proposals[1:] {
+			if scoreBy(score, key) > scoreBy(best, key) {
+				best = score
+			}
+		}
+		comparison.Best[key] = best.Proposal.ID
+	}
+	return comparison, nil
+}
+
+// scoreProject scores every rankable proposal on the project, by proposal ID
+func (s *proposalScoringService) scoreProject(projectID uint) ([]ProposalScore, error) {
+	project, err := repositories.NewProjectRepository(s.repo.GetDB()).FindByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	proposals, err := s.repo.FindRankable(projectID)
+	if err != nil || len(proposals) == 0 {
+		return []ProposalScore{}, err
+	}
+
+	ids := make([]uint, 0, len(proposals))
+	for _, p := range proposals {
+		ids = append(ids, p.FreelancerID)
+	}
+	users, err := s.repo.FindFreelancers(ids)
+	if err != nil {
+		return nil, err
+	}
+	freelancers := make(map[uint]models.User, len(users))
+	for _, u := range users {
+		freelancers[u.ID] = u
+	}
+	records, err := s.repo.FindRecords(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]ProposalScore, 0, len(proposals))
+	for _, p := range proposals {
+		freelancer := freelancers[p.FreelancerID]
+		factors := []ScoreFactor{
+			s.priceFactor(p, project),
+			durationFactor(p, project),
+			ratingFactor(freelancer, records[p.FreelancerID]),
+			skillsFactor(freelancer, project),
+			completionFactor(records[p.FreelancerID]),
+		}
+		total := 0.0
+		for i := range factors {
+			factors[i].Weight = factorWeight(factors[i].Name)
+			factors[i].Score = roundScore(factors[i].Score)
+			total += factors[i].Score * float64(factors[i].Weight) / 100
+		}
+		scores = append(scores, ProposalScore{Proposal: p, Score: roundScore(total), Factors: factors})
+	}
+	return scores, nil
+}
+
+// priceFactor compares the bid with the budget, in the budget's currency
+func (s *proposalScoringService) priceFactor(p models.Proposal, project *models.Project) ScoreFactor {
+	factor := ScoreFactor{Name: "price", Score: neutralScore}
+	if !project.Budget.IsPositive() {
+		factor.Explanation = "The project has no budget to compare the bid with"
+		return factor
+	}
+	bid := p.BidAmount
+	if bid.CurrencyOrDefault() != project.Budget.CurrencyOrDefault() {
+		rates := NewExchangeRateService(repositories.NewExchangeRateRepository(s.repo.GetDB()))
+		conv, err := rates.Convert(bid, project.Budget.CurrencyOrDefault(), time.Now())
+		if err != nil {
+			factor.Explanation = fmt.Sprintf("No exchange rate to compare the %s bid with the budget", bid.CurrencyOrDefault())
+			return factor
+		}
+		bid = conv.Amount
+	}
+	ratio := float64(bid.Amount) / float64(project.Budget.Amount)
+	factor.Score = ratioScore(ratio)
+	factor.Explanation = fmt.Sprintf("Bid of %s is %.0f%% of the %s budget", formatMoney(p.BidAmount), ratio*100, formatMoney(project.Budget))
+	return factor
+}
+
+// durationFactor compares the estimated duration with the project's
+func durationFactor(p models.Proposal, project *models.Project) ScoreFactor {
+	factor := ScoreFactor{Name: "duration", Score: neutralScore}
+	if project.Duration <= 0 {
+		factor.Explanation = "The project has no duration to compare the estimate with"
+		return factor
+	}
+	ratio := float64(p.EstimatedDuration) / float64(project.Duration)
+	factor.Score = ratioScore(ratio)
+	factor.Explanation = fmt.Sprintf("%d days against the project's %d days", p.EstimatedDuration, project.Duration)
+	return factor
+}
+
+// ratingFactor uses the freelancer's average review, or their profile
+// rating until they have reviews
+func ratingFactor(freelancer models.User, record repositories.FreelancerRecord) ScoreFactor {
+	factor := ScoreFactor{Name: "rating", Score: neutralScore}
+	switch {
+	case record.Reviews > 0:
+		factor.Score = record.AvgRating / 5 * 100
+		factor.Explanation = fmt.Sprintf("Average rating %.1f from %d reviews", record.AvgRating, record.Reviews)
+	case freelancer.Rating > 0:
+		factor.Score = freelancer.Rating / 5 * 100
+		factor.Explanation = fmt.Sprintf("Profile rating %.1f, no reviews yet", freelancer.Rating)
+	default:
+		factor.Explanation = "No ratings yet"
+	}
+	return factor
+}
+
+// skillsFactor is the share of the project's required skills the freelancer has
+func skillsFactor(freelancer models.User, project *models.Project) ScoreFactor {
+	factor := ScoreFactor{Name: "skills", Score: neutralScore}
+	if len(project.Skills) == 0 {
+		factor.Explanation = "The project lists no required skills"
+		return factor
+	}
+	has := make(map[uint]bool, len(freelancer.Skills))
+	for _, skill := range freelancer.Skills {
+		has[skill.ID] = true
+	}
+	var matched []string
+	for _, skill := range project.Skills {
+		if has[skill.ID] {
+			matched = append(matched, skill.Name)
+		}
+	}
+	factor.Score = float64(len(matched)) / float64(len(project.Skills)) * 100
+	factor.Explanation = fmt.Sprintf("Has %d of %d required skills", len(matched), len(project.Skills))
+	if len(matched) > 0 {
+		factor.Explanation += ": " + strings.Join(matched, ", ")
+	}
+	return factor
+}
+
+// completionFactor is the share of the freelancer's finished projects that
+// were completed rather than cancelled
+func completionFactor(record repositories.FreelancerRecord) ScoreFactor {
+	factor := ScoreFactor{Name: "completion", Score: neutralScore}
+	finished := record.Completed + record.Cancelled
+	if finished == 0 {
+		factor.Explanation = "No finished projects yet"
+		return factor
+	}
+	factor.Score = float64(record.Completed) / float64(finished) * 100
+	factor.Explanation = fmt.Sprintf("Completed %d of %d finished projects", record.Completed, finished)
+	return factor
+}
+
+// ratioScore scores an offer against what the project asks for: up to three
+// quarters of it scores 100, falling to 0 at one and a half times as much
+func ratioScore(ratio float64) float64 {
+	return math.Max(0, math.Min(100, (1.5-ratio)/0.75*100))
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*10) / 10
+}
+
+func factorWeight(name string) int {
+	for _, w := range scoreWeights {
+		if w.name == name {
+			return w.weight
+		}
+	}
+	return 0
+}
+
+// scoreBy returns the total score or the score of one factor
+func scoreBy(score ProposalScore, key string) float64 {
+	if key == "score" {
+		return score.Score
+	}
+	for _, f := range score.Factors {
+		if f.Name == key {
+			return f.Score
+		}
+	}
+	return 0
+}
@@ -1,8 +1,37 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotProposalParty    = errors.New("only the project's client and the proposal's freelancer can negotiate it")
+	ErrProposalClosed      = errors.New("proposal is no longer open for negotiation")
+	ErrInvalidOffer        = errors.New("an offer needs a positive amount in the bid's currency and a positive duration")
+	ErrTermsNegotiated     = errors.New("bid amount and duration can only change through counter-offers")
+	ErrOwnOffer            = errors.New("the terms on the table were offered by you; the other party has to accept them")
+	ErrProposalStatusOwned = errors.New("proposal status changes through its own endpoints")
+	ErrClientOnly          = errors.New("only the project's client can shortlist or reject a proposal")
+	ErrFreelancerOnly      = errors.New("only the proposal's freelancer can withdraw or submit it")
+	ErrProjectNotOpen      = errors.New("the project isn't taking proposals")
+	ErrDuplicateProposal   = errors.New("you already have a proposal on this project")
+	ErrNotInvited          = errors.New("the project only takes proposals from invited freelancers")
+	ErrNotDraft            = errors.New("only draft proposals can be submitted")
+	ErrIncompleteDraft     = errors.New("a proposal needs text, a positive bid and a duration before it is submitted")
+)
+
+// Negotiating parties, see models.Proposal.OfferedBy
+const (
+	PartyFreelancer = "freelancer"
+	PartyClient     = "client"
 )
 
 type ProposalService interface {
@@ -12,8 +41,25 @@ type ProposalService interface {
 	UpdateProposal(proposal *models.Proposal) error
 	DeleteProposal(id uint) error
 
-	// NEW: specialized logic to accept a proposal and update the project
-	AcceptProposal(proposal *models.Proposal) error
+	// NEW: specialized logic to accept a proposal and update the project.
+	// userID accepts as the party who didn't offer the terms on the table; an
+	// admin may accept for either party.
+	AcceptProposal(proposal *models.Proposal, userID uint, admin bool) error
+
+	// Negotiation. ProposalParty tells whether userID negotiates the proposal
+	// as its client or its freelancer.
+	ProposalParty(proposal *models.Proposal, userID uint) (string, error)
+	CounterOffer(proposalID, userID uint, amount money.Money, duration int, message string) (*models.Proposal, error)
+	GetRevisions(proposalID uint) ([]models.ProposalRevision, error)
+
+	// Status changes by the client (shortlist, reject) and the freelancer
+	// (withdraw). The message is passed on to the other party.
+	ShortlistProposal(proposalID, userID uint, message string) (*models.Proposal, error)
+	RejectProposal(proposalID, userID uint, message string) (*models.Proposal, error)
+	WithdrawProposal(proposalID, userID uint, reason string) (*models.Proposal, error)
+
+	// SubmitProposal turns the freelancer's draft into a pending proposal.
+	SubmitProposal(proposalID, userID uint) (*models.Proposal, error)
 }
 
 type proposalService struct {
@@ -24,10 +70,132 @@ func NewProposalService(repo repositories.ProposalRepository) ProposalService {
 	return &proposalService{repo: repo}
 }
 
-// CreateProposal creates a new proposal
+// CreateProposal creates a new proposal on an open project, records the bid
+// as the first revision of its negotiation and charges the freelancer's bid
+// credits, unless they were invited. A freelancer bids once per project,
+// unless they withdrew.
 func (s *proposalService) CreateProposal(proposal *models.Proposal) error {
 	// Additional validations can be added here if needed.
-	return s.repo.Create(proposal)
+	if err := checkCurrency(proposal.BidAmount); err != nil {
+		return err
+	}
+	proposal.Status = "pending"
+	proposal.OfferedBy = PartyFreelancer
+	return s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		project, invited, err := lockBiddableProject(db, proposal.ProjectID, proposal.FreelancerID)
+		if err != nil {
+			return err
+		}
+		bid, err := hasBid(db, project.ID, proposal.FreelancerID)
+		if err != nil {
+			return err
+		}
+		if bid {
+			return ErrDuplicateProposal
+		}
+		if err := repositories.NewProposalRepository(db).Create(proposal); err != nil {
+			return err
+		}
+		return openBid(db, proposal, project, invited)
+	})
+}
+
+// SubmitProposal submits a draft opened from an invitation. Invited
+// freelancers don't pay bid credits.
+func (s *proposalService) SubmitProposal(proposalID, userID uint) (*models.Proposal, error) {
+	draft, err := s.repo.FindByID(proposalID)
+	if err != nil {
+		return nil, err
+	}
+	var proposal *models.Proposal
+	err = s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		// Lock the project before the proposal, like closing the project does
+		project, invited, err := lockBiddableProject(db, draft.ProjectID, draft.FreelancerID)
+		if err != nil {
+			return err
+		}
+		repo := repositories.NewProposalRepository(db)
+		if proposal, err = repo.LockByID(proposalID); err != nil {
+			return err
+		}
+		if proposal.FreelancerID != userID {
+			return ErrFreelancerOnly
+		}
+		if proposal.Status != "draft" {
+			return ErrNotDraft
+		}
+		if proposal.ProposalText == "" || !proposal.BidAmount.IsPositive() || proposal.EstimatedDuration <= 0 {
+			return ErrIncompleteDraft
+		}
+		if err := setProposalStatus(proposal, "pending", ""); err != nil {
+			return err
+		}
+		proposal.SubmissionDate = *proposal.StatusChangedAt
+		if err := repo.Update(proposal); err != nil {
+			return err
+		}
+		if err := openBid(db, proposal, project, invited); err != nil {
+			return err
+		}
+
+		text := fmt.Sprintf("Proposal %d on %s was submitted: %s for %d days.", proposal.ID, project.Title, formatMoney(proposal.BidAmount), proposal.EstimatedDuration)
+		return notifyOtherParty(db, proposal, PartyFreelancer, text)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// lockBiddableProject locks the project a freelancer bids on, which
+// serialises the bids so the duplicate check holds, and checks that it takes
+// their proposal. It reports whether the freelancer accepted an invitation.
+func lockBiddableProject(db *gorm.DB, projectID, freelancerID uint) (*models.Project, bool, error) {
+	project, err := repositories.NewProjectRepository(db).LockByID(projectID)
+	if err != nil {
+		return nil, false, err
+	}
+	if project.Status != "open" {
+		return nil, false, ErrProjectNotOpen
+	}
+	var accepted int64
+	if err := db.Model(&models.ProjectInvitation{}).
+		Where("project_id = ? AND freelancer_id = ? AND status = ?", projectID, freelancerID, "accepted").
+		Count(&accepted).Error; err != nil {
+		return nil, false, err
+	}
+	if project.InviteOnly && accepted == 0 {
+		return nil, false, ErrNotInvited
+	}
+	return project, accepted > 0, nil
+}
+
+// hasBid reports whether the freelancer already has a proposal or draft on
+// the project they haven't withdrawn
+func hasBid(db *gorm.DB, projectID, freelancerID uint) (bool, error) {
+	var bids int64
+	err := db.Model(&models.Proposal{}).
+		Where("project_id = ? AND freelancer_id = ? AND status <> ?", projectID, freelancerID, "withdrawn").
+		Count(&bids).Error
+	return bids > 0, err
+}
+
+// openBid records the freelancer's bid as the first revision of a submitted
+// proposal and charges their bid credits if they weren't invited
+func openBid(db *gorm.DB, proposal *models.Proposal, project *models.Project, invited bool) error {
+	if err := repositories.NewProposalRepository(db).CreateRevision(&models.ProposalRevision{
+		Kind:              "bid",
+		Party:             PartyFreelancer,
+		BidAmount:         proposal.BidAmount,
+		EstimatedDuration: proposal.EstimatedDuration,
+		ProposalID:        proposal.ID,
+	}); err != nil {
+		return err
+	}
+	if invited {
+		return nil
+	}
+	return chargeProposal(db, proposal, project)
 }
 
 // GetProposalByID returns a proposal by ID
@@ -40,52 +208,250 @@ func (s *proposalService) GetProposalsByProject(projectID uint) ([]models.Propos
 	return s.repo.FindByProject(projectID)
 }
 
-// UpdateProposal updates a given proposal
+// UpdateProposal updates the text of a proposal, and the terms of a draft.
+// Once submitted, the terms only change through counter-offers, so the
+// negotiation keeps a record of them, and the status only through its own
+// methods. Closed proposals can't be edited.
 func (s *proposalService) UpdateProposal(proposal *models.Proposal) error {
+	existing, err := s.repo.FindByID(proposal.ID)
+	if err != nil {
+		return err
+	}
+	if existing.Status == "draft" {
+		if err := checkCurrency(proposal.BidAmount); err != nil {
+			return err
+		}
+	} else if !ProposalIsOpen(existing.Status) {
+		return ErrProposalClosed
+	} else if existing.BidAmount != proposal.BidAmount || existing.EstimatedDuration != proposal.EstimatedDuration {
+		return ErrTermsNegotiated
+	}
+	if existing.Status != proposal.Status || existing.StatusReason != proposal.StatusReason || existing.OfferedBy != proposal.OfferedBy {
+		return ErrProposalStatusOwned
+	}
 	return s.repo.Update(proposal)
 }
 
-// DeleteProposal deletes a proposal by ID
+// DeleteProposal deletes a proposal by ID. Freelancers withdraw their
+// proposals instead; deleting is left to admins.
 func (s *proposalService) DeleteProposal(id uint) error {
 	return s.repo.Delete(id)
 }
 
-// AcceptProposal marks a proposal as accepted and updates the linked project
-func (s *proposalService) AcceptProposal(proposal *models.Proposal) error {
-	// 1) Set proposal status
-	proposal.Status = "accepted"
-	if err := s.repo.Update(proposal); err != nil {
-		return err
+// ProposalParty returns PartyClient or PartyFreelancer for the user, or
+// ErrNotProposalParty when they take no part in the proposal
+func (s *proposalService) ProposalParty(proposal *models.Proposal, userID uint) (string, error) {
+	if proposal.FreelancerID == userID {
+		return PartyFreelancer, nil
 	}
-
-	// 2) Retrieve the associated project
-	db := s.repo.GetDB() // we can do this now that we added GetDB() in the repository
-	projRepo := repositories.NewProjectRepository(db)
-	project, err := projRepo.FindByID(proposal.ProjectID)
+	project, err := repositories.NewProjectRepository(s.repo.GetDB()).FindByID(proposal.ProjectID)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if project.ClientID == userID {
+		return PartyClient, nil
 	}
+	return "", ErrNotProposalParty
+}
 
-	// 3) Update project: assign the freelancer and set status = "in_progress"
-	project.FreelancerID = &proposal.FreelancerID
-	project.Status = "in_progress"
-	if err := projRepo.Update(project); err != nil {
-		return err
+// CounterOffer puts new terms on the table. Either party may counter, also
+// to revise their own offer; the other party is notified.
+func (s *proposalService) CounterOffer(proposalID, userID uint, amount money.Money, duration int, message string) (*models.Proposal, error) {
+	var proposal *models.Proposal
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		repo := repositories.NewProposalRepository(db)
+		var err error
+		if proposal, err = repo.LockByID(proposalID); err != nil {
+			return err
+		}
+		party, err := NewProposalService(repo).ProposalParty(proposal, userID)
+		if err != nil {
+			return err
+		}
+		if !ProposalIsOpen(proposal.Status) {
+			return ErrProposalClosed
+		}
+		if amount.Currency == "" {
+			amount.Currency = proposal.BidAmount.CurrencyOrDefault()
+		}
+		if !amount.IsPositive() || duration <= 0 || amount.CurrencyOrDefault() != proposal.BidAmount.CurrencyOrDefault() {
+			return ErrInvalidOffer
+		}
+
+		proposal.BidAmount = amount
+		proposal.EstimatedDuration = duration
+		proposal.OfferedBy = party
+		if err := repo.Update(proposal); err != nil {
+			return err
+		}
+		if err := repo.CreateRevision(&models.ProposalRevision{
+			Kind:              "counter_offer",
+			Party:             party,
+			BidAmount:         amount,
+			EstimatedDuration: duration,
+			Message:           message,
+			ProposalID:        proposal.ID,
+		}); err != nil {
+			return err
+		}
+
+		text := fmt.Sprintf("The %s made a counter-offer on proposal %d: %s for %d days.", party, proposal.ID, formatMoney(amount), duration)
+		return notifyOtherParty(db, proposal, party, text)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return proposal, nil
+}
+
+// GetRevisions lists the negotiation of a proposal, oldest first
+func (s *proposalService) GetRevisions(proposalID uint) ([]models.ProposalRevision, error) {
+	return s.repo.FindRevisions(proposalID)
+}
+
+// AcceptProposal accepts the terms on the table, marks the proposal as
+// accepted and assigns its freelancer to the linked project, which must still
+// be open. The other open proposals on the project are declined. The terms are
+// checked on the locked row, so a counter-offer made at the same time can't be
+// accepted by the party who made it.
+func (s *proposalService) AcceptProposal(proposal *models.Proposal, userID uint, admin bool) error {
+	return s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		// 1) Lock the project before the proposal, like closing the project does
+		projRepo := repositories.NewProjectRepository(db)
+		project, err := projRepo.LockByID(proposal.ProjectID)
+		if err != nil {
+			return err
+		}
+		if project.Status != "open" {
+			return ErrProjectNotOpen
+		}
+
+		// 2) Lock in the current terms; the caller's copy may be stale
+		repo := repositories.NewProposalRepository(db)
+		locked, err := repo.LockByID(proposal.ID)
+		if err != nil {
+			return err
+		}
+		if !admin {
+			party, err := NewProposalService(repo).ProposalParty(locked, userID)
+			if err != nil {
+				return err
+			}
+			if party == locked.OfferedBy {
+				return ErrOwnOffer
+			}
+		}
+		if err := setProposalStatus(locked, "accepted", ""); err != nil {
+			return err
+		}
+		locked.AcceptedAt = locked.StatusChangedAt
+		if err := repo.Update(locked); err != nil {
+			return err
+		}
+		accepter := PartyClient
+		if locked.OfferedBy == PartyClient {
+			accepter = PartyFreelancer
+		}
+		if err := repo.CreateRevision(&models.ProposalRevision{
+			Kind:              "accepted",
+			Party:             accepter,
+			BidAmount:         locked.BidAmount,
+			EstimatedDuration: locked.EstimatedDuration,
+			ProposalID:        locked.ID,
+		}); err != nil {
+			return err
+		}
+		*proposal = *locked
 
-	// 4) (Optional) create a notification for the freelancer
-	//    For example:
-	/*
-		notifRepo := repositories.NewNotificationRepository(db)
-		notif := models.Notification{
-			Message: "Your proposal has been accepted!",
-			UserID:  proposal.FreelancerID,
-			Type:    "proposal_update",
+		// 3) Update project: assign the freelancer and set status = "in_progress"
+		project.FreelancerID = &proposal.FreelancerID
+		project.Status = "in_progress"
+		if err := projRepo.Update(project); err != nil {
+			return err
 		}
-		if err := notifRepo.Create(&notif); err != nil {
+
+		// 4) Decline the other bids on the project
+		if err := closeOpenProposals(db, proposal.ProjectID, "declined", "another proposal was accepted", proposal.ID); err != nil {
 			return err
 		}
-	*/
 
-	return nil
+		// 5) Let the other party know
+		text := fmt.Sprintf("Proposal %d was accepted: %s for %d days.", proposal.ID, formatMoney(proposal.BidAmount), proposal.EstimatedDuration)
+		return notifyOtherParty(db, proposal, accepter, text)
+	})
+}
+
+// ShortlistProposal marks an open proposal as shortlisted by the client
+func (s *proposalService) ShortlistProposal(proposalID, userID uint, message string) (*models.Proposal, error) {
+	return s.changeStatus(proposalID, userID, PartyClient, "shortlisted", message)
+}
+
+// RejectProposal closes a proposal on behalf of the client
+func (s *proposalService) RejectProposal(proposalID, userID uint, message string) (*models.Proposal, error) {
+	return s.changeStatus(proposalID, userID, PartyClient, "rejected", message)
+}
+
+// WithdrawProposal closes a proposal on behalf of its freelancer. The
+// proposal and its negotiation are kept.
+func (s *proposalService) WithdrawProposal(proposalID, userID uint, reason string) (*models.Proposal, error) {
+	return s.changeStatus(proposalID, userID, PartyFreelancer, "withdrawn", reason)
+}
+
+// changeStatus moves a proposal to a new status on behalf of one party,
+// keeping the reason, and notifies the other party
+func (s *proposalService) changeStatus(proposalID, userID uint, party, to, reason string) (*models.Proposal, error) {
+	var proposal *models.Proposal
+	err := s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		repo := repositories.NewProposalRepository(db)
+		var err error
+		if proposal, err = repo.LockByID(proposalID); err != nil {
+			return err
+		}
+		actor, err := NewProposalService(repo).ProposalParty(proposal, userID)
+		if err != nil {
+			return err
+		}
+		if actor != party {
+			if party == PartyClient {
+				return ErrClientOnly
+			}
+			return ErrFreelancerOnly
+		}
+		if err := setProposalStatus(proposal, to, reason); err != nil {
+			return err
+		}
+		if err := repo.Update(proposal); err != nil {
+			return err
+		}
+
+		text := fmt.Sprintf("Proposal %d was %s by the %s.", proposal.ID, to, party)
+		if reason != "" {
+			text += " " + reason
+		}
+		return notifyOtherParty(db, proposal, party, text)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// notifyOtherParty sends a proposal_update notification to the party of the
+// proposal that didn't act
+func notifyOtherParty(db *gorm.DB, proposal *models.Proposal, actor, text string) error {
+	userID := proposal.FreelancerID
+	if actor == PartyFreelancer {
+		var project models.Project
+		if err := db.Select("project_id", "client_id").First(&project, proposal.ProjectID).Error; err != nil {
+			return err
+		}
+		userID = project.ClientID
+	}
+	notifications := NewNotificationService(repositories.NewNotificationRepository(db))
+	return notifications.CreateNotification(&models.Notification{
+		Message: text,
+		Date:    time.Now(),
+		Type:    "proposal_update",
+		UserID:  userID,
+	})
 }
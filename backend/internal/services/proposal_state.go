@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidProposalTransition = errors.New("invalid proposal status transition")
+
+// proposalTransitions is the proposal state machine. A bid is open while
+// pending or shortlisted: the client shortlists, accepts or rejects it and the
+// freelancer can withdraw it. Accepting one bid declines the other open bids
+// and drafts on the project, and closing the project expires them. A draft
+// becomes pending once its freelancer submits it. Every other state is final.
+var proposalTransitions = map[string][]string{
+	"draft":       {"pending", "withdrawn", "declined", "expired"},
+	"pending":     {"shortlisted", "accepted", "rejected", "withdrawn", "declined", "expired"},
+	"shortlisted": {"accepted", "rejected", "withdrawn", "declined", "expired"},
+}
+
+// CanTransitionProposal reports whether a proposal may move from one status to another.
+func CanTransitionProposal(from, to string) bool {
+	for _, next := range proposalTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ProposalIsOpen reports whether a proposal in the given status can still be
+// negotiated and accepted.
+func ProposalIsOpen(status string) bool {
+	return status == "pending" || status == "shortlisted"
+}
+
+// setProposalStatus moves a locked proposal to a new status and records why.
+// The caller saves the proposal.
+func setProposalStatus(proposal *models.Proposal, to, reason string) error {
+	if !CanTransitionProposal(proposal.Status, to) {
+		if !ProposalIsOpen(proposal.Status) && proposal.Status != "draft" {
+			return ErrProposalClosed
+		}
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidProposalTransition, proposal.Status, to)
+	}
+	now := time.Now()
+	proposal.Status = to
+	proposal.StatusReason = reason
+	proposal.StatusChangedAt = &now
+	return nil
+}
+
+// closeOpenProposals moves every open or draft proposal on a project, except
+// the one with exceptID, to a final status ("declined" or "expired") and
+// tells the freelancers why.
+func closeOpenProposals(db *gorm.DB, projectID uint, to, reason string, exceptID uint) error {
+	repo := repositories.NewProposalRepository(db)
+	open, err := repo.LockOpenByProject(projectID)
+	if err != nil {
+		return err
+	}
+	for i := range open {
+		proposal := &open[i]
+		if proposal.ID == exceptID {
+			continue
+		}
+		if err := setProposalStatus(proposal, to, reason); err != nil {
+			return err
+		}
+		if err := repo.Update(proposal); err != nil {
+			return err
+		}
+		text := fmt.Sprintf("Your proposal %d was %s: %s", proposal.ID, to, reason)
+		if err := notifyOtherParty(db, proposal, PartyClient, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+)
+
+var ErrNotFixable = errors.New("this discrepancy can't be fixed automatically")
+
+// Discrepancy kinds. The fixable ones are corrected from a source of truth
+// (the gateway, the refund/chargeback records, the due date) and never move
+// money; balance mismatches always need a human.
+const (
+	DiscrepancyGatewayStatus       = "gateway_status_mismatch"  // fixable: sync through the state machine
+	DiscrepancyGatewayIntent       = "gateway_intent_missing"   // the provider doesn't know the payment
+	DiscrepancyReversedAmount      = "reversed_amount_mismatch" // fixable: recompute from refunds and chargebacks
+	DiscrepancyUncredited          = "completed_without_credit"
+	DiscrepancyEarnings            = "earnings_mismatch"
+	DiscrepancyTotalSpent          = "total_spent_mismatch"
+	DiscrepancyInvoicePaidUnfunded = "invoice_paid_unfunded"
+	DiscrepancyInvoiceOverdue      = "invoice_overdue_not_flagged" // fixable: mark overdue
+	DiscrepancyRefundNotBooked     = "refund_not_booked"           // fixable once the gateway made it: book it
+)
+
+// refundGracePeriod is how long a refund may stay pending, waiting for the
+// gateway, before reconciliation reports it
+const refundGracePeriod = 15 * time.Minute
+
+type ReconciliationService interface {
+	// Run compares transactions, invoices and balances and stores what it
+	// finds. With autoFix, fixable discrepancies are corrected right away.
+	Run(trigger string, autoFix bool) (*models.ReconciliationRun, error)
+	GetRuns(limit int) ([]models.ReconciliationRun, error)
+	GetRun(id uint) (*models.ReconciliationRun, error)
+	FixDiscrepancy(id uint) (*models.Discrepancy, error)
+}
+
+type reconciliationService struct {
+	repo         repositories.ReconciliationRepository
+	transactions TransactionService
+	gateway      payments.PaymentGateway
+	now          func() time.Time
+}
+
+func NewReconciliationService(repo repositories.ReconciliationRepository, transactions TransactionService, gateway payments.PaymentGateway) ReconciliationService {
+	return &reconciliationService{repo: repo, transactions: transactions, gateway: gateway, now: time.Now}
+}
+
+func (s *reconciliationService) GetRuns(limit int) ([]models.ReconciliationRun, error) {
+	return s.repo.FindRuns(limit)
+}
+
+func (s *reconciliationService) GetRun(id uint) (*models.ReconciliationRun, error) {
+	return s.repo.FindRunByID(id)
+}
+
+func (s *reconciliationService) Run(trigger string, autoFix bool) (*models.ReconciliationRun, error) {
+	run := &models.ReconciliationRun{Trigger: trigger, AutoFix: autoFix, StartedAt: s.now()}
+	if err := s.repo.CreateRun(run); err != nil {
+		return nil, err
+	}
+
+	found, err := s.check()
+	for i := range found {
+		d := &found[i]
+		d.RunID = run.ID
+		if err := s.repo.CreateDiscrepancy(d); err != nil {
+			return nil, err
+		}
+		if autoFix && d.Fixable {
+			if fixErr := s.fix(d); fixErr == nil {
+				run.FixedCount++
+			} else {
+				d.Details += "; auto-fix failed: " + fixErr.Error()
+				_ = s.repo.UpdateDiscrepancy(d)
+			}
+		}
+	}
+	run.Discrepancies = found
+	run.DiscrepancyCount = len(found)
+
+	finished := s.now()
+	run.FinishedAt = &finished
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if updErr := s.repo.UpdateRun(run); updErr != nil {
+		return nil, updErr
+	}
+	return run, err
+}
+
+func (s *reconciliationService) FixDiscrepancy(id uint) (*models.Discrepancy, error) {
+	d, err := s.repo.FindDiscrepancyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if d.FixedAt != nil {
+		return d, nil
+	}
+	if err := s.fix(d); err != nil {
+		return nil, err
+	}
+	if run, err := s.repo.FindRunByID(d.RunID); err == nil {
+		run.FixedCount++
+		_ = s.repo.UpdateRun(run)
+	}
+	return d, nil
+}
+
+// fix corrects a fixable discrepancy and marks it fixed
+func (s *reconciliationService) fix(d *models.Discrepancy) error {
+	if !d.Fixable {
+		return ErrNotFixable
+	}
+	db := s.repo.GetDB()
+
+	switch d.Kind {
+	case DiscrepancyGatewayStatus:
+		if _, err := s.transactions.SyncTransactionStatus(d.EntityID); err != nil {
+			return err
+		}
+	case DiscrepancyReversedAmount:
+		var tx models.Transaction
+		if err := db.First(&tx, d.EntityID).Error; err != nil {
+			return err
+		}
+		reversed, err := s.recordedReversals(tx)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&tx).Updates(map[string]interface{}{
+			"reversed_amount_amount":   reversed.Amount,
+			"reversed_amount_currency": reversed.Currency,
+		}).Error; err != nil {
+			return err
+		}
+	case DiscrepancyRefundNotBooked:
+		if _, err := s.transactions.SettleRefund(d.EntityID); err != nil {
+			return err
+		}
+	case DiscrepancyInvoiceOverdue:
+		if err := db.Model(&models.Invoice{}).
+			Where("invoice_id = ? AND payment_status = ?", d.EntityID, "pending").
+			Update("payment_status", "overdue").Error; err != nil {
+			return err
+		}
+	default:
+		return ErrNotFixable
+	}
+
+	now := s.now()
+	d.FixedAt = &now
+	return s.repo.UpdateDiscrepancy(d)
+}
+
+// check runs every comparison and returns what doesn't agree
+func (s *reconciliationService) check() ([]models.Discrepancy, error) {
+	db := s.repo.GetDB()
+	var txs []models.Transaction
+	if err := db.Order("transaction_id").Find(&txs).Error; err != nil {
+		return nil, err
+	}
+
+	var found []models.Discrepancy
+	for _, check := range []func([]models.Transaction) ([]models.Discrepancy, error){
+		s.checkTransactions,
+		s.checkBalances,
+		s.checkInvoices,
+		s.checkRefunds,
+	} {
+		ds, err := check(txs)
+		found = append(found, ds...)
+		if err != nil {
+			return found, err
+		}
+	}
+	return found, nil
+}
+
+// checkTransactions compares each transaction with the gateway and with its
+// own refund and chargeback records.
+func (s *reconciliationService) checkTransactions(txs []models.Transaction) ([]models.Discrepancy, error) {
+	var found []models.Discrepancy
+	for _, tx := range txs {
+		reversed, err := s.recordedReversals(tx)
+		if err != nil {
+			return found, err
+		}
+		if actual := balanceIn(tx.ReversedAmount, tx.Amount.Currency); actual != reversed {
+			found = append(found, models.Discrepancy{
+				Kind: DiscrepancyReversedAmount, EntityType: "transaction", EntityID: tx.ID,
+				Expected: formatMoney(reversed), Actual: formatMoney(actual),
+				Details: "reversed amount differs from the sum of refunds and chargebacks",
+				Fixable: true,
+			})
+		}
+
+		credited := tx.Status == "completed" || tx.Status == "refunded" || tx.Status == "charged_back"
+		if credited && tx.BalancesCreditedAt == nil {
+			found = append(found, models.Discrepancy{
+				Kind: DiscrepancyUncredited, EntityType: "transaction", EntityID: tx.ID,
+				Expected: "balances credited", Actual: "not credited",
+				Details: fmt.Sprintf("transaction is %s but user balances were never credited", tx.Status),
+			})
+		}
+
+		// Open payments are compared with what the gateway says
+		if (tx.Status != "pending" && tx.Status != "processing") || tx.GatewayReference == "" || tx.Gateway != s.gateway.Name() {
+			continue
+		}
+		intent, err := s.gateway.Status(tx.GatewayReference)
+		if errors.Is(err, payments.ErrIntentNotFound) {
+			found = append(found, models.Discrepancy{
+				Kind: DiscrepancyGatewayIntent, EntityType: "transaction", EntityID: tx.ID,
+				Expected: tx.GatewayReference, Actual: "unknown to " + tx.Gateway,
+			})
+			continue
+		}
+		if err != nil {
+			return found, err
+		}
+		if status := transactionStatusFor(intent.Status); status != tx.Status && status != "pending" {
+			found = append(found, models.Discrepancy{
+				Kind: DiscrepancyGatewayStatus, EntityType: "transaction", EntityID: tx.ID,
+				Expected: status, Actual: tx.Status,
+				Details: "gateway reports " + string(intent.Status),
+				Fixable: transitionPath(tx.Status, status) != nil,
+			})
+		}
+	}
+	return found, nil
+}
+
+// checkBalances rebuilds every user's earnings and total spent from the
+// ledger (credited transactions, refunds, chargebacks and paid withdrawals)
+// and compares them with the stored balances.
+func (s *reconciliationService) checkBalances(txs []models.Transaction) ([]models.Discrepancy, error) {
+	db := s.repo.GetDB()
+	var users []models.User
+	if err := db.Order("user_id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	rates := NewExchangeRateService(repositories.NewExchangeRateRepository(db))
+
+	earnings := map[uint]money.Money{}
+	spent := map[uint]money.Money{}
+	for _, u := range users {
+		earnings[u.ID] = money.Zero(u.Currency)
+		spent[u.ID] = money.Zero(u.Currency)
+	}
+	add := func(balances map[uint]money.Money, userID uint, m money.Money, at time.Time) error {
+		current, ok := balances[userID]
+		if !ok {
+			return nil
+		}
+		conv, err := rates.Convert(m, current.Currency, at)
+		if err != nil {
+			return err
+		}
+		balances[userID], err = current.Add(conv.Amount)
+		return err
+	}
+
+	for _, tx := range txs {
+		if tx.BalancesCreditedAt == nil {
+			continue
+		}
+		net, err := netSettlement(&tx)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(earnings, tx.FreelancerID, net, tx.Date); err != nil {
+			return nil, err
+		}
+		if err := add(spent, tx.ClientID, tx.Amount, tx.Date); err != nil {
+			return nil, err
+		}
+
+		var refunds []models.Refund
+		if err := db.Where("transaction_id = ? AND status = ?", tx.ID, "succeeded").Find(&refunds).Error; err != nil {
+			return nil, err
+		}
+		var chargebacks []models.Chargeback
+		if err := db.Where("transaction_id = ?", tx.ID).Find(&chargebacks).Error; err != nil {
+			return nil, err
+		}
+		reversals := make([][2]money.Money, 0, len(refunds)+len(chargebacks))
+		for _, r := range refunds {
+			reversals = append(reversals, [2]money.Money{r.Amount, r.SettlementAmount})
+		}
+		for _, c := range chargebacks {
+			reversals = append(reversals, [2]money.Money{c.Amount, c.SettlementAmount})
+		}
+		for _, r := range reversals {
+			if err := add(earnings, tx.FreelancerID, r[1].Neg(), tx.Date); err != nil {
+				return nil, err
+			}
+			if err := add(spent, tx.ClientID, r[0].Neg(), tx.Date); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var paid []models.WithdrawalRequest
+	if err := db.Where("status = ?", "paid").Find(&paid).Error; err != nil {
+		return nil, err
+	}
+	for _, w := range paid {
+		if err := add(earnings, w.UserID, w.Amount.Neg(), s.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	var found []models.Discrepancy
+	for _, u := range users {
+		// Per-piece conversion may differ from the stored balance by rounding,
+		// so a single minor unit per direction is tolerated.
+		if actual := balanceIn(u.Earnings, u.Currency); !closeEnough(actual, earnings[u.ID]) {
+			found = append(found, models.Discrepancy{
+				Kind: DiscrepancyEarnings, EntityType: "user", EntityID: u.ID,
+				Expected: formatMoney(earnings[u.ID]), Actual: formatMoney(actual),
+			})
+		}
+		if actual := balanceIn(u.TotalSpent, u.Currency); !closeEnough(actual, spent[u.ID]) {
+			found = append(found, models.Discrepancy{
+				Kind: DiscrepancyTotalSpent, EntityType: "user", EntityID: u.ID,
+				Expected: formatMoney(spent[u.ID]), Actual: formatMoney(actual),
+			})
+		}
+	}
+	return found, nil
+}
+
+// checkInvoices compares each project's paid invoices with the money actually
+// received for it, and finds pending invoices that are past due. Drafts
+// haven't been issued and are ignored.
+func (s *reconciliationService) checkInvoices(txs []models.Transaction) ([]models.Discrepancy, error) {
+	db := s.repo.GetDB()
+	var invoices []models.Invoice
+	if err := db.Where("kind = ? AND status = ?", "invoice", "finalised").Order("invoice_id").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		projectID uint
+		currency  string
+	}
+	received := map[key]int64{}
+	for _, tx := range txs {
+		if tx.BalancesCreditedAt == nil {
+			continue
+		}
+		received[key{tx.ProjectID, tx.Amount.CurrencyOrDefault()}] += tx.Amount.Amount - tx.ReversedAmount.Amount
+	}
+	invoicedPaid := map[key]int64{}
+
+	var found []models.Discrepancy
+	now := s.now()
+	for _, inv := range invoices {
+		if inv.PaymentStatus == "paid" {
+			invoicedPaid[key{inv.ProjectID, inv.AmountDue.CurrencyOrDefault()}] += inv.AmountDue.Amount
+		}
+		if inv.PaymentStatus == "pending" && IsOverdue(inv.DueDate, now) {
+			found = append(found, models.Discrepancy{
+				Kind: DiscrepancyInvoiceOverdue, EntityType: "invoice", EntityID: inv.ID,
+				Expected: "overdue", Actual: "pending",
+				Details: "due " + inv.DueDate.Format("2006-01-02"),
+				Fixable: true,
+			})
+		}
+	}
+	for k, paid := range invoicedPaid {
+		if paid > received[k] {
+			found = append(found, models.Discrepancy{
+				Kind: DiscrepancyInvoicePaidUnfunded, EntityType: "project", EntityID: k.projectID,
+				Expected: formatMoney(money.New(paid, k.currency)), Actual: formatMoney(money.New(received[k], k.currency)),
+				Details: "invoices marked paid exceed the payments received for the project",
+			})
+		}
+	}
+	return found, nil
+}
+
+// checkRefunds reports refunds left pending: the gateway may have returned
+// the money without it reaching the ledger. Those with a gateway reference
+// can be booked; the others need someone to check with the provider.
+func (s *reconciliationService) checkRefunds(_ []models.Transaction) ([]models.Discrepancy, error) {
+	var pending []models.Refund
+	if err := s.repo.GetDB().Where("status = ? AND created_at < ?", "pending", s.now().Add(-refundGracePeriod)).
+		Order("refund_id").Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	var found []models.Discrepancy
+	for _, r := range pending {
+		d := models.Discrepancy{
+			Kind: DiscrepancyRefundNotBooked, EntityType: "refund", EntityID: r.ID,
+			Expected: "booked", Actual: "pending",
+			Details: fmt.Sprintf("refund of %s on transaction %d", formatMoney(r.Amount), r.TransactionID),
+			Fixable: r.GatewayReference != "",
+		}
+		if r.GatewayReference == "" {
+			d.Details += " has no gateway reference; check with the provider"
+		}
+		found = append(found, d)
+	}
+	return found, nil
+}
+
+// recordedReversals sums the booked refunds and the chargebacks recorded for tx
+func (s *reconciliationService) recordedReversals(tx models.Transaction) (money.Money, error) {
+	var refunded, chargedBack int64
+	db := s.repo.GetDB()
+	if err := db.Model(&models.Refund{}).Where("transaction_id = ? AND status = ?", tx.ID, "succeeded").
+		Select("COALESCE(SUM(amount_amount), 0)").Scan(&refunded).Error; err != nil {
+		return money.Money{}, err
+	}
+	if err := db.Model(&models.Chargeback{}).Where("transaction_id = ?", tx.ID).
+		Select("COALESCE(SUM(amount_amount), 0)").Scan(&chargedBack).Error; err != nil {
+		return money.Money{}, err
+	}
+	return money.New(refunded+chargedBack, tx.Amount.CurrencyOrDefault()), nil
+}
+
+func closeEnough(a, b money.Money) bool {
+	d := a.Amount - b.Amount
+	return a.Currency == b.Currency && d >= -1 && d <= 1
+}
+
+func formatMoney(m money.Money) string {
+	return m.String() + " " + m.CurrencyOrDefault()
+}
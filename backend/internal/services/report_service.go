@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+)
+
+// CurrencyTotal is the sum of amounts in one original currency together with
+// its value in the report currency.
+type CurrencyTotal struct {
+	Currency   string      `json:"currency"`
+	Count      int         `json:"count"`
+	Total      money.Money `json:"total"`
+	Normalized money.Money `json:"normalized"`
+}
+
+// TransactionVolumeReport summarises completed transactions for a period,
+// normalised into a single report currency.
+type TransactionVolumeReport struct {
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Currency   string          `json:"currency"`
+	ByCurrency []CurrencyTotal `json:"by_currency"`
+	Total      money.Money     `json:"total"`
+}
+
+// RevenuePeriod is the platform fee income of one period. Fees on refunded or
+// charged back amounts are returned to the payer and deducted as Reversed.
+type RevenuePeriod struct {
+	Start    time.Time   `json:"start"`
+	Count    int         `json:"count"`
+	Gross    money.Money `json:"gross"`
+	Reversed money.Money `json:"reversed"`
+	Net      money.Money `json:"net"`
+}
+
+// RevenueReport is platform revenue for [From, To) split into periods,
+// normalised into a single report currency.
+type RevenueReport struct {
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Currency string          `json:"currency"`
+	Interval string          `json:"interval"`
+	Periods  []RevenuePeriod `json:"periods"`
+	Total    money.Money     `json:"total"`
+}
+
+var ErrInvalidInterval = errors.New("interval must be one of day, week, month")
+
+type ReportService interface {
+	TransactionVolume(from, to time.Time, currency string) (*TransactionVolumeReport, error)
+	PlatformRevenue(from, to time.Time, currency, interval string) (*RevenueReport, error)
+}
+
+type reportService struct {
+	txRepo repositories.TransactionRepository
+	rates  ExchangeRateService
+}
+
+func NewReportService(txRepo repositories.TransactionRepository, rates ExchangeRateService) ReportService {
+	return &reportService{txRepo: txRepo, rates: rates}
+}
+
+// TransactionVolume sums completed transactions in [from, to). Each
+// transaction is converted at the rate effective on its own date, so the
+// report doesn't drift when new rates are imported.
+func (s *reportService) TransactionVolume(from, to time.Time, currency string) (*TransactionVolumeReport, error) {
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if err := checkCurrency(money.Zero(currency)); err != nil {
+		return nil, err
+	}
+	currency = money.Zero(currency).Currency
+
+	txs, err := s.txRepo.FindByStatusBetween("completed", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]*CurrencyTotal{}
+	for _, tx := range txs {
+		cur := tx.Amount.CurrencyOrDefault()
+		ct, ok := totals[cur]
+		if !ok {
+			ct = &CurrencyTotal{Currency: cur, Total: money.Zero(cur), Normalized: money.Zero(currency)}
+			totals[cur] = ct
+		}
+
+		conv, err := s.rates.Convert(tx.Amount, currency, tx.Date)
+		if err != nil {
+			return nil, err
+		}
+		if ct.Total, err = ct.Total.Add(tx.Amount); err != nil {
+			return nil, err
+		}
+		if ct.Normalized, err = ct.Normalized.Add(conv.Amount); err != nil {
+			return nil, err
+		}
+		ct.Count++
+	}
+
+	report := &TransactionVolumeReport{From: from, To: to, Currency: currency, Total: money.Zero(currency)}
+	for _, ct := range totals {
+		report.ByCurrency = append(report.ByCurrency, *ct)
+		if report.Total, err = report.Total.Add(ct.Normalized); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(report.ByCurrency, func(i, j int) bool {
+		return report.ByCurrency[i].Currency < report.ByCurrency[j].Currency
+	})
+	return report, nil
+}
+
+// PlatformRevenue sums the platform fees of paid transactions in [from, to),
+// grouped by day, week (starting Monday) or month. As with TransactionVolume,
+// each fee is converted at the rate effective on the transaction date.
+func (s *reportService) PlatformRevenue(from, to time.Time, currency, interval string) (*RevenueReport, error) {
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if err := checkCurrency(money.Zero(currency)); err != nil {
+		return nil, err
+	}
+	currency = money.Zero(currency).Currency
+	if interval == "" {
+		interval = "month"
+	}
+	if interval != "day" && interval != "week" && interval != "month" {
+		return nil, ErrInvalidInterval
+	}
+
+	// Refunded and charged back transactions still count: their gross fee
+	// was earned and is reversed below.
+	txs, err := s.txRepo.FindByStatusesBetween([]string{"completed", "refunded", "charged_back"}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RevenueReport{From: from, To: to, Currency: currency, Interval: interval, Total: money.Zero(currency)}
+	periods := map[time.Time]*RevenuePeriod{}
+	for _, tx := range txs {
+		if tx.FeeAmount.IsZero() {
+			continue
+		}
+		start := periodStart(tx.Date, interval)
+		p, ok := periods[start]
+		if !ok {
+			zero := money.Zero(currency)
+			p = &RevenuePeriod{Start: start, Gross: zero, Reversed: zero, Net: zero}
+			periods[start] = p
+		}
+
+		gross, err := s.rates.Convert(tx.FeeAmount, currency, tx.Date)
+		if err != nil {
+			return nil, err
+		}
+		reversedFee := tx.FeeAmount.MulRatio(tx.ReversedAmount.Amount, tx.Amount.Amount)
+		reversed, err := s.rates.Convert(reversedFee, currency, tx.Date)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Gross, err = p.Gross.Add(gross.Amount); err != nil {
+			return nil, err
+		}
+		if p.Reversed, err = p.Reversed.Add(reversed.Amount); err != nil {
+			return nil, err
+		}
+		if p.Net, err = p.Gross.Sub(p.Reversed); err != nil {
+			return nil, err
+		}
+		p.Count++
+	}
+
+	for _, p := range periods {
+		report.Periods = append(report.Periods, *p)
+		if report.Total, err = report.Total.Add(p.Net); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(report.Periods, func(i, j int) bool {
+		return report.Periods[i].Start.Before(report.Periods[j].Start)
+	})
+	return report, nil
+}
+
+// periodStart truncates t to the start of its day, ISO week or month (UTC)
+func periodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// Tax treatments of an invoice
+const (
+	TaxNone          = "none"           // the issuer has no tax profile
+	TaxStandard      = "standard"       // the issuer's rate applies
+	TaxReverseCharge = "reverse_charge" // intra-EU B2B; the client accounts for the tax
+	TaxExempt        = "exempt"         // the issuer doesn't charge tax
+	TaxOutsideScope  = "outside_scope"  // supply to a client outside the issuer's tax area
+)
+
+var (
+	ErrInvalidTaxProfile = errors.New("invalid tax profile")
+	ErrInvalidVATID      = errors.New("invalid VAT ID")
+	ErrInvalidTaxRule    = errors.New("invalid tax rule")
+)
+
+var (
+	countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	vatIDPattern       = regexp.MustCompile(`^[A-Z]{2}[0-9A-Z]{2,13}$`)
+)
+
+// TaxDecision is how an invoice between two parties is taxed
+type TaxDecision struct {
+	Treatment string `json:"treatment"`
+	Rate      int64  `json:"rate"` // basis points
+	Country   string `json:"country,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// TaxSummaryLine totals the invoices of one treatment, country and rate
+type TaxSummaryLine struct {
+	Treatment string      `json:"treatment"`
+	Country   string      `json:"country,omitempty"`
+	Rate      int64       `json:"rate"`
+	Count     int         `json:"count"`
+	Net       money.Money `json:"net"`
+	Tax       money.Money `json:"tax"`
+	Gross     money.Money `json:"gross"`
+}
+
+// TaxSummaryReport is the tax on invoices issued in [From, To), normalised
+// into a single report currency.
+type TaxSummaryReport struct {
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Currency string           `json:"currency"`
+	Lines    []TaxSummaryLine `json:"lines"`
+	Net      money.Money      `json:"net"`
+	Tax      money.Money      `json:"tax"`
+	Gross    money.Money      `json:"gross"`
+}
+
+type TaxService interface {
+	GetProfile(userID uint) (*models.TaxProfile, error)
+	SaveProfile(profile *models.TaxProfile) error
+	GetRules() ([]models.TaxRule, error)
+	SaveRule(rule *models.TaxRule) error
+
+	// Determine works out the tax treatment of an invoice from the issuer
+	// to the client.
+	Determine(issuerID *uint, clientID uint) (TaxDecision, error)
+
+	Summary(from, to time.Time, currency string) (*TaxSummaryReport, error)
+}
+
+type taxService struct {
+	repo        repositories.TaxRepository
+	invoiceRepo repositories.InvoiceRepository
+	rates       ExchangeRateService
+}
+
+func NewTaxService(repo repositories.TaxRepository, invoiceRepo repositories.InvoiceRepository, rates ExchangeRateService) TaxService {
+	return &taxService{repo: repo, invoiceRepo: invoiceRepo, rates: rates}
+}
+
+func (s *taxService) GetProfile(userID uint) (*models.TaxProfile, error) {
+	return s.repo.FindProfileByUser(userID)
+}
+
+func (s *taxService) SaveProfile(profile *models.TaxProfile) error {
+	profile.Country = strings.ToUpper(strings.TrimSpace(profile.Country))
+	if !countryCodePattern.MatchString(profile.Country) {
+		return fmt.Errorf("%w: country must be a two-letter ISO code", ErrInvalidTaxProfile)
+	}
+	if profile.VATID != "" {
+		vatID, err := NormalizeVATID(profile.VATID, profile.Country)
+		if err != nil {
+			return err
+		}
+		profile.VATID = vatID
+	}
+	return s.repo.SaveProfile(profile)
+}
+
+func (s *taxService) GetRules() ([]models.TaxRule, error) {
+	return s.repo.FindRules()
+}
+
+func (s *taxService) SaveRule(rule *models.TaxRule) error {
+	rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+	if !countryCodePattern.MatchString(rule.Country) {
+		return fmt.Errorf("%w: country must be a two-letter ISO code", ErrInvalidTaxRule)
+	}
+	if rule.Rate < 0 || rule.Rate > 10000 {
+		return fmt.Errorf("%w: rate must be between 0 and 10000 basis points", ErrInvalidTaxRule)
+	}
+	if rule.Name == "" {
+		rule.Name = "VAT"
+	}
+	return s.repo.SaveRule(rule)
+}
+
+func (s *taxService) Determine(issuerID *uint, clientID uint) (TaxDecision, error) {
+	if issuerID == nil {
+		return DetermineTax(nil, nil, nil, nil), nil
+	}
+	issuer, err := s.profile(*issuerID)
+	if err != nil {
+		return TaxDecision{}, err
+	}
+	if issuer == nil {
+		return DetermineTax(nil, nil, nil, nil), nil
+	}
+	client, err := s.profile(clientID)
+	if err != nil {
+		return TaxDecision{}, err
+	}
+
+	issuerRule, err := s.rule(issuer.Country)
+	if err != nil {
+		return TaxDecision{}, err
+	}
+	var clientRule *models.TaxRule
+	if client != nil {
+		if clientRule, err = s.rule(client.Country); err != nil {
+			return TaxDecision{}, err
+		}
+	}
+	return DetermineTax(issuer, client, issuerRule, clientRule), nil
+}
+
+// profile returns the user's tax profile, or nil if they have none
+func (s *taxService) profile(userID uint) (*models.TaxProfile, error) {
+	return optionalProfile(s.repo.FindProfileByUser(userID))
+}
+
+// rule returns the country's tax rule, or nil if none is configured
+func (s *taxService) rule(country string) (*models.TaxRule, error) {
+	r, err := s.repo.FindRuleByCountry(country)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// DetermineTax applies the tax rules to an invoice from issuer to client.
+// Profiles and rules may be nil when they don't exist.
+//
+//   - no issuer profile or no rule for the issuer's country: no tax
+//   - tax-exempt issuer: exempt
+//   - client in the issuer's country, or unknown: the issuer's rate
+//   - both in the EU, client a business with a VAT ID: reverse charge
+//   - client a business outside the issuer's tax area: outside scope
+//   - any other consumer: the issuer's rate
+func DetermineTax(issuer, client *models.TaxProfile, issuerRule, clientRule *models.TaxRule) TaxDecision {
+	if issuer == nil {
+		return TaxDecision{Treatment: TaxNone}
+	}
+	if issuer.TaxExempt {
+		note := "Exempt from VAT"
+		if issuer.ExemptionReason != "" {
+			note += ": " + issuer.ExemptionReason
+		}
+		return TaxDecision{Treatment: TaxExempt, Country: issuer.Country, Note: note}
+	}
+	if issuerRule == nil {
+		return TaxDecision{Treatment: TaxNone, Country: issuer.Country}
+	}
+
+	standard := TaxDecision{Treatment: TaxStandard, Rate: issuerRule.Rate, Country: issuer.Country}
+	if client == nil || client.Country == issuer.Country {
+		return standard
+	}
+
+	clientEU := clientRule != nil && clientRule.EUMember
+	if issuerRule.EUMember && clientEU {
+		if client.IsBusiness && client.VATID != "" {
+			return TaxDecision{
+				Treatment: TaxReverseCharge,
+				Country:   client.Country,
+				Note:      "Reverse charge: VAT to be accounted for by the recipient (Art. 196 Directive 2006/112/EC), VAT ID " + client.VATID,
+			}
+		}
+		return standard
+	}
+	if client.IsBusiness {
+		return TaxDecision{Treatment: TaxOutsideScope, Country: client.Country, Note: "Outside the scope of " + issuerRule.Name}
+	}
+	return standard
+}
+
+// NormalizeVATID strips spaces and punctuation from a VAT ID and checks that
+// it starts with the prefix of the given country.
+func NormalizeVATID(vatID, country string) (string, error) {
+	vatID = strings.ToUpper(vatID)
+	vatID = strings.NewReplacer(" ", "", ".", "", "-", "").Replace(vatID)
+	if !vatIDPattern.MatchString(vatID) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidVATID, vatID)
+	}
+	prefix := country
+	if country == "GR" {
+		prefix = "EL"
+	}
+	if vatID[:2] != prefix {
+		return "", fmt.Errorf("%w: %q doesn't match country %s", ErrInvalidVATID, vatID, country)
+	}
+	return vatID, nil
+}
+
+// applyTax sets the tax and gross amounts of an invoice. Tax is calculated
+// per line and added up; an invoice without lines is taxed on its net amount.
+func applyTax(invoice *models.Invoice, d TaxDecision) error {
+	invoice.TaxTreatment = d.Treatment
+	invoice.TaxRate = d.Rate
+	invoice.TaxCountry = d.Country
+	invoice.TaxNote = d.Note
+
+	if len(invoice.Lines) == 0 {
+		invoice.TaxAmount = invoice.NetAmount.Percent(d.Rate)
+	} else {
+		currency := invoice.Lines[0].UnitPrice.Currency
+		net, tax := money.Zero(currency), money.Zero(currency)
+		for i := range invoice.Lines {
+			line := &invoice.Lines[i]
+			line.Position = i + 1
+			line.NetAmount = line.UnitPrice.Mul(line.Quantity)
+			line.TaxRate = d.Rate
+			line.TaxAmount = line.NetAmount.Percent(d.Rate)
+			line.GrossAmount = money.New(line.NetAmount.Amount+line.TaxAmount.Amount, line.NetAmount.Currency)
+
+			var err error
+			if net, err = net.Add(line.NetAmount); err != nil {
+				return err
+			}
+			if tax, err = tax.Add(line.TaxAmount); err != nil {
+				return err
+			}
+		}
+		invoice.NetAmount, invoice.TaxAmount = net, tax
+	}
+	invoice.AmountDue = money.New(invoice.NetAmount.Amount+invoice.TaxAmount.Amount, invoice.NetAmount.Currency)
+	return nil
+}
+
+// Summary totals invoices issued in [from, to) by treatment, country and
+// rate. Each invoice is converted at the rate effective on its date. Credit
+// notes count negatively; voided invoices are left out.
+func (s *taxService) Summary(from, to time.Time, currency string) (*TaxSummaryReport, error) {
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if err := checkCurrency(money.Zero(currency)); err != nil {
+		return nil, err
+	}
+	currency = money.Zero(currency).Currency
+
+	invoices, err := s.invoiceRepo.FindIssuedBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	zero := money.Zero(currency)
+	report := &TaxSummaryReport{From: from, To: to, Currency: currency, Net: zero, Tax: zero, Gross: zero}
+	type key struct {
+		treatment, country string
+		rate               int64
+	}
+	lines := map[key]*TaxSummaryLine{}
+	for _, inv := range invoices {
+		k := key{inv.TaxTreatment, inv.TaxCountry, inv.TaxRate}
+		line, ok := lines[k]
+		if !ok {
+			line = &TaxSummaryLine{Treatment: k.treatment, Country: k.country, Rate: k.rate, Net: zero, Tax: zero, Gross: zero}
+			lines[k] = line
+		}
+
+		net, err := s.rates.Convert(inv.NetAmount, currency, inv.Date)
+		if err != nil {
+			return nil, err
+		}
+		tax, err := s.rates.Convert(inv.TaxAmount, currency, inv.Date)
+		if err != nil {
+			return nil, err
+		}
+		netAmount, taxAmount := net.Amount, tax.Amount
+		if inv.Kind == "credit_note" {
+			netAmount, taxAmount = netAmount.Neg(), taxAmount.Neg()
+		}
+		if line.Net, err = line.Net.Add(netAmount); err != nil {
+			return nil, err
+		}
+		if line.Tax, err = line.Tax.Add(taxAmount); err != nil {
+			return nil, err
+		}
+		if line.Gross, err = line.Net.Add(line.Tax); err != nil {
+			return nil, err
+		}
+		line.Count++
+	}
+
+	for _, line := range lines {
+		report.Lines = append(report.Lines, *line)
+		if report.Net, err = report.Net.Add(line.Net); err != nil {
+			return nil, err
+		}
+		if report.Tax, err = report.Tax.Add(line.Tax); err != nil {
+			return nil, err
+		}
+	}
+	if report.Gross, err = report.Net.Add(report.Tax); err != nil {
+		return nil, err
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if a.Treatment != b.Treatment {
+			return a.Treatment < b.Treatment
+		}
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		return a.Rate < b.Rate
+	})
+	return report, nil
+}
@@ -1,30 +1,134 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
 	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrStatusManagedByGateway = errors.New("transaction status can only be changed by the payment gateway")
+	ErrPaymentTermsLocked     = errors.New("amount and payment method can't change once a payment has been started")
+	ErrNotReversible          = errors.New("only completed transactions can be refunded or charged back")
+	ErrReversalTooLarge       = errors.New("amount exceeds what is left of the transaction")
+	ErrReasonRequired         = errors.New("a reason is required")
+	ErrRefundNotSent          = errors.New("the refund never reached the payment gateway")
+	ErrTransactionInLedger    = errors.New("a transaction sent to the payment gateway or booked can't be deleted")
 )
 
+// errRefundBooked stops booking a refund that was booked concurrently
+var errRefundBooked = errors.New("refund already booked")
+
 type TransactionService interface {
-	CreateTransaction(transaction *models.Transaction) error
+	CreateTransaction(transaction *models.Transaction, paymentToken string) error
 	GetTransactionByID(id uint) (*models.Transaction, error)
 	GetTransactionsByProject(projectID uint) ([]models.Transaction, error)
 	UpdateTransaction(transaction *models.Transaction) error
 	DeleteTransaction(id uint) error
+
+	// Gateway-driven status changes
+	CaptureTransaction(id uint) (*models.Transaction, error)
+	SyncTransactionStatus(id uint) (*models.Transaction, error)
+	ApplyGatewayUpdate(gateway string, intent *payments.Intent) (*models.Transaction, error)
+	RetryTransaction(id uint, paymentToken string) (*models.Transaction, error)
+	GetStatusHistory(id uint) ([]models.TransactionStatusChange, error)
+
+	// Reversals. A zero amount means everything not yet reversed.
+	RefundTransaction(id uint, amount money.Money, reason string) (*models.Refund, error)
+	GetRefunds(transactionID uint) ([]models.Refund, error)
+	// SettleRefund books a pending refund the gateway already made.
+	SettleRefund(refundID uint) (*models.Refund, error)
+	RecordChargeback(id uint, amount money.Money, reason, providerReference string) (*models.Chargeback, error)
+	GetChargebacks() ([]models.Chargeback, error)
 }
 
 type transactionService struct {
-	repo repositories.TransactionRepository
+	repo    repositories.TransactionRepository
+	gateway payments.PaymentGateway
 }
 
-func NewTransactionService(repo repositories.TransactionRepository) TransactionService {
-	return &transactionService{repo: repo}
+func NewTransactionService(repo repositories.TransactionRepository, gateway payments.PaymentGateway) TransactionService {
+	return &transactionService{repo: repo, gateway: gateway}
 }
 
-// CreateTransaction creates a new transaction
-// Optionally, we could finalize immediately if status == "completed"
-func (s *transactionService) CreateTransaction(transaction *models.Transaction) error {
-	return s.repo.Create(transaction)
+// CreateTransaction creates a new transaction and authorises it with the
+// payment gateway. A declined authorisation is stored as a failed transaction.
+// The amount is converted into the freelancer's currency right away and the
+// applied rate is stored, so later rate changes never affect this payment.
+// Invoices listed in Allocations are paid when the transaction completes.
+func (s *transactionService) CreateTransaction(transaction *models.Transaction, paymentToken string) error {
+	if err := checkCurrency(transaction.Amount); err != nil {
+		return err
+	}
+	if err := checkAllocations(s.repo.GetDB(), transaction); err != nil {
+		return err
+	}
+	if transaction.Date.IsZero() {
+		transaction.Date = time.Now()
+	}
+
+	var freelancer models.User
+	if err := s.repo.GetDB().First(&freelancer, transaction.FreelancerID).Error; err != nil {
+		return err
+	}
+
+	conv, err := s.rates().Convert(transaction.Amount, freelancer.Currency, transaction.Date)
+	if err != nil {
+		return err
+	}
+	transaction.SettlementAmount = conv.Amount
+	transaction.AppliedRate = conv.Rate
+	transaction.ExchangeRateID = conv.ExchangeRateID
+
+	// Platform fee, stored as separate ledger lines
+	fees, feeTotal, err := s.fees().Calculate(transaction)
+	if err != nil {
+		return err
+	}
+	transaction.Fees = fees
+	transaction.FeeAmount = feeTotal
+	transaction.FeeSettlement = money.Zero(transaction.SettlementAmount.Currency)
+	for _, f := range fees {
+		if transaction.FeeSettlement, err = transaction.FeeSettlement.Add(f.SettlementAmount); err != nil {
+			return err
+		}
+	}
+
+	intent, err := s.gateway.CreateIntent(payments.IntentRequest{
+		Amount:        transaction.Amount,
+		PaymentMethod: transaction.PaymentMethod,
+		PaymentToken:  paymentToken,
+	})
+	if err != nil {
+		return err
+	}
+	transaction.Gateway = s.gateway.Name()
+	transaction.GatewayReference = intent.ID
+	transaction.Status = "pending"
+	transaction.FailureReason = intent.FailureReason
+
+	return s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		if err := repositories.NewTransactionRepository(db).Create(transaction); err != nil {
+			return err
+		}
+		if err := recordInitialStatus(db, transaction, "payment authorised"); err != nil {
+			return err
+		}
+		if intent.Status == payments.IntentDeclined {
+			if err := s.transition(db, transaction, "failed", intent.FailureReason); err != nil {
+				return err
+			}
+			return db.Save(transaction).Error
+		}
+		return nil
+	})
 }
 
 // GetTransactionByID returns transaction by ID
@@ -37,8 +141,9 @@ func (s *transactionService) GetTransactionsByProject(projectID uint) ([]models.
 	return s.repo.FindByProject(projectID)
 }
 
-// UpdateTransaction updates a transaction; if status changes to "completed",
-// it updates the client/freelancer balances automatically.
+// UpdateTransaction updates a transaction's editable fields. The status is
+// owned by the payment gateway (see CaptureTransaction/SyncTransactionStatus),
+// and the amount and method are fixed once the gateway knows about them.
 func (s *transactionService) UpdateTransaction(transaction *models.Transaction) error {
 	// Compare old vs new
 	oldTx, err := s.repo.FindByID(transaction.ID)
@@ -46,24 +151,521 @@ func (s *transactionService) UpdateTransaction(transaction *models.Transaction)
 		return err
 	}
 
-	// If we're transitioning to "completed", update user balances
-	if oldTx.Status != "completed" && transaction.Status == "completed" {
-		if err := s.updateUserBalances(transaction); err != nil {
+	if oldTx.Status != transaction.Status {
+		return ErrStatusManagedByGateway
+	}
+	if oldTx.GatewayReference != "" &&
+		(oldTx.Amount != transaction.Amount || oldTx.PaymentMethod != transaction.PaymentMethod) {
+		return ErrPaymentTermsLocked
+	}
+
+	return s.repo.Update(transaction)
+}
+
+// CaptureTransaction asks the gateway to collect an authorised payment and
+// applies whatever state the gateway reports back.
+func (s *transactionService) CaptureTransaction(id uint) (*models.Transaction, error) {
+	tx, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	intent, err := s.gateway.Capture(tx.GatewayReference)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyIntent(tx, intent); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// SyncTransactionStatus queries the gateway and applies the current state,
+// e.g. to pick up a delayed capture that has settled in the meantime.
+func (s *transactionService) SyncTransactionStatus(id uint) (*models.Transaction, error) {
+	tx, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	intent, err := s.gateway.Status(tx.GatewayReference)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyIntent(tx, intent); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// ApplyGatewayUpdate applies an intent state pushed by the gateway (e.g. from
+// a webhook) to the transaction that references it. Applying the same state
+// twice is a no-op.
+func (s *transactionService) ApplyGatewayUpdate(gateway string, intent *payments.Intent) (*models.Transaction, error) {
+	tx, err := s.repo.FindByGatewayReference(gateway, intent.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyIntent(tx, intent); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// RetryTransaction authorises a failed payment again, typically with a new
+// payment token, and starts it over as pending.
+func (s *transactionService) RetryTransaction(id uint, paymentToken string) (*models.Transaction, error) {
+	tx, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransition(tx.Status, "pending") {
+		return nil, fmt.Errorf("%w: only failed transactions can be retried", ErrInvalidTransition)
+	}
+
+	intent, err := s.gateway.CreateIntent(payments.IntentRequest{
+		Amount:        tx.Amount,
+		PaymentMethod: tx.PaymentMethod,
+		PaymentToken:  paymentToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		if err := lockTransaction(db, tx); err != nil {
+			return err
+		}
+		if err := s.transition(db, tx, "pending", fmt.Sprintf("retry %d", tx.RetryCount+1)); err != nil {
 			return err
 		}
+		tx.RetryCount++
+		tx.Gateway = s.gateway.Name()
+		tx.GatewayReference = intent.ID
+		tx.FailureReason = intent.FailureReason
+		if intent.Status == payments.IntentDeclined {
+			if err := s.transition(db, tx, "failed", intent.FailureReason); err != nil {
+				return err
+			}
+		}
+		return db.Save(tx).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// GetStatusHistory lists the steps a transaction went through, oldest first
+func (s *transactionService) GetStatusHistory(id uint) ([]models.TransactionStatusChange, error) {
+	var changes []models.TransactionStatusChange
+	if err := s.repo.GetDB().Where("transaction_id = ?", id).
+		Order("created_at, transaction_status_change_id").Find(&changes).Error; err != nil {
+		return nil, err
 	}
+	return changes, nil
+}
 
-	return s.repo.Update(transaction)
+// applyIntent moves the transaction to the status matching the gateway
+// intent, step by step through the state machine. Reaching "completed"
+// credits the user balances; a refund made directly at the provider reverses
+// whatever is left of the transaction. A state the transaction already
+// passed (a late or out-of-order event) is ignored; other states it can't
+// reach from where it is are rejected with ErrInvalidTransition.
+func (s *transactionService) applyIntent(tx *models.Transaction, intent *payments.Intent) error {
+	status := transactionStatusFor(intent.Status)
+	if status == tx.Status || isBehind(status, tx.Status) || tx.Status == "refunded" || tx.Status == "charged_back" {
+		return nil
+	}
+
+	if status == "refunded" && tx.Status == "completed" {
+		// Book our own refunds the provider already made first, so they
+		// aren't counted again in the rest
+		if err := s.settlePendingRefunds(tx.ID); err != nil {
+			return err
+		}
+		if err := s.repo.GetDB().First(tx, tx.ID).Error; err != nil {
+			return err
+		}
+		remaining, err := s.remaining(tx)
+		if err != nil || !remaining.IsPositive() {
+			return err
+		}
+		return s.reverse(tx, remaining, "refunded", "", func(db *gorm.DB, settlement money.Money) error {
+			return repositories.NewRefundRepository(db).Create(&models.Refund{
+				TransactionID:    tx.ID,
+				Amount:           remaining,
+				SettlementAmount: settlement,
+				Reason:           "refunded at the payment provider",
+				Status:           "succeeded",
+			})
+		})
+	}
+
+	return s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		if err := lockTransaction(db, tx); err != nil {
+			return err
+		}
+		if status == tx.Status || isBehind(status, tx.Status) {
+			return nil // applied concurrently
+		}
+		path := transitionPath(tx.Status, status)
+		if path == nil {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, tx.Status, status)
+		}
+		for _, step := range path {
+			if err := s.transition(db, tx, step, gatewayReason(step, intent)); err != nil {
+				return err
+			}
+		}
+		tx.FailureReason = intent.FailureReason
+		return db.Save(tx).Error
+	})
+}
+
+// gatewayReason describes why the gateway moved a transaction into status
+func gatewayReason(status string, intent *payments.Intent) string {
+	switch status {
+	case "processing":
+		return "capture submitted"
+	case "completed":
+		return "payment captured"
+	case "failed":
+		return intent.FailureReason
+	}
+	return "gateway status " + string(intent.Status)
+}
+
+// transactionStatusFor maps a gateway intent status to a transaction status.
+func transactionStatusFor(status payments.IntentStatus) string {
+	switch status {
+	case payments.IntentProcessing:
+		return "processing"
+	case payments.IntentSucceeded:
+		return "completed"
+	case payments.IntentRefunded:
+		return "refunded"
+	case payments.IntentDeclined:
+		return "failed"
+	}
+	return "pending"
+}
+
+// RefundTransaction refunds all or part of a completed transaction through the
+// gateway and reverses the matching share of the freelancer's earnings and
+// the client's spending. Refunding everything marks the transaction "refunded".
+//
+// The refund is stored as pending before the gateway is asked. If the gateway
+// refunds but booking it fails, the refund stays pending with the gateway's
+// reference until SettleRefund (or reconciliation) books it.
+func (s *transactionService) RefundTransaction(id uint, amount money.Money, reason string) (*models.Refund, error) {
+	tx, amount, err := s.prepareReversal(id, amount, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &models.Refund{TransactionID: tx.ID, Amount: amount, Reason: reason, Status: "pending"}
+	err = s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		// Refunds still pending count as spent, so two at once can't exceed the rest
+		if err := lockTransaction(db, tx); err != nil {
+			return err
+		}
+		repo := repositories.NewRefundRepository(db)
+		pending, err := repo.FindPendingByTransaction(tx.ID)
+		if err != nil {
+			return err
+		}
+		left, err := s.remaining(tx)
+		if err != nil {
+			return err
+		}
+		for _, p := range pending {
+			if left, err = left.Sub(p.Amount); err != nil {
+				return err
+			}
+		}
+		cmp, err := amount.Cmp(left)
+		if err != nil {
+			return err
+		}
+		if cmp > 0 {
+			return ErrReversalTooLarge
+		}
+		return repo.Create(refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refunds := repositories.NewRefundRepository(s.repo.GetDB())
+	gwRefund, err := s.gateway.Refund(tx.GatewayReference, amount)
+	if err != nil {
+		refund.Status, refund.FailureReason = "failed", err.Error()
+		if saveErr := refunds.Update(refund); saveErr != nil {
+			return nil, saveErr
+		}
+		return nil, err
+	}
+	refund.GatewayReference = gwRefund.ID
+	if err := refunds.Update(refund); err != nil {
+		return nil, err
+	}
+	if err := s.bookRefund(refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+func (s *transactionService) SettleRefund(refundID uint) (*models.Refund, error) {
+	refund, err := repositories.NewRefundRepository(s.repo.GetDB()).FindByID(refundID)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != "pending" {
+		return refund, nil
+	}
+	if refund.GatewayReference == "" {
+		return nil, ErrRefundNotSent
+	}
+	if err := s.bookRefund(refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// settlePendingRefunds books the refunds of a transaction that the gateway
+// made but that are still pending
+func (s *transactionService) settlePendingRefunds(transactionID uint) error {
+	pending, err := repositories.NewRefundRepository(s.repo.GetDB()).FindPendingByTransaction(transactionID)
+	if err != nil {
+		return err
+	}
+	for i := range pending {
+		if pending[i].GatewayReference == "" {
+			continue
+		}
+		if err := s.bookRefund(&pending[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bookRefund reverses the earnings and spending of a refund the gateway made
+// and marks it succeeded, once
+func (s *transactionService) bookRefund(refund *models.Refund) error {
+	tx, err := s.repo.FindByID(refund.TransactionID)
+	if err != nil {
+		return err
+	}
+	err = s.reverse(tx, refund.Amount, "refunded", "", func(db *gorm.DB, settlement money.Money) error {
+		repo := repositories.NewRefundRepository(db)
+		current, err := repo.LockByID(refund.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status != "pending" {
+			*refund = *current
+			return errRefundBooked
+		}
+		refund.Status = "succeeded"
+		refund.SettlementAmount = settlement
+		return repo.Update(refund)
+	})
+	if errors.Is(err, errRefundBooked) {
+		return nil
+	}
+	return err
+}
+
+// GetRefunds lists the refunds of a transaction
+func (s *transactionService) GetRefunds(transactionID uint) ([]models.Refund, error) {
+	return repositories.NewRefundRepository(s.repo.GetDB()).FindByTransaction(transactionID)
+}
+
+// RecordChargeback records a chargeback reported by the payer's bank. The
+// money is already gone, so nothing is sent to the gateway: the freelancer's
+// share is clawed back (earnings may go negative) and both accounts are
+// flagged for review.
+func (s *transactionService) RecordChargeback(id uint, amount money.Money, reason, providerReference string) (*models.Chargeback, error) {
+	tx, amount, err := s.prepareReversal(id, amount, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	chargeback := &models.Chargeback{
+		TransactionID:     tx.ID,
+		Amount:            amount,
+		Reason:            reason,
+		ProviderReference: providerReference,
+	}
+	flag := fmt.Sprintf("chargeback on transaction %d", tx.ID)
+	err = s.reverse(tx, amount, "charged_back", flag, func(db *gorm.DB, settlement money.Money) error {
+		chargeback.SettlementAmount = settlement
+		return repositories.NewChargebackRepository(db).Create(chargeback)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chargeback, nil
+}
+
+// GetChargebacks lists all chargebacks, newest first
+func (s *transactionService) GetChargebacks() ([]models.Chargeback, error) {
+	return repositories.NewChargebackRepository(s.repo.GetDB()).FindAll()
+}
+
+// prepareReversal loads the transaction and validates a refund or chargeback
+// against it. A zero amount is replaced by the remaining amount.
+func (s *transactionService) prepareReversal(id uint, amount money.Money, reason string) (*models.Transaction, money.Money, error) {
+	if reason == "" {
+		return nil, amount, ErrReasonRequired
+	}
+	tx, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, amount, err
+	}
+	if tx.Status != "completed" {
+		return nil, amount, ErrNotReversible
+	}
+
+	remaining, err := s.remaining(tx)
+	if err != nil {
+		return nil, amount, err
+	}
+	if amount.IsZero() {
+		return tx, remaining, nil
+	}
+	if amount.IsNegative() {
+		return nil, amount, money.ErrInvalidAmount
+	}
+	if amount.Currency == "" {
+		amount.Currency = tx.Amount.Currency
+	}
+	cmp, err := amount.Cmp(remaining)
+	if err != nil {
+		return nil, amount, err
+	}
+	if cmp > 0 {
+		return nil, amount, ErrReversalTooLarge
+	}
+	return tx, amount, nil
+}
+
+// remaining is the part of the transaction not yet refunded or charged back
+func (s *transactionService) remaining(tx *models.Transaction) (money.Money, error) {
+	return tx.Amount.Sub(balanceIn(tx.ReversedAmount, tx.Amount.Currency))
+}
+
+// reverse takes amount back out of the ledger: the freelancer loses the
+// matching share of the net settlement (the platform returns its fee share) and the client's spending drops by the
+// same share in their currency. The shares are computed on the cumulative
+// reversed amount, so a series of partial reversals adds up exactly to the
+// original credit. What the transaction paid on invoices is taken back too
+// (see unallocateTransaction). record stores the refund or chargeback in the
+// same DB transaction; a non-empty flag marks both accounts for review.
+func (s *transactionService) reverse(tx *models.Transaction, amount money.Money, fullStatus, flag string,
+	record func(db *gorm.DB, settlement money.Money) error) error {
+
+	return s.repo.GetDB().Transaction(func(db *gorm.DB) error {
+		// Re-check on the locked row so concurrent reversals can't exceed the amount
+		if err := lockTransaction(db, tx); err != nil {
+			return err
+		}
+		before := balanceIn(tx.ReversedAmount, tx.Amount.Currency)
+		after, err := before.Add(amount)
+		if err != nil {
+			return err
+		}
+		if after.Amount > tx.Amount.Amount {
+			return ErrReversalTooLarge
+		}
+
+		net, err := netSettlement(tx)
+		if err != nil {
+			return err
+		}
+		settlement, err := net.MulRatio(after.Amount, tx.Amount.Amount).
+			Sub(net.MulRatio(before.Amount, tx.Amount.Amount))
+		if err != nil {
+			return err
+		}
+
+		if err := record(db, settlement); err != nil {
+			return err
+		}
+		if err := unallocateTransaction(db, tx, amount, time.Now()); err != nil {
+			return err
+		}
+
+		var client, freelancer models.User
+		if err := db.First(&client, tx.ClientID).Error; err != nil {
+			return err
+		}
+		if err := db.First(&freelancer, tx.FreelancerID).Error; err != nil {
+			return err
+		}
+
+		earnings, err := balanceIn(freelancer.Earnings, freelancer.Currency).Sub(settlement)
+		if err != nil {
+			return err
+		}
+		freelancer.Earnings = earnings
+
+		convBefore, err := s.rates().Convert(before, client.Currency, tx.Date)
+		if err != nil {
+			return err
+		}
+		convAfter, err := s.rates().Convert(after, client.Currency, tx.Date)
+		if err != nil {
+			return err
+		}
+		clientShare, err := convAfter.Amount.Sub(convBefore.Amount)
+		if err != nil {
+			return err
+		}
+		spent, err := balanceIn(client.TotalSpent, client.Currency).Sub(clientShare)
+		if err != nil {
+			return err
+		}
+		client.TotalSpent = spent
+
+		if flag != "" {
+			client.Flagged, client.FlagReason = true, flag
+			freelancer.Flagged, freelancer.FlagReason = true, flag
+		}
+		if err := db.Save(&freelancer).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&client).Error; err != nil {
+			return err
+		}
+
+		tx.ReversedAmount = after
+		if after.Amount == tx.Amount.Amount {
+			if err := s.transition(db, tx, fullStatus, "fully reversed"); err != nil {
+				return err
+			}
+		}
+		return repositories.NewTransactionRepository(db).Update(tx)
+	})
 }
 
-// DeleteTransaction deletes a transaction
+// DeleteTransaction deletes a transaction that never reached the gateway.
+// Once a payment was started or its balances credited it stays, for the
+// audit trail; money is returned through refunds instead.
 func (s *transactionService) DeleteTransaction(id uint) error {
+	tx, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if tx.GatewayReference != "" || tx.BalancesCreditedAt != nil {
+		return ErrTransactionInLedger
+	}
 	return s.repo.Delete(id)
 }
 
-// updateUserBalances updates the client & freelancer after the transaction completes
-func (s *transactionService) updateUserBalances(tx *models.Transaction) error {
-	db := s.repo.GetDB() // from the transaction_repository
+// creditBalances updates the client & freelancer when the transaction completes.
+// It only runs from transition, on a locked transaction row.
+func (s *transactionService) creditBalances(db *gorm.DB, tx *models.Transaction) error {
 
 	// Fetch the client
 	var client models.User
@@ -77,10 +679,29 @@ func (s *transactionService) updateUserBalances(tx *models.Transaction) error {
 		return err
 	}
 
-	// Increase freelancer earnings
-	freelancer.Earnings += tx.Amount
-	// Increase client total_spent
-	client.TotalSpent += tx.Amount
+	// Increase freelancer earnings by the settled amount (already in the
+	// freelancer's currency) minus the platform fee
+	net, err := netSettlement(tx)
+	if err != nil {
+		return err
+	}
+	earnings, err := balanceIn(freelancer.Earnings, freelancer.Currency).Add(net)
+	if err != nil {
+		return err
+	}
+	freelancer.Earnings = earnings
+
+	// Increase client total_spent, converted into the client's currency
+	// at the rate of the transaction date
+	conv, err := s.rates().Convert(tx.Amount, client.Currency, tx.Date)
+	if err != nil {
+		return err
+	}
+	spent, err := balanceIn(client.TotalSpent, client.Currency).Add(conv.Amount)
+	if err != nil {
+		return err
+	}
+	client.TotalSpent = spent
 
 	// Save
 	if err := db.Save(&freelancer).Error; err != nil {
@@ -92,3 +713,33 @@ func (s *transactionService) updateUserBalances(tx *models.Transaction) error {
 
 	return nil
 }
+
+// netSettlement is what the freelancer is credited for a transaction
+func netSettlement(tx *models.Transaction) (money.Money, error) {
+	return tx.SettlementAmount.Sub(balanceIn(tx.FeeSettlement, tx.SettlementAmount.Currency))
+}
+
+// withDB returns a copy of the service working on the given connection,
+// e.g. inside a transaction
+func (s *transactionService) withDB(db *gorm.DB) *transactionService {
+	return &transactionService{repo: repositories.NewTransactionRepository(db), gateway: s.gateway}
+}
+
+// fees returns a FeeService on the same connection as the repository
+func (s *transactionService) fees() FeeService {
+	return NewFeeService(repositories.NewFeeRuleRepository(s.repo.GetDB()), s.repo, s.rates())
+}
+
+// rates returns an ExchangeRateService on the same connection as the repository
+func (s *transactionService) rates() ExchangeRateService {
+	return NewExchangeRateService(repositories.NewExchangeRateRepository(s.repo.GetDB()))
+}
+
+// balanceIn returns the balance, re-denominating an empty balance into the
+// user's currency (balances start out in the column default).
+func balanceIn(balance money.Money, currency string) money.Money {
+	if balance.IsZero() {
+		return money.Zero(currency)
+	}
+	return balance
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"FreeConnect/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidTransition = errors.New("invalid transaction status transition")
+
+// transactionTransitions is the transaction state machine. A payment is
+// authorised (pending), captured (processing) and then settles (completed) or
+// fails; a failed payment can be retried, which starts it over as pending.
+// Completed payments can only be reversed.
+var transactionTransitions = map[string][]string{
+	"pending":    {"processing", "failed"},
+	"processing": {"completed", "failed"},
+	"failed":     {"pending"},
+	"completed":  {"refunded", "charged_back"},
+}
+
+// CanTransition reports whether a transaction may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, next := range transactionTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionPath returns the steps that lead from one status to another, so a
+// gateway that reports "succeeded" for a pending payment still walks it
+// through "processing". It returns nil if there is no such path.
+func transitionPath(from, to string) []string {
+	if CanTransition(from, to) {
+		return []string{to}
+	}
+	for _, via := range transactionTransitions[from] {
+		if CanTransition(via, to) {
+			return []string{via, to}
+		}
+	}
+	return nil
+}
+
+// transactionStages orders the statuses along the state machine, ignoring the
+// retry loop back to pending.
+var transactionStages = map[string]int{
+	"pending":      0,
+	"processing":   1,
+	"completed":    2,
+	"failed":       2,
+	"refunded":     3,
+	"charged_back": 3,
+}
+
+// isBehind reports whether status is a step the transaction already passed,
+// e.g. a "processing" event arriving after the payment completed.
+func isBehind(status, current string) bool {
+	return transactionStages[status] < transactionStages[current]
+}
+
+// lockTransaction reloads tx with a row lock inside db. All status changes go
+// through a locked row, which is what makes concurrent updates (a capture
+// racing a webhook) apply their balance effects exactly once.
+func lockTransaction(db *gorm.DB, tx *models.Transaction) error {
+	return db.Clauses(clause.Locking{Strength: "UPDATE"}).First(tx, tx.ID).Error
+}
+
+// transition moves a locked transaction one step and records the step.
+// Entering "completed" credits the user balances unless that already happened.
+// The caller saves the transaction.
+func (s *transactionService) transition(db *gorm.DB, tx *models.Transaction, to, reason string) error {
+	if !CanTransition(tx.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, tx.Status, to)
+	}
+
+	if to == "completed" && tx.BalancesCreditedAt == nil {
+		if err := s.creditBalances(db, tx); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := allocateTransaction(db, tx, now); err != nil {
+			return err
+		}
+		tx.BalancesCreditedAt = &now
+	}
+
+	change := models.TransactionStatusChange{
+		TransactionID: tx.ID,
+		FromStatus:    tx.Status,
+		ToStatus:      to,
+		Reason:        reason,
+	}
+	if err := db.Create(&change).Error; err != nil {
+		return err
+	}
+	tx.Status = to
+	return nil
+}
+
+// recordInitialStatus stores the state a new transaction starts in
+func recordInitialStatus(db *gorm.DB, tx *models.Transaction, reason string) error {
+	return db.Create(&models.TransactionStatusChange{
+		TransactionID: tx.ID,
+		ToStatus:      tx.Status,
+		Reason:        reason,
+	}).Error
+}
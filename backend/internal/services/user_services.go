@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 	"FreeConnect/internal/repositories"
 
 	"golang.org/x/crypto/bcrypt"
@@ -32,6 +33,15 @@ func (s *userService) Register(user *models.User, plainPassword string) error {
 	if existingUser, _ := s.repo.FindByEmail(user.Email); existingUser != nil {
 		return errors.New("user with that email already exists")
 	}
+	if user.Currency == "" {
+		user.Currency = money.DefaultCurrency
+	}
+	if !money.IsSupported(user.Currency) {
+		return errors.New("unsupported currency")
+	}
+	user.Currency = money.Zero(user.Currency).Currency
+	user.Earnings = money.Zero(user.Currency)
+	user.TotalSpent = money.Zero(user.Currency)
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plainPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrWebhookProcessing = errors.New("webhook event could not be processed")
+
+type WebhookService interface {
+	// Receive verifies, stores and processes a provider callback. duplicate is
+	// true when the event had already been processed successfully.
+	Receive(provider, signature string, body []byte) (event *models.WebhookEvent, duplicate bool, err error)
+	Reprocess(eventID uint) (*models.WebhookEvent, error)
+	GetOpenDeadLetters() ([]models.WebhookDeadLetter, error)
+}
+
+type webhookService struct {
+	repo         repositories.WebhookRepository
+	transactions TransactionService
+	secret       string
+	now          func() time.Time
+}
+
+func NewWebhookService(repo repositories.WebhookRepository, transactions TransactionService, secret string) WebhookService {
+	return &webhookService{repo: repo, transactions: transactions, secret: secret, now: time.Now}
+}
+
+func (s *webhookService) Receive(provider, signature string, body []byte) (*models.WebhookEvent, bool, error) {
+	if err := payments.VerifySignature(s.secret, signature, body, s.now()); err != nil {
+		return nil, false, err
+	}
+	evt, err := payments.ParseEvent(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Store the raw event first; (provider, event_id) is unique, so a
+	// redelivery finds the existing row instead.
+	stored := &models.WebhookEvent{
+		Provider:   provider,
+		EventID:    evt.ID,
+		EventType:  evt.Type,
+		Payload:    string(body),
+		Signature:  signature,
+		Status:     "received",
+		ReceivedAt: s.now(),
+	}
+	if _, err := s.repo.CreateIfAbsent(stored); err != nil {
+		return nil, false, err
+	}
+
+	return s.process(provider, evt.ID)
+}
+
+// Reprocess replays a stored event from its raw payload, typically one that
+// ended up in the dead-letter table.
+func (s *webhookService) Reprocess(eventID uint) (*models.WebhookEvent, error) {
+	stored, err := s.repo.FindByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	event, _, err := s.process(stored.Provider, stored.EventID)
+	return event, err
+}
+
+func (s *webhookService) GetOpenDeadLetters() ([]models.WebhookDeadLetter, error) {
+	return s.repo.FindOpenDeadLetters()
+}
+
+// process applies a stored event while holding a row lock on it, so
+// concurrent deliveries of the same event are applied at most once. The event
+// is applied and marked processed in the same DB transaction; a failed
+// attempt is rolled back to a savepoint and only the failure is recorded.
+func (s *webhookService) process(provider, eventID string) (*models.WebhookEvent, bool, error) {
+	var (
+		stored     models.WebhookEvent
+		duplicate  bool
+		processErr error
+	)
+
+	err := s.repo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND event_id = ?", provider, eventID).
+			First(&stored).Error; err != nil {
+			return err
+		}
+		if stored.Status == "processed" {
+			duplicate = true
+			return nil
+		}
+
+		stored.Attempts++
+		processErr = tx.Transaction(func(db *gorm.DB) error {
+			return s.apply(db, &stored)
+		})
+
+		txRepo := repositories.NewWebhookRepository(tx)
+		if processErr != nil {
+			stored.Status = "failed"
+			stored.LastError = processErr.Error()
+			if err := txRepo.RecordDeadLetter(stored.ID, processErr.Error()); err != nil {
+				return err
+			}
+		} else {
+			now := s.now()
+			stored.Status = "processed"
+			stored.LastError = ""
+			stored.ProcessedAt = &now
+			if err := txRepo.ResolveDeadLetter(stored.ID); err != nil {
+				return err
+			}
+		}
+		return txRepo.Update(&stored)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if processErr != nil {
+		return &stored, false, errors.Join(ErrWebhookProcessing, processErr)
+	}
+	return &stored, duplicate, nil
+}
+
+// apply drives the transaction state from the event on db. Event types other
+// than payment intent updates are stored but have no effect, and so are late
+// updates for a state the transaction already passed.
+func (s *webhookService) apply(db *gorm.DB, stored *models.WebhookEvent) error {
+	evt, err := payments.ParseEvent([]byte(stored.Payload))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(evt.Type, "payment_intent.") {
+		return nil
+	}
+	transactions := s.transactions
+	if ts, ok := transactions.(*transactionService); ok {
+		transactions = ts.withDB(db)
+	}
+	_, err = transactions.ApplyGatewayUpdate(stored.Provider, evt.Intent())
+	return err
+}
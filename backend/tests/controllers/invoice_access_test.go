@@ -0,0 +1,108 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"FreeConnect/internal/controllers"
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/services"
+)
+
+// invoiceStub serves fixed invoices; methods the tests don't reach panic
+// through the nil embedded interface
+type invoiceStub struct {
+	services.InvoiceService
+	invoices []models.Invoice
+	deleted  []uint
+}
+
+func (s *invoiceStub) GetInvoiceByID(id uint) (*models.Invoice, error) {
+	for i := range s.invoices {
+		if s.invoices[i].ID == id {
+			inv := s.invoices[i]
+			return &inv, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *invoiceStub) GetInvoicesByProject(projectID uint) ([]models.Invoice, error) {
+	var out []models.Invoice
+	for _, inv := range s.invoices {
+		if inv.ProjectID == projectID {
+			out = append(out, inv)
+		}
+	}
+	return out, nil
+}
+
+func (s *invoiceStub) DeleteInvoice(id uint) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+// invoiceRouter serves the invoice endpoints as the given user, the way the
+// auth middleware sets them up
+func invoiceRouter(stub *invoiceStub, userID uint, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	ic := controllers.NewInvoiceController(stub, nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("userRole", role)
+	})
+	r.GET("/invoices/:id", ic.GetInvoice)
+	r.DELETE("/invoices/:id", ic.DeleteInvoice)
+	r.GET("/projects/:id/invoices", ic.GetInvoicesByProject)
+	return r
+}
+
+func serve(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestInvoiceAccess(t *testing.T) {
+	issuer, client, stranger := uint(10), uint(20), uint(30)
+	otherIssuer := uint(40)
+	stub := &invoiceStub{invoices: []models.Invoice{
+		{ID: 1, ProjectID: 7, ClientID: client, IssuerID: &issuer, Status: "draft"},
+		{ID: 2, ProjectID: 7, ClientID: stranger, IssuerID: &otherIssuer, Status: "finalised"},
+	}}
+
+	// 1) Only the parties and admins read an invoice
+	assert.Equal(t, http.StatusOK, serve(invoiceRouter(stub, issuer, "freelancer"), "GET", "/invoices/1").Code)
+	assert.Equal(t, http.StatusOK, serve(invoiceRouter(stub, client, "client"), "GET", "/invoices/1").Code)
+	assert.Equal(t, http.StatusOK, serve(invoiceRouter(stub, 99, "admin"), "GET", "/invoices/1").Code)
+	assert.Equal(t, http.StatusForbidden, serve(invoiceRouter(stub, stranger, "client"), "GET", "/invoices/1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(invoiceRouter(stub, issuer, "freelancer"), "GET", "/invoices/3").Code)
+
+	// 2) A project's invoices are filtered to the caller's
+	w := serve(invoiceRouter(stub, client, "client"), "GET", "/projects/7/invoices")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Invoices []models.Invoice `json:"invoices"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	if assert.Len(t, listed.Invoices, 1) {
+		assert.Equal(t, uint(1), listed.Invoices[0].ID)
+	}
+	w = serve(invoiceRouter(stub, 99, "admin"), "GET", "/projects/7/invoices")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Len(t, listed.Invoices, 2)
+
+	// 3) Only the issuer deletes a draft; the client can't
+	assert.Equal(t, http.StatusForbidden, serve(invoiceRouter(stub, client, "client"), "DELETE", "/invoices/1").Code)
+	assert.Equal(t, http.StatusForbidden, serve(invoiceRouter(stub, otherIssuer, "freelancer"), "DELETE", "/invoices/1").Code)
+	assert.Empty(t, stub.deleted)
+	assert.Equal(t, http.StatusOK, serve(invoiceRouter(stub, issuer, "freelancer"), "DELETE", "/invoices/1").Code)
+	assert.Equal(t, []uint{1}, stub.deleted)
+}
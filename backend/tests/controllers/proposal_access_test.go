@@ -0,0 +1,90 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"FreeConnect/internal/controllers"
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/services"
+)
+
+// proposalStub serves fixed proposals; methods the tests don't reach panic
+// through the nil embedded interface
+type proposalStub struct {
+	services.ProposalService
+	proposals []models.Proposal
+}
+
+func (s *proposalStub) GetProposalByID(id uint) (*models.Proposal, error) {
+	for i := range s.proposals {
+		if s.proposals[i].ID == id {
+			p := s.proposals[i]
+			return &p, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *proposalStub) GetProposalsByProject(projectID uint) ([]models.Proposal, error) {
+	var out []models.Proposal
+	for _, p := range s.proposals {
+		if p.ProjectID == projectID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// proposalRouter serves the proposal endpoints as the given user
+func proposalRouter(stub *proposalStub, userID uint, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	pc := controllers.NewProposalController(stub)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("userRole", role)
+	})
+	r.GET("/proposals/:id", pc.GetProposal)
+	r.GET("/projects/:id/proposals", pc.GetProposalsByProject)
+	return r
+}
+
+func TestProposalDraftsArePrivate(t *testing.T) {
+	writer, other, client := uint(10), uint(11), uint(20)
+	stub := &proposalStub{proposals: []models.Proposal{
+		{ID: 1, ProjectID: 7, FreelancerID: writer, Status: "draft"},
+		{ID: 2, ProjectID: 7, FreelancerID: other, Status: "pending"},
+	}}
+
+	// 1) A draft is found only by its freelancer and admins
+	assert.Equal(t, http.StatusOK, serve(proposalRouter(stub, writer, "freelancer"), "GET", "/proposals/1").Code)
+	assert.Equal(t, http.StatusOK, serve(proposalRouter(stub, 99, "admin"), "GET", "/proposals/1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(proposalRouter(stub, client, "client"), "GET", "/proposals/1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(proposalRouter(stub, other, "freelancer"), "GET", "/proposals/1").Code)
+	assert.Equal(t, http.StatusOK, serve(proposalRouter(stub, client, "client"), "GET", "/proposals/2").Code)
+
+	// 2) The project's list leaves out other people's drafts
+	listed := func(userID uint, role string) []uint {
+		w := serve(proposalRouter(stub, userID, role), "GET", "/projects/7/proposals")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var body struct {
+			Proposals []models.Proposal `json:"proposals"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		var ids []uint
+		for _, p := range body.Proposals {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []uint{2}, listed(client, "client"))
+	assert.Equal(t, []uint{2}, listed(other, "freelancer"))
+	assert.Equal(t, []uint{1, 2}, listed(writer, "freelancer"))
+	assert.Equal(t, []uint{1, 2}, listed(99, "admin"))
+}
@@ -0,0 +1,179 @@
+package documents_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"FreeConnect/internal/documents"
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+)
+
+// Run with -update to rewrite the golden files after an intended layout change
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func issuedInvoice() documents.InvoiceData {
+	issuerID := uint(2)
+	date := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	return documents.InvoiceData{
+		Invoice: models.Invoice{
+			ID:            41,
+			InvoiceNumber: "FC-2026-000123",
+			Status:        "finalised",
+			Date:          date,
+			DueDate:       date.AddDate(0, 0, 14),
+			NetAmount:     money.New(182500, "EUR"),
+			TaxAmount:     money.New(0, "EUR"),
+			AmountDue:     money.New(182500, "EUR"),
+			TaxTreatment:  "reverse_charge",
+			TaxCountry:    "FR",
+			TaxNote:       "Reverse charge: VAT to be accounted for by the recipient (Art. 196 Directive 2006/112/EC), VAT ID FR12345678901",
+			ProjectID:     3,
+			Project:       models.Project{Title: "Checkout redesign"},
+			ClientID:      1,
+			Client:        models.User{Name: "Ana Client", CompanyName: "Client SARL", Email: "billing@client.example"},
+			IssuerID:      &issuerID,
+			Issuer:        &models.User{Name: "Jörg Freelancer", Email: "jorg@example.com"},
+			Lines: []models.InvoiceLine{
+				{Description: "UX research (interviews & synthesis)", Quantity: 1, UnitPrice: money.New(60000, "EUR"), NetAmount: money.New(60000, "EUR"), GrossAmount: money.New(60000, "EUR")},
+				{Description: "Design sprint", Quantity: 3, UnitPrice: money.New(37500, "EUR"), NetAmount: money.New(112500, "EUR"), GrossAmount: money.New(112500, "EUR")},
+				{Description: "Hosting", Quantity: 2, UnitPrice: money.New(5000, "EUR"), NetAmount: money.New(10000, "EUR"), GrossAmount: money.New(10000, "EUR")},
+			},
+		},
+		IssuerTax: &models.TaxProfile{Country: "DE", VATID: "DE123456789", IsBusiness: true},
+		ClientTax: &models.TaxProfile{Country: "FR", VATID: "FR12345678901", IsBusiness: true},
+	}
+}
+
+func draftInvoice() documents.InvoiceData {
+	date := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	return documents.InvoiceData{
+		Invoice: models.Invoice{
+			ID:        7,
+			Status:    "draft",
+			Date:      date,
+			DueDate:   date.AddDate(0, 1, 0),
+			NetAmount: money.New(100000, "EUR"),
+			TaxAmount: money.New(19000, "EUR"),
+			AmountDue: money.New(119000, "EUR"),
+			TaxRate:   1900,
+			Project:   models.Project{Title: "Logo (final artwork)"},
+			Client:    models.User{Name: "Client"},
+		},
+	}
+}
+
+func TestInvoicePDFGolden(t *testing.T) {
+	tmpl, err := documents.NewInvoiceTemplate(documents.Branding{})
+	require.NoError(t, err)
+
+	cases := map[string]documents.InvoiceData{
+		"invoice_issued.pdf": issuedInvoice(),
+		"invoice_draft.pdf":  draftInvoice(),
+	}
+	for name, data := range cases {
+		got, err := tmpl.Render(data)
+		require.NoError(t, err)
+
+		// Rendering is deterministic
+		again, err := tmpl.Render(data)
+		require.NoError(t, err)
+		assert.Equal(t, got, again, name)
+
+		golden := filepath.Join("testdata", name)
+		if *update {
+			require.NoError(t, os.WriteFile(golden, got, 0o644))
+		}
+		want, err := os.ReadFile(golden)
+		require.NoError(t, err, "missing golden file; run go test ./tests/documents -update")
+		assert.True(t, bytes.Equal(want, got), "%s differs from the golden file; run with -update if the change is intended", name)
+		assertValidXref(t, got)
+	}
+}
+
+func TestInvoicePDFBranding(t *testing.T) {
+	tmpl, err := documents.NewInvoiceTemplate(documents.Branding{
+		Name:   "Acme Studio",
+		Color:  "#FF8800",
+		Footer: "{{.Brand.Name}} - {{.Reference}} - page {{.Page}}/{{.Pages}}",
+	})
+	require.NoError(t, err)
+
+	out, err := tmpl.Render(issuedInvoice())
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "(Acme Studio)")
+	assert.Contains(t, string(out), "(Acme Studio - FC-2026-000123 - page 1/1)")
+	assert.Contains(t, string(out), "1 0.53 0 rg")
+
+	// Many lines flow onto more pages
+	data := issuedInvoice()
+	for i := 0; i < 40; i++ {
+		data.Invoice.Lines = append(data.Invoice.Lines, data.Invoice.Lines[2])
+	}
+	out, err = tmpl.Render(data)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "/Count 2")
+	assert.Contains(t, string(out), "page 2/2")
+
+	_, err = documents.NewInvoiceTemplate(documents.Branding{Color: "orange"})
+	assert.Error(t, err)
+	_, err = documents.NewInvoiceTemplate(documents.Branding{Footer: "{{.Nope"})
+	assert.Error(t, err)
+}
+
+// assertValidXref checks that every cross-reference entry points at its object
+func assertValidXref(t *testing.T, doc []byte) {
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	require.NotNil(t, m)
+	xref, _ := strconv.Atoi(string(m[1]))
+	require.True(t, bytes.HasPrefix(doc[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		assert.True(t, bytes.HasPrefix(doc[off:], []byte(strconv.Itoa(i+1)+" 0 obj")), "object %d", i+1)
+	}
+}
+
+func TestCreditNoteAndVoidPDF(t *testing.T) {
+	tmpl, err := documents.NewInvoiceTemplate(documents.Branding{})
+	require.NoError(t, err)
+
+	original := issuedInvoice().Invoice
+	note := issuedInvoice()
+	note.Invoice.ID = 42
+	note.Invoice.Kind = "credit_note"
+	note.Invoice.InvoiceNumber = "CN-2026-000001"
+	note.Invoice.CreditedInvoice = &original
+	note.Invoice.CreditReason = "Hosting was not delivered"
+	out, err := tmpl.Render(note)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "(CREDIT NOTE)")
+	assert.Contains(t, string(out), "(Credits invoice)")
+	assert.Contains(t, string(out), "(FC-2026-000123)")
+	assert.Contains(t, string(out), "(Total credited)")
+	assert.Contains(t, string(out), "(Reason for credit: Hosting was not delivered)")
+	assert.NotContains(t, string(out), "Please pay")
+	assertValidXref(t, out)
+
+	voided := issuedInvoice()
+	voidedAt := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	voided.Invoice.Status = "void"
+	voided.Invoice.VoidedAt = &voidedAt
+	voided.Invoice.VoidReason = "Issued to the wrong client"
+	out, err = tmpl.Render(voided)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "(VOID INVOICE)")
+	assert.Contains(t, string(out), "(This invoice was voided on 2026-03-05: Issued to the wrong client)")
+	assert.NotContains(t, string(out), "Please pay")
+}
@@ -0,0 +1,12 @@
+#!/bin/sh
+# Fetches the official OASIS UBL 2.1 schemas the UBL export is validated
+# against (tests/documents/ubl_test.go) into ./xsd. Commit the result.
+set -eu
+
+cd "$(dirname "$0")"
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+curl -fsSL -o "$tmp/UBL-2.1.zip" https://docs.oasis-open.org/ubl/os-UBL-2.1/UBL-2.1.zip
+rm -rf xsd
+unzip -q "$tmp/UBL-2.1.zip" 'xsd/*' -d .
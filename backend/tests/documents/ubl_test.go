@@ -0,0 +1,350 @@
+package documents_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"FreeConnect/internal/documents"
+	"FreeConnect/internal/money"
+)
+
+var ublPrefixes = map[string]string{
+	"urn:oasis:names:specification:ubl:schema:xsd:Invoice-2":                   "",
+	"urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2":                "",
+	"urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2": "cac:",
+	"urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2":     "cbc:",
+}
+
+// ublSchemas holds the official OASIS UBL 2.1 schemas; fetch them with
+// testdata/ubl-2.1/fetch.sh
+var ublSchemas = filepath.Join("testdata", "ubl-2.1", "xsd")
+
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*xmlNode
+}
+
+func (n *xmlNode) child(name string) *xmlNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *xmlNode) all(name string) []*xmlNode {
+	var out []*xmlNode
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// path follows a chain of single children, e.g. "cac:Party/cbc:EndpointID"
+func (n *xmlNode) path(p string) *xmlNode {
+	for _, name := range strings.Split(p, "/") {
+		if n = n.child(name); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// parseUBL reads the document into a tree, naming elements by their UBL
+// prefix; elements outside the UBL namespaces fail the test
+func parseUBL(t *testing.T, doc []byte) *xmlNode {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		switch el := tok.(type) {
+		case xml.StartElement:
+			prefix, ok := ublPrefixes[el.Name.Space]
+			require.True(t, ok, "element %s in unknown namespace %q", el.Name.Local, el.Name.Space)
+			n := &xmlNode{name: prefix + el.Name.Local, attrs: map[string]string{}}
+			for _, a := range el.Attr {
+				if a.Name.Space == "" {
+					n.attrs[a.Name.Local] = a.Value
+				}
+			}
+			if len(stack) == 0 {
+				root = n
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += strings.TrimSpace(string(el))
+			}
+		}
+	}
+	require.NotNil(t, root)
+	return root
+}
+
+// validateUBL validates the document against the UBL 2.1 schema of its
+// root element with xmllint. Where xmllint or the schemas aren't available
+// the test is skipped, or fails in CI and when FREECONNECT_REQUIRE_UBL_SCHEMA
+// is set, so the validation can't silently stop running there.
+func validateUBL(t *testing.T, doc []byte, root string) {
+	missing := t.Skipf
+	if os.Getenv("FREECONNECT_REQUIRE_UBL_SCHEMA") != "" || os.Getenv("CI") != "" {
+		missing = t.Fatalf
+	}
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		missing("xmllint is not installed")
+	}
+	schema := filepath.Join(ublSchemas, "maindoc", "UBL-"+root+"-2.1.xsd")
+	if _, err := os.Stat(schema); err != nil {
+		missing("missing %s; run testdata/ubl-2.1/fetch.sh", schema)
+	}
+	file := filepath.Join(t.TempDir(), root+".xml")
+	require.NoError(t, os.WriteFile(file, doc, 0o644))
+	out, err := exec.Command(xmllint, "--noout", "--nonet", "--schema", schema, file).CombinedOutput()
+	assert.NoError(t, err, "%s", out)
+}
+
+// assertUBLAmounts checks that every amount carries a currency and is a
+// decimal, which the schema's string-typed content doesn't enforce
+func assertUBLAmounts(t *testing.T, n *xmlNode) {
+	if strings.HasSuffix(n.name, "Amount") {
+		assert.NotEmpty(t, n.attrs["currencyID"], "%s without currencyID", n.name)
+		_, err := strconv.ParseFloat(n.text, 64)
+		assert.NoError(t, err, "%s is not a decimal", n.name)
+	}
+	for _, c := range n.children {
+		assertUBLAmounts(t, c)
+	}
+}
+
+func cents(t *testing.T, n *xmlNode) int64 {
+	require.NotNil(t, n)
+	f, err := strconv.ParseFloat(n.text, 64)
+	require.NoError(t, err)
+	if f < 0 {
+		return int64(f*100 - 0.5)
+	}
+	return int64(f*100 + 0.5)
+}
+
+// assertUBLTotals checks the Peppol calculation rules the export relies on
+func assertUBLTotals(t *testing.T, doc *xmlNode, lineName string) {
+	var lines int64
+	for _, l := range doc.all(lineName) {
+		lines += cents(t, l.child("cbc:LineExtensionAmount"))
+	}
+	totals := doc.child("cac:LegalMonetaryTotal")
+	taxTotal := doc.child("cac:TaxTotal")
+	var taxable, tax int64
+	for _, s := range taxTotal.all("cac:TaxSubtotal") {
+		taxable += cents(t, s.child("cbc:TaxableAmount"))
+		tax += cents(t, s.child("cbc:TaxAmount"))
+	}
+	assert.Equal(t, lines, cents(t, totals.child("cbc:LineExtensionAmount")))
+	assert.Equal(t, lines, taxable)
+	assert.Equal(t, tax, cents(t, taxTotal.child("cbc:TaxAmount")))
+	assert.Equal(t, cents(t, totals.child("cbc:TaxExclusiveAmount"))+tax, cents(t, totals.child("cbc:TaxInclusiveAmount")))
+	assert.Equal(t, cents(t, totals.child("cbc:TaxInclusiveAmount")), cents(t, totals.child("cbc:PayableAmount")))
+}
+
+func TestInvoiceUBL(t *testing.T) {
+	data := issuedInvoice()
+	out, err := documents.RenderUBL(data)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out, []byte(xml.Header)))
+
+	doc := parseUBL(t, out)
+	assert.Equal(t, "Invoice", doc.name)
+	assertUBLAmounts(t, doc)
+	assertUBLTotals(t, doc, "cac:InvoiceLine")
+
+	assert.Equal(t, "FC-2026-000123", doc.child("cbc:ID").text)
+	assert.Equal(t, "2026-03-02", doc.child("cbc:IssueDate").text)
+	assert.Equal(t, "2026-03-16", doc.child("cbc:DueDate").text)
+	assert.Equal(t, "380", doc.child("cbc:InvoiceTypeCode").text)
+	assert.Equal(t, "EUR", doc.child("cbc:DocumentCurrencyCode").text)
+	assert.Equal(t, "Checkout redesign", doc.child("cbc:BuyerReference").text)
+
+	supplier := doc.path("cac:AccountingSupplierParty/cac:Party")
+	assert.Equal(t, "jorg@example.com", supplier.child("cbc:EndpointID").text)
+	assert.Equal(t, "EM", supplier.child("cbc:EndpointID").attrs["schemeID"])
+	assert.Equal(t, "Jörg Freelancer", supplier.path("cac:PartyLegalEntity/cbc:RegistrationName").text)
+	assert.Equal(t, "DE", supplier.path("cac:PostalAddress/cac:Country/cbc:IdentificationCode").text)
+	assert.Equal(t, "DE123456789", supplier.path("cac:PartyTaxScheme/cbc:CompanyID").text)
+	customer := doc.path("cac:AccountingCustomerParty/cac:Party")
+	assert.Equal(t, "Client SARL", customer.path("cac:PartyName/cbc:Name").text)
+	assert.Equal(t, "FR12345678901", customer.path("cac:PartyTaxScheme/cbc:CompanyID").text)
+
+	// Reverse charge is category AE with its exemption reason
+	category := doc.path("cac:TaxTotal/cac:TaxSubtotal/cac:TaxCategory")
+	assert.Equal(t, "AE", category.child("cbc:ID").text)
+	assert.Equal(t, "0", category.child("cbc:Percent").text)
+	assert.Equal(t, "VATEX-EU-AE", category.child("cbc:TaxExemptionReasonCode").text)
+
+	lines := doc.all("cac:InvoiceLine")
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, "2", lines[1].child("cbc:ID").text)
+		assert.Equal(t, "3", lines[1].child("cbc:InvoicedQuantity").text)
+		assert.Equal(t, "C62", lines[1].child("cbc:InvoicedQuantity").attrs["unitCode"])
+		assert.Equal(t, "1125.00", lines[1].child("cbc:LineExtensionAmount").text)
+		assert.Equal(t, "375.00", lines[1].path("cac:Price/cbc:PriceAmount").text)
+		assert.Equal(t, "AE", lines[1].path("cac:Item/cac:ClassifiedTaxCategory/cbc:ID").text)
+	}
+
+	again, err := documents.RenderUBL(data)
+	require.NoError(t, err)
+	assert.Equal(t, out, again, "the export must be deterministic")
+}
+
+// standardRatedInvoice is an issued invoice with standard-rated VAT and a
+// single amount instead of lines
+func standardRatedInvoice() documents.InvoiceData {
+	data := draftInvoice()
+	data.Invoice.Status = "finalised"
+	data.Invoice.InvoiceNumber = "FC-2026-000124"
+	data.Invoice.TaxTreatment = "standard"
+	data.Invoice.ProjectID = 9
+	data.Invoice.Project.Title = ""
+	return data
+}
+
+// creditNote credits the last line of issuedInvoice
+func creditNote() documents.InvoiceData {
+	original := issuedInvoice().Invoice
+	data := issuedInvoice()
+	data.Invoice.ID = 42
+	data.Invoice.Kind = "credit_note"
+	data.Invoice.InvoiceNumber = "CN-2026-000001"
+	data.Invoice.CreditReason = "Hosting not delivered"
+	data.Invoice.CreditedInvoiceID = &original.ID
+	data.Invoice.CreditedInvoice = &original
+	data.Invoice.Lines = data.Invoice.Lines[2:]
+	data.Invoice.NetAmount = money.New(10000, "EUR")
+	data.Invoice.AmountDue = money.New(10000, "EUR")
+	return data
+}
+
+func TestUBLSchema(t *testing.T) {
+	for _, tc := range []struct {
+		name, root string
+		data       documents.InvoiceData
+	}{
+		{"reverse charge", "Invoice", issuedInvoice()},
+		{"standard rated", "Invoice", standardRatedInvoice()},
+		{"credit note", "CreditNote", creditNote()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := documents.RenderUBL(tc.data)
+			require.NoError(t, err)
+			validateUBL(t, out, tc.root)
+		})
+	}
+}
+
+func TestStandardRatedUBL(t *testing.T) {
+	out, err := documents.RenderUBL(standardRatedInvoice())
+	require.NoError(t, err)
+
+	doc := parseUBL(t, out)
+	assertUBLAmounts(t, doc)
+	assertUBLTotals(t, doc, "cac:InvoiceLine")
+	assert.Equal(t, "Project 9", doc.child("cbc:BuyerReference").text)
+	assert.Equal(t, "190.00", doc.path("cac:TaxTotal/cbc:TaxAmount").text)
+	category := doc.path("cac:TaxTotal/cac:TaxSubtotal/cac:TaxCategory")
+	assert.Equal(t, "S", category.child("cbc:ID").text)
+	assert.Equal(t, "19", category.child("cbc:Percent").text)
+	assert.Nil(t, category.child("cbc:TaxExemptionReason"))
+	assert.Len(t, doc.all("cac:InvoiceLine"), 1, "invoices without lines export a single line")
+}
+
+func TestCreditNoteUBL(t *testing.T) {
+	out, err := documents.RenderUBL(creditNote())
+	require.NoError(t, err)
+	doc := parseUBL(t, out)
+	assert.Equal(t, "CreditNote", doc.name)
+	assertUBLAmounts(t, doc)
+	assertUBLTotals(t, doc, "cac:CreditNoteLine")
+
+	assert.Equal(t, "381", doc.child("cbc:CreditNoteTypeCode").text)
+	assert.Nil(t, doc.child("cbc:DueDate"))
+	assert.Equal(t, "FC-2026-000123", doc.path("cac:BillingReference/cac:InvoiceDocumentReference/cbc:ID").text)
+	notes := doc.all("cbc:Note")
+	if assert.Len(t, notes, 2) {
+		assert.Equal(t, "Hosting not delivered", notes[1].text)
+	}
+	lines := doc.all("cac:CreditNoteLine")
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, "2", lines[0].child("cbc:CreditedQuantity").text)
+	}
+	assert.Equal(t, "100.00", doc.path("cac:LegalMonetaryTotal/cbc:PayableAmount").text)
+}
+
+func TestUBLRefusesUnissuedInvoices(t *testing.T) {
+	_, err := documents.RenderUBL(draftInvoice())
+	assert.ErrorIs(t, err, documents.ErrNotIssued)
+
+	void := issuedInvoice()
+	void.Invoice.Status = "void"
+	_, err = documents.RenderUBL(void)
+	assert.ErrorIs(t, err, documents.ErrNotIssued)
+}
+
+func TestUBLArchive(t *testing.T) {
+	invoice := issuedInvoice()
+	note := issuedInvoice()
+	note.Invoice.Kind = "credit_note"
+	note.Invoice.InvoiceNumber = "CN/2026/1"
+	note.Invoice.Date = time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	require.NoError(t, documents.WriteUBLArchive(&buf, []documents.InvoiceData{invoice, note}))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	if assert.Len(t, zr.File, 2) {
+		assert.Equal(t, "invoice-FC-2026-000123.xml", zr.File[0].Name)
+		assert.Equal(t, "credit-note-CN-2026-1.xml", zr.File[1].Name)
+		assert.True(t, zr.File[1].Modified.Equal(note.Invoice.Date))
+
+		f, err := zr.File[0].Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(f)
+		require.NoError(t, err)
+		expected, err := documents.RenderUBL(invoice)
+		require.NoError(t, err)
+		assert.Equal(t, expected, body)
+	}
+
+	var again bytes.Buffer
+	require.NoError(t, documents.WriteUBLArchive(&again, []documents.InvoiceData{invoice, note}))
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+
+	draft := draftInvoice()
+	assert.ErrorIs(t, documents.WriteUBLArchive(io.Discard, []documents.InvoiceData{invoice, draft}), documents.ErrNotIssued)
+}
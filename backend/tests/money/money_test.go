@@ -0,0 +1,82 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/money"
+)
+
+func TestMoney(t *testing.T) {
+	// 1) Parse / String round trip
+	m, err := money.Parse("1234.5", "eur")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123450), m.Amount)
+	assert.Equal(t, "EUR", m.Currency)
+	assert.Equal(t, "1234.50", m.String())
+
+	neg := money.MustParse("-0.05", "EUR")
+	assert.Equal(t, "-0.05", neg.String())
+
+	_, err = money.Parse("1.005", "EUR")
+	assert.Error(t, err, "more than two decimals must be rejected")
+	for _, bad := range []string{"--5", "+-5", "1.+5", "1.-5", "-"} {
+		_, err = money.Parse(bad, "EUR")
+		assert.ErrorIs(t, err, money.ErrInvalidAmount, bad)
+	}
+
+	// 2) Arithmetic keeps currencies apart
+	sum, err := m.Add(money.New(50, "EUR"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123500), sum.Amount)
+
+	_, err = m.Add(money.New(50, "USD"))
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+
+	// 3) Percent rounds half away from zero
+	assert.Equal(t, int64(13), money.New(125, "EUR").Percent(1000).Amount)   // 12.5 -> 13
+	assert.Equal(t, int64(-13), money.New(-125, "EUR").Percent(1000).Amount) // -12.5 -> -13
+	assert.Equal(t, int64(12), money.New(124, "EUR").Percent(1000).Amount)   // 12.4 -> 12
+
+	// 4) Allocate never loses a cent
+	parts, err := money.New(100, "EUR").Split(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{34, 33, 33}, []int64{parts[0].Amount, parts[1].Amount, parts[2].Amount})
+
+	parts, err = money.New(1000, "EUR").Allocate(70, 30)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(700), parts[0].Amount)
+	assert.Equal(t, int64(300), parts[1].Amount)
+
+	// 5) JSON encoding
+	data, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"1234.50","currency":"EUR"}`, string(data))
+
+	var decoded money.Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":"99.99","currency":"USD"}`), &decoded))
+	assert.Equal(t, money.New(9999, "USD"), decoded)
+
+	// Legacy clients still send bare numbers
+	assert.NoError(t, json.Unmarshal([]byte(`250.5`), &decoded))
+	assert.Equal(t, money.New(25050, money.DefaultCurrency), decoded)
+}
+
+func TestRate(t *testing.T) {
+	r, err := money.ParseRate("1.95583")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.95583", r.String())
+
+	// 100.00 EUR -> 195.58 BGN (195.583 rounds down)
+	assert.Equal(t, money.New(19558, "BGN"), money.New(10000, "EUR").Convert(r, "BGN"))
+
+	// Inverse keeps eight decimals
+	assert.Equal(t, "0.51129188", r.Inverse().String())
+
+	_, err = money.ParseRate("0")
+	assert.Error(t, err)
+	_, err = money.ParseRate("-1.2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package payments_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+)
+
+func TestFakeGateway(t *testing.T) {
+	gw := payments.NewFakeGateway()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	gw.Now = func() time.Time { return now }
+
+	// 1) Success: authorise, capture, partial then full refund
+	intent, err := gw.CreateIntent(payments.IntentRequest{Amount: money.New(10000, "EUR")})
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentRequiresCapture, intent.Status)
+
+	intent, err = gw.Capture(intent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentSucceeded, intent.Status)
+
+	_, err = gw.Refund(intent.ID, money.New(4000, "EUR"))
+	assert.NoError(t, err)
+	_, err = gw.Refund(intent.ID, money.New(7000, "EUR"))
+	assert.ErrorIs(t, err, payments.ErrRefundTooLarge)
+	_, err = gw.Refund(intent.ID, money.New(6000, "EUR"))
+	assert.NoError(t, err)
+
+	status, err := gw.Status(intent.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentRefunded, status.Status)
+
+	// 2) Decline happens at authorisation and can't be captured
+	declined, err := gw.CreateIntent(payments.IntentRequest{Amount: money.New(500, "EUR"), PaymentToken: payments.FakeTokenDecline})
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentDeclined, declined.Status)
+	_, err = gw.Capture(declined.ID)
+	assert.ErrorIs(t, err, payments.ErrInvalidState)
+
+	// 3) Delay keeps the capture processing until the delay has passed
+	delayed, err := gw.CreateIntent(payments.IntentRequest{Amount: money.New(500, "EUR"), PaymentToken: payments.FakeTokenDelay})
+	assert.NoError(t, err)
+	delayed, err = gw.Capture(delayed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentProcessing, delayed.Status)
+
+	now = now.Add(gw.Delay)
+	delayed, err = gw.Status(delayed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentSucceeded, delayed.Status)
+
+	// 4) Unknown intents
+	_, err = gw.Status("missing")
+	assert.ErrorIs(t, err, payments.ErrIntentNotFound)
+}
@@ -0,0 +1,30 @@
+package payments_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+)
+
+func TestFakePayoutProvider(t *testing.T) {
+	p := payments.NewFakePayoutProvider()
+
+	req := payments.PayoutRequest{Amount: money.New(5000, "EUR"), Method: "paypal", Destination: "me@example.com", Reference: "withdrawal-1"}
+	first, err := p.Send(req)
+	assert.NoError(t, err)
+	assert.Equal(t, payments.PayoutPaid, first.Status)
+
+	// Same reference is the same payout
+	again, err := p.Send(req)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, int64(5000), p.Sent("EUR").Amount)
+
+	failed, err := p.Send(payments.PayoutRequest{Amount: money.New(100, "EUR"), Destination: "fail@example.com", Reference: "withdrawal-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, payments.PayoutFailed, failed.Status)
+	assert.NotEmpty(t, failed.FailureReason)
+}
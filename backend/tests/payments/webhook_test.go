@@ -0,0 +1,32 @@
+package payments_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/payments"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"id":"evt_1","type":"payment_intent.succeeded","data":{"intent_id":"pi_1","status":"succeeded"}}`)
+	now := time.Unix(1_700_000_000, 0)
+
+	header := payments.SignPayload(secret, body, now)
+	assert.NoError(t, payments.VerifySignature(secret, header, body, now))
+
+	// Tampered body, wrong secret, stale timestamp and garbage are rejected
+	assert.ErrorIs(t, payments.VerifySignature(secret, header, append(body, ' '), now), payments.ErrInvalidSignature)
+	assert.ErrorIs(t, payments.VerifySignature("other", header, body, now), payments.ErrInvalidSignature)
+	assert.ErrorIs(t, payments.VerifySignature(secret, header, body, now.Add(payments.SignatureTolerance+time.Second)), payments.ErrInvalidSignature)
+	assert.ErrorIs(t, payments.VerifySignature(secret, "nonsense", body, now), payments.ErrInvalidSignature)
+
+	evt, err := payments.ParseEvent(body)
+	assert.NoError(t, err)
+	assert.Equal(t, payments.IntentSucceeded, evt.Intent().Status)
+
+	_, err = payments.ParseEvent([]byte(`{"id":"evt_2"}`))
+	assert.ErrorIs(t, err, payments.ErrInvalidEvent)
+}
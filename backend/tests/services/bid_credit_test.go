@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestBidCredits(t *testing.T) {
+	db := tests.SetupTestDB()
+	gateway := payments.NewFakeGateway()
+	credits := services.NewBidCreditService(repositories.NewBidCreditRepository(db), gateway)
+	propService := services.NewProposalService(repositories.NewProposalRepository(db))
+	projService := services.NewProjectService(repositories.NewProjectRepository(db))
+
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Spender", Email: fmt.Sprintf("spender-%d@credits.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	client := models.User{Name: "Poster", Email: fmt.Sprintf("poster-%d@credits.test", stamp), PasswordHash: "x", Role: "client"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	assert.NoError(t, db.Create(&client).Error)
+	newProject := func(title string) models.Project {
+		project := models.Project{Title: title, Description: title, Duration: 30, Budget: money.New(300000, "EUR"), ClientID: client.ID}
+		assert.NoError(t, db.Create(&project).Error)
+		return project
+	}
+	bid := func(projectID uint) (models.Proposal, error) {
+		proposal := models.Proposal{ProposalText: "Bid", EstimatedDuration: 10, BidAmount: money.New(250000, "EUR"),
+			ProjectID: projectID, FreelancerID: freelancer.ID}
+		return proposal, propService.CreateProposal(&proposal)
+	}
+	balance := func() int {
+		b, err := credits.GetBalance(freelancer.ID)
+		assert.NoError(t, err)
+		return b.Balance
+	}
+
+	// 1) The monthly allowance opens the account; the budget sets the cost
+	assert.Equal(t, services.MonthlyBidCredits, balance())
+	first := newProject("Cancelled")
+	cost, err := credits.GetProposalCost(first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, cost)
+
+	// 2) Bidding spends credits, once per project
+	_, err = bid(first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, services.MonthlyBidCredits-6, balance())
+	_, err = bid(first.ID)
+	assert.ErrorIs(t, err, services.ErrDuplicateProposal)
+
+	// 3) Without enough credits no proposal is made
+	for i := 0; i < 2; i++ {
+		_, err = bid(newProject(fmt.Sprintf("Spent %d", i)).ID)
+		assert.NoError(t, err)
+	}
+	broke := newProject("Too expensive")
+	_, err = bid(broke.ID)
+	assert.ErrorIs(t, err, services.ErrInsufficientBidCredits)
+	var made int64
+	assert.NoError(t, db.Model(&models.Proposal{}).Where("project_id = ?", broke.ID).Count(&made).Error)
+	assert.Zero(t, made)
+
+	// 4) Top-ups add credits once the payment succeeds
+	purchase, err := credits.BuyCredits(freelancer.ID, "small", "credit_card", payments.FakeTokenDecline)
+	assert.NoError(t, err)
+	assert.Equal(t, "failed", purchase.Status)
+	purchase, err = credits.BuyCredits(freelancer.ID, "small", "credit_card", payments.FakeTokenSuccess)
+	assert.NoError(t, err)
+	assert.Equal(t, "succeeded", purchase.Status)
+	assert.Equal(t, 12, balance())
+	_, err = credits.BuyCredits(freelancer.ID, "huge", "credit_card", payments.FakeTokenSuccess)
+	assert.ErrorIs(t, err, services.ErrUnknownCreditPack)
+
+	// 5) Cancelling a project without a hire refunds its bids, once
+	first.Status = "cancelled"
+	assert.NoError(t, projService.UpdateProject(&first))
+	assert.Equal(t, 18, balance())
+	assert.NoError(t, projService.UpdateProject(&first))
+	assert.Equal(t, 18, balance())
+
+	// 6) The next month brings a new allowance
+	lastMonth := time.Now().UTC().AddDate(0, -1, 0)
+	assert.NoError(t, db.Model(&models.BidCreditAccount{}).Where("user_id = ?", freelancer.ID).
+		Update("allowance_month", lastMonth).Error)
+	assert.Equal(t, 18+services.MonthlyBidCredits, balance())
+
+	ledger, err := credits.GetLedger(freelancer.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, ledger, 7) {
+		assert.Equal(t, "allowance", ledger[0].Kind)
+		assert.Equal(t, "refund", ledger[1].Kind)
+		assert.Equal(t, 6, ledger[1].Credits)
+		assert.Equal(t, "top_up", ledger[2].Kind)
+		assert.Equal(t, "proposal", ledger[3].Kind)
+		assert.Equal(t, -6, ledger[3].Credits)
+		assert.Equal(t, services.MonthlyBidCredits, ledger[6].BalanceAfter)
+	}
+}
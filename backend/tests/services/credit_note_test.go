@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestCreditNotesAndVoiding(t *testing.T) {
+	db := tests.SetupTestDB()
+	invoices := services.NewInvoiceService(repositories.NewInvoiceRepository(db),
+		services.InvoiceNumbering{Format: "CT-{YYYY}-{SEQ}", CreditNoteFormat: "CTN-{YYYY}-{SEQ:6}"})
+
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Corrector", Email: fmt.Sprintf("corrector-%d@credit.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	project := models.Project{Title: "Credits", Description: "Credits", Duration: 10, ClientID: 1, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+	for _, budget := range []int64{40000, 60000} {
+		task := models.Task{Title: "Work", Description: "Work", Deadline: time.Now(), Budget: money.New(budget, "EUR"), Status: "completed", ProjectID: project.ID}
+		assert.NoError(t, db.Create(&task).Error)
+	}
+	issue := func() *models.Invoice {
+		draft, err := invoices.GenerateFromTasks(project.ID, freelancer.ID, nil, time.Now().AddDate(0, 0, 14))
+		assert.NoError(t, err)
+		invoice, err := invoices.FinaliseInvoice(draft.ID)
+		assert.NoError(t, err)
+		return invoice
+	}
+	invoice := issue()
+
+	// 1) A partial credit note gets its own series and references the invoice
+	partial := money.New(30000, "EUR")
+	_, err := invoices.CreateCreditNote(invoice.ID, &partial, "")
+	assert.ErrorIs(t, err, services.ErrReasonRequired)
+	note, err := invoices.CreateCreditNote(invoice.ID, &partial, "Discount for the delay")
+	assert.NoError(t, err)
+	assert.Equal(t, "credit_note", note.Kind)
+	assert.Equal(t, "finalised", note.Status)
+	assert.Regexp(t, fmt.Sprintf(`^CTN-%d-\d{6}$`, time.Now().UTC().Year()), note.InvoiceNumber)
+	assert.Equal(t, invoice.ID, *note.CreditedInvoiceID)
+	assert.Equal(t, int64(30000), note.AmountDue.Amount)
+
+	// 2) Credited invoices can't be voided, and credits can't exceed the invoice
+	_, err = invoices.VoidInvoice(invoice.ID, "Wrong client")
+	assert.ErrorIs(t, err, services.ErrInvoiceNotVoidable)
+	tooMuch := money.New(70001, "EUR")
+	_, err = invoices.CreateCreditNote(invoice.ID, &tooMuch, "Too much")
+	assert.ErrorIs(t, err, services.ErrInvalidCreditNote)
+	otherCurrency := money.New(1000, "USD")
+	_, err = invoices.CreateCreditNote(invoice.ID, &otherCurrency, "Wrong currency")
+	assert.ErrorIs(t, err, services.ErrInvalidCreditNote)
+	_, err = invoices.CreateCreditNote(note.ID, nil, "Credit of a credit")
+	assert.ErrorIs(t, err, services.ErrInvoiceNotCreditable)
+
+	// 3) Crediting the rest settles the invoice and frees its tasks
+	rest, err := invoices.CreateCreditNote(invoice.ID, nil, "Project cancelled")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(70000), rest.NetAmount.Amount)
+	credited, err := invoices.GetInvoiceByID(invoice.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "credited", credited.PaymentStatus)
+	assert.Equal(t, int64(100000), credited.AmountDue.Amount, "the original stays as issued")
+	notes, err := invoices.GetCreditNotes(invoice.ID)
+	assert.NoError(t, err)
+	assert.Len(t, notes, 2)
+	_, err = invoices.CreateCreditNote(invoice.ID, nil, "Again")
+	assert.ErrorIs(t, err, services.ErrInvalidCreditNote)
+
+	// 4) Voiding keeps the number, frees the tasks and ends all changes
+	second := issue()
+	_, err = invoices.VoidInvoice(second.ID, "")
+	assert.ErrorIs(t, err, services.ErrReasonRequired)
+	voided, err := invoices.VoidInvoice(second.ID, "Issued twice")
+	assert.NoError(t, err)
+	assert.Equal(t, "void", voided.Status)
+	assert.Equal(t, second.InvoiceNumber, voided.InvoiceNumber)
+	assert.NotNil(t, voided.VoidedAt)
+	_, err = invoices.VoidInvoice(second.ID, "Again")
+	assert.ErrorIs(t, err, services.ErrInvoiceNotVoidable)
+	_, err = invoices.CreateCreditNote(second.ID, nil, "Credit a void")
+	assert.ErrorIs(t, err, services.ErrInvoiceNotCreditable)
+	voided.DueDate = voided.DueDate.AddDate(0, 0, 7)
+	assert.ErrorIs(t, invoices.UpdateInvoice(voided), services.ErrInvoiceImmutable)
+	assert.ErrorIs(t, invoices.DeleteInvoice(second.ID), services.ErrInvoiceImmutable)
+
+	third := issue()
+	assert.Len(t, third.Lines, 2)
+
+	// 5) Paid invoices must be credited rather than voided; what was paid
+	// becomes client credit
+	markPaid := func(invoice *models.Invoice, amount int64, status string) {
+		assert.NoError(t, db.Model(invoice).Updates(map[string]interface{}{
+			"payment_status": status, "amount_paid_amount": amount, "amount_paid_currency": "EUR"}).Error)
+	}
+	credits := func(invoice *models.Invoice) []models.ClientCredit {
+		var credits []models.ClientCredit
+		assert.NoError(t, db.Where("invoice_id = ?", invoice.ID).Find(&credits).Error)
+		return credits
+	}
+	markPaid(third, third.AmountDue.Amount, "paid")
+	_, err = invoices.VoidInvoice(third.ID, "Too late")
+	assert.ErrorIs(t, err, services.ErrInvoiceNotVoidable)
+	full, err := invoices.CreateCreditNote(third.ID, nil, "Refunded")
+	assert.NoError(t, err)
+	assert.Len(t, full.Lines, 2, "a full credit mirrors the lines")
+	paid, err := invoices.GetInvoiceByID(third.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "paid", paid.PaymentStatus)
+	if assert.Len(t, credits(third), 1) {
+		assert.Equal(t, third.AmountDue, credits(third)[0].Amount)
+	}
+
+	// 6) So must partly paid ones; only the paid part beyond what is left
+	// unpaid becomes credit
+	fourth := issue()
+	markPaid(fourth, 20000, "pending")
+	_, err = invoices.VoidInvoice(fourth.ID, "Not needed")
+	assert.ErrorIs(t, err, services.ErrInvoiceNotVoidable)
+	unpaid := money.New(80000, "EUR")
+	_, err = invoices.CreateCreditNote(fourth.ID, &unpaid, "Only the first task")
+	assert.NoError(t, err)
+	assert.Empty(t, credits(fourth), "the unpaid part is credited first")
+	_, err = invoices.CreateCreditNote(fourth.ID, nil, "Nothing delivered")
+	assert.NoError(t, err)
+	if assert.Len(t, credits(fourth), 1) {
+		assert.Equal(t, money.New(20000, "EUR"), credits(fourth)[0].Amount)
+	}
+	credited, err = invoices.GetInvoiceByID(fourth.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "credited", credited.PaymentStatus)
+}
@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestExchangeRateService(t *testing.T) {
+	db := tests.SetupTestDB()
+	rateRepo := repositories.NewExchangeRateRepository(db)
+	rateService := services.NewExchangeRateService(rateRepo)
+
+	// 1) Import rates from CSV
+	csv := strings.Join([]string{
+		"base_currency,quote_currency,rate,effective_date",
+		"EUR,BGN,1.95583,2026-01-01",
+		"EUR,USD,1.10,2026-01-01",
+		"EUR,USD,1.20,2026-02-01",
+	}, "\n")
+	imported, err := rateService.ImportCSV(strings.NewReader(csv), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, imported)
+
+	// 2) Direct conversion uses the rate effective on the given date
+	jan := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	conv, err := rateService.Convert(money.New(10000, "EUR"), "USD", jan)
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(11000, "USD"), conv.Amount)
+	assert.NotNil(t, conv.ExchangeRateID)
+
+	feb := time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)
+	conv, err = rateService.Convert(money.New(10000, "EUR"), "USD", feb)
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(12000, "USD"), conv.Amount)
+
+	// 3) The inverse direction is derived from the stored pair
+	conv, err = rateService.Convert(money.New(195583, "BGN"), "EUR", jan)
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(100000, "EUR"), conv.Amount)
+
+	// 4) Same currency needs no rate
+	conv, err = rateService.Convert(money.New(500, "EUR"), "EUR", jan)
+	assert.NoError(t, err)
+	assert.Nil(t, conv.ExchangeRateID)
+
+	// 5) No rate before the first effective date
+	_, err = rateService.Convert(money.New(100, "EUR"), "USD", time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, services.ErrNoExchangeRate)
+
+	// 6) A bad row rejects the whole file
+	_, err = rateService.ImportCSV(strings.NewReader("base_currency,quote_currency,rate,effective_date\nEUR,JPY,160,2026-01-01"), "test")
+	assert.Error(t, err)
+}
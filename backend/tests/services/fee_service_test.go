@@ -0,0 +1,101 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestFeeService(t *testing.T) {
+	db := tests.SetupTestDB()
+	txRepo := repositories.NewTransactionRepository(db)
+	feeService := services.NewFeeService(repositories.NewFeeRuleRepository(db), txRepo,
+		services.NewExchangeRateService(repositories.NewExchangeRateRepository(db)))
+	txService := services.NewTransactionService(txRepo, payments.NewFakeGateway())
+
+	// Rules are scoped to categories unique to this run so they don't leak
+	// into other tests
+	suffix := time.Now().UnixNano()
+	design := fmt.Sprintf("design-%d", suffix)
+	dev := fmt.Sprintf("dev-%d", suffix)
+
+	users := make([]models.User, 2)
+	for i, role := range []string{"client", "freelancer"} {
+		users[i] = models.User{Name: role, Email: fmt.Sprintf("%s-%d@fees.test", role, suffix), PasswordHash: "x", Role: role}
+		assert.NoError(t, db.Create(&users[i]).Error)
+	}
+	client, freelancer := users[0], users[1]
+
+	newProject := func(category string) models.Project {
+		p := models.Project{Title: "Fees", Description: "Fees", Budget: money.New(100000, "EUR"), Duration: 5, Category: category, ClientID: client.ID}
+		assert.NoError(t, db.Create(&p).Error)
+		return p
+	}
+	pay := func(project models.Project, amount int64) models.Transaction {
+		tx := models.Transaction{
+			Amount:        money.New(amount, "EUR"),
+			PaymentMethod: "credit_card",
+			ClientID:      client.ID,
+			FreelancerID:  freelancer.ID,
+			ProjectID:     project.ID,
+		}
+		assert.NoError(t, txService.CreateTransaction(&tx, payments.FakeTokenSuccess))
+		_, err := txService.CaptureTransaction(tx.ID)
+		assert.NoError(t, err)
+		return tx
+	}
+
+	// 1) Validation
+	err := feeService.CreateRule(&models.FeeRule{Name: "bad", Type: "flat", Category: design})
+	assert.ErrorIs(t, err, services.ErrInvalidFeeRule)
+
+	// 2) Percentage + flat rules add up, each stored as its own ledger line
+	percent := models.FeeRule{Name: "Commission", Type: "percentage", Category: design, Percent: 1000, Active: true}
+	flat := models.FeeRule{Name: "Processing", Type: "flat", Category: design, FlatAmount: money.New(100, "EUR"), Active: true}
+	assert.NoError(t, feeService.CreateRule(&percent))
+	assert.NoError(t, feeService.CreateRule(&flat))
+	defer feeService.DeleteRule(percent.ID)
+	defer feeService.DeleteRule(flat.ID)
+
+	tx := pay(newProject(design), 10000)
+	assert.Equal(t, int64(1100), tx.FeeAmount.Amount)
+	assert.Len(t, tx.Fees, 2)
+
+	var f models.User
+	assert.NoError(t, db.First(&f, freelancer.ID).Error)
+	assert.Equal(t, int64(8900), f.Earnings.Amount)
+
+	// 3) Tiered by lifetime billing: 20% until 500.00 has been billed, then 10%
+	tiered := models.FeeRule{Name: "Tiered", Type: "tiered", Category: dev, Active: true, Tiers: []models.FeeTier{
+		{MinBilling: money.New(0, "EUR"), Percent: 2000},
+		{MinBilling: money.New(50000, "EUR"), Percent: 1000},
+	}}
+	assert.NoError(t, feeService.CreateRule(&tiered))
+	defer feeService.DeleteRule(tiered.ID)
+
+	devProject := newProject(dev)
+	first := pay(devProject, 60000)
+	assert.Equal(t, int64(12000), first.FeeAmount.Amount)
+	// Lifetime billing is now 100.00 + 600.00
+	second := pay(devProject, 10000)
+	assert.Equal(t, int64(1000), second.FeeAmount.Amount)
+
+	// 4) The revenue report includes the fees
+	reports := services.NewReportService(txRepo, services.NewExchangeRateService(repositories.NewExchangeRateRepository(db)))
+	from := time.Now().AddDate(0, 0, -1)
+	report, err := reports.PlatformRevenue(from, time.Now().AddDate(0, 0, 1), "EUR", "day")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, report.Total.Amount, int64(1100+12000+1000))
+
+	_, err = reports.PlatformRevenue(from, time.Now(), "EUR", "year")
+	assert.ErrorIs(t, err, services.ErrInvalidInterval)
+}
@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestIdempotencyService(t *testing.T) {
+	db := tests.SetupTestDB()
+	idem := services.NewIdempotencyService(repositories.NewIdempotencyRepository(db))
+
+	key := fmt.Sprintf("key-%d", time.Now().UnixNano())
+	body := []byte(`{"amount":"50.00"}`)
+
+	// 1) First use claims the key
+	record, replay, err := idem.Begin(1, key, "POST", "/api/transactions", body)
+	assert.NoError(t, err)
+	assert.False(t, replay)
+
+	// 2) A concurrent retry while the first request runs is rejected
+	_, _, err = idem.Begin(1, key, "POST", "/api/transactions", body)
+	assert.ErrorIs(t, err, services.ErrIdempotencyKeyInProgress)
+
+	// 3) Once completed, the stored response is replayed
+	assert.NoError(t, idem.Complete(record, http.StatusCreated, []byte(`{"transaction_id":7}`)))
+	stored, replay, err := idem.Begin(1, key, "POST", "/api/transactions", body)
+	assert.NoError(t, err)
+	assert.True(t, replay)
+	assert.Equal(t, http.StatusCreated, stored.StatusCode)
+	assert.Equal(t, `{"transaction_id":7}`, string(stored.ResponseBody))
+
+	// 4) Reusing the key with a different body is a conflict
+	_, _, err = idem.Begin(1, key, "POST", "/api/transactions", []byte(`{"amount":"60.00"}`))
+	assert.ErrorIs(t, err, services.ErrIdempotencyKeyMismatch)
+
+	// 5) Keys are scoped per user
+	_, replay, err = idem.Begin(2, key, "POST", "/api/transactions", body)
+	assert.NoError(t, err)
+	assert.False(t, replay)
+
+	// 6) A released key can be claimed again
+	other := key + "-released"
+	record, _, err = idem.Begin(1, other, "POST", "/api/invoices", body)
+	assert.NoError(t, err)
+	assert.NoError(t, idem.Release(record))
+	_, replay, err = idem.Begin(1, other, "POST", "/api/invoices", body)
+	assert.NoError(t, err)
+	assert.False(t, replay)
+}
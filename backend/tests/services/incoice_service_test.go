@@ -1,12 +1,15 @@
 package services_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 	"FreeConnect/internal/repositories"
 	"FreeConnect/internal/services"
 	"FreeConnect/tests"
@@ -15,41 +18,76 @@ import (
 func TestInvoiceService(t *testing.T) {
 	db := tests.SetupTestDB()
 	invoiceRepo := repositories.NewInvoiceRepository(db)
-	invoiceService := services.NewInvoiceService(invoiceRepo)
+	invoiceService := services.NewInvoiceService(invoiceRepo, services.InvoiceNumbering{Format: "INV-{YYYY}-{SEQ:6}"})
 
-	// 1) Create a sample Invoice
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Invoicer", Email: fmt.Sprintf("invoicer-%d@invoice.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	client := models.User{Name: "Invoiced", Email: fmt.Sprintf("invoiced-%d@invoice.test", stamp), PasswordHash: "x", Role: "client"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	assert.NoError(t, db.Create(&client).Error)
+	project := models.Project{Title: "Invoiced", Description: "Invoiced", Duration: 10, ClientID: client.ID, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+	issuerID := freelancer.ID
+
+	// 1) Create a sample Invoice; it starts as a draft without a number and
+	// charges the project's client
 	invoice := models.Invoice{
-		InvoiceNumber: "INV-001",
-		AmountDue:     500.0,
-		PaymentStatus: "pending",
-		DueDate:       time.Now().AddDate(0, 0, 7),
-		ProjectID:     1, // optional: if a Project with ID=1 exists/required
-		ClientID:      2, // optional: if a User with ID=2 exists/required
+		AmountDue: money.New(50000, "EUR"),
+		DueDate:   time.Now().AddDate(0, 0, 7),
+		ProjectID: project.ID,
+		ClientID:  freelancer.ID,
+		IssuerID:  &issuerID,
 	}
 
 	err := invoiceService.CreateInvoice(&invoice)
 	assert.NoError(t, err)
 	assert.NotZero(t, invoice.ID)
+	assert.Equal(t, "draft", invoice.Status)
+	assert.Empty(t, invoice.InvoiceNumber)
+	assert.Equal(t, client.ID, invoice.ClientID)
+
+	// Only the project's hired freelancer issues invoices, for projects that exist
+	stranger := client.ID
+	foreign := models.Invoice{AmountDue: money.New(50000, "EUR"), DueDate: time.Now(), ProjectID: project.ID, IssuerID: &stranger}
+	assert.ErrorIs(t, invoiceService.CreateInvoice(&foreign), services.ErrNotProjectFreelancer)
+	missing := models.Invoice{AmountDue: money.New(50000, "EUR"), DueDate: time.Now(), ProjectID: 0, IssuerID: &issuerID}
+	assert.ErrorIs(t, invoiceService.CreateInvoice(&missing), gorm.ErrRecordNotFound)
+
+	// 2) Finalise and retrieve Invoice
+	finalised, err := invoiceService.FinaliseInvoice(invoice.ID)
+	assert.NoError(t, err)
+	_, err = invoiceService.FinaliseInvoice(invoice.ID)
+	assert.ErrorIs(t, err, services.ErrInvoiceNotDraft)
 
-	// 2) Retrieve Invoice
 	retrieved, err := invoiceService.GetInvoiceByID(invoice.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, "INV-001", retrieved.InvoiceNumber)
+	assert.Equal(t, "finalised", retrieved.Status)
+	assert.Equal(t, finalised.InvoiceNumber, retrieved.InvoiceNumber)
+	assert.Regexp(t, fmt.Sprintf(`^INV-%d-\d{6}$`, time.Now().UTC().Year()), retrieved.InvoiceNumber)
+	invoice = *retrieved
 
-	// 3) Update Invoice
+	// 3) The payment status follows payments, not updates
 	invoice.PaymentStatus = "paid"
 	err = invoiceService.UpdateInvoice(&invoice)
 	assert.NoError(t, err)
 
 	updated, err := invoiceService.GetInvoiceByID(invoice.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, "paid", updated.PaymentStatus)
+	assert.Equal(t, "pending", updated.PaymentStatus)
+
+	// 4) Finalised invoices can't be changed or deleted
+	invoice = *updated
+	invoice.NetAmount = money.New(40000, "EUR")
+	assert.ErrorIs(t, invoiceService.UpdateInvoice(&invoice), services.ErrInvoiceImmutable)
+	assert.ErrorIs(t, invoiceService.DeleteInvoice(invoice.ID), services.ErrInvoiceImmutable)
 
-	// 4) Delete Invoice
-	err = invoiceService.DeleteInvoice(invoice.ID)
+	// 5) Delete a draft Invoice
+	draft := models.Invoice{AmountDue: money.New(50000, "EUR"), DueDate: time.Now(), ProjectID: project.ID, IssuerID: &issuerID}
+	assert.NoError(t, invoiceService.CreateInvoice(&draft))
+	err = invoiceService.DeleteInvoice(draft.ID)
 	assert.NoError(t, err)
 
-	// 5) Confirm deletion
-	_, err = invoiceService.GetInvoiceByID(invoice.ID)
+	// 6) Confirm deletion
+	_, err = invoiceService.GetInvoiceByID(draft.ID)
 	assert.Error(t, err, "Should fail after deletion")
 }
@@ -0,0 +1,86 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestInvoiceGenerationFromTasks(t *testing.T) {
+	db := tests.SetupTestDB()
+	invoices := services.NewInvoiceService(repositories.NewInvoiceRepository(db), services.InvoiceNumbering{})
+
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Biller", Email: fmt.Sprintf("biller-%d@lines.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	project := models.Project{Title: "Lines", Description: "Lines", Duration: 10, ClientID: 1, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	newTask := func(title, status string, budget int64) models.Task {
+		task := models.Task{Title: title, Description: title, Deadline: time.Now(), Budget: money.New(budget, "EUR"), Status: status, ProjectID: project.ID}
+		assert.NoError(t, db.Create(&task).Error)
+		return task
+	}
+	design := newTask("Design", "completed", 40000)
+	build := newTask("Build", "completed", 60000)
+	newTask("Deploy", "in_progress", 20000)
+
+	// 1) Only the project's freelancer can bill it
+	_, err := invoices.GenerateFromTasks(project.ID, 1, nil, time.Now())
+	assert.ErrorIs(t, err, services.ErrNotProjectFreelancer)
+
+	// 2) Completed tasks become one line each
+	invoice, err := invoices.GenerateFromTasks(project.ID, freelancer.ID, nil, time.Now().AddDate(0, 0, 14))
+	assert.NoError(t, err)
+	assert.Len(t, invoice.Lines, 2)
+	assert.Equal(t, int64(100000), invoice.NetAmount.Amount)
+	assert.Equal(t, design.ID, *invoice.Lines[0].TaskID)
+
+	// 3) They can't be billed twice
+	_, err = invoices.GenerateFromTasks(project.ID, freelancer.ID, nil, time.Now())
+	assert.ErrorIs(t, err, services.ErrNothingToInvoice)
+	_, err = invoices.GenerateFromTasks(project.ID, freelancer.ID, []uint{build.ID}, time.Now())
+	assert.ErrorIs(t, err, services.ErrTaskNotBillable)
+
+	// 4) Removing a line releases its task and recalculates the totals
+	stored, err := invoices.GetInvoiceByID(invoice.ID)
+	assert.NoError(t, err)
+	stored.Lines = stored.Lines[:1]
+	stored.Lines = append(stored.Lines, models.InvoiceLine{Description: "Hosting", Quantity: 3, UnitPrice: money.New(1500, "EUR")})
+	assert.NoError(t, invoices.UpdateInvoice(stored))
+	assert.Equal(t, int64(44500), stored.NetAmount.Amount)
+
+	again, err := invoices.GenerateFromTasks(project.ID, freelancer.ID, []uint{build.ID}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(60000), again.AmountDue.Amount)
+
+	// 5) Replacing the lines keeps the tasks they carry billed, once each
+	stored, err = invoices.GetInvoiceByID(again.ID)
+	assert.NoError(t, err)
+	stored.Lines = []models.InvoiceLine{{Description: "Build, revised", Quantity: 1, UnitPrice: money.New(55000, "EUR"), TaskID: &build.ID}}
+	assert.NoError(t, invoices.UpdateInvoice(stored))
+	_, err = invoices.GenerateFromTasks(project.ID, freelancer.ID, []uint{build.ID}, time.Now())
+	assert.ErrorIs(t, err, services.ErrTaskNotBillable)
+
+	stored.Lines = append(stored.Lines, models.InvoiceLine{Description: "Again", Quantity: 1, UnitPrice: money.New(100, "EUR"), TaskID: &build.ID})
+	assert.ErrorIs(t, invoices.UpdateInvoice(stored), services.ErrInvalidInvoiceLine)
+	stored.Lines = []models.InvoiceLine{{Description: "Design", Quantity: 1, UnitPrice: money.New(100, "EUR"), TaskID: &design.ID}}
+	assert.ErrorIs(t, invoices.UpdateInvoice(stored), services.ErrInvalidInvoiceLine)
+
+	// 6) Lines must be valid
+	bad := models.Invoice{
+		DueDate:   time.Now(),
+		ProjectID: project.ID,
+		ClientID:  1,
+		Lines:     []models.InvoiceLine{{Description: "Nothing", Quantity: 0, UnitPrice: money.New(100, "EUR")}},
+	}
+	assert.ErrorIs(t, invoices.CreateInvoice(&bad), services.ErrInvalidInvoiceLine)
+}
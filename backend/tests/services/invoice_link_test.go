@@ -0,0 +1,170 @@
+package services_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestInvoiceLinks(t *testing.T) {
+	db := tests.SetupTestDB()
+	invoices := services.NewInvoiceService(repositories.NewInvoiceRepository(db), services.InvoiceNumbering{Format: "L-{YYYY}-{SEQ}"})
+	gateway := payments.NewFakeGateway()
+	links := services.NewInvoiceLinkService(repositories.NewInvoiceLinkRepository(db), invoices, gateway, "test-secret")
+
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Sharer", Email: fmt.Sprintf("sharer-%d@link.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	client := models.User{Name: "Payer", Email: fmt.Sprintf("payer-%d@link.test", stamp), PasswordHash: "x", Role: "client"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	assert.NoError(t, db.Create(&client).Error)
+	project := models.Project{Title: "Shared", Description: "Shared", Duration: 10, ClientID: client.ID, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	invoice := models.Invoice{NetAmount: money.New(40000, "EUR"), DueDate: time.Now().AddDate(0, 0, 14),
+		ProjectID: project.ID, ClientID: client.ID, IssuerID: &freelancer.ID}
+	assert.NoError(t, invoices.CreateInvoice(&invoice))
+
+	// 1) Drafts can't be shared, and lifetimes are bounded
+	_, _, err := links.CreateLink(invoice.ID, freelancer.ID, 0)
+	assert.ErrorIs(t, err, services.ErrInvoiceNotShared)
+	_, err = invoices.FinaliseInvoice(invoice.ID)
+	assert.NoError(t, err)
+	_, _, err = links.CreateLink(invoice.ID, freelancer.ID, services.MaxInvoiceLinkTTL+time.Hour)
+	assert.ErrorIs(t, err, services.ErrInvalidLinkExpiry)
+
+	link, token, err := links.CreateLink(invoice.ID, freelancer.ID, 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	// 2) Opening shows the invoice and counts the open
+	view, data, err := links.OpenInvoice(token)
+	assert.NoError(t, err)
+	if assert.NotNil(t, view) {
+		assert.True(t, view.Payable)
+		assert.Equal(t, "Sharer", view.Issuer)
+		assert.Equal(t, data.Invoice.AmountDue, view.Outstanding)
+	}
+	_, _, err = links.OpenInvoice(token)
+	assert.NoError(t, err)
+	var reloaded models.Invoice
+	assert.NoError(t, db.First(&reloaded, invoice.ID).Error)
+	assert.Equal(t, 2, reloaded.OpenCount)
+	assert.NotNil(t, reloaded.FirstOpenedAt)
+
+	// 3) Tampered tokens are rejected
+	_, _, err = links.OpenInvoice(token + "x")
+	assert.ErrorIs(t, err, services.ErrInvalidInvoiceLink)
+	_, _, err = links.OpenInvoice(fmt.Sprintf("%d.%d.forged", link.ID, link.ExpiresAt.Add(time.Hour).Unix()))
+	assert.ErrorIs(t, err, services.ErrInvalidInvoiceLink)
+
+	// 4) A declined payment leaves the invoice open
+	payment, err := links.PayInvoice(token, "credit_card", payments.FakeTokenDecline)
+	assert.NoError(t, err)
+	assert.Equal(t, "failed", payment.Status)
+	assert.NoError(t, db.First(&reloaded, invoice.ID).Error)
+	assert.Equal(t, "pending", reloaded.PaymentStatus)
+
+	// 5) While a payment is processing the invoice can't be paid again
+	payment, err = links.PayInvoice(token, "credit_card", payments.FakeTokenDelay)
+	assert.NoError(t, err)
+	assert.Equal(t, "processing", payment.Status)
+	assert.Equal(t, data.Invoice.AmountDue, payment.Amount)
+	_, err = links.PayInvoice(token, "credit_card", payments.FakeTokenSuccess)
+	assert.ErrorIs(t, err, services.ErrPaymentInProgress)
+
+	// 6) Once the gateway settles, the invoice is paid and the issuer notified
+	gateway.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	view, _, err = links.OpenInvoice(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "paid", view.PaymentStatus)
+	assert.False(t, view.Payable)
+	assert.NotNil(t, view.PaidAt)
+	_, err = links.PayInvoice(token, "credit_card", payments.FakeTokenSuccess)
+	assert.ErrorIs(t, err, services.ErrInvoiceNotPayable)
+
+	paid, err := links.GetPayments(invoice.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, paid, 2) {
+		assert.Equal(t, "failed", paid[0].Status)
+		assert.Equal(t, "succeeded", paid[1].Status)
+	}
+	var notes []models.Notification
+	assert.NoError(t, db.Where("user_id = ? AND type = ?", freelancer.ID, "payment_received").Find(&notes).Error)
+	assert.Len(t, notes, 1)
+
+	// 7) Revoked links stop working
+	_, err = links.RevokeLink(invoice.ID, link.ID)
+	assert.NoError(t, err)
+	_, _, err = links.OpenInvoice(token)
+	assert.ErrorIs(t, err, services.ErrInvalidInvoiceLink)
+}
+
+// lostCapture captures at the fake gateway but loses the answer once, like a
+// timeout after the provider took the money
+type lostCapture struct {
+	*payments.FakeGateway
+	lost bool
+}
+
+func (g *lostCapture) Capture(intentID string) (*payments.Intent, error) {
+	intent, err := g.FakeGateway.Capture(intentID)
+	if err == nil && !g.lost {
+		g.lost = true
+		return nil, errors.New("capture timed out")
+	}
+	return intent, err
+}
+
+func TestInvoiceLinkLostCapture(t *testing.T) {
+	db := tests.SetupTestDB()
+	invoices := services.NewInvoiceService(repositories.NewInvoiceRepository(db), services.InvoiceNumbering{Format: "LC-{YYYY}-{SEQ}"})
+	gateway := &lostCapture{FakeGateway: payments.NewFakeGateway()}
+	links := services.NewInvoiceLinkService(repositories.NewInvoiceLinkRepository(db), invoices, gateway, "test-secret")
+
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Timed out", Email: fmt.Sprintf("timeout-%d@link.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	client := models.User{Name: "Waiting", Email: fmt.Sprintf("waiting-%d@link.test", stamp), PasswordHash: "x", Role: "client"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	assert.NoError(t, db.Create(&client).Error)
+	project := models.Project{Title: "Lost", Description: "Lost", Duration: 10, ClientID: client.ID, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+	invoice := models.Invoice{NetAmount: money.New(25000, "EUR"), DueDate: time.Now().AddDate(0, 0, 14), ProjectID: project.ID}
+	assert.NoError(t, invoices.CreateInvoice(&invoice))
+	_, err := invoices.FinaliseInvoice(invoice.ID)
+	assert.NoError(t, err)
+	_, token, err := links.CreateLink(invoice.ID, freelancer.ID, 0)
+	assert.NoError(t, err)
+
+	// 1) A failed capture call keeps the payment processing, so it isn't paid twice
+	_, err = links.PayInvoice(token, "credit_card", payments.FakeTokenDelay)
+	assert.Error(t, err)
+	paid, err := links.GetPayments(invoice.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, paid, 1) {
+		assert.Equal(t, "processing", paid[0].Status)
+		assert.NotEmpty(t, paid[0].GatewayReference)
+	}
+	_, err = links.PayInvoice(token, "credit_card", payments.FakeTokenSuccess)
+	assert.ErrorIs(t, err, services.ErrPaymentInProgress)
+
+	// 2) The gateway's answer settles it on the next sync
+	gateway.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	view, _, err := links.OpenInvoice(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "paid", view.PaymentStatus)
+	paid, err = links.GetPayments(invoice.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, paid, 1) {
+		assert.Equal(t, "succeeded", paid[0].Status)
+	}
+}
@@ -0,0 +1,106 @@
+package services_test
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestInvoiceNumberFormat(t *testing.T) {
+	assert.Equal(t, "FC-2026-000123", services.FormatInvoiceNumber(services.DefaultInvoiceNumberFormat, 2026, 123))
+	assert.Equal(t, "26/7", services.FormatInvoiceNumber("{YY}/{SEQ}", 2026, 7))
+	assert.Equal(t, "FC-2026-1234567", services.FormatInvoiceNumber("FC-{YYYY}-{SEQ:6}", 2026, 1234567))
+
+	assert.NoError(t, services.ValidateInvoiceNumberFormat(services.DefaultInvoiceNumberFormat))
+	assert.ErrorIs(t, services.ValidateInvoiceNumberFormat("FC-{YYYY}"), services.ErrInvalidNumberFormat)
+	assert.ErrorIs(t, services.ValidateInvoiceNumberFormat("{SEQ}-{SEQ}"), services.ErrInvalidNumberFormat)
+	assert.ErrorIs(t, services.ValidateInvoiceNumberFormat("{MM}-{SEQ}"), services.ErrInvalidNumberFormat)
+	assert.ErrorIs(t, services.ValidateInvoiceNumberFormat("{SEQ:x}"), services.ErrInvalidNumberFormat)
+	assert.ErrorIs(t, services.ValidateInvoiceNumberFormat("FC-{SEQ:6}"), services.ErrInvalidNumberFormat)
+
+	// Invoices and credit notes may not share numbers, however the formats are spelled
+	valid := services.InvoiceNumbering{Format: services.DefaultInvoiceNumberFormat, CreditNoteFormat: services.DefaultCreditNoteNumberFormat}
+	assert.NoError(t, valid.Validate())
+	same := services.InvoiceNumbering{Format: "{YYYY}-{SEQ}", CreditNoteFormat: "{YYYY}-{SEQ:1}"}
+	assert.ErrorIs(t, same.Validate(), services.ErrInvalidNumberFormat)
+}
+
+func TestInvoiceNumberingIsGapFree(t *testing.T) {
+	db := tests.SetupTestDB()
+	invoices := services.NewInvoiceService(repositories.NewInvoiceRepository(db), services.InvoiceNumbering{Format: "T-{YYYY}-{SEQ}"})
+
+	issuer := models.User{Name: "Numbered", Email: fmt.Sprintf("numbered-%d@numbering.test", time.Now().UnixNano()), PasswordHash: "x", Role: "freelancer"}
+	assert.NoError(t, db.Create(&issuer).Error)
+	project := models.Project{Title: "Numbered", Description: "Numbered", Duration: 10, ClientID: 1, FreelancerID: &issuer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	const n = 8
+	ids := make([]uint, n)
+	for i := range ids {
+		draft := models.Invoice{NetAmount: money.New(1000, "EUR"), DueDate: time.Now(), ProjectID: project.ID, IssuerID: &issuer.ID}
+		assert.NoError(t, invoices.CreateInvoice(&draft))
+		ids[i] = draft.ID
+	}
+
+	// Finalising concurrently hands out 1..n without gaps or duplicates
+	var wg sync.WaitGroup
+	numbers := make([]string, n)
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id uint) {
+			defer wg.Done()
+			inv, err := invoices.FinaliseInvoice(id)
+			if assert.NoError(t, err) {
+				numbers[i] = inv.InvoiceNumber
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	year := time.Now().UTC().Year()
+	var want []string
+	for i := 1; i <= n; i++ {
+		want = append(want, fmt.Sprintf("T-%d-%d", year, i))
+	}
+	sort.Strings(numbers)
+	sort.Strings(want)
+	assert.Equal(t, want, numbers)
+
+	// An issuer-less draft can't be numbered
+	orphan := models.Invoice{NetAmount: money.New(1000, "EUR"), DueDate: time.Now(), ProjectID: project.ID, ClientID: 1}
+	assert.NoError(t, db.Create(&orphan).Error)
+	_, err := invoices.FinaliseInvoice(orphan.ID)
+	assert.ErrorIs(t, err, services.ErrNoIssuer)
+}
+
+func TestInvoiceIssuerBackfill(t *testing.T) {
+	db := tests.SetupTestDB()
+
+	freelancer := models.User{Name: "Backfilled", Email: fmt.Sprintf("backfilled-%d@numbering.test", time.Now().UnixNano()), PasswordHash: "x", Role: "freelancer"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	project := models.Project{Title: "Backfilled", Description: "Backfilled", Duration: 10, ClientID: 1, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	// An invoice from before issuers were stored gets its project's freelancer
+	legacy := models.Invoice{NetAmount: money.New(1000, "EUR"), AmountDue: money.New(1000, "EUR"), DueDate: time.Now(),
+		ProjectID: project.ID, ClientID: 1, Status: "finalised", InvoiceNumber: fmt.Sprintf("LEGACY-%d", time.Now().UnixNano())}
+	assert.NoError(t, db.Create(&legacy).Error)
+	assert.Nil(t, legacy.IssuerID)
+
+	assert.NoError(t, models.Migrate(db))
+	var got models.Invoice
+	assert.NoError(t, db.First(&got, legacy.ID).Error)
+	if assert.NotNil(t, got.IssuerID) {
+		assert.Equal(t, freelancer.ID, *got.IssuerID)
+	}
+}
@@ -0,0 +1,158 @@
+package services_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/mail"
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+// recordingMailer keeps sent messages instead of delivering them
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *recordingMailer) to(address string) []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []mail.Message
+	for _, msg := range m.sent {
+		if msg.To == address {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+func TestReminderStage(t *testing.T) {
+	due := time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		now     time.Time
+		stage   string
+		overdue bool
+	}{
+		{due.AddDate(0, 0, -4), "", false},
+		{due.AddDate(0, 0, -3), services.ReminderBeforeDue, false},
+		{due.Add(23 * time.Hour), services.ReminderDue, false},
+		{due.AddDate(0, 0, 1), services.ReminderDue, true},
+		{due.AddDate(0, 0, 7), services.ReminderOverdue7, true},
+		{due.AddDate(0, 0, 29), services.ReminderOverdue7, true},
+		{due.AddDate(0, 1, 0), services.ReminderOverdue30, true},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.stage, services.ReminderStage(due, tc.now), tc.now.String())
+		assert.Equal(t, tc.overdue, services.IsOverdue(due, tc.now), tc.now.String())
+	}
+}
+
+func TestInvoiceReminders(t *testing.T) {
+	db := tests.SetupTestDB()
+	invoices := services.NewInvoiceService(repositories.NewInvoiceRepository(db), services.InvoiceNumbering{Format: "R-{YYYY}-{SEQ}"})
+	mailer := &recordingMailer{}
+	reminders := services.NewInvoiceReminderService(repositories.NewInvoiceReminderRepository(db), mailer)
+
+	stamp := time.Now().UnixNano()
+	newUser := func(role string) models.User {
+		u := models.User{Name: role, Email: fmt.Sprintf("%s-%d@reminder.test", role, stamp), PasswordHash: "x", Role: role}
+		assert.NoError(t, db.Create(&u).Error)
+		return u
+	}
+	freelancer := newUser("freelancer")
+	client := newUser("client")
+	project := models.Project{Title: "Reminders", Description: "Reminders", Duration: 10, ClientID: client.ID, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	assert.ErrorIs(t, reminders.SaveTerms(&models.BillingTerms{ClientID: freelancer.ID, LateFeeRate: 200}), services.ErrInvalidBillingTerms)
+	assert.NoError(t, reminders.SaveTerms(&models.BillingTerms{ClientID: client.ID, LateFeeRate: 200, GraceDays: 5}))
+
+	now := time.Now().UTC()
+	issue := func(due time.Time) models.Invoice {
+		inv := models.Invoice{NetAmount: money.New(50000, "EUR"), DueDate: due, ProjectID: project.ID, ClientID: client.ID}
+		assert.NoError(t, invoices.CreateInvoice(&inv))
+		_, err := invoices.FinaliseInvoice(inv.ID)
+		assert.NoError(t, err)
+		return inv
+	}
+	soon := issue(now.AddDate(0, 0, 2))
+	late := issue(now.AddDate(0, 0, -10))
+
+	// Drafts are never chased
+	draft := models.Invoice{NetAmount: money.New(50000, "EUR"), DueDate: now.AddDate(0, 0, -10), ProjectID: project.ID, ClientID: client.ID}
+	assert.NoError(t, invoices.CreateInvoice(&draft))
+
+	// 1) The late invoice is flagged, charged a fee and gets the +7 reminder only
+	_, err := reminders.Run(now)
+	assert.NoError(t, err)
+
+	reload := func(id uint) models.Invoice {
+		var inv models.Invoice
+		assert.NoError(t, db.First(&inv, id).Error)
+		return inv
+	}
+	reloaded := reload(late.ID)
+	assert.Equal(t, "overdue", reloaded.PaymentStatus)
+	assert.Equal(t, int64(1000), reloaded.LateFee.Amount)
+	assert.NotNil(t, reloaded.LateFeeAppliedAt)
+	reloaded = reload(soon.ID)
+	assert.Equal(t, "pending", reloaded.PaymentStatus)
+	reloaded = reload(draft.ID)
+	assert.Equal(t, "pending", reloaded.PaymentStatus)
+
+	sent, err := reminders.GetReminders(late.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, services.ReminderOverdue7, sent[0].Stage)
+		assert.True(t, sent[0].Emailed)
+	}
+	sent, err = reminders.GetReminders(soon.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 1) {
+		assert.Equal(t, services.ReminderBeforeDue, sent[0].Stage)
+	}
+
+	assert.Len(t, mailer.to(client.Email), 2)
+	var notes []models.Notification
+	assert.NoError(t, db.Where("user_id = ? AND type = ?", client.ID, "invoice_reminder").Find(&notes).Error)
+	assert.Len(t, notes, 2)
+
+	// 2) Running again the same day sends nothing and charges no second fee
+	_, err = reminders.Run(now)
+	assert.NoError(t, err)
+	assert.Len(t, mailer.to(client.Email), 2)
+	reloaded = reload(late.ID)
+	assert.Equal(t, int64(1000), reloaded.LateFee.Amount)
+
+	// 3) Weeks later both invoices move on to their next stage
+	_, err = reminders.Run(now.AddDate(0, 0, 25))
+	assert.NoError(t, err)
+	sent, err = reminders.GetReminders(late.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, services.ReminderOverdue30, sent[1].Stage)
+	}
+	sent, err = reminders.GetReminders(soon.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, services.ReminderOverdue7, sent[1].Stage)
+	}
+	reloaded = reload(soon.ID)
+	assert.Equal(t, int64(1000), reloaded.LateFee.Amount)
+	assert.Len(t, mailer.to(client.Email), 4)
+}
@@ -0,0 +1,147 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestSchedulePeriodStart(t *testing.T) {
+	jan31 := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		interval string
+		n        int
+		want     time.Time
+	}{
+		{services.IntervalMonthly, 0, jan31},
+		{services.IntervalMonthly, 1, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{services.IntervalMonthly, 2, time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{services.IntervalMonthly, 3, time.Date(2026, 4, 30, 0, 0, 0, 0, time.UTC)},
+		{services.IntervalMonthly, 13, time.Date(2027, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{services.IntervalQuarterly, 1, time.Date(2026, 4, 30, 0, 0, 0, 0, time.UTC)},
+		{services.IntervalYearly, 2, time.Date(2028, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{services.IntervalWeekly, 5, time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, services.SchedulePeriodStart(jan31, tc.interval, tc.n), "%s %d", tc.interval, tc.n)
+	}
+
+	leap := time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2029, 2, 28, 0, 0, 0, 0, time.UTC), services.SchedulePeriodStart(leap, services.IntervalYearly, 1))
+	assert.Equal(t, time.Date(2032, 2, 29, 0, 0, 0, 0, time.UTC), services.SchedulePeriodStart(leap, services.IntervalYearly, 4))
+}
+
+func TestInvoiceSchedules(t *testing.T) {
+	db := tests.SetupTestDB()
+	mailer := &recordingMailer{}
+	schedules := services.NewInvoiceScheduleService(repositories.NewInvoiceScheduleRepository(db),
+		services.InvoiceNumbering{Format: "RT-{YYYY}-{SEQ}"}, mailer)
+
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Retained", Email: fmt.Sprintf("retained-%d@schedule.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	client := models.User{Name: "Retainer", Email: fmt.Sprintf("retainer-%d@schedule.test", stamp), PasswordHash: "x", Role: "client"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	assert.NoError(t, db.Create(&client).Error)
+	project := models.Project{Title: "Retainer", Description: "Retainer", Duration: 365, ClientID: client.ID, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	newSchedule := func() models.InvoiceSchedule {
+		return models.InvoiceSchedule{
+			Interval:    services.IntervalMonthly,
+			Amount:      money.New(150000, "EUR"),
+			Description: "Monthly retainer",
+			DueDays:     10,
+			StartDate:   today,
+			ProjectID:   project.ID,
+			IssuerID:    freelancer.ID,
+		}
+	}
+
+	// 1) Validation: only the freelancer, never in the past, known intervals
+	invalid := newSchedule()
+	invalid.StartDate = today.AddDate(0, 0, -1)
+	assert.ErrorIs(t, schedules.CreateSchedule(&invalid), services.ErrInvalidSchedule)
+	invalid = newSchedule()
+	invalid.Interval = "daily"
+	assert.ErrorIs(t, schedules.CreateSchedule(&invalid), services.ErrInvalidSchedule)
+	invalid = newSchedule()
+	invalid.IssuerID = client.ID
+	assert.ErrorIs(t, schedules.CreateSchedule(&invalid), services.ErrNotProjectFreelancer)
+
+	schedule := newSchedule()
+	assert.NoError(t, schedules.CreateSchedule(&schedule))
+	assert.Equal(t, "active", schedule.Status)
+	assert.True(t, schedule.NextRunAt.Equal(today))
+
+	// 2) After downtime every missed period is billed once
+	later := services.SchedulePeriodStart(today, services.IntervalMonthly, 2).Add(time.Hour)
+	result, err := schedules.Run(later)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Issued)
+	issued, err := schedules.GetScheduleInvoices(schedule.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, issued, 3) {
+		for i, inv := range issued {
+			assert.Equal(t, "finalised", inv.Status)
+			assert.NotEmpty(t, inv.InvoiceNumber)
+			assert.True(t, inv.PeriodStart.Equal(services.SchedulePeriodStart(today, services.IntervalMonthly, i)))
+			assert.Equal(t, int64(150000), inv.NetAmount.Amount)
+			if assert.Len(t, inv.Lines, 1) {
+				assert.Contains(t, inv.Lines[0].Description, "Monthly retainer (")
+			}
+		}
+	}
+	assert.Len(t, mailer.to(client.Email), 3)
+
+	// 3) Running again bills nothing new
+	result, err = schedules.Run(later)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Issued)
+	reloaded, err := schedules.GetSchedule(schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, reloaded.RunCount)
+
+	// 4) Paused schedules skip their periods
+	paused, err := schedules.PauseSchedule(schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "paused", paused.Status)
+	_, err = schedules.PauseSchedule(schedule.ID)
+	assert.ErrorIs(t, err, services.ErrScheduleStateChange)
+	muchLater := services.SchedulePeriodStart(today, services.IntervalMonthly, 5).Add(time.Hour)
+	result, err = schedules.Run(muchLater)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Issued)
+	resumed, err := schedules.ResumeSchedule(schedule.ID, muchLater)
+	assert.NoError(t, err)
+	assert.Equal(t, "active", resumed.Status)
+	assert.True(t, resumed.NextRunAt.Equal(services.SchedulePeriodStart(today, services.IntervalMonthly, 6)))
+
+	// 5) Cancelled schedules stay cancelled
+	cancelled, err := schedules.CancelSchedule(schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	_, err = schedules.ResumeSchedule(schedule.ID, muchLater)
+	assert.ErrorIs(t, err, services.ErrScheduleStateChange)
+
+	// 6) A schedule completes after its end date
+	ending := newSchedule()
+	end := today.AddDate(0, 0, 20)
+	ending.Interval = services.IntervalWeekly
+	ending.EndDate = &end
+	assert.NoError(t, schedules.CreateSchedule(&ending))
+	result, err = schedules.Run(today.AddDate(0, 2, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Issued, "days 0, 7 and 14 start before the end date")
+	completed, err := schedules.GetSchedule(ending.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", completed.Status)
+}
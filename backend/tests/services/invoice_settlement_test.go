@@ -0,0 +1,148 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestInvoiceSettlement(t *testing.T) {
+	db := tests.SetupTestDB()
+	invoices := services.NewInvoiceService(repositories.NewInvoiceRepository(db), services.InvoiceNumbering{Format: "S-{YYYY}-{SEQ}"})
+	txService := services.NewTransactionService(repositories.NewTransactionRepository(db), payments.NewFakeGateway())
+	settlement := services.NewInvoiceSettlementService(repositories.NewInvoiceAllocationRepository(db))
+
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Settled", Email: fmt.Sprintf("settled-%d@settlement.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	client := models.User{Name: "Settler", Email: fmt.Sprintf("settler-%d@settlement.test", stamp), PasswordHash: "x", Role: "client"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	assert.NoError(t, db.Create(&client).Error)
+	project := models.Project{Title: "Settlement", Description: "Settlement", Duration: 10, ClientID: client.ID, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	now := time.Now()
+	issue := func(net int64, due time.Time) models.Invoice {
+		inv := models.Invoice{NetAmount: money.New(net, "EUR"), DueDate: due, ProjectID: project.ID, ClientID: client.ID, IssuerID: &freelancer.ID}
+		assert.NoError(t, invoices.CreateInvoice(&inv))
+		_, err := invoices.FinaliseInvoice(inv.ID)
+		assert.NoError(t, err)
+		return inv
+	}
+	later := issue(30000, now.AddDate(0, 0, 20))
+	sooner := issue(20000, now.AddDate(0, 0, 10))
+	third := issue(10000, now.AddDate(0, 0, 30))
+	reload := func(id uint) models.Invoice {
+		var inv models.Invoice
+		assert.NoError(t, db.First(&inv, id).Error)
+		return inv
+	}
+	pay := func(amount int64, invoiceIDs ...uint) models.Transaction {
+		tx := models.Transaction{Amount: money.New(amount, "EUR"), PaymentMethod: "bank_transfer",
+			ClientID: client.ID, FreelancerID: freelancer.ID, ProjectID: project.ID}
+		for _, id := range invoiceIDs {
+			tx.Allocations = append(tx.Allocations, models.InvoiceAllocation{InvoiceID: id})
+		}
+		assert.NoError(t, txService.CreateTransaction(&tx, payments.FakeTokenSuccess))
+		return tx
+	}
+
+	// 1) Only open invoices of the same parties can be named
+	draft := models.Invoice{NetAmount: money.New(5000, "EUR"), DueDate: now, ProjectID: project.ID, ClientID: client.ID, IssuerID: &freelancer.ID}
+	assert.NoError(t, invoices.CreateInvoice(&draft))
+	invalid := models.Transaction{Amount: money.New(5000, "EUR"), PaymentMethod: "bank_transfer",
+		ClientID: client.ID, FreelancerID: freelancer.ID, ProjectID: project.ID,
+		Allocations: []models.InvoiceAllocation{{InvoiceID: draft.ID}}}
+	assert.ErrorIs(t, txService.CreateTransaction(&invalid, payments.FakeTokenSuccess), services.ErrInvalidAllocation)
+
+	// 2) Nothing is allocated before the payment completes
+	tx := pay(25000, later.ID, sooner.ID)
+	assert.Equal(t, "pending", reload(sooner.ID).PaymentStatus)
+	_, err := txService.CaptureTransaction(tx.ID)
+	assert.NoError(t, err)
+
+	// 3) The invoice due first is paid in full, the rest goes to the other one
+	paid := reload(sooner.ID)
+	assert.Equal(t, "paid", paid.PaymentStatus)
+	assert.NotNil(t, paid.PaidAt)
+	partial := reload(later.ID)
+	assert.Equal(t, "pending", partial.PaymentStatus)
+	assert.Equal(t, int64(5000), partial.AmountPaid.Amount)
+
+	balance, err := settlement.GetInvoiceBalance(later.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25000), balance.Balance.Amount)
+	if assert.Len(t, balance.Allocations, 1) {
+		assert.Equal(t, tx.ID, balance.Allocations[0].TransactionID)
+		assert.Equal(t, int64(5000), balance.Allocations[0].Amount.Amount)
+	}
+
+	var notes []models.Notification
+	assert.NoError(t, db.Where("user_id = ? AND type = ?", freelancer.ID, "payment_received").Find(&notes).Error)
+	assert.Len(t, notes, 1)
+
+	// 4) Overpaying settles the invoice and leaves client credit
+	tx = pay(40000, later.ID)
+	_, err = txService.CaptureTransaction(tx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "paid", reload(later.ID).PaymentStatus)
+
+	balances, credits, err := settlement.GetCredits(client.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, balances, 1) {
+		assert.Equal(t, freelancer.ID, balances[0].FreelancerID)
+		assert.Equal(t, money.New(15000, "EUR"), balances[0].Balance)
+	}
+	if assert.Len(t, credits, 1) {
+		assert.Equal(t, tx.ID, *credits[0].TransactionID)
+	}
+
+	// 5) Credit pays the next invoice; what is left stays as credit
+	balance, err = settlement.ApplyCredit(third.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "paid", balance.PaymentStatus)
+	assert.True(t, balance.Balance.IsZero())
+	balances, _, err = settlement.GetCredits(client.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, balances, 1) {
+		assert.Equal(t, money.New(5000, "EUR"), balances[0].Balance)
+	}
+	_, err = settlement.ApplyCredit(third.ID)
+	assert.ErrorIs(t, err, services.ErrInvoiceNotPayable)
+
+	// 6) Refunds take back the overpayment's credit first, even when it was
+	// spent, then reopen the invoices the transaction paid
+	_, err = txService.RefundTransaction(tx.ID, money.New(10000, "EUR"), "partly cancelled")
+	assert.NoError(t, err)
+	assert.Equal(t, "paid", reload(later.ID).PaymentStatus)
+	balances, _, err = settlement.GetCredits(client.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, balances, 1) {
+		assert.Equal(t, money.New(-5000, "EUR"), balances[0].Balance)
+	}
+
+	_, err = txService.RefundTransaction(tx.ID, money.Money{}, "cancelled")
+	assert.NoError(t, err)
+	reopened := reload(later.ID)
+	assert.Equal(t, "pending", reopened.PaymentStatus)
+	assert.Nil(t, reopened.PaidAt)
+	assert.Equal(t, int64(5000), reopened.AmountPaid.Amount)
+	balance, err = settlement.GetInvoiceBalance(later.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(25000), balance.Balance.Amount)
+	balances, _, err = settlement.GetCredits(client.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, balances, 1) {
+		assert.Equal(t, money.New(-10000, "EUR"), balances[0].Balance)
+	}
+	assert.NoError(t, db.Where("user_id = ? AND type = ?", freelancer.ID, "payment_reversed").Find(&notes).Error)
+	assert.Len(t, notes, 1)
+}
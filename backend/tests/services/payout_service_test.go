@@ -0,0 +1,118 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestPayoutService(t *testing.T) {
+	db := tests.SetupTestDB()
+	repo := repositories.NewPayoutRepository(db)
+	provider := payments.NewFakePayoutProvider()
+	payouts := services.NewPayoutService(repo, provider, services.PayoutPolicy{Minimum: money.New(5000, "EUR")})
+
+	freelancer := models.User{
+		Name:         "Payee",
+		Email:        fmt.Sprintf("payee-%d@payouts.test", time.Now().UnixNano()),
+		PasswordHash: "x",
+		Role:         "freelancer",
+		Earnings:     money.New(30000, "EUR"),
+	}
+	assert.NoError(t, db.Create(&freelancer).Error)
+
+	// 1) Payout methods; the first one becomes the default
+	method := models.PayoutMethod{UserID: freelancer.ID, Type: "paypal", Destination: "payee@example.com"}
+	assert.NoError(t, payouts.AddMethod(&method))
+	assert.True(t, method.IsDefault)
+	failing := models.PayoutMethod{UserID: freelancer.ID, Type: "bank_transfer", Destination: "fail-IBAN"}
+	assert.NoError(t, payouts.AddMethod(&failing))
+
+	// 2) Minimum and available balance are enforced
+	_, err := payouts.RequestWithdrawal(freelancer.ID, method.ID, money.New(1000, "EUR"))
+	assert.ErrorIs(t, err, services.ErrBelowMinimumPayout)
+	_, err = payouts.RequestWithdrawal(freelancer.ID, method.ID, money.New(40000, "EUR"))
+	assert.ErrorIs(t, err, services.ErrInsufficientBalance)
+
+	// 3) A request reserves its amount
+	w, err := payouts.RequestWithdrawal(freelancer.ID, method.ID, money.New(20000, "EUR"))
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", w.Status)
+	balance, err := payouts.GetBalance(freelancer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10000), balance.Available.Amount)
+
+	// 4) Rejection releases it again
+	other, err := payouts.RequestWithdrawal(freelancer.ID, failing.ID, money.New(10000, "EUR"))
+	assert.NoError(t, err)
+	_, err = payouts.RejectWithdrawal(other.ID, 1, "")
+	assert.ErrorIs(t, err, services.ErrReasonRequired)
+	_, err = payouts.RejectWithdrawal(other.ID, 1, "wrong account")
+	assert.NoError(t, err)
+	balance, _ = payouts.GetBalance(freelancer.ID)
+	assert.Equal(t, int64(10000), balance.Available.Amount)
+
+	// 5) The batch pays approved requests and debits the earnings once
+	_, err = payouts.ApproveWithdrawal(w.ID, 1)
+	assert.NoError(t, err)
+	failed, err := payouts.RequestWithdrawal(freelancer.ID, failing.ID, money.New(5000, "EUR"))
+	assert.NoError(t, err)
+	_, err = payouts.ApproveWithdrawal(failed.ID, 1)
+	assert.NoError(t, err)
+
+	result, err := payouts.RunBatch()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, result.Paid, 1)
+	assert.GreaterOrEqual(t, result.Failed, 1)
+
+	paid, err := repo.FindWithdrawalByID(w.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "paid", paid.Status)
+	stillFailed, err := repo.FindWithdrawalByID(failed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "failed", stillFailed.Status)
+
+	var u models.User
+	assert.NoError(t, db.First(&u, freelancer.ID).Error)
+	assert.Equal(t, int64(10000), u.Earnings.Amount)
+
+	_, err = payouts.RunBatch()
+	assert.NoError(t, err)
+	assert.NoError(t, db.First(&u, freelancer.ID).Error)
+	assert.Equal(t, int64(10000), u.Earnings.Amount)
+
+	// 6) Earnings lost after approval (e.g. to a chargeback) are re-checked
+	// before paying out and when approving
+	approved, err := payouts.RequestWithdrawal(freelancer.ID, method.ID, money.New(8000, "EUR"))
+	assert.NoError(t, err)
+	_, err = payouts.ApproveWithdrawal(approved.ID, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Model(&u).Update("earnings_amount", 5000).Error)
+	_, err = payouts.RunBatch()
+	assert.NoError(t, err)
+	uncovered, err := repo.FindWithdrawalByID(approved.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "failed", uncovered.Status)
+	assert.NoError(t, db.First(&u, freelancer.ID).Error)
+	assert.Equal(t, int64(5000), u.Earnings.Amount)
+
+	pending, err := payouts.RequestWithdrawal(freelancer.ID, method.ID, money.New(5000, "EUR"))
+	assert.NoError(t, err)
+	assert.NoError(t, db.Model(&u).Update("earnings_amount", 4000).Error)
+	_, err = payouts.ApproveWithdrawal(pending.ID, 1)
+	assert.ErrorIs(t, err, services.ErrInsufficientBalance)
+
+	// 7) New accounts are on hold
+	held := services.NewPayoutService(repo, provider, services.PayoutPolicy{NewAccountHold: 24 * time.Hour})
+	_, err = held.RequestWithdrawal(freelancer.ID, method.ID, money.New(5000, "EUR"))
+	assert.ErrorIs(t, err, services.ErrAccountOnHold)
+}
@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestProjectInvitations(t *testing.T) {
+	db := tests.SetupTestDB()
+	invitations := services.NewProjectInvitationService(repositories.NewProjectInvitationRepository(db))
+	propService := services.NewProposalService(repositories.NewProposalRepository(db))
+	projService := services.NewProjectService(repositories.NewProjectRepository(db))
+	credits := services.NewBidCreditService(repositories.NewBidCreditRepository(db), payments.NewFakeGateway())
+
+	stamp := time.Now().UnixNano()
+	user := func(name, role string) models.User {
+		u := models.User{Name: name, Email: fmt.Sprintf("%s-%d@invitations.test", name, stamp), PasswordHash: "x", Role: role}
+		assert.NoError(t, db.Create(&u).Error)
+		return u
+	}
+	client, invitee, busy, late, stranger := user("inviter", "client"), user("invitee", "freelancer"),
+		user("busy", "freelancer"), user("late", "freelancer"), user("stranger", "freelancer")
+	project := models.Project{Title: "Private", Description: "Private", Duration: 14, Budget: money.New(100000, "EUR"),
+		ClientID: client.ID, InviteOnly: true}
+	assert.NoError(t, db.Create(&project).Error)
+
+	// 1) Invite-only projects turn away uninvited freelancers
+	uninvited := models.Proposal{ProposalText: "Let me in", EstimatedDuration: 10, BidAmount: money.New(90000, "EUR"),
+		ProjectID: project.ID, FreelancerID: stranger.ID}
+	assert.ErrorIs(t, propService.CreateProposal(&uninvited), services.ErrNotInvited)
+
+	// 2) Only the client invites, and only freelancers, once each
+	_, err := invitations.Invite(project.ID, invitee.ID, busy.ID, "")
+	assert.ErrorIs(t, err, services.ErrNotProjectOwner)
+	_, err = invitations.Invite(project.ID, client.ID, client.ID, "")
+	assert.ErrorIs(t, err, services.ErrInvalidInvitee)
+	invitation, err := invitations.Invite(project.ID, client.ID, invitee.ID, "You did great work last time")
+	assert.NoError(t, err)
+	_, err = invitations.Invite(project.ID, client.ID, invitee.ID, "")
+	assert.ErrorIs(t, err, services.ErrAlreadyInvited)
+	declined, err := invitations.Invite(project.ID, client.ID, busy.ID, "")
+	assert.NoError(t, err)
+	unanswered, err := invitations.Invite(project.ID, client.ID, late.ID, "")
+	assert.NoError(t, err)
+
+	var notes []models.Notification
+	assert.NoError(t, db.Where("user_id = ? AND type = ?", invitee.ID, "project_invitation").Find(&notes).Error)
+	assert.Len(t, notes, 1)
+
+	// 3) Freelancers answer their own invitations
+	_, err = invitations.DeclineInvitation(declined.ID, invitee.ID, "")
+	assert.ErrorIs(t, err, services.ErrNotInvitee)
+	_, err = invitations.DeclineInvitation(declined.ID, busy.ID, "Fully booked")
+	assert.NoError(t, err)
+
+	accepted, draft, err := invitations.AcceptInvitation(invitation.ID, invitee.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "accepted", accepted.Status)
+	assert.Equal(t, "draft", draft.Status)
+	assert.Equal(t, project.Budget, draft.BidAmount)
+	assert.Equal(t, project.Duration, draft.EstimatedDuration)
+	if assert.NotNil(t, accepted.ProposalID) {
+		assert.Equal(t, draft.ID, *accepted.ProposalID)
+	}
+	_, _, err = invitations.AcceptInvitation(invitation.ID, invitee.ID)
+	assert.ErrorIs(t, err, services.ErrInvitationClosed)
+
+	// 4) The draft is filled in and submitted, without spending bid credits
+	_, err = propService.SubmitProposal(draft.ID, invitee.ID)
+	assert.ErrorIs(t, err, services.ErrIncompleteDraft)
+	draft.ProposalText = "Happy to help again"
+	draft.BidAmount = money.New(95000, "EUR")
+	assert.NoError(t, propService.UpdateProposal(draft))
+	_, err = propService.SubmitProposal(draft.ID, stranger.ID)
+	assert.ErrorIs(t, err, services.ErrFreelancerOnly)
+	submitted, err := propService.SubmitProposal(draft.ID, invitee.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", submitted.Status)
+	_, err = propService.SubmitProposal(draft.ID, invitee.ID)
+	assert.ErrorIs(t, err, services.ErrNotDraft)
+
+	revisions, err := propService.GetRevisions(draft.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 1) {
+		assert.Equal(t, int64(95000), revisions[0].BidAmount.Amount)
+	}
+	ledger, err := credits.GetLedger(invitee.ID)
+	assert.NoError(t, err)
+	for _, entry := range ledger {
+		assert.NotEqual(t, "proposal", entry.Kind)
+	}
+
+	// 5) The client tracks the answers; closing the project expires the rest
+	project.Status = "cancelled"
+	assert.NoError(t, projService.UpdateProject(&project))
+	sent, err := invitations.GetProjectInvitations(project.ID)
+	assert.NoError(t, err)
+	statuses := map[uint]string{}
+	for _, inv := range sent {
+		statuses[inv.ID] = inv.Status
+	}
+	assert.Equal(t, map[uint]string{invitation.ID: "accepted", declined.ID: "declined", unanswered.ID: "expired"}, statuses)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 	"FreeConnect/internal/repositories"
 	"FreeConnect/internal/services"
 	"FreeConnect/tests"
@@ -20,7 +21,7 @@ func TestProjectService(t *testing.T) {
 	project := models.Project{
 		Title:       "Build an API",
 		Description: "Create a REST API in Go",
-		Budget:      1500.0,
+		Budget:      money.New(150000, "EUR"),
 		Duration:    20,
 		ClientID:    1, // If you have a client with ID=1
 	}
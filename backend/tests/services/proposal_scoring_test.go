@@ -0,0 +1,95 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestProposalScoring(t *testing.T) {
+	db := tests.SetupTestDB()
+	scoring := services.NewProposalScoringService(repositories.NewProposalScoringRepository(db))
+	projService := services.NewProjectService(repositories.NewProjectRepository(db))
+	userService := services.NewUserService(repositories.NewUserRepository(db))
+
+	stamp := time.Now().UnixNano()
+	user := func(name, role string) models.User {
+		u := models.User{Name: name, Email: fmt.Sprintf("%s-%d@scoring.test", name, stamp), PasswordHash: "x", Role: role}
+		assert.NoError(t, db.Create(&u).Error)
+		return u
+	}
+	client, expert, novice, drafter := user("scorer", "client"), user("expert", "freelancer"),
+		user("novice", "freelancer"), user("drafter", "freelancer")
+
+	goSkill, sqlSkill := models.Skill{Name: "Go"}, models.Skill{Name: "SQL"}
+	assert.NoError(t, db.Create(&goSkill).Error)
+	assert.NoError(t, db.Create(&sqlSkill).Error)
+
+	project := models.Project{Title: "Ranked", Description: "Ranked", Duration: 20, Budget: money.New(100000, "EUR"),
+		Status: "open", ClientID: client.ID}
+	assert.NoError(t, db.Create(&project).Error)
+	assert.NoError(t, projService.UpdateProjectSkills(project.ID, []uint{goSkill.ID, sqlSkill.ID}))
+	assert.NoError(t, userService.UpdateUserSkills(expert.ID, []uint{goSkill.ID, sqlSkill.ID}))
+
+	// The expert has a good track record: one completed project, reviewed 4.5
+	past := models.Project{Title: "Past", Description: "Past", Status: "completed", ClientID: client.ID, FreelancerID: &expert.ID}
+	assert.NoError(t, db.Create(&past).Error)
+	assert.NoError(t, db.Create(&models.Review{Rating: 4.5, ReviewedBy: client.ID, ReviewedeeID: expert.ID, ProjectID: past.ID}).Error)
+
+	bid := func(freelancer models.User, amount int64, days int, status string) models.Proposal {
+		p := models.Proposal{ProposalText: "Bid", EstimatedDuration: days, BidAmount: money.New(amount, "EUR"),
+			Status: status, ProjectID: project.ID, FreelancerID: freelancer.ID}
+		assert.NoError(t, db.Create(&p).Error)
+		return p
+	}
+	strong := bid(expert, 90000, 20, "pending")
+	weak := bid(novice, 150000, 40, "pending")
+	draft := bid(drafter, 10000, 5, "draft")
+
+	// 1) Proposals come best first, each factor explained; drafts are left out
+	ranked, err := scoring.RankProposals(project.ID, "")
+	assert.NoError(t, err)
+	if assert.Len(t, ranked, 2) {
+		assert.Equal(t, strong.ID, ranked[0].Proposal.ID)
+		assert.Greater(t, ranked[0].Score, ranked[1].Score)
+		if assert.Len(t, ranked[0].Factors, 5) {
+			for _, f := range ranked[0].Factors {
+				assert.NotEmpty(t, f.Explanation)
+			}
+			assert.Equal(t, "skills", ranked[0].Factors[3].Name)
+			assert.Equal(t, 100.0, ranked[0].Factors[3].Score)
+		}
+		assert.Equal(t, 0.0, ranked[1].Factors[0].Score) // bid 150% of the budget
+	}
+
+	// 2) Sorting by one factor, and rejecting unknown ones
+	_, err = scoring.RankProposals(project.ID, "vibes")
+	assert.ErrorIs(t, err, services.ErrInvalidSortKey)
+	byRating, err := scoring.RankProposals(project.ID, "rating")
+	assert.NoError(t, err)
+	if assert.Len(t, byRating, 2) {
+		assert.Equal(t, strong.ID, byRating[0].Proposal.ID)
+	}
+
+	// 3) Comparing keeps the order asked for and names the leader of each factor
+	comparison, err := scoring.CompareProposals(project.ID, []uint{weak.ID, strong.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, comparison.Proposals, 2) {
+		assert.Equal(t, weak.ID, comparison.Proposals[0].Proposal.ID)
+	}
+	assert.Equal(t, strong.ID, comparison.Best["score"])
+	assert.Equal(t, strong.ID, comparison.Best["completion"])
+
+	_, err = scoring.CompareProposals(project.ID, []uint{strong.ID})
+	assert.ErrorIs(t, err, services.ErrInvalidComparison)
+	_, err = scoring.CompareProposals(project.ID, []uint{strong.ID, draft.ID})
+	assert.ErrorIs(t, err, services.ErrInvalidComparison)
+}
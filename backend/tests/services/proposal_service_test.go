@@ -1,11 +1,14 @@
 package services_test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
 	"FreeConnect/internal/repositories"
 	"FreeConnect/internal/services"
 	"FreeConnect/tests"
@@ -16,13 +19,22 @@ func TestProposalService(t *testing.T) {
 	propRepo := repositories.NewProposalRepository(db)
 	propService := services.NewProposalService(propRepo)
 
+	// Proposals need an open project and a freelancer with bid credits
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Proposer", Email: fmt.Sprintf("proposer-%d@proposal.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	client := models.User{Name: "Owner", Email: fmt.Sprintf("owner-%d@proposal.test", stamp), PasswordHash: "x", Role: "client"}
+	assert.NoError(t, db.Create(&freelancer).Error)
+	assert.NoError(t, db.Create(&client).Error)
+	project := models.Project{Title: "Proposed", Description: "Proposed", Duration: 10, ClientID: client.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
 	// 1) Create a Proposal
 	proposal := models.Proposal{
 		ProposalText:      "I can finish this in 10 days",
 		EstimatedDuration: 10,
-		BidAmount:         800.0,
-		ProjectID:         1, // If a project with ID=1 exists
-		FreelancerID:      2, // If user with ID=2 is a freelancer
+		BidAmount:         money.New(80000, "EUR"),
+		ProjectID:         project.ID,
+		FreelancerID:      freelancer.ID,
 	}
 
 	err := propService.CreateProposal(&proposal)
@@ -32,22 +44,185 @@ func TestProposalService(t *testing.T) {
 	// 2) Retrieve
 	got, err := propService.GetProposalByID(proposal.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, 800.0, got.BidAmount)
+	assert.Equal(t, int64(80000), got.BidAmount.Amount)
 
-	// 3) Update
-	proposal.BidAmount = 900.0
+	// 3) Update: the bid only changes through a counter-offer
+	proposal.BidAmount = money.New(90000, "EUR")
 	err = propService.UpdateProposal(&proposal)
+	assert.ErrorIs(t, err, services.ErrTermsNegotiated)
+
+	_, err = propService.CounterOffer(proposal.ID, proposal.FreelancerID, money.New(90000, "EUR"), 10, "")
 	assert.NoError(t, err)
 
 	updated, err := propService.GetProposalByID(proposal.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, 900.0, updated.BidAmount)
+	assert.Equal(t, int64(90000), updated.BidAmount.Amount)
 
 	// 4) Accept
-	err = propService.AcceptProposal(&proposal)
-	assert.NoError(t, err, "AcceptProposal might fail if Project or Freelancer are missing in DB")
+	err = propService.AcceptProposal(&proposal, 0, true)
+	assert.NoError(t, err)
 
 	// 5) Delete
 	err = propService.DeleteProposal(proposal.ID)
 	assert.NoError(t, err)
 }
+
+func TestProposalNegotiation(t *testing.T) {
+	db := tests.SetupTestDB()
+	propService := services.NewProposalService(repositories.NewProposalRepository(db))
+
+	stamp := time.Now().UnixNano()
+	freelancer := models.User{Name: "Bidder", Email: fmt.Sprintf("bidder-%d@negotiation.test", stamp), PasswordHash: "x", Role: "freelancer"}
+	client := models.User{Name: "Haggler", Email: fmt.Sprintf("haggler-%d@negotiation.test", stamp), PasswordHash: "x", Role: "client"}
+	outsider := models.User{Name: "Outsider", Email: fmt.Sprintf("outsider-%d@negotiation.test", stamp), PasswordHash: "x", Role: "client"}
+	for _, u := range []*models.User{&freelancer, &client, &outsider} {
+		assert.NoError(t, db.Create(u).Error)
+	}
+	project := models.Project{Title: "Negotiated", Description: "Negotiated", Duration: 30, ClientID: client.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	proposal := models.Proposal{ProposalText: "Done in 20 days", EstimatedDuration: 20, BidAmount: money.New(500000, "EUR"),
+		ProjectID: project.ID, FreelancerID: freelancer.ID}
+	assert.NoError(t, propService.CreateProposal(&proposal))
+	assert.Equal(t, services.PartyFreelancer, proposal.OfferedBy)
+
+	// 1) Only the parties negotiate, with valid terms
+	_, err := propService.CounterOffer(proposal.ID, outsider.ID, money.New(400000, "EUR"), 20, "")
+	assert.ErrorIs(t, err, services.ErrNotProposalParty)
+	_, err = propService.CounterOffer(proposal.ID, client.ID, money.New(400000, "USD"), 20, "")
+	assert.ErrorIs(t, err, services.ErrInvalidOffer)
+	_, err = propService.CounterOffer(proposal.ID, client.ID, money.New(400000, "EUR"), 0, "")
+	assert.ErrorIs(t, err, services.ErrInvalidOffer)
+
+	// 2) The client counters, the freelancer counters back
+	countered, err := propService.CounterOffer(proposal.ID, client.ID, money.New(400000, "EUR"), 25, "Tighter budget")
+	assert.NoError(t, err)
+	assert.Equal(t, services.PartyClient, countered.OfferedBy)
+	countered, err = propService.CounterOffer(proposal.ID, freelancer.ID, money.New(450000, "EUR"), 25, "Meet in the middle")
+	assert.NoError(t, err)
+	assert.Equal(t, services.PartyFreelancer, countered.OfferedBy)
+
+	party, err := propService.ProposalParty(countered, client.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, services.PartyClient, party)
+
+	// 3) Only the other party accepts, judged by the proposal as it is
+	// rather than the caller's copy
+	stale := *countered
+	stale.OfferedBy = services.PartyClient
+	assert.ErrorIs(t, propService.AcceptProposal(&stale, freelancer.ID, false), services.ErrOwnOffer)
+	assert.ErrorIs(t, propService.AcceptProposal(countered, outsider.ID, false), services.ErrNotProposalParty)
+
+	// 4) Accepting locks in the terms on the table
+	assert.NoError(t, propService.AcceptProposal(countered, client.ID, false))
+	assert.Equal(t, "accepted", countered.Status)
+	assert.Equal(t, money.New(450000, "EUR"), countered.BidAmount)
+	assert.Equal(t, 25, countered.EstimatedDuration)
+	assert.NotNil(t, countered.AcceptedAt)
+
+	_, err = propService.CounterOffer(proposal.ID, client.ID, money.New(300000, "EUR"), 25, "")
+	assert.ErrorIs(t, err, services.ErrProposalClosed)
+	assert.ErrorIs(t, propService.AcceptProposal(countered, client.ID, false), services.ErrProjectNotOpen)
+
+	var hired models.Project
+	assert.NoError(t, db.First(&hired, project.ID).Error)
+	assert.Equal(t, "in_progress", hired.Status)
+	if assert.NotNil(t, hired.FreelancerID) {
+		assert.Equal(t, freelancer.ID, *hired.FreelancerID)
+	}
+
+	// 5) Every step is kept
+	revisions, err := propService.GetRevisions(proposal.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 4) {
+		assert.Equal(t, "bid", revisions[0].Kind)
+		assert.Equal(t, "counter_offer", revisions[1].Kind)
+		assert.Equal(t, services.PartyClient, revisions[1].Party)
+		assert.Equal(t, "Tighter budget", revisions[1].Message)
+		assert.Equal(t, "counter_offer", revisions[2].Kind)
+		assert.Equal(t, "accepted", revisions[3].Kind)
+		assert.Equal(t, services.PartyClient, revisions[3].Party)
+		assert.Equal(t, int64(450000), revisions[3].BidAmount.Amount)
+	}
+}
+
+func TestProposalStatuses(t *testing.T) {
+	db := tests.SetupTestDB()
+	propService := services.NewProposalService(repositories.NewProposalRepository(db))
+	projService := services.NewProjectService(repositories.NewProjectRepository(db))
+
+	stamp := time.Now().UnixNano()
+	client := models.User{Name: "Picker", Email: fmt.Sprintf("picker-%d@statuses.test", stamp), PasswordHash: "x", Role: "client"}
+	assert.NoError(t, db.Create(&client).Error)
+	project := models.Project{Title: "Picked", Description: "Picked", Duration: 30, ClientID: client.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	bid := func(projectID uint, name string) models.Proposal {
+		freelancer := models.User{Name: name, Email: fmt.Sprintf("%s-%d@statuses.test", name, stamp), PasswordHash: "x", Role: "freelancer"}
+		assert.NoError(t, db.Create(&freelancer).Error)
+		proposal := models.Proposal{ProposalText: name, EstimatedDuration: 10, BidAmount: money.New(100000, "EUR"),
+			ProjectID: projectID, FreelancerID: freelancer.ID}
+		assert.NoError(t, propService.CreateProposal(&proposal))
+		return proposal
+	}
+	status := func(id uint) models.Proposal {
+		got, err := propService.GetProposalByID(id)
+		assert.NoError(t, err)
+		return *got
+	}
+	chosen, withdrawn, rejected, other := bid(project.ID, "chosen"), bid(project.ID, "withdrawn"), bid(project.ID, "rejected"), bid(project.ID, "other")
+
+	// 1) The client shortlists and rejects, the freelancer withdraws
+	_, err := propService.ShortlistProposal(chosen.ID, chosen.FreelancerID, "")
+	assert.ErrorIs(t, err, services.ErrClientOnly)
+	shortlisted, err := propService.ShortlistProposal(chosen.ID, client.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "shortlisted", shortlisted.Status)
+
+	_, err = propService.WithdrawProposal(withdrawn.ID, client.ID, "")
+	assert.ErrorIs(t, err, services.ErrFreelancerOnly)
+	_, err = propService.WithdrawProposal(withdrawn.ID, withdrawn.FreelancerID, "Booked elsewhere")
+	assert.NoError(t, err)
+	assert.Equal(t, "withdrawn", status(withdrawn.ID).Status)
+	assert.Equal(t, "Booked elsewhere", status(withdrawn.ID).StatusReason)
+
+	_, err = propService.RejectProposal(rejected.ID, client.ID, "Over budget")
+	assert.NoError(t, err)
+	assert.Equal(t, "Over budget", status(rejected.ID).StatusReason)
+
+	// 2) Closed proposals stay closed
+	_, err = propService.ShortlistProposal(rejected.ID, client.ID, "")
+	assert.ErrorIs(t, err, services.ErrProposalClosed)
+	_, err = propService.CounterOffer(withdrawn.ID, client.ID, money.New(90000, "EUR"), 10, "")
+	assert.ErrorIs(t, err, services.ErrProposalClosed)
+
+	var notes []models.Notification
+	assert.NoError(t, db.Where("user_id = ? AND type = ?", rejected.FreelancerID, "proposal_update").Find(&notes).Error)
+	assert.Len(t, notes, 1)
+
+	// 3) Accepting the shortlisted proposal declines the other open one
+	assert.NoError(t, propService.AcceptProposal(&chosen, client.ID, false))
+	assert.Equal(t, "accepted", chosen.Status)
+	assert.Equal(t, "declined", status(other.ID).Status)
+	assert.Equal(t, "withdrawn", status(withdrawn.ID).Status)
+
+	// 4) Closing a project expires its open proposals
+	closing := models.Project{Title: "Closing", Description: "Closing", Duration: 30, ClientID: client.ID}
+	assert.NoError(t, db.Create(&closing).Error)
+	open := bid(closing.ID, "late")
+	closing.Status = "cancelled"
+	assert.NoError(t, projService.UpdateProject(&closing))
+	expired := status(open.ID)
+	assert.Equal(t, "expired", expired.Status)
+	assert.NotNil(t, expired.StatusChangedAt)
+
+	// 5) A proposal still open on a cancelled project can't be accepted
+	cancelled := models.Project{Title: "Cancelled", Description: "Cancelled", Duration: 30, ClientID: client.ID}
+	assert.NoError(t, db.Create(&cancelled).Error)
+	stranded := bid(cancelled.ID, "stranded")
+	assert.NoError(t, db.Model(&cancelled).Update("status", "cancelled").Error)
+	assert.ErrorIs(t, propService.AcceptProposal(&stranded, client.ID, false), services.ErrProjectNotOpen)
+	assert.Equal(t, "pending", status(stranded.ID).Status)
+	assert.NoError(t, db.First(&cancelled, cancelled.ID).Error)
+	assert.Nil(t, cancelled.FreelancerID)
+}
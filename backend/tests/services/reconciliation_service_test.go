@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestReconciliationService(t *testing.T) {
+	db := tests.SetupTestDB()
+	gateway := payments.NewFakeGateway()
+	txService := services.NewTransactionService(repositories.NewTransactionRepository(db), gateway)
+	recon := services.NewReconciliationService(repositories.NewReconciliationRepository(db), txService, gateway)
+
+	// A completed transaction whose reversed amount was tampered with
+	tx := models.Transaction{Amount: money.New(4000, "EUR"), PaymentMethod: "paypal", ClientID: 1, FreelancerID: 2, ProjectID: 3}
+	assert.NoError(t, txService.CreateTransaction(&tx, payments.FakeTokenSuccess))
+	_, err := txService.CaptureTransaction(tx.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Model(&models.Transaction{}).Where("transaction_id = ?", tx.ID).
+		Update("reversed_amount_amount", 123).Error)
+
+	// A pending transaction the gateway has already captured
+	open := models.Transaction{Amount: money.New(1500, "EUR"), PaymentMethod: "paypal", ClientID: 1, FreelancerID: 2, ProjectID: 3}
+	assert.NoError(t, txService.CreateTransaction(&open, payments.FakeTokenSuccess))
+	_, err = gateway.Capture(open.GatewayReference)
+	assert.NoError(t, err)
+
+	// A pending invoice past its due date
+	invoice := models.Invoice{
+		InvoiceNumber: fmt.Sprintf("REC-%d", time.Now().UnixNano()),
+		Status:        "finalised",
+		AmountDue:     money.New(1000, "EUR"),
+		PaymentStatus: "pending",
+		DueDate:       time.Now().AddDate(0, 0, -3),
+		ProjectID:     3,
+		ClientID:      1,
+	}
+	assert.NoError(t, db.Create(&invoice).Error)
+
+	// A user whose stored earnings don't match the ledger
+	drifted := models.User{Name: "Drift", Email: fmt.Sprintf("drift-%d@recon.test", time.Now().UnixNano()),
+		PasswordHash: "x", Role: "freelancer", Earnings: money.New(999, "EUR")}
+	assert.NoError(t, db.Create(&drifted).Error)
+
+	// 1) A report-only run finds everything and changes nothing
+	run, err := recon.Run("cli", false)
+	assert.NoError(t, err)
+	find := func(run *models.ReconciliationRun, kind string, id uint) *models.Discrepancy {
+		for i, d := range run.Discrepancies {
+			if d.Kind == kind && d.EntityID == id {
+				return &run.Discrepancies[i]
+			}
+		}
+		return nil
+	}
+	assert.NotNil(t, find(run, services.DiscrepancyReversedAmount, tx.ID))
+	assert.NotNil(t, find(run, services.DiscrepancyGatewayStatus, open.ID))
+	assert.NotNil(t, find(run, services.DiscrepancyInvoiceOverdue, invoice.ID))
+	earningsGap := find(run, services.DiscrepancyEarnings, drifted.ID)
+	if assert.NotNil(t, earningsGap) {
+		assert.False(t, earningsGap.Fixable)
+		_, err = recon.FixDiscrepancy(earningsGap.ID)
+		assert.ErrorIs(t, err, services.ErrNotFixable)
+	}
+
+	stored, err := recon.GetRun(run.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, run.DiscrepancyCount, len(stored.Discrepancies))
+
+	// 2) An auto-fix run corrects the safe cases
+	run, err = recon.Run("admin", true)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, run.FixedCount, 3)
+
+	got, err := txService.GetTransactionByID(tx.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, got.ReversedAmount.Amount)
+	got, err = txService.GetTransactionByID(open.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", got.Status)
+	var inv models.Invoice
+	assert.NoError(t, db.First(&inv, invoice.ID).Error)
+	assert.Equal(t, "overdue", inv.PaymentStatus)
+
+	// 3) The drifted balance is still reported
+	run, err = recon.Run("cli", true)
+	assert.NoError(t, err)
+	assert.NotNil(t, find(run, services.DiscrepancyEarnings, drifted.ID))
+}
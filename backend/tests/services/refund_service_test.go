@@ -0,0 +1,132 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestRefundsAndChargebacks(t *testing.T) {
+	db := tests.SetupTestDB()
+	txService := services.NewTransactionService(repositories.NewTransactionRepository(db), payments.NewFakeGateway())
+
+	completed := func(amount int64) models.Transaction {
+		tx := models.Transaction{
+			Amount:        money.New(amount, "EUR"),
+			PaymentMethod: "credit_card",
+			ClientID:      1,
+			FreelancerID:  2,
+			ProjectID:     3,
+		}
+		assert.NoError(t, txService.CreateTransaction(&tx, payments.FakeTokenSuccess))
+		_, err := txService.CaptureTransaction(tx.ID)
+		assert.NoError(t, err)
+		return tx
+	}
+	earnings := func() int64 {
+		var u models.User
+		assert.NoError(t, db.First(&u, 2).Error)
+		return u.Earnings.Amount
+	}
+
+	// 1) A pending transaction can't be refunded
+	pending := models.Transaction{Amount: money.New(1000, "EUR"), PaymentMethod: "credit_card", ClientID: 1, FreelancerID: 2, ProjectID: 3}
+	assert.NoError(t, txService.CreateTransaction(&pending, payments.FakeTokenSuccess))
+	_, err := txService.RefundTransaction(pending.ID, money.New(100, "EUR"), "not delivered")
+	assert.ErrorIs(t, err, services.ErrNotReversible)
+
+	// 2) Partial refund reverses part of the earnings
+	tx := completed(10000)
+	start := earnings()
+	refund, err := txService.RefundTransaction(tx.ID, money.New(3000, "EUR"), "partly delivered")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3000), refund.Amount.Amount)
+	assert.NotEmpty(t, refund.GatewayReference)
+	assert.Equal(t, start-refund.SettlementAmount.Amount, earnings())
+
+	got, err := txService.GetTransactionByID(tx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", got.Status)
+	assert.Equal(t, int64(3000), got.ReversedAmount.Amount)
+
+	// 3) Refunding more than is left fails; refunding the rest marks it refunded
+	_, err = txService.RefundTransaction(tx.ID, money.New(8000, "EUR"), "too much")
+	assert.ErrorIs(t, err, services.ErrReversalTooLarge)
+	_, err = txService.RefundTransaction(tx.ID, money.Money{}, "cancelled")
+	assert.NoError(t, err)
+
+	got, err = txService.GetTransactionByID(tx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "refunded", got.Status)
+	refunds, err := txService.GetRefunds(tx.ID)
+	assert.NoError(t, err)
+	assert.Len(t, refunds, 2)
+	assert.Equal(t, start-(got.SettlementAmount.Amount-got.FeeSettlement.Amount), earnings())
+
+	// 4) A chargeback claws back the balance and flags both accounts
+	cb := completed(5000)
+	start = earnings()
+	chargeback, err := txService.RecordChargeback(cb.ID, money.Money{}, "fraudulent", "dp_123")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5000), chargeback.Amount.Amount)
+	assert.Equal(t, start-chargeback.SettlementAmount.Amount, earnings())
+
+	got, err = txService.GetTransactionByID(cb.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "charged_back", got.Status)
+
+	var client, freelancer models.User
+	assert.NoError(t, db.First(&client, 1).Error)
+	assert.NoError(t, db.First(&freelancer, 2).Error)
+	assert.True(t, client.Flagged)
+	assert.True(t, freelancer.Flagged)
+
+	// 5) A reason is always required
+	_, err = txService.RecordChargeback(completed(1000).ID, money.Money{}, "", "")
+	assert.ErrorIs(t, err, services.ErrReasonRequired)
+
+	// 6) A refund the gateway made but that wasn't booked holds its amount
+	// until it is settled, exactly once
+	stuck := completed(2000)
+	lost := models.Refund{TransactionID: stuck.ID, Amount: money.New(2000, "EUR"), Reason: "booking failed",
+		Status: "pending", GatewayReference: "fake_re_lost"}
+	assert.NoError(t, db.Create(&lost).Error)
+	_, err = txService.RefundTransaction(stuck.ID, money.New(100, "EUR"), "one more")
+	assert.ErrorIs(t, err, services.ErrReversalTooLarge)
+
+	start = earnings()
+	settled, err := txService.SettleRefund(lost.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "succeeded", settled.Status)
+	_, err = txService.SettleRefund(lost.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, start-settled.SettlementAmount.Amount, earnings())
+
+	got, err = txService.GetTransactionByID(stuck.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "refunded", got.Status)
+
+	// 7) A transaction from before settlement amounts were stored refunds
+	// what it was credited once the migration backfilled it
+	legacy := completed(4000)
+	assert.NoError(t, db.Exec(`UPDATE transactions SET settlement_amount_amount = 0, settlement_amount_currency = 'EUR',
+		fee_amount_amount = 0, fee_settlement_amount = 0 WHERE transaction_id = ?`, legacy.ID).Error)
+	assert.NoError(t, models.Migrate(db))
+	got, err = txService.GetTransactionByID(legacy.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(4000, "EUR"), got.SettlementAmount)
+	assert.Equal(t, money.Zero("EUR"), got.FeeSettlement)
+
+	start = earnings()
+	refund, err = txService.RefundTransaction(legacy.ID, money.Money{}, "legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4000), refund.SettlementAmount.Amount)
+	assert.Equal(t, start-4000, earnings())
+}
@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestDetermineTax(t *testing.T) {
+	de := &models.TaxRule{Country: "DE", Name: "VAT", Rate: 1900, EUMember: true}
+	fr := &models.TaxRule{Country: "FR", Name: "VAT", Rate: 2000, EUMember: true}
+	ch := &models.TaxRule{Country: "CH", Name: "VAT", Rate: 810}
+
+	issuer := &models.TaxProfile{Country: "DE", IsBusiness: true, VATID: "DE123456789"}
+	cases := []struct {
+		name       string
+		issuer     *models.TaxProfile
+		client     *models.TaxProfile
+		clientRule *models.TaxRule
+		treatment  string
+		rate       int64
+	}{
+		{"no issuer profile", nil, nil, nil, services.TaxNone, 0},
+		{"exempt issuer", &models.TaxProfile{Country: "DE", TaxExempt: true}, nil, nil, services.TaxExempt, 0},
+		{"unknown client", issuer, nil, nil, services.TaxStandard, 1900},
+		{"domestic business", issuer, &models.TaxProfile{Country: "DE", IsBusiness: true, VATID: "DE987654321"}, de, services.TaxStandard, 1900},
+		{"EU business", issuer, &models.TaxProfile{Country: "FR", IsBusiness: true, VATID: "FR12345678901"}, fr, services.TaxReverseCharge, 0},
+		{"EU business without VAT ID", issuer, &models.TaxProfile{Country: "FR", IsBusiness: true}, fr, services.TaxStandard, 1900},
+		{"EU consumer", issuer, &models.TaxProfile{Country: "FR"}, fr, services.TaxStandard, 1900},
+		{"non-EU business", issuer, &models.TaxProfile{Country: "CH", IsBusiness: true}, ch, services.TaxOutsideScope, 0},
+		{"non-EU consumer", issuer, &models.TaxProfile{Country: "US"}, nil, services.TaxStandard, 1900},
+	}
+	for _, tc := range cases {
+		d := services.DetermineTax(tc.issuer, tc.client, de, tc.clientRule)
+		assert.Equal(t, tc.treatment, d.Treatment, tc.name)
+		assert.Equal(t, tc.rate, d.Rate, tc.name)
+	}
+
+	vatID, err := services.NormalizeVATID("de 123.456.789", "DE")
+	assert.NoError(t, err)
+	assert.Equal(t, "DE123456789", vatID)
+	_, err = services.NormalizeVATID("EL123456789", "GR")
+	assert.NoError(t, err)
+	_, err = services.NormalizeVATID("FR12345678901", "DE")
+	assert.ErrorIs(t, err, services.ErrInvalidVATID)
+}
+
+func TestInvoiceTax(t *testing.T) {
+	db := tests.SetupTestDB()
+	invoiceRepo := repositories.NewInvoiceRepository(db)
+	invoices := services.NewInvoiceService(invoiceRepo, services.InvoiceNumbering{})
+	taxes := services.NewTaxService(repositories.NewTaxRepository(db), invoiceRepo,
+		services.NewExchangeRateService(repositories.NewExchangeRateRepository(db)))
+
+	stamp := time.Now().UnixNano()
+	newUser := func(role string) models.User {
+		u := models.User{Name: role, Email: fmt.Sprintf("%s-%d@tax.test", role, stamp), PasswordHash: "x", Role: role}
+		assert.NoError(t, db.Create(&u).Error)
+		return u
+	}
+	freelancer := newUser("freelancer")
+	client := newUser("client")
+	project := models.Project{Title: "Tax", Description: "Tax", Duration: 10, ClientID: client.ID, FreelancerID: &freelancer.ID}
+	assert.NoError(t, db.Create(&project).Error)
+
+	assert.NoError(t, taxes.SaveProfile(&models.TaxProfile{UserID: freelancer.ID, Country: "de", VATID: "DE123456789", IsBusiness: true}))
+	assert.ErrorIs(t, taxes.SaveProfile(&models.TaxProfile{UserID: client.ID, Country: "FR", VATID: "DE1"}), services.ErrInvalidVATID)
+	assert.NoError(t, taxes.SaveProfile(&models.TaxProfile{UserID: client.ID, Country: "FR"}))
+
+	// 1) A French consumer pays German VAT; the issuer defaults to the freelancer
+	invoice := models.Invoice{
+		NetAmount: money.New(100000, "EUR"),
+		DueDate:   time.Now().AddDate(0, 0, 14),
+		ProjectID: project.ID,
+		ClientID:  client.ID,
+	}
+	assert.NoError(t, invoices.CreateInvoice(&invoice))
+	assert.Equal(t, freelancer.ID, *invoice.IssuerID)
+	assert.Equal(t, services.TaxStandard, invoice.TaxTreatment)
+	assert.Equal(t, int64(19000), invoice.TaxAmount.Amount)
+	assert.Equal(t, int64(119000), invoice.AmountDue.Amount)
+
+	// 2) Changing the amount keeps the treatment and recalculates the tax
+	invoice.NetAmount = money.New(50000, "EUR")
+	assert.NoError(t, invoices.UpdateInvoice(&invoice))
+	assert.Equal(t, int64(59500), invoice.AmountDue.Amount)
+
+	// 3) A French business with a VAT ID is reverse charged
+	assert.NoError(t, taxes.SaveProfile(&models.TaxProfile{UserID: client.ID, Country: "FR", VATID: "FR12345678901", IsBusiness: true}))
+	b2b := invoice
+	b2b.ID = 0
+	assert.NoError(t, invoices.CreateInvoice(&b2b))
+	assert.Equal(t, services.TaxReverseCharge, b2b.TaxTreatment)
+	assert.True(t, b2b.TaxAmount.IsZero())
+	assert.Equal(t, int64(50000), b2b.AmountDue.Amount)
+	assert.Contains(t, b2b.TaxNote, "FR12345678901")
+
+	// 4) The summary groups both once they are issued
+	var report *services.TaxSummaryReport
+	_, err := invoices.FinaliseInvoice(invoice.ID)
+	assert.NoError(t, err)
+	_, err = invoices.FinaliseInvoice(b2b.ID)
+	assert.NoError(t, err)
+	report, err = taxes.Summary(time.Now().AddDate(0, 0, -1), time.Now().AddDate(0, 0, 1), "EUR")
+	assert.NoError(t, err)
+	var found int
+	for _, line := range report.Lines {
+		if line.Treatment == services.TaxStandard && line.Country == "DE" && line.Rate == 1900 {
+			found++
+			assert.GreaterOrEqual(t, line.Tax.Amount, int64(9500))
+		}
+		if line.Treatment == services.TaxReverseCharge && line.Country == "FR" {
+			found++
+			assert.True(t, line.Tax.IsZero())
+		}
+	}
+	assert.Equal(t, 2, found)
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
 	"FreeConnect/internal/repositories"
 	"FreeConnect/internal/services"
 	"FreeConnect/tests"
@@ -15,11 +17,12 @@ import (
 func TestTransactionService(t *testing.T) {
 	db := tests.SetupTestDB()
 	txRepo := repositories.NewTransactionRepository(db)
-	txService := services.NewTransactionService(txRepo)
+	gateway := payments.NewFakeGateway()
+	txService := services.NewTransactionService(txRepo, gateway)
 
 	// 1) Create a Transaction
 	tx := models.Transaction{
-		Amount:        250.0,
+		Amount:        money.New(25000, "EUR"),
 		Date:          time.Now(),
 		PaymentMethod: "bank_transfer",
 		Status:        "pending",
@@ -28,29 +31,74 @@ func TestTransactionService(t *testing.T) {
 		ProjectID:     3,
 	}
 
-	err := txService.CreateTransaction(&tx)
+	err := txService.CreateTransaction(&tx, payments.FakeTokenSuccess)
 	assert.NoError(t, err)
 	assert.NotZero(t, tx.ID)
+	assert.Equal(t, "pending", tx.Status)
+	assert.NotEmpty(t, tx.GatewayReference)
 
 	// 2) Retrieve
 	got, err := txService.GetTransactionByID(tx.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, 250.0, got.Amount)
+	assert.Equal(t, int64(25000), got.Amount.Amount)
 
-	// 3) Update to completed
-	tx.Status = "completed"
-	err = txService.UpdateTransaction(&tx)
+	// 3) Status can't be set by hand
+	got.Status = "completed"
+	err = txService.UpdateTransaction(got)
+	assert.ErrorIs(t, err, services.ErrStatusManagedByGateway)
+
+	// 4) Capturing through the gateway completes it
+	captured, err := txService.CaptureTransaction(tx.ID)
 	assert.NoError(t, err)
+	assert.Equal(t, "completed", captured.Status)
 
 	updated, err := txService.GetTransactionByID(tx.ID)
 	assert.NoError(t, err)
 	assert.Equal(t, "completed", updated.Status)
 
-	// 4) Delete
-	err = txService.DeleteTransaction(tx.ID)
+	// 5) A declined payment is stored as failed
+	declined := models.Transaction{
+		Amount:        money.New(1000, "EUR"),
+		PaymentMethod: "credit_card",
+		ClientID:      1,
+		FreelancerID:  2,
+		ProjectID:     3,
+	}
+	err = txService.CreateTransaction(&declined, payments.FakeTokenDecline)
 	assert.NoError(t, err)
+	assert.Equal(t, "failed", declined.Status)
+	assert.Equal(t, "card_declined", declined.FailureReason)
+
+	// 6) A delayed capture stays processing until the gateway settles it
+	now := time.Now()
+	gateway.Now = func() time.Time { return now }
+	delayed := models.Transaction{
+		Amount:        money.New(1000, "EUR"),
+		PaymentMethod: "paypal",
+		ClientID:      1,
+		FreelancerID:  2,
+		ProjectID:     3,
+	}
+	err = txService.CreateTransaction(&delayed, payments.FakeTokenDelay)
+	assert.NoError(t, err)
+	processing, err := txService.CaptureTransaction(delayed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "processing", processing.Status)
+
+	now = now.Add(gateway.Delay)
+	settled, err := txService.SyncTransactionStatus(delayed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", settled.Status)
+
+	// 7) Payments that reached the gateway stay for the audit trail
+	err = txService.DeleteTransaction(tx.ID)
+	assert.ErrorIs(t, err, services.ErrTransactionInLedger)
 
-	// 5) Confirm
-	_, err = txService.GetTransactionByID(tx.ID)
+	// 8) Only a transaction that never reached the gateway can be deleted
+	draft := models.Transaction{Amount: money.New(1000, "EUR"), Date: time.Now(), PaymentMethod: "paypal",
+		Status: "pending", ClientID: 1, FreelancerID: 2, ProjectID: 3}
+	assert.NoError(t, db.Create(&draft).Error)
+	assert.NoError(t, txService.DeleteTransaction(draft.ID))
+	_, err = txService.GetTransactionByID(draft.ID)
 	assert.Error(t, err)
 }
@@ -0,0 +1,91 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestTransactionTransitions(t *testing.T) {
+	allowed := [][2]string{
+		{"pending", "processing"},
+		{"pending", "failed"},
+		{"processing", "completed"},
+		{"processing", "failed"},
+		{"failed", "pending"},
+		{"completed", "refunded"},
+		{"completed", "charged_back"},
+	}
+	for _, tr := range allowed {
+		assert.True(t, services.CanTransition(tr[0], tr[1]), "%s -> %s", tr[0], tr[1])
+	}
+
+	forbidden := [][2]string{
+		{"completed", "pending"},
+		{"completed", "processing"},
+		{"failed", "completed"},
+		{"refunded", "completed"},
+		{"pending", "refunded"},
+	}
+	for _, tr := range forbidden {
+		assert.False(t, services.CanTransition(tr[0], tr[1]), "%s -> %s", tr[0], tr[1])
+	}
+}
+
+func TestTransactionRetryAndHistory(t *testing.T) {
+	db := tests.SetupTestDB()
+	gateway := payments.NewFakeGateway()
+	txService := services.NewTransactionService(repositories.NewTransactionRepository(db), gateway)
+
+	earnings := func() int64 {
+		var u models.User
+		assert.NoError(t, db.First(&u, 2).Error)
+		return u.Earnings.Amount
+	}
+
+	// 1) Declined, then retried with a working card
+	tx := models.Transaction{Amount: money.New(2000, "EUR"), PaymentMethod: "credit_card", ClientID: 1, FreelancerID: 2, ProjectID: 3}
+	assert.NoError(t, txService.CreateTransaction(&tx, payments.FakeTokenDecline))
+	assert.Equal(t, "failed", tx.Status)
+
+	_, err := txService.CaptureTransaction(tx.ID)
+	assert.Error(t, err)
+
+	retried, err := txService.RetryTransaction(tx.ID, payments.FakeTokenSuccess)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", retried.Status)
+	assert.Equal(t, 1, retried.RetryCount)
+
+	_, err = txService.RetryTransaction(tx.ID, payments.FakeTokenSuccess)
+	assert.ErrorIs(t, err, services.ErrInvalidTransition)
+
+	// 2) Completing credits the balance exactly once
+	start := earnings()
+	_, err = txService.CaptureTransaction(tx.ID)
+	assert.NoError(t, err)
+	credited := earnings()
+	assert.Equal(t, start+2000-tx.FeeSettlement.Amount, credited)
+
+	_, err = txService.SyncTransactionStatus(tx.ID)
+	assert.NoError(t, err)
+	_, err = txService.CaptureTransaction(tx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, credited, earnings())
+
+	// 3) Every step is recorded with its reason
+	history, err := txService.GetStatusHistory(tx.ID)
+	assert.NoError(t, err)
+	var steps []string
+	for _, h := range history {
+		steps = append(steps, h.FromStatus+">"+h.ToStatus)
+	}
+	assert.Equal(t, []string{">pending", "pending>failed", "failed>pending", "pending>processing", "processing>completed"}, steps)
+	assert.Equal(t, "card_declined", history[1].Reason)
+}
@@ -0,0 +1,89 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"FreeConnect/internal/models"
+	"FreeConnect/internal/money"
+	"FreeConnect/internal/payments"
+	"FreeConnect/internal/repositories"
+	"FreeConnect/internal/services"
+	"FreeConnect/tests"
+)
+
+func TestWebhookService(t *testing.T) {
+	db := tests.SetupTestDB()
+	gateway := payments.NewFakeGateway()
+	txService := services.NewTransactionService(repositories.NewTransactionRepository(db), gateway)
+	secret := "whsec_test"
+	webhookService := services.NewWebhookService(repositories.NewWebhookRepository(db), txService, secret)
+
+	// A pending transaction waiting for the provider
+	tx := models.Transaction{
+		Amount:        money.New(5000, "EUR"),
+		PaymentMethod: "credit_card",
+		ClientID:      1,
+		FreelancerID:  2,
+		ProjectID:     3,
+	}
+	assert.NoError(t, txService.CreateTransaction(&tx, payments.FakeTokenSuccess))
+
+	eventID := fmt.Sprintf("evt_%d", time.Now().UnixNano())
+	body := []byte(fmt.Sprintf(`{"id":%q,"type":"payment_intent.succeeded","data":{"intent_id":%q,"status":"succeeded"}}`, eventID, tx.GatewayReference))
+
+	// 1) Bad signature is rejected before anything is stored
+	_, _, err := webhookService.Receive("fake", "t=1,v1=deadbeef", body)
+	assert.ErrorIs(t, err, payments.ErrInvalidSignature)
+
+	// 2) Valid event completes the transaction
+	event, duplicate, err := webhookService.Receive("fake", payments.SignPayload(secret, body, time.Now()), body)
+	assert.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, "processed", event.Status)
+
+	updated, err := txService.GetTransactionByID(tx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", updated.Status)
+
+	// 3) Redelivery is deduplicated
+	_, duplicate, err = webhookService.Receive("fake", payments.SignPayload(secret, body, time.Now()), body)
+	assert.NoError(t, err)
+	assert.True(t, duplicate)
+
+	// 4) A late event for a state the payment already passed is acknowledged
+	// without effect
+	lateID := eventID + "_late"
+	late := []byte(fmt.Sprintf(`{"id":%q,"type":"payment_intent.processing","data":{"intent_id":%q,"status":"processing"}}`, lateID, tx.GatewayReference))
+	event, duplicate, err = webhookService.Receive("fake", payments.SignPayload(secret, late, time.Now()), late)
+	assert.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, "processed", event.Status)
+	updated, err = txService.GetTransactionByID(tx.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", updated.Status)
+
+	// 5) An event for an unknown intent is dead-lettered and can be reprocessed
+	orphanID := eventID + "_orphan"
+	orphan := []byte(fmt.Sprintf(`{"id":%q,"type":"payment_intent.succeeded","data":{"intent_id":"missing","status":"succeeded"}}`, orphanID))
+	failed, _, err := webhookService.Receive("fake", payments.SignPayload(secret, orphan, time.Now()), orphan)
+	assert.ErrorIs(t, err, services.ErrWebhookProcessing)
+	assert.Equal(t, "failed", failed.Status)
+
+	letters, err := webhookService.GetOpenDeadLetters()
+	assert.NoError(t, err)
+	found := false
+	for _, dl := range letters {
+		if dl.WebhookEventID == failed.ID {
+			found = true
+		}
+	}
+	assert.True(t, found, "failed event should be in the dead-letter table")
+
+	replayed, err := webhookService.Reprocess(failed.ID)
+	assert.ErrorIs(t, err, services.ErrWebhookProcessing)
+	assert.Equal(t, 2, replayed.Attempts)
+}
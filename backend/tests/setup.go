@@ -29,18 +29,8 @@ func SetupTestDB() *gorm.DB {
 		log.Fatalf("Failed to connect to test DB: %v", err)
 	}
 
-	// 4. AutoMigrate
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Project{},
-		&models.Skill{},
-		&models.Proposal{},
-		&models.Review{},
-		&models.Transaction{},
-		&models.Task{},
-		&models.Notification{},
-		&models.Invoice{},
-	)
+	// 4. Migrate (data migrations + AutoMigrate)
+	err = models.Migrate(db)
 	if err != nil {
 		log.Fatalf("Failed to migrate test DB: %v", err)
 	}
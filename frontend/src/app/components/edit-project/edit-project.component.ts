@@ -56,7 +56,7 @@ export class EditProjectComponent implements OnInit {
         // Patch the formData with existing project info
         this.formData.title = project.title;
         this.formData.description = project.description;
-        this.formData.budget = project.budget;
+        this.formData.budget = Number(project.budget?.amount ?? 0);
         this.formData.duration = project.duration;
       },
       error: (err) => {
@@ -65,7 +65,7 @@ export class EditTaskComponent implements OnInit {
         const dateObj = new Date(task.deadline);
         this.formData.deadline = dateObj.toISOString().split('T')[0];
 
-        this.formData.budget = task.budget;
+        this.formData.budget = Number(task.budget?.amount ?? 0);
         this.formData.status = task.status || 'open';
       },
       error: (err) => {